@@ -4,5 +4,5 @@ type Actor struct {
 	UID   string        `json:"uid,omitempty"`
 	DType []string      `json:"dgraph.type,omitempty"`
 	Name  string        `json:"name,omitempty" dgraph:"index=hash,term,trigram,fulltext"`
-	Films []Performance `json:"films,omitempty" dgraph:"predicate=actor.film count"`
+	Films []Performance `json:"films,omitempty" dgraph:"predicate=actor.film reverse count"`
 }
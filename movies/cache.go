@@ -0,0 +1,265 @@
+package movies
+
+import (
+	"container/list"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Cache stores the encoded results of reads, grouped by the entity type
+// read. Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(typ, key string) ([]byte, bool)
+	Set(typ, key string, value []byte)
+	// Invalidate drops every entry of typ.
+	Invalidate(typ string)
+}
+
+// CacheStats counts a client's cache use for one entity type. Entries is
+// filled in when the Cache has a Len(typ string) int method, as LRUCache
+// does.
+type CacheStats struct {
+	Type          string  `json:"type"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	HitRate       float64 `json:"hitRate"`
+	Invalidations int64   `json:"invalidations"`
+	Entries       int     `json:"entries,omitempty"`
+}
+
+// CacheStats returns the cache statistics of every entity type, by type
+// name, or nil without a cache.
+func (c *Client) CacheStats() []CacheStats {
+	t, ok := c.conn.(*tracingConn)
+	if !ok || t.cache == nil {
+		return nil
+	}
+	return t.cache.stats()
+}
+
+// cache is a client's read-through cache: the Cache, what depends on
+// each type, and the statistics.
+type cache struct {
+	store Cache
+
+	mu     sync.Mutex
+	counts map[string]*CacheStats
+	// gens counts the invalidations of each type, so that a read that
+	// raced with a change does not store what it read.
+	gens map[string]uint64
+}
+
+func newCache(store Cache) *cache {
+	return &cache{store: store, counts: map[string]*CacheStats{}, gens: map[string]uint64{}}
+}
+
+// get returns the entry of typ at key. On a miss it returns the
+// generation to pass to set.
+func (c *cache) get(typ, key string) (b []byte, gen uint64, hit bool) {
+	b, ok := c.store.Get(typ, key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.count(typ)
+	if ok {
+		s.Hits++
+	} else {
+		s.Misses++
+	}
+	return b, c.gens[typ], ok
+}
+
+// set stores b, read at generation gen, unless typ was invalidated since.
+func (c *cache) set(typ, key string, gen uint64, b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gens[typ] == gen {
+		c.store.Set(typ, key, b)
+	}
+}
+
+// invalidate drops the entries of typ and of the types whose results can
+// hold a typ through their edges, after a change to a typ.
+func (c *cache) invalidate(typ string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, t := range dependents()[typ] {
+		c.gens[t]++
+		c.count(t).Invalidations++
+		c.store.Invalidate(t)
+	}
+}
+
+func (c *cache) count(typ string) *CacheStats {
+	s, ok := c.counts[typ]
+	if !ok {
+		s = &CacheStats{Type: typ}
+		c.counts[typ] = s
+	}
+	return s
+}
+
+func (c *cache) stats() []CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sizer, _ := c.store.(interface{ Len(typ string) int })
+	var out []CacheStats
+	for _, v := range entities {
+		typ := reflect.TypeOf(v).Elem().Name()
+		s := *c.count(typ)
+		if reads := s.Hits + s.Misses; reads > 0 {
+			s.HitRate = float64(s.Hits) / float64(reads)
+		}
+		if sizer != nil {
+			s.Entries = sizer.Len(typ)
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// dependents maps each entity type to itself and the types that reach it
+// through edges, directly or not: a Genre's films carry their names, so
+// renaming a Film changes what reading a Genre returns.
+var dependents = sync.OnceValue(func() map[string][]string {
+	reach := map[string][]string{}
+	for _, v := range entities {
+		t := reflect.TypeOf(v).Elem()
+		seen := map[reflect.Type]bool{t: true}
+		var walk func(reflect.Type)
+		walk = func(t reflect.Type) {
+			for _, f := range reflect.VisibleFields(t) {
+				ft := f.Type
+				for ft.Kind() == reflect.Slice || ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if ft.Kind() != reflect.Struct || seen[ft] {
+					continue
+				}
+				if _, ok := ft.FieldByName("UID"); ok {
+					seen[ft] = true
+					walk(ft)
+				}
+			}
+		}
+		walk(t)
+		for r := range seen {
+			reach[r.Name()] = append(reach[r.Name()], t.Name())
+		}
+	}
+	for _, ts := range reach {
+		slices.Sort(ts)
+	}
+	return reach
+})
+
+// LRUConfig configures NewLRUCache.
+type LRUConfig struct {
+	// Size is the most entries kept per entity type; the default is 1000.
+	Size int
+	// Sizes overrides Size by type name, e.g. {"Film": 10000}.
+	Sizes map[string]int
+	// TTL is how long an entry is served after it is stored; zero keeps
+	// entries until they are evicted or invalidated.
+	TTL time.Duration
+}
+
+// LRUCache is an in-memory Cache that keeps the entries of each type in
+// their own least-recently-used list, so that reads of one type do not
+// evict another's.
+type LRUCache struct {
+	cfg LRUConfig
+
+	mu    sync.Mutex
+	lists map[string]*lruList
+}
+
+type lruList struct {
+	order *list.List // of *lruEntry, most recently used first
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRUCache returns an empty LRUCache.
+func NewLRUCache(cfg LRUConfig) *LRUCache {
+	return &LRUCache{cfg: cfg, lists: map[string]*lruList{}}
+}
+
+func (l *LRUCache) Get(typ, key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ll, ok := l.lists[typ]
+	if !ok {
+		return nil, false
+	}
+	el, ok := ll.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		ll.order.Remove(el)
+		delete(ll.items, key)
+		return nil, false
+	}
+	ll.order.MoveToFront(el)
+	return e.value, true
+}
+
+func (l *LRUCache) Set(typ, key string, value []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ll, ok := l.lists[typ]
+	if !ok {
+		ll = &lruList{order: list.New(), items: map[string]*list.Element{}}
+		l.lists[typ] = ll
+	}
+	e := &lruEntry{key: key, value: value}
+	if l.cfg.TTL > 0 {
+		e.expires = time.Now().Add(l.cfg.TTL)
+	}
+	if el, ok := ll.items[key]; ok {
+		el.Value = e
+		ll.order.MoveToFront(el)
+		return
+	}
+	ll.items[key] = ll.order.PushFront(e)
+	for ll.order.Len() > l.size(typ) {
+		oldest := ll.order.Back()
+		ll.order.Remove(oldest)
+		delete(ll.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (l *LRUCache) Invalidate(typ string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.lists, typ)
+}
+
+// Len returns the number of entries of typ, including expired ones not
+// yet dropped.
+func (l *LRUCache) Len(typ string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ll, ok := l.lists[typ]; ok {
+		return ll.order.Len()
+	}
+	return 0
+}
+
+func (l *LRUCache) size(typ string) int {
+	if n, ok := l.cfg.Sizes[typ]; ok && n > 0 {
+		return n
+	}
+	if l.cfg.Size > 0 {
+		return l.cfg.Size
+	}
+	return 1000
+}
@@ -0,0 +1,107 @@
+package movies
+
+import (
+	"time"
+
+	"github.com/matthewmcneely/modusgraph"
+)
+
+// Option configures a Client made by New or NewFromClient.
+type Option interface {
+	applyClient(cfg *clientConfig)
+}
+
+type clientConfig struct {
+	conn    []modusgraph.ClientOpt
+	cache   Cache
+	timeout time.Duration
+	depth   int
+}
+
+// defaultEdgeDepth is how deep modusgraph expands edges unless told
+// otherwise.
+const defaultEdgeDepth = 10
+
+type optionFunc func(cfg *clientConfig)
+
+func (f optionFunc) applyClient(cfg *clientConfig) { f(cfg) }
+
+// WithClientOptions passes options to modusgraph.NewClient, e.g.
+// modusgraph.WithAutoSchema. NewFromClient ignores them.
+func WithClientOptions(opts ...modusgraph.ClientOpt) Option {
+	return optionFunc(func(cfg *clientConfig) { cfg.conn = append(cfg.conn, opts...) })
+}
+
+// WithMaxEdgeTraversal sets how deep queries expand edges, passing
+// modusgraph.WithMaxEdgeTraversal on to modusgraph.NewClient. Give the
+// depth here rather than through WithClientOptions: traced and cached
+// queries are built by the client, which cannot see modusgraph's options.
+// With NewFromClient, give the depth conn was made with.
+func WithMaxEdgeTraversal(n int) Option {
+	return optionFunc(func(cfg *clientConfig) {
+		cfg.conn = append(cfg.conn, modusgraph.WithMaxEdgeTraversal(n))
+		cfg.depth = n
+	})
+}
+
+// WithCache makes the sub-clients' Get, List and Search, and so the
+// iterators and query builders, read through c. Add, Update and Delete
+// made through the client drop the entries they may have changed; changes
+// made by other clients are seen once entries expire, so give c a TTL when
+// there are any.
+func WithCache(c Cache) Option {
+	return optionFunc(func(cfg *clientConfig) { cfg.cache = c })
+}
+
+// New creates a new Client connected to the graph database at connStr.
+// modusgraph's own options are given with WithClientOptions.
+func New(connStr string, opts ...Option) (*Client, error) {
+	cfg := clientConfig{depth: defaultEdgeDepth}
+	for _, opt := range opts {
+		opt.applyClient(&cfg)
+	}
+	conn, err := modusgraph.NewClient(connStr, cfg.conn...)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(conn, cfg), nil
+}
+
+// NewFromClient creates a new Client from an existing modusgraph.Client connection.
+func NewFromClient(conn modusgraph.Client, opts ...Option) *Client {
+	cfg := clientConfig{depth: defaultEdgeDepth}
+	for _, opt := range opts {
+		opt.applyClient(&cfg)
+	}
+	return newClient(conn, cfg)
+}
+
+// newClient wraps conn in the connection that carries the client's cache,
+// hooks, outbox and debugging, and gives it to every sub-client.
+func newClient(conn modusgraph.Client, cfg clientConfig) *Client {
+	if cfg.timeout > 0 {
+		conn = &timeoutConn{Client: conn, timeout: cfg.timeout}
+	}
+	t := &tracingConn{Client: conn, depth: cfg.depth}
+	if cfg.cache != nil {
+		t.cache = newCache(cfg.cache)
+	}
+	return &Client{
+		conn:          t,
+		Actor:         &ActorClient{conn: t},
+		ContentRating: &ContentRatingClient{conn: t},
+		Country:       &CountryClient{conn: t},
+		Director:      &DirectorClient{conn: t},
+		Film:          &FilmClient{conn: t},
+		Genre:         &GenreClient{conn: t},
+		Location:      &LocationClient{conn: t},
+		Performance:   &PerformanceClient{conn: t},
+		Rating:        &RatingClient{conn: t},
+	}
+}
+
+// ext returns the connection that carries the client's cache, hooks,
+// outbox and debugging.
+func (c *Client) ext() *tracingConn {
+	return c.conn.(*tracingConn)
+}
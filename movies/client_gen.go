@@ -22,31 +22,6 @@ type Client struct {
 	Rating        *RatingClient
 }
 
-// New creates a new Client connected to the graph database at connStr.
-func New(connStr string, opts ...modusgraph.ClientOpt) (*Client, error) {
-	conn, err := modusgraph.NewClient(connStr, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return NewFromClient(conn), nil
-}
-
-// NewFromClient creates a new Client from an existing modusgraph.Client connection.
-func NewFromClient(conn modusgraph.Client) *Client {
-	return &Client{
-		conn:          conn,
-		Actor:         &ActorClient{conn: conn},
-		ContentRating: &ContentRatingClient{conn: conn},
-		Country:       &CountryClient{conn: conn},
-		Director:      &DirectorClient{conn: conn},
-		Film:          &FilmClient{conn: conn},
-		Genre:         &GenreClient{conn: conn},
-		Location:      &LocationClient{conn: conn},
-		Performance:   &PerformanceClient{conn: conn},
-		Rating:        &RatingClient{conn: conn},
-	}
-}
-
 // QueryRaw executes a raw DQL query against the database.
 // The query parameter is the Dgraph query string (DQL syntax).
 // The vars parameter is an optional map of variable names to values for parameterized queries.
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+type ActorCoStarsCmd struct {
+	UID    string `arg:"" required:"" help:"The UID of the Actor."`
+	First  int    `help:"Maximum results to return." default:"10"`
+	Offset int    `help:"Number of results to skip." default:"0"`
+}
+
+func (c *ActorCoStarsCmd) Run(client *movies.Client) error {
+	results, err := client.Actor.CoStars(context.Background(), c.UID,
+		movies.First(c.First), movies.Offset(c.Offset))
+	if err != nil {
+		return err
+	}
+	return printResult(results)
+}
+
+type DirectorCollaboratorsCmd struct {
+	UID    string `arg:"" required:"" help:"The UID of the Director."`
+	First  int    `help:"Maximum results to return." default:"10"`
+	Offset int    `help:"Number of results to skip." default:"0"`
+}
+
+func (c *DirectorCollaboratorsCmd) Run(client *movies.Client) error {
+	results, err := client.Director.FrequentCollaborators(context.Background(), c.UID,
+		movies.First(c.First), movies.Offset(c.Offset))
+	if err != nil {
+		return err
+	}
+	return printResult(results)
+}
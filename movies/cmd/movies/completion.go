@@ -0,0 +1,647 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/matthewmcneely/modusgraph"
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+// CompletionCmd prints a completion script. The scripts call back into the
+// hidden __complete command, so subcommands, flags and their values are
+// completed from the same command tree the CLI parses with.
+type CompletionCmd struct {
+	Shell string `arg:"" enum:"bash,zsh,fish" help:"Shell to generate the script for: bash, zsh or fish."`
+}
+
+func (c *CompletionCmd) Run(kctx *kong.Context) error {
+	return completionScripts.ExecuteTemplate(os.Stdout, c.Shell, kctx.Model.Name)
+}
+
+var completionScripts = template.Must(template.New("").Parse(`
+{{- define "bash"}}# bash completion for {{.}}; add to ~/.bashrc:
+#   source <({{.}} completion bash)
+_{{.}}_complete() {
+    local IFS=$'\n' line
+    COMPREPLY=()
+    for line in $({{.}} __complete -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null); do
+        COMPREPLY+=("$(printf '%q' "${line%%$'\t'*}")")
+    done
+}
+complete -o default -F _{{.}}_complete {{.}}
+{{end}}
+{{- define "zsh"}}#compdef {{.}}
+# zsh completion for {{.}}; add to ~/.zshrc:
+#   source <({{.}} completion zsh)
+_{{.}}() {
+    local -a lines values descs
+    local line
+    lines=("${(@f)$({{.}} __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    for line in $lines; do
+        [[ -z $line ]] && continue
+        values+=("${line%%$'\t'*}")
+        if [[ $line == *$'\t'* ]]; then
+            descs+=("${line%%$'\t'*}  -- ${line#*$'\t'}")
+        else
+            descs+=("$line")
+        fi
+    done
+    if (( ${#values} == 0 )); then
+        _files
+        return
+    fi
+    # Candidates are already filtered; -U lets a UID replace a typed name.
+    compadd -U -l -d descs -a values
+}
+compdef _{{.}} {{.}}
+{{end}}
+{{- define "fish"}}# fish completion for {{.}}; install with:
+#   {{.}} completion fish > ~/.config/fish/completions/{{.}}.fish
+function __{{.}}_complete
+    set -l out ({{.}} __complete -- (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)
+    if test (count $out) -eq 0
+        __fish_complete_path (commandline -ct)
+        return
+    end
+    printf '%s\n' $out
+end
+complete -c {{.}} -f -a '(__{{.}}_complete)'
+{{end}}`))
+
+// CompleteCmd prints the completions for a partial command line, one per
+// line as "value<TAB>description". The last word is the one being
+// completed and may be empty.
+type CompleteCmd struct {
+	Words []string `arg:"" optional:"" passthrough:""`
+}
+
+func (c *CompleteCmd) Run(kctx *kong.Context) error {
+	words := c.Words
+	if len(words) > 0 && words[0] == "--" {
+		// The scripts end flag parsing with --; passthrough keeps it.
+		words = words[1:]
+	}
+	// Bash splits --flag=value at the =, and replaces only the text after
+	// it, so the flag is left off the candidates.
+	n := len(words)
+	bareValues := n > 0 && words[n-1] == "=" || n > 1 && words[n-2] == "="
+	words = joinAssignments(words)
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur, prior := words[len(words)-1], words[:len(words)-1]
+	defer func() {
+		if lookupConn != nil {
+			lookupConn.Close()
+		}
+	}()
+
+	// A flag waiting for its value is left out of the trace, which would
+	// fail on it, and its value completed instead.
+	var valueFlag string
+	if n := len(prior); n > 0 && strings.HasPrefix(prior[n-1], "-") && !strings.Contains(prior[n-1], "=") {
+		valueFlag, prior = prior[n-1], prior[:n-1]
+	}
+	tctx, err := kong.Trace(kctx.Kong, prior)
+	if err != nil || tctx.Error != nil {
+		return nil
+	}
+	// Apply what was typed so far, so that connection flags and profiles
+	// take effect for lookups. Errors only mean the line is incomplete.
+	_ = tctx.Reset()
+	_ = tctx.Resolve()
+	_, _ = tctx.Apply()
+
+	node := tctx.Selected()
+	if node == nil {
+		node = kctx.Model.Node
+	}
+	comp := &cliCompleter{node: node}
+	var out []completion
+	switch {
+	case valueFlag != "":
+		if f := comp.flag(valueFlag); f != nil && !f.IsBool() {
+			out = comp.flagValues(f, cur)
+		} else {
+			out = comp.positional(tctx, cur)
+		}
+	case strings.HasPrefix(cur, "-") && strings.Contains(cur, "="):
+		name, value, _ := strings.Cut(cur, "=")
+		if f := comp.flag(name); f != nil {
+			for _, v := range comp.flagValues(f, value) {
+				if !bareValues {
+					v.value = name + "=" + v.value
+				}
+				out = append(out, v)
+			}
+		}
+	case strings.HasPrefix(cur, "-"):
+		out = comp.flags(cur)
+	default:
+		out = comp.positional(tctx, cur)
+	}
+	for _, c := range out {
+		if c.help != "" {
+			fmt.Printf("%s\t%s\n", c.value, c.help)
+		} else {
+			fmt.Println(c.value)
+		}
+	}
+	return nil
+}
+
+// joinAssignments rejoins "--flag", "=", "value", which bash splits a
+// --flag=value word into.
+func joinAssignments(words []string) []string {
+	var out []string
+	for i := 0; i < len(words); i++ {
+		if words[i] == "=" && len(out) > 0 && strings.HasPrefix(out[len(out)-1], "-") {
+			out[len(out)-1] += "="
+			if i+1 < len(words) {
+				i++
+				out[len(out)-1] += words[i]
+			}
+			continue
+		}
+		out = append(out, words[i])
+	}
+	return out
+}
+
+type completion struct {
+	value, help string
+}
+
+// cliCompleter finds candidates in the command tree below node.
+type cliCompleter struct {
+	node *kong.Node
+}
+
+func (c *cliCompleter) flag(word string) *kong.Flag {
+	name := strings.TrimLeft(word, "-")
+	for _, group := range c.node.AllFlags(true) {
+		for _, f := range group {
+			if (strings.HasPrefix(word, "--") && (f.Name == name || slices.Contains(f.Aliases, name))) ||
+				(!strings.HasPrefix(word, "--") && len(name) == 1 && f.Short == rune(name[0])) {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+func (c *cliCompleter) flags(prefix string) []completion {
+	var out []completion
+	for _, group := range c.node.AllFlags(true) {
+		for _, f := range group {
+			name := "--" + f.Name
+			if strings.HasPrefix(name, prefix) {
+				out = append(out, completion{name, f.Help})
+			}
+			if f.Tag.Negatable != "" && strings.HasPrefix("--no-"+f.Name, prefix) {
+				out = append(out, completion{"--no-" + f.Name, ""})
+			}
+		}
+	}
+	return out
+}
+
+// positional completes subcommands and positional arguments of the node.
+func (c *cliCompleter) positional(tctx *kong.Context, prefix string) []completion {
+	var out []completion
+	taken := 0
+	for _, p := range tctx.Path {
+		if p.Positional != nil && slices.Contains(c.node.Positional, p.Positional) {
+			taken++
+		}
+	}
+	if taken == 0 {
+		for _, child := range c.node.Children {
+			if !child.Hidden && strings.HasPrefix(child.Name, prefix) {
+				out = append(out, completion{child.Name, child.Help})
+			}
+		}
+	}
+	if taken >= len(c.node.Positional) {
+		return out
+	}
+	arg := c.node.Positional[taken]
+	parent := ""
+	if c.node.Parent != nil {
+		parent = c.node.Parent.Name
+	}
+	switch {
+	case arg.Name == "uid":
+		if typeName := entityType(parent); typeName != "" {
+			out = append(out, completeUIDs(typeName, prefix)...)
+		}
+	case arg.Name == "uid-or-name":
+		if typeName := entityType(parent); typeName != "" {
+			if prefix == "" || strings.HasPrefix(prefix, "0x") {
+				out = append(out, completeUIDs(typeName, prefix)...)
+			} else {
+				out = append(out, completeNames(typeName, prefix)...)
+			}
+		}
+	case arg.Name == "name" && parent == "config":
+		out = append(out, completeProfiles(prefix)...)
+	case arg.Name == "name" && parent == "query":
+		out = append(out, completeSavedQueries(prefix)...)
+	case arg.Enum != "":
+		out = append(out, completeEnum(arg.Enum, prefix)...)
+	}
+	return out
+}
+
+func (c *cliCompleter) flagValues(f *kong.Flag, prefix string) []completion {
+	switch {
+	case f.Enum != "":
+		return completeEnum(f.Enum, prefix)
+	case f.Name == "profile":
+		return completeProfiles(prefix)
+	case f.Name == "type" && c.node.Name == "export":
+		var out []completion
+		for name := range exportTypes {
+			if strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix)) {
+				out = append(out, completion{name, ""})
+			}
+		}
+		slices.SortFunc(out, func(a, b completion) int { return strings.Compare(a.value, b.value) })
+		return out
+	case strings.HasPrefix(f.Help, "Link "):
+		// Edge flags on add and update take a UID or a name.
+		typeName := entityType(f.Name)
+		if typeName == "" || strings.HasPrefix(prefix, "0x") || !strings.Contains(f.Help, "or name") {
+			return completeUIDs(typeName, prefix)
+		}
+		return completeNames(typeName, prefix)
+	}
+	return nil
+}
+
+func completeEnum(enum, prefix string) []completion {
+	var out []completion
+	for _, v := range strings.Split(enum, ",") {
+		if v = strings.TrimSpace(v); strings.HasPrefix(v, prefix) {
+			out = append(out, completion{v, ""})
+		}
+	}
+	return out
+}
+
+func completeProfiles(prefix string) []completion {
+	path, err := configPath(CLI.ConfigFile)
+	if err != nil {
+		return nil
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil
+	}
+	var out []completion
+	for name, p := range cfg.Profiles {
+		if strings.HasPrefix(name, prefix) {
+			out = append(out, completion{name, p.Addr + p.Dir})
+		}
+	}
+	slices.SortFunc(out, func(a, b completion) int { return strings.Compare(a.value, b.value) })
+	return out
+}
+
+func completeSavedQueries(prefix string) []completion {
+	dir, err := libraryFlags{}.dir()
+	if err != nil {
+		return nil
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, prefix+"*.dql"))
+	var out []completion
+	for _, path := range paths {
+		if q, err := loadSavedQuery(path); err == nil {
+			out = append(out, completion{q.Name, q.Description})
+		}
+	}
+	return out
+}
+
+// entityType maps a command or flag name such as content-rating or
+// starring to its Dgraph type.
+func entityType(name string) string {
+	if name == "starring" {
+		return "Performance"
+	}
+	for typeName := range exportTypes {
+		if normalizeColumn(typeName) == normalizeColumn(name) {
+			return typeName
+		}
+	}
+	return ""
+}
+
+// completeUIDs suggests UIDs of typeName: recently seen ones for an empty
+// or 0x prefix, otherwise those whose names match the prefix.
+func completeUIDs(typeName, prefix string) []completion {
+	if typeName == "" {
+		return nil
+	}
+	cache := loadCompletionCache()
+	conn := cache.conn()
+	var out []completion
+	add := func(n seenNode) {
+		if !slices.ContainsFunc(out, func(c completion) bool { return c.value == n.UID }) {
+			out = append(out, completion{n.UID, n.Name})
+		}
+	}
+	if prefix == "" || strings.HasPrefix(prefix, "0x") {
+		for _, n := range conn.Seen[typeName] {
+			if strings.HasPrefix(n.UID, prefix) {
+				add(n)
+			}
+		}
+		return out
+	}
+	for _, n := range conn.Seen[typeName] {
+		if hasNamePrefix(n.Name, prefix) {
+			add(n)
+		}
+	}
+	for _, n := range cache.lookup(typeName, prefix) {
+		add(n)
+	}
+	return out
+}
+
+// completeNames suggests names of typeName starting with prefix.
+func completeNames(typeName, prefix string) []completion {
+	cache := loadCompletionCache()
+	var out []completion
+	add := func(n seenNode) {
+		if n.Name != "" && hasNamePrefix(n.Name, prefix) &&
+			!slices.ContainsFunc(out, func(c completion) bool { return c.value == n.Name }) {
+			out = append(out, completion{n.Name, n.UID})
+		}
+	}
+	for _, n := range cache.conn().Seen[typeName] {
+		add(n)
+	}
+	if prefix != "" {
+		for _, n := range cache.lookup(typeName, prefix) {
+			add(n)
+		}
+	}
+	return out
+}
+
+func hasNamePrefix(name, prefix string) bool {
+	return strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix))
+}
+
+const (
+	// seenLimit caps the recently seen nodes kept per type.
+	seenLimit = 50
+	// lookupTTL is how long a name lookup is reused.
+	lookupTTL = 5 * time.Minute
+	// lookupTimeout bounds a lookup so that a slow or absent database
+	// does not stall the shell.
+	lookupTimeout = 2 * time.Second
+)
+
+// completionCache is kept in the user cache directory, separately for each
+// database so that UIDs are not offered against the wrong one.
+type completionCache struct {
+	Conns map[string]*connCache `json:"connections"`
+
+	path, key string
+}
+
+type connCache struct {
+	Seen    map[string][]seenNode `json:"seen"`    // by type, most recent first
+	Lookups map[string]nameLookup `json:"lookups"` // by type and lower-cased prefix
+}
+
+type seenNode struct {
+	UID  string `json:"uid"`
+	Name string `json:"name,omitempty"`
+}
+
+type nameLookup struct {
+	At    time.Time  `json:"at"`
+	Nodes []seenNode `json:"nodes"`
+}
+
+// loadCompletionCache reads the cache for the current connection. Any
+// error gives an empty cache; completion never fails on it.
+func loadCompletionCache() *completionCache {
+	c := &completionCache{Conns: map[string]*connCache{}}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return c
+	}
+	c.path = filepath.Join(dir, "movies", "completion.json")
+	if connStr, err := connectString(); err == nil {
+		// Hashed, since the connection string can hold credentials.
+		sum := sha256.Sum256([]byte(connStr))
+		c.key = hex.EncodeToString(sum[:8])
+	}
+	if data, err := os.ReadFile(c.path); err == nil {
+		_ = json.Unmarshal(data, c)
+	}
+	if c.Conns == nil {
+		c.Conns = map[string]*connCache{}
+	}
+	return c
+}
+
+func (c *completionCache) conn() *connCache {
+	cc := c.Conns[c.key]
+	if cc == nil {
+		cc = &connCache{}
+		c.Conns[c.key] = cc
+	}
+	if cc.Seen == nil {
+		cc.Seen = map[string][]seenNode{}
+	}
+	if cc.Lookups == nil {
+		cc.Lookups = map[string]nameLookup{}
+	}
+	return cc
+}
+
+func (c *completionCache) save() error {
+	if c.path == "" || c.key == "" {
+		return nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	// Written aside and renamed, so that concurrent commands never read a
+	// partial file.
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), "completion-*.json")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// lookup returns nodes of typeName whose names match prefix, from the cache
+// if it has a recent answer and otherwise from the database.
+func (c *completionCache) lookup(typeName, prefix string) []seenNode {
+	conn := c.conn()
+	key := typeName + "/" + strings.ToLower(prefix)
+	if l, ok := conn.Lookups[key]; ok && time.Since(l.At) < lookupTTL {
+		return l.Nodes
+	}
+	client, err := lookupClient()
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	nodes, err := lookupNames(ctx, client, typeName, prefix)
+	if err != nil {
+		return nil
+	}
+	for k, l := range conn.Lookups {
+		if time.Since(l.At) >= lookupTTL {
+			delete(conn.Lookups, k)
+		}
+	}
+	conn.Lookups[key] = nameLookup{At: time.Now(), Nodes: nodes}
+	// Completion never fails; an unsaved lookup is only repeated.
+	_ = c.save()
+	return nodes
+}
+
+// lookupConn is opened by the first name lookup and shared by the rest
+// of the completion; CompleteCmd closes it.
+var lookupConn *movies.Client
+
+func lookupClient() (*movies.Client, error) {
+	if lookupConn != nil {
+		return lookupConn, nil
+	}
+	connStr, err := connectString()
+	if err != nil {
+		return nil, err
+	}
+	client, err := movies.New(connStr, movies.WithClientOptions(modusgraph.WithAutoSchema(false)))
+	if err != nil {
+		return nil, err
+	}
+	lookupConn = client
+	return client, nil
+}
+
+// lookupNames queries names starting with prefix through the trigram index,
+// which needs three characters; shorter prefixes match whole terms through
+// the term index instead.
+func lookupNames(ctx context.Context, client *movies.Client, typeName, prefix string) ([]seenNode, error) {
+	if ok, err := hasNamePredicate(ctx, client); err != nil || !ok {
+		return nil, err
+	}
+	var query string
+	var vars map[string]string
+	if len([]rune(prefix)) >= 3 {
+		re := strings.ReplaceAll(regexp.QuoteMeta(prefix), "/", `\/`)
+		query = `{ nodes(func: regexp(name, /^` + re + `/i), first: 20) @filter(type(` + typeName + `)) { uid name } }`
+	} else {
+		query = `query complete($term: string) {
+	nodes(func: anyofterms(name, $term), first: 20) @filter(type(` + typeName + `)) { uid name }
+}`
+		vars = map[string]string{"$term": prefix}
+	}
+	resp, err := client.QueryRaw(ctx, query, vars)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Nodes []seenNode `json:"nodes"`
+	}
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return nil, err
+	}
+	return data.Nodes, nil
+}
+
+// rememberNodes records the entities in a command's result as recently
+// seen, for UID completion. Commands call it with --remember.
+func rememberNodes(v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	found := map[string][]seenNode{}
+	count := 0
+	var walk func(any)
+	walk = func(v any) {
+		if count >= seenLimit*len(exportTypes) {
+			return
+		}
+		switch v := v.(type) {
+		case []any:
+			for _, e := range v {
+				walk(e)
+			}
+		case map[string]any:
+			uid, _ := v["uid"].(string)
+			types, _ := v["dgraph.type"].([]any)
+			name, _ := v["name"].(string)
+			for _, t := range types {
+				if t, ok := t.(string); ok && uid != "" {
+					if _, ok := exportTypes[t]; ok {
+						found[t] = append(found[t], seenNode{UID: uid, Name: name})
+						count++
+					}
+				}
+			}
+			for _, e := range v {
+				walk(e)
+			}
+		}
+	}
+	walk(doc)
+	if count == 0 {
+		return nil
+	}
+	cache := loadCompletionCache()
+	conn := cache.conn()
+	for typeName, nodes := range found {
+		var seen []seenNode
+		for _, n := range append(nodes, conn.Seen[typeName]...) {
+			if !slices.ContainsFunc(seen, func(s seenNode) bool { return s.UID == n.UID }) {
+				seen = append(seen, n)
+			}
+		}
+		conn.Seen[typeName] = seen[:min(len(seen), seenLimit)]
+	}
+	return cache.save()
+}
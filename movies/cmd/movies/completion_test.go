@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// useCompletionCache points the completion cache at a temporary directory
+// and returns its path.
+func useCompletionCache(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	addr, remember := CLI.Addr, CLI.Remember
+	t.Cleanup(func() { CLI.Addr, CLI.Remember = addr, remember })
+	CLI.Addr = "dgraph://completion-test:9080"
+	return filepath.Join(dir, "movies", "completion.json")
+}
+
+func TestRememberNodes(t *testing.T) {
+	useCompletionCache(t)
+	result := []any{
+		map[string]any{"uid": "0x2", "name": "Heat", "dgraph.type": []any{"Film"},
+			"genres": []any{map[string]any{"uid": "0x7", "name": "Crime", "dgraph.type": []any{"Genre"}}}},
+		map[string]any{"uid": "0x3", "name": "Thief", "dgraph.type": []any{"Film"}},
+		map[string]any{"uid": "0x9", "name": "untyped"},
+	}
+	if err := rememberNodes(result); err != nil {
+		t.Fatal(err)
+	}
+	// Seen again later, 0x3 moves to the front without a duplicate.
+	if err := rememberNodes(map[string]any{"uid": "0x3", "name": "Thief", "dgraph.type": []any{"Film"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []completion{{"0x3", "Thief"}, {"0x2", "Heat"}}
+	if got := completeUIDs("Film", ""); !reflect.DeepEqual(got, want) {
+		t.Errorf("completeUIDs(Film) = %v, want %v", got, want)
+	}
+	if got := completeUIDs("Film", "0x2"); !reflect.DeepEqual(got, want[1:]) {
+		t.Errorf("completeUIDs(Film, 0x2) = %v, want %v", got, want[1:])
+	}
+	if got := completeUIDs("Genre", ""); !reflect.DeepEqual(got, []completion{{"0x7", "Crime"}}) {
+		t.Errorf("completeUIDs(Genre) = %v", got)
+	}
+
+	// The cache is kept per database.
+	CLI.Addr = "dgraph://elsewhere:9080"
+	if got := completeUIDs("Film", ""); got != nil {
+		t.Errorf("another database offers %v", got)
+	}
+}
+
+func TestPrintResultRemember(t *testing.T) {
+	path := useCompletionCache(t)
+	stdout := os.Stdout
+	t.Cleanup(func() { os.Stdout = stdout })
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout = devNull
+
+	film := map[string]any{"uid": "0x2", "name": "Heat", "dgraph.type": []any{"Film"}}
+	CLI.Remember = false
+	if err := printResult(film); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("without --remember the cache was written: %v", err)
+	}
+	CLI.Remember = true
+	if err := printResult(film); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("with --remember: %v", err)
+	}
+}
+
+func TestCompleteNamesCached(t *testing.T) {
+	useCompletionCache(t)
+	cache := loadCompletionCache()
+	conn := cache.conn()
+	conn.Seen["Genre"] = []seenNode{{UID: "0x7", Name: "Action"}, {UID: "0x8", Name: "Drama"}}
+	conn.Lookups["Genre/act"] = nameLookup{At: time.Now(), Nodes: []seenNode{
+		{UID: "0x7", Name: "Action"}, {UID: "0x9", Name: "Action Comedy"},
+	}}
+	if err := cache.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A recent lookup is answered from the cache, without a client.
+	want := []completion{{"Action", "0x7"}, {"Action Comedy", "0x9"}}
+	if got := completeNames("Genre", "Act"); !reflect.DeepEqual(got, want) {
+		t.Errorf("completeNames(Genre, Act) = %v, want %v", got, want)
+	}
+	if got := completeUIDs("Genre", "act"); !reflect.DeepEqual(got, []completion{{"0x7", "Action"}, {"0x9", "Action Comedy"}}) {
+		t.Errorf("completeUIDs(Genre, act) = %v", got)
+	}
+	if lookupConn != nil {
+		t.Error("a cached lookup opened a client")
+	}
+}
+
+func TestJoinAssignments(t *testing.T) {
+	tests := []struct {
+		words, want []string
+	}{
+		{[]string{"film", "add", "--genre", "=", "Act"}, []string{"film", "add", "--genre=Act"}},
+		{[]string{"film", "add", "--genre", "="}, []string{"film", "add", "--genre="}},
+		{[]string{"query", "a", "=", "b"}, []string{"query", "a", "=", "b"}},
+	}
+	for _, tt := range tests {
+		if got := joinAssignments(tt.words); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("joinAssignments(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestEntityType(t *testing.T) {
+	for name, want := range map[string]string{
+		"film": "Film", "content-rating": "ContentRating", "starring": "Performance", "genre": "Genre", "config": "",
+	} {
+		if got := entityType(name); got != want {
+			t.Errorf("entityType(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
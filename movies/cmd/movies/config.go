@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"go.yaml.in/yaml/v3"
+)
+
+const defaultAddr = "dgraph://localhost:9080"
+
+// Config is the CLI configuration file, by default
+// ~/.config/movies/config.yaml:
+//
+//	current: local
+//	profiles:
+//	  local:
+//	    addr: dgraph://localhost:9080
+//	  staging:
+//	    addr: dgraph://staging.example.com:9080
+//	    tls: verify-ca
+//	    user: groot
+//	    password: ${STAGING_DGRAPH_PASSWORD}
+//	    output: table
+//	    timeout: 10s
+//	  scratch:
+//	    dir: ~/movies-db
+type Config struct {
+	Current  string              `yaml:"current,omitempty"`
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// Profile is a named set of connection settings and flag defaults. String
+// values may refer to environment variables as $VAR or ${VAR}, so that
+// secrets need not be stored in the file.
+type Profile struct {
+	Addr         string        `yaml:"addr,omitempty" json:"addr,omitempty"`
+	Dir          string        `yaml:"dir,omitempty" json:"dir,omitempty"`
+	TLS          string        `yaml:"tls,omitempty" json:"tls,omitempty"` // disable, require or verify-ca
+	User         string        `yaml:"user,omitempty" json:"user,omitempty"`
+	Password     string        `yaml:"password,omitempty" json:"password,omitempty"`
+	APIKey       string        `yaml:"api_key,omitempty" json:"api_key,omitempty"`
+	BearerToken  string        `yaml:"bearer_token,omitempty" json:"bearer_token,omitempty"`
+	Namespace    string        `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	Output       string        `yaml:"output,omitempty" json:"output,omitempty"`
+	Template     string        `yaml:"template,omitempty" json:"template,omitempty"`
+	Timeout      time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	NoAutoSchema bool          `yaml:"no_auto_schema,omitempty" json:"no_auto_schema,omitempty"`
+}
+
+// configPath returns the configuration file location: --config, then
+// $XDG_CONFIG_HOME/movies/config.yaml, then ~/.config/movies/config.yaml.
+func configPath(flag string) (string, error) {
+	if flag != "" {
+		return expandHome(flag)
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "movies", "config.yaml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "movies", "config.yaml"), nil
+}
+
+// loadConfig reads the configuration file. A missing file is an empty
+// configuration.
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	for name, p := range cfg.Profiles {
+		if p == nil {
+			return nil, fmt.Errorf("%s: profile %q is empty", path, name)
+		}
+		if err := p.expand(); err != nil {
+			return nil, fmt.Errorf("%s: profile %q: %w", path, name, err)
+		}
+	}
+	if cfg.Current != "" && cfg.Profiles[cfg.Current] == nil {
+		return nil, fmt.Errorf("%s: current profile %q is not defined", path, cfg.Current)
+	}
+	return &cfg, nil
+}
+
+// profile returns the named profile, or the current one for "". It returns
+// nil when no name is given and no profile is current.
+func (c *Config) profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.Current
+	}
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("no profile named %q; see 'movies config list'", name)
+	}
+	return p, nil
+}
+
+func (c *Config) names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// expand substitutes environment variables, expands ~ in the directory
+// and checks the settings.
+func (p *Profile) expand() error {
+	for _, s := range []*string{&p.Addr, &p.Dir, &p.User, &p.Password, &p.APIKey, &p.BearerToken, &p.Namespace} {
+		*s = os.ExpandEnv(*s)
+	}
+	if p.Dir != "" {
+		dir, err := expandHome(p.Dir)
+		if err != nil {
+			return err
+		}
+		p.Dir = dir
+	}
+	switch {
+	case p.Addr != "" && p.Dir != "":
+		return fmt.Errorf("addr and dir are mutually exclusive")
+	case p.Dir != "" && (p.TLS != "" || p.User != "" || p.APIKey != "" || p.BearerToken != "" || p.Namespace != ""):
+		return fmt.Errorf("tls and credentials apply only to addr")
+	}
+	switch p.TLS {
+	case "", "disable", "require", "verify-ca":
+	default:
+		return fmt.Errorf("tls must be disable, require or verify-ca, not %q", p.TLS)
+	}
+	return nil
+}
+
+// connectAddr returns the profile's address with its TLS mode and
+// credentials added as connection string parameters. Credentials are
+// checked here rather than on loading, so that an unset variable only
+// matters to the profile that uses it.
+func (p *Profile) connectAddr() (string, error) {
+	if (p.User == "") != (p.Password == "") {
+		return "", fmt.Errorf("profile user and password must be given together")
+	}
+	u, err := url.Parse(p.Addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid addr %q: %w", p.Addr, err)
+	}
+	if p.User != "" {
+		u.User = url.UserPassword(p.User, p.Password)
+	}
+	q := u.Query()
+	for key, v := range map[string]string{
+		"sslmode":     p.TLS,
+		"apikey":      p.APIKey,
+		"bearertoken": p.BearerToken,
+		"namespace":   p.Namespace,
+	} {
+		if v != "" {
+			q.Set(key, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// redacted returns a copy with secrets masked, for display.
+func (p Profile) redacted() Profile {
+	for _, s := range []*string{&p.Password, &p.APIKey, &p.BearerToken} {
+		if *s != "" {
+			*s = "********"
+		}
+	}
+	return p
+}
+
+func expandHome(path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, "~")
+	if !ok || (rest != "" && rest[0] != '/') {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return home + rest, nil
+}
+
+// profileResolver supplies flag values from the selected profile. Kong only
+// consults it for flags not given on the command line, and it yields to a
+// flag's environment variable, so the precedence is flag, environment,
+// profile, then the built-in default.
+type profileResolver struct {
+	loaded  bool
+	profile *Profile
+	err     error // reported after parsing, as Kong would blame a flag
+}
+
+func (r *profileResolver) Validate(*kong.Application) error { return nil }
+
+func (r *profileResolver) Resolve(ctx *kong.Context, parent *kong.Path, flag *kong.Flag) (any, error) {
+	if envSet(flag) {
+		return nil, nil
+	}
+	p := r.load(ctx)
+	if p == nil {
+		return nil, nil
+	}
+	switch flag.Name {
+	case "addr":
+		// A directory given by flag or environment replaces the profile's
+		// connection rather than conflicting with it.
+		if p.Addr != "" && flagString(ctx, "dir") == "" {
+			return p.connectAddr()
+		}
+	case "dir":
+		if p.Dir != "" && flagString(ctx, "addr") == "" {
+			return p.Dir, nil
+		}
+	case "output":
+		if p.Output != "" {
+			return p.Output, nil
+		}
+	case "template":
+		if p.Template != "" {
+			return p.Template, nil
+		}
+	case "timeout":
+		if p.Timeout > 0 {
+			return p.Timeout.String(), nil
+		}
+	case "no-auto-schema":
+		if p.NoAutoSchema {
+			return true, nil
+		}
+	}
+	return nil, nil
+}
+
+// load returns the selected profile, reading it on first use.
+func (r *profileResolver) load(ctx *kong.Context) *Profile {
+	if !r.loaded {
+		r.loaded = true
+		r.profile, r.err = selectProfile(flagString(ctx, "config"), flagString(ctx, "profile"))
+	}
+	return r.profile
+}
+
+func selectProfile(configFlag, name string) (*Profile, error) {
+	path, err := configPath(configFlag)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.profile(name)
+}
+
+func envSet(flag *kong.Flag) bool {
+	for _, env := range flag.Envs {
+		if _, ok := os.LookupEnv(env); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// flagString returns the value a string flag has from the command line,
+// its environment variable or its default.
+func flagString(ctx *kong.Context, name string) string {
+	for _, f := range ctx.Flags() {
+		if f.Name == name {
+			s, _ := ctx.FlagValue(f).(string)
+			return s
+		}
+	}
+	return ""
+}
+
+// ConfigCmd manages connection profiles.
+type ConfigCmd struct {
+	List ConfigListCmd `cmd:"" help:"List profiles."`
+	Use  ConfigUseCmd  `cmd:"" help:"Make a profile the current one."`
+	Show ConfigShowCmd `cmd:"" help:"Show a profile's settings, with secrets masked."`
+}
+
+// ConfigListCmd lists the profiles in the configuration file.
+type ConfigListCmd struct{}
+
+type profileSummary struct {
+	Name    string `json:"name"`
+	Current bool   `json:"current"`
+	Target  string `json:"target"`
+}
+
+func (c *ConfigListCmd) Run() error {
+	path, err := configPath(CLI.ConfigFile)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	list := []profileSummary{}
+	for _, name := range cfg.names() {
+		p := cfg.Profiles[name]
+		target := p.Addr
+		if p.Dir != "" {
+			target = "file://" + p.Dir
+		}
+		list = append(list, profileSummary{Name: name, Current: name == cfg.Current, Target: target})
+	}
+	return printResult(list)
+}
+
+// ConfigUseCmd sets the current profile, keeping the rest of the file,
+// including comments, as it is.
+type ConfigUseCmd struct {
+	Name string `arg:"" help:"Profile name."`
+}
+
+func (c *ConfigUseCmd) Run() error {
+	path, err := configPath(CLI.ConfigFile)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Profiles[c.Name]; !ok {
+		return fmt.Errorf("no profile named %q in %s", c.Name, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	root := doc.Content[0]
+	set := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "current" {
+			root.Content[i+1].SetString(c.Name)
+			set = true
+		}
+	}
+	if !set {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: "current"}
+		val := &yaml.Node{Kind: yaml.ScalarNode}
+		val.SetString(c.Name)
+		root.Content = append([]*yaml.Node{key, val}, root.Content...)
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "now using profile %q\n", c.Name)
+	return nil
+}
+
+// ConfigShowCmd prints a profile.
+type ConfigShowCmd struct {
+	Name string `arg:"" optional:"" help:"Profile name (default: --profile or the current profile)."`
+}
+
+func (c *ConfigShowCmd) Run() error {
+	path, err := configPath(CLI.ConfigFile)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	name := c.Name
+	if name == "" {
+		name = CLI.Profile
+	}
+	if name == "" {
+		name = cfg.Current
+	}
+	if name == "" {
+		return fmt.Errorf("no current profile in %s; name one or run 'movies config use'", path)
+	}
+	p, err := cfg.profile(name)
+	if err != nil {
+		return err
+	}
+	var timeout string
+	if p.Timeout > 0 {
+		timeout = p.Timeout.String()
+	}
+	return printResult(struct {
+		Name string `json:"name"`
+		Profile
+		Timeout string `json:"timeout,omitempty"`
+	}{name, p.redacted(), timeout})
+}
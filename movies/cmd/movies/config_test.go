@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+)
+
+const testConfig = `current: local
+profiles:
+  local:
+    addr: dgraph://local:9080
+    output: table
+    timeout: 10s
+  other:
+    dir: /tmp/movies-other
+    output: yaml
+`
+
+func TestProfilePrecedence(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(config, []byte(testConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		// want
+		addr, dir, output string
+		timeout           time.Duration
+	}{
+		{name: "profile", addr: "dgraph://local:9080", output: "table", timeout: 10 * time.Second},
+		{name: "flag over profile", args: []string{"--output=csv", "--timeout=5s"},
+			addr: "dgraph://local:9080", output: "csv", timeout: 5 * time.Second},
+		{name: "env over profile", env: map[string]string{"MOVIES_OUTPUT": "ndjson"},
+			addr: "dgraph://local:9080", output: "ndjson", timeout: 10 * time.Second},
+		{name: "flag over env", args: []string{"--output=csv"}, env: map[string]string{"MOVIES_OUTPUT": "ndjson"},
+			addr: "dgraph://local:9080", output: "csv", timeout: 10 * time.Second},
+		{name: "addr flag", args: []string{"--addr=dgraph://flag:9080"},
+			addr: "dgraph://flag:9080", output: "table", timeout: 10 * time.Second},
+		{name: "dir env replaces profile addr", env: map[string]string{"DGRAPH_DIR": "/data"},
+			dir: "/data", output: "table", timeout: 10 * time.Second},
+		{name: "profile flag", args: []string{"--profile=other"},
+			dir: "/tmp/movies-other", output: "yaml", timeout: 30 * time.Second},
+		{name: "profile env", env: map[string]string{"MOVIES_PROFILE": "other"},
+			dir: "/tmp/movies-other", output: "yaml", timeout: 30 * time.Second},
+		{name: "no config file", args: []string{"--config=" + filepath.Join(dir, "missing.yaml")},
+			output: "json", timeout: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"DGRAPH_ADDR", "DGRAPH_DIR", "MOVIES_PROFILE", "MOVIES_CONFIG", "MOVIES_OUTPUT"} {
+				t.Setenv(k, "")
+				os.Unsetenv(k)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cli := CLI
+			args := append([]string{"--config=" + config, "schema", "show"}, tt.args...)
+			if err := parseCLI(&cli, args...); err != nil {
+				t.Fatal(err)
+			}
+			if cli.Addr != tt.addr || cli.Dir != tt.dir || cli.Output != tt.output || cli.Schema.Show.Timeout != tt.timeout {
+				t.Errorf("got addr %q, dir %q, output %q, timeout %v; want %q, %q, %q, %v",
+					cli.Addr, cli.Dir, cli.Output, cli.Schema.Show.Timeout, tt.addr, tt.dir, tt.output, tt.timeout)
+			}
+		})
+	}
+
+	t.Run("invalid profile", func(t *testing.T) {
+		broken := filepath.Join(dir, "broken.yaml")
+		if err := os.WriteFile(broken, []byte("profiles:\n  x:\n    addr: dgraph://x:9080\n    tls: sometimes\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		cli := CLI
+		if err := parseCLI(&cli, "--config="+broken, "--profile=x", "schema", "show"); err == nil {
+			t.Error("expected an invalid profile to fail")
+		}
+		cli = CLI
+		if err := parseCLI(&cli, "--config="+config, "--profile=nope", "schema", "show"); err == nil {
+			t.Error("expected an unknown profile to fail")
+		}
+	})
+}
+
+// parseCLI parses args into cli, a copy of CLI, with the profile resolver
+// as main does.
+func parseCLI[T any](cli *T, args ...string) error {
+	profiles := &profileResolver{}
+	parser, err := kong.New(cli, kong.Name("movies"), kong.Resolvers(profiles))
+	if err != nil {
+		return err
+	}
+	if _, err := parser.Parse(args); err != nil {
+		return err
+	}
+	return profiles.err
+}
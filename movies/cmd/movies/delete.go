@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"golang.org/x/term"
+)
+
+// deleteFlags let a delete command remove every node matching a filter
+// instead of one UID.
+type deleteFlags struct {
+	Filter string `help:"Delete the nodes matching this DQL filter expression instead of one UID."`
+	Yes    bool   `short:"y" help:"Delete without asking for confirmation."`
+	Limit  int    `help:"Refuse to delete when more than this many nodes match; 0 for no limit." default:"100"`
+}
+
+// previewSize is the number of matching nodes listed before deleting.
+const previewSize = 10
+
+// run deletes the node uid or, without one, the nodes of typeName matching
+// --filter and search, a fulltext name search. Matches are found with a dry
+// run of where, previewed on stderr, and deleted once confirmed; exactly the
+// nodes shown as matching are deleted, even if more match by then.
+func (f deleteFlags) run(client *movies.Client, typeName, uid, search string,
+	del func(context.Context, string) error,
+	where func(context.Context, string, ...movies.DeleteOption) ([]string, error),
+) error {
+	ctx := context.Background()
+	filters := []string{}
+	if f.Filter != "" {
+		filters = append(filters, "("+f.Filter+")")
+	}
+	if search != "" {
+		filters = append(filters, "alloftext(name, "+movies.Quote(search)+")")
+	}
+	switch {
+	case uid != "" && len(filters) > 0:
+		return errors.New("give either a UID or a filter, not both")
+	case uid != "":
+		return del(ctx, uid)
+	case len(filters) == 0:
+		return errors.New("give a UID or a filter to delete")
+	}
+	filter := strings.Join(filters, " AND ")
+
+	uids, err := where(ctx, filter, movies.DryRun(), movies.DeleteLimit(f.Limit))
+	var tooMany *movies.TooManyMatchesError
+	if errors.As(err, &tooMany) {
+		return fmt.Errorf("%d %s nodes match, more than --limit %d; narrow the filter or raise --limit",
+			tooMany.Matches, typeName, f.Limit)
+	}
+	if err != nil {
+		return err
+	}
+	noun, verb := typeName+" nodes", "match"
+	if len(uids) == 1 {
+		noun, verb = typeName+" node", "matches"
+	}
+	if len(uids) == 0 {
+		fmt.Fprintf(os.Stderr, "no %s nodes match\n", typeName)
+		return nil
+	}
+
+	shown := uids[:min(len(uids), previewSize)]
+	names, err := nodeNames(ctx, client, shown)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d %s %s:\n", len(uids), noun, verb)
+	for _, u := range shown {
+		fmt.Fprintf(os.Stderr, "  %s\t%s\n", u, names[u])
+	}
+	if len(uids) > previewSize {
+		fmt.Fprintf(os.Stderr, "  ... and %d more\n", len(uids)-previewSize)
+	}
+	if !f.Yes {
+		ok, err := confirm(fmt.Sprintf("Delete %d %s?", len(uids), noun))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("nothing deleted")
+		}
+	}
+
+	// Restricting the filter to the previewed UIDs deletes no node that
+	// started matching since.
+	deleted, err := where(ctx, "uid("+strings.Join(uids, ", ")+") AND ("+filter+")", movies.DeleteLimit(0))
+	if err != nil {
+		return err
+	}
+	if len(deleted) != 1 {
+		noun = typeName + " nodes"
+	}
+	fmt.Fprintf(os.Stderr, "deleted %d %s\n", len(deleted), noun)
+	return nil
+}
+
+// nodeNames returns the names of the nodes uids, or none when the schema
+// has no name predicate.
+func nodeNames(ctx context.Context, client *movies.Client, uids []string) (map[string]string, error) {
+	names := map[string]string{}
+	named, err := hasNamePredicate(ctx, client)
+	if err != nil || !named {
+		return names, err
+	}
+	resp, err := client.QueryRaw(ctx, "{ nodes(func: uid("+strings.Join(uids, ", ")+")) { uid name } }", nil)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Nodes []struct {
+			UID  string `json:"uid"`
+			Name string `json:"name"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return nil, fmt.Errorf("parsing names: %w", err)
+	}
+	for _, n := range data.Nodes {
+		names[n.UID] = n.Name
+	}
+	return names, nil
+}
+
+// confirm asks a yes/no question on the terminal. Without one, there is no
+// one to ask, so it fails rather than guess.
+func confirm(question string) (bool, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false, errors.New("stdin is not a terminal; pass --yes to delete without confirmation")
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
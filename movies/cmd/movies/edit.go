@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+// link is an edge stored on another node, such as a director's director.film
+// edge to a film. It is applied once the entity being added has a UID.
+type link func(ctx context.Context, uid string) error
+
+// applyLinks applies links to the node just added as uid. If one fails the
+// node is deleted again with del, so that a failed add does not leave an
+// unlinked node behind.
+func applyLinks(ctx context.Context, links []link, uid string, del func(ctx context.Context, uid string) error) error {
+	for _, l := range links {
+		if err := l(ctx, uid); err != nil {
+			if derr := del(ctx, uid); derr != nil {
+				return fmt.Errorf("%w; removing the added node %s also failed: %v", err, uid, derr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// entityClient is what add and update use of a generated entity client,
+// such as movies.Client.Actor.
+type entityClient[E any] interface {
+	Get(ctx context.Context, uid string) (*E, error)
+	Add(ctx context.Context, v *E) error
+	Update(ctx context.Context, v *E) error
+	Delete(ctx context.Context, uid string) error
+}
+
+// addEntity runs an add command: it adds the entity fields describe,
+// applies its links, and prints it as stored.
+func addEntity[E any](client *movies.Client, typeName string, fields entityFields[E], ec entityClient[E], uidOf func(*E) *string) error {
+	ctx := context.Background()
+	v, links, err := fields.build(ctx, client)
+	if err != nil {
+		return err
+	}
+	if uid := *uidOf(v); uid != "" {
+		return fmt.Errorf("--from-json document has uid %s; use update to change an existing %s", uid, typeName)
+	}
+	if err := ec.Add(ctx, v); err != nil {
+		return err
+	}
+	if err := applyLinks(ctx, links, *uidOf(v), ec.Delete); err != nil {
+		return err
+	}
+	result, err := ec.Get(ctx, *uidOf(v))
+	if err != nil {
+		return err
+	}
+	return printResult(result)
+}
+
+// updateEntity runs an update command: it sets the fields given on the
+// existing node uid, applies the links, and prints the node as stored.
+func updateEntity[E any](client *movies.Client, uid string, fields entityFields[E], ec entityClient[E], uidOf func(*E) *string) error {
+	ctx := context.Background()
+	v, links, err := fields.build(ctx, client)
+	if err != nil {
+		return err
+	}
+	if got := *uidOf(v); got != "" && got != uid {
+		return fmt.Errorf("--from-json document has uid %s, not %s", got, uid)
+	}
+	if _, err := ec.Get(ctx, uid); err != nil {
+		return err
+	}
+	*uidOf(v) = uid
+	if err := ec.Update(ctx, v); err != nil {
+		return err
+	}
+	for _, l := range links {
+		if err := l(ctx, uid); err != nil {
+			return err
+		}
+	}
+	result, err := ec.Get(ctx, uid)
+	if err != nil {
+		return err
+	}
+	return printResult(result)
+}
+
+// dateLayouts are the accepted date formats, most specific first.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	time.DateOnly,
+	"2006-01",
+	"2006",
+}
+
+// parseDate parses an RFC 3339 timestamp or a partial date such as
+// 1999-03-31, 1999-03 or 1999. Partial dates are taken as UTC.
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q: use RFC 3339, YYYY-MM-DD, YYYY-MM or YYYY", s)
+}
+
+// parseGeoPoint parses "lat,lng".
+func parseGeoPoint(s string) (*movies.GeoPoint, error) {
+	lat, lng, ok := strings.Cut(s, ",")
+	if ok {
+		la, err1 := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+		lo, err2 := strconv.ParseFloat(strings.TrimSpace(lng), 64)
+		if err1 == nil && err2 == nil && la >= -90 && la <= 90 && lo >= -180 && lo <= 180 {
+			return movies.NewGeoPoint(la, lo), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid point %q: use lat,lng", s)
+}
+
+// readJSONDoc decodes a full entity document from path, or stdin for "-".
+// Unknown fields are rejected so that typos are not silently dropped.
+func readJSONDoc(path string, v any) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	return nil
+}
+
+// resolveRefs turns edge references into UIDs. A reference is either a UID
+// or, for types with a name, the exact name of a single node of typeName.
+func resolveRefs(ctx context.Context, client *movies.Client, typeName string, byName bool, refs []string) ([]string, error) {
+	uids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if movies.IsUID(ref) {
+			uids = append(uids, ref)
+			continue
+		}
+		if !byName {
+			return nil, fmt.Errorf("%q is not a UID; %s can only be referenced by UID", ref, typeName)
+		}
+		found, err := lookupByName(ctx, client, typeName, ref)
+		if err != nil {
+			return nil, err
+		}
+		switch len(found) {
+		case 0:
+			return nil, fmt.Errorf("no %s named %q", typeName, ref)
+		case 1:
+			uids = append(uids, found[0])
+		default:
+			return nil, fmt.Errorf("more than one %s is named %q; use a UID", typeName, ref)
+		}
+	}
+	return uids, nil
+}
+
+// lookupByName returns the UIDs of up to two nodes of typeName with exactly
+// the given name; two means the name is ambiguous.
+func lookupByName(ctx context.Context, client *movies.Client, typeName, name string) ([]string, error) {
+	if ok, err := hasNamePredicate(ctx, client); err != nil || !ok {
+		return nil, err
+	}
+	query := `query lookup($name: string) {
+	nodes(func: eq(name, $name), first: 2) @filter(type(` + typeName + `)) { uid }
+}`
+	resp, err := client.QueryRaw(ctx, query, map[string]string{"$name": name})
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Nodes []struct {
+			UID string `json:"uid"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return nil, fmt.Errorf("parsing lookup response: %w", err)
+	}
+	uids := make([]string, len(data.Nodes))
+	for i, n := range data.Nodes {
+		uids[i] = n.UID
+	}
+	return uids, nil
+}
+
+// nameSeen records that the name predicate exists, so it is checked at most
+// until the first named node is stored.
+var nameSeen bool
+
+// hasNamePredicate reports whether any stored type has a name predicate.
+// Embedded Dgraph panics on a query that uses a predicate it has never seen,
+// which is the case for name on a fresh database. Listing types is safe.
+func hasNamePredicate(ctx context.Context, client *movies.Client) (bool, error) {
+	if nameSeen {
+		return true, nil
+	}
+	resp, err := client.QueryRaw(ctx, "schema {}", nil)
+	if err != nil {
+		return false, err
+	}
+	var data struct {
+		Types []struct {
+			Fields []struct {
+				Name string `json:"name"`
+			} `json:"fields"`
+		} `json:"types"`
+	}
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return false, fmt.Errorf("parsing schema response: %w", err)
+	}
+	for _, t := range data.Types {
+		for _, f := range t.Fields {
+			if f.Name == "name" {
+				nameSeen = true
+			}
+		}
+	}
+	return nameSeen, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+func TestApplyLinksRollsBack(t *testing.T) {
+	ctx := context.Background()
+	var applied, deleted []string
+	ok := func(ctx context.Context, uid string) error {
+		applied = append(applied, uid)
+		return nil
+	}
+	fail := func(ctx context.Context, uid string) error { return errors.New("link failed") }
+	del := func(ctx context.Context, uid string) error {
+		deleted = append(deleted, uid)
+		return nil
+	}
+
+	if err := applyLinks(ctx, []link{ok, ok}, "0x1", del); err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || len(deleted) != 0 {
+		t.Fatalf("applied %v, deleted %v; want two links and no delete", applied, deleted)
+	}
+
+	if err := applyLinks(ctx, []link{ok, fail}, "0x2", del); err == nil || err.Error() != "link failed" {
+		t.Fatalf("err = %v, want the link error", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "0x2" {
+		t.Fatalf("deleted %v, want [0x2]", deleted)
+	}
+
+	failDel := func(ctx context.Context, uid string) error { return errors.New("delete failed") }
+	err := applyLinks(ctx, []link{fail}, "0x3", failDel)
+	if err == nil || !strings.Contains(err.Error(), "link failed") || !strings.Contains(err.Error(), "0x3") {
+		t.Fatalf("err = %v, want the link error naming the node left behind", err)
+	}
+}
+
+// genreStore is an entityClient keeping genres in memory.
+type genreStore struct {
+	genres map[string]movies.Genre
+	calls  []string
+}
+
+func (s *genreStore) Get(ctx context.Context, uid string) (*movies.Genre, error) {
+	g, ok := s.genres[uid]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &g, nil
+}
+
+func (s *genreStore) Add(ctx context.Context, v *movies.Genre) error {
+	s.calls = append(s.calls, "add")
+	v.UID = "0x9"
+	s.genres[v.UID] = *v
+	return nil
+}
+
+func (s *genreStore) Update(ctx context.Context, v *movies.Genre) error {
+	s.calls = append(s.calls, "update "+v.UID)
+	s.genres[v.UID] = *v
+	return nil
+}
+
+func (s *genreStore) Delete(ctx context.Context, uid string) error {
+	s.calls = append(s.calls, "delete "+uid)
+	delete(s.genres, uid)
+	return nil
+}
+
+// builtGenre is entityFields returning a fixed genre and links.
+type builtGenre struct {
+	genre movies.Genre
+	links []link
+}
+
+func (b builtGenre) build(ctx context.Context, client *movies.Client) (*movies.Genre, []link, error) {
+	g := b.genre
+	return &g, b.links, nil
+}
+
+func TestAddUpdateEntity(t *testing.T) {
+	stdout := os.Stdout
+	t.Cleanup(func() { os.Stdout = stdout })
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout = devNull
+
+	uidOf := func(v *movies.Genre) *string { return &v.UID }
+	store := &genreStore{genres: map[string]movies.Genre{"0x1": {UID: "0x1", Name: "Noir"}}}
+	var linked []string
+	ok := func(ctx context.Context, uid string) error {
+		linked = append(linked, uid)
+		return nil
+	}
+	fail := func(ctx context.Context, uid string) error { return errors.New("link failed") }
+
+	if err := addEntity(nil, "Genre", builtGenre{genre: movies.Genre{Name: "Heist"}, links: []link{ok}}, store, uidOf); err != nil {
+		t.Fatal(err)
+	}
+	if store.genres["0x9"].Name != "Heist" || !slices.Equal(linked, []string{"0x9"}) {
+		t.Errorf("expected Heist added as 0x9 and linked, got %v, links %v", store.genres, linked)
+	}
+	err = addEntity(nil, "Genre", builtGenre{genre: movies.Genre{UID: "0x1", Name: "Noir"}}, store, uidOf)
+	if err == nil || !strings.Contains(err.Error(), "use update to change an existing Genre") {
+		t.Errorf("err = %v, want add to refuse a document with a UID", err)
+	}
+	store.calls = nil
+	if err := addEntity(nil, "Genre", builtGenre{genre: movies.Genre{Name: "Caper"}, links: []link{fail}}, store, uidOf); err == nil {
+		t.Error("expected the link error")
+	}
+	if !slices.Equal(store.calls, []string{"add", "delete 0x9"}) {
+		t.Errorf("expected the added node to be removed again, got %v", store.calls)
+	}
+
+	linked = nil
+	if err := updateEntity(nil, "0x1", builtGenre{genre: movies.Genre{Name: "Film Noir"}, links: []link{ok}}, store, uidOf); err != nil {
+		t.Fatal(err)
+	}
+	if store.genres["0x1"].Name != "Film Noir" || !slices.Equal(linked, []string{"0x1"}) {
+		t.Errorf("expected 0x1 renamed and linked, got %v, links %v", store.genres, linked)
+	}
+	err = updateEntity(nil, "0x1", builtGenre{genre: movies.Genre{UID: "0x2"}}, store, uidOf)
+	if err == nil || !strings.Contains(err.Error(), "has uid 0x2, not 0x1") {
+		t.Errorf("err = %v, want update to refuse a document with another UID", err)
+	}
+	if err := updateEntity(nil, "0x5", builtGenre{genre: movies.Genre{Name: "Ghost"}}, store, uidOf); err == nil {
+		t.Error("expected update of a missing node to fail")
+	}
+	if _, ok := store.genres["0x5"]; ok {
+		t.Error("expected update not to create a missing node")
+	}
+}
+
+func TestParseGeoPoint(t *testing.T) {
+	p, err := parseGeoPoint("37.7749, -122.4194")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// GeoJSON puts the longitude first.
+	if p.Type != "Point" || !slices.Equal(p.Coordinates, []float64{-122.4194, 37.7749}) {
+		t.Errorf("got %+v, want a Point at [-122.4194, 37.7749]", p)
+	}
+	for _, s := range []string{"", "37.7749", "-122.4194,37.7749", "37.7749,190", "north,west"} {
+		if _, err := parseGeoPoint(s); err == nil {
+			t.Errorf("parseGeoPoint(%q): expected an error", s)
+		}
+	}
+}
@@ -0,0 +1,571 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+// ExportCmd writes every node of a type, or the subgraph around given
+// nodes, as NDJSON, JSON or N-Quad RDF. Nodes are written as they are
+// fetched, so exports of any size run in constant memory.
+type ExportCmd struct {
+	Type       string   `help:"Export every node of this type, e.g. Film." xor:"mode" required:""`
+	Filter     string   `help:"DQL filter for --type, e.g. 'ge(initial_release_date, \"2000\")'."`
+	Root       []string `help:"Export the subgraph reachable from these UIDs." xor:"mode" required:"" sep:","`
+	Depth      int      `help:"Number of edges to follow from each --root." default:"2"`
+	Reverse    bool     `help:"Also follow reverse edges from each --root, e.g. from a genre to its films."`
+	Format     string   `help:"Export format: ndjson, json or rdf." default:"ndjson" enum:"ndjson,json,rdf"`
+	Out        string   `short:"O" help:"Write to this file instead of stdout." type:"path"`
+	PageSize   int      `help:"Nodes fetched per page with --type." default:"500"`
+	BlankNodes bool     `help:"Write UIDs as blank nodes (_:0x1f) so the export loads as new nodes." default:"true" negatable:""`
+}
+
+func (c *ExportCmd) Run(client *movies.Client) error {
+	ctx := context.Background()
+	if c.Depth < 0 {
+		return fmt.Errorf("--depth must not be negative")
+	}
+	if c.PageSize <= 0 {
+		return fmt.Errorf("--page-size must be positive")
+	}
+
+	var w io.Writer = os.Stdout
+	if c.Out != "" {
+		f, err := os.Create(c.Out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	e := &exporter{w: bufio.NewWriter(w), format: c.Format, blank: c.BlankNodes}
+
+	var err error
+	if c.Type != "" {
+		err = c.exportByType(ctx, client, e)
+	} else {
+		err = c.exportRoots(ctx, client, e)
+	}
+	if err != nil {
+		return err
+	}
+	if err := e.close(); err != nil {
+		return err
+	}
+	if c.Out != "" {
+		fmt.Fprintf(os.Stderr, "exported %d nodes to %s\n", e.nodes, c.Out)
+	}
+	return nil
+}
+
+func (c *ExportCmd) exportByType(ctx context.Context, client *movies.Client, e *exporter) error {
+	var typeName string
+	for name := range exportTypes {
+		if normalizeColumn(name) == normalizeColumn(c.Type) {
+			typeName = name
+		}
+	}
+	if typeName == "" {
+		names := make([]string, 0, len(exportTypes))
+		for name := range exportTypes {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return fmt.Errorf("unknown type %q: use one of %s", c.Type, strings.Join(names, ", "))
+	}
+	for node, err := range exportTypes[typeName].nodes(ctx, client, c.Filter, c.PageSize) {
+		if err != nil {
+			return err
+		}
+		if err := e.write(node, typeName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ExportCmd) exportRoots(ctx context.Context, client *movies.Client, e *exporter) error {
+	for _, root := range c.Root {
+		if !movies.IsUID(root) {
+			return fmt.Errorf("invalid --root %q: use a UID such as 0x1f", root)
+		}
+	}
+	var reverse []string
+	if c.Reverse {
+		schema, err := client.Schema(ctx)
+		if err != nil {
+			return err
+		}
+		for _, p := range schema.Predicates {
+			if p.Reverse {
+				reverse = append(reverse, "~"+p.Predicate)
+			}
+		}
+	}
+	query := "query export($root: string) {\n\tnodes(func: uid($root)) " + subgraphBlock(c.Depth, reverse, 1) + "\n}"
+	for _, root := range c.Root {
+		resp, err := client.QueryRaw(ctx, query, map[string]string{"$root": root})
+		if err != nil {
+			return err
+		}
+		var data struct {
+			Nodes []json.RawMessage `json:"nodes"`
+		}
+		if err := json.Unmarshal(resp, &data); err != nil {
+			return fmt.Errorf("parsing export response: %w", err)
+		}
+		for _, node := range data.Nodes {
+			if err := e.write(node, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// subgraphBlock selects every predicate of a node and, while depth remains,
+// the same again for each node it links to.
+func subgraphBlock(depth int, reverse []string, indent int) string {
+	pad := strings.Repeat("\t", indent+1)
+	var b strings.Builder
+	b.WriteString("{\n" + pad + "uid\n" + pad + "dgraph.type\n" + pad + "expand(_all_)")
+	if depth > 0 {
+		inner := subgraphBlock(depth-1, reverse, indent+1)
+		b.WriteString(" " + inner)
+		for _, r := range reverse {
+			b.WriteString("\n" + pad + r + " " + inner)
+		}
+	}
+	b.WriteString("\n" + strings.Repeat("\t", indent) + "}")
+	return b.String()
+}
+
+// exportType streams the nodes of one entity type and maps its JSON field
+// names to predicates for RDF output.
+type exportType struct {
+	nodes     func(ctx context.Context, client *movies.Client, filter string, pageSize int) iter.Seq2[any, error]
+	predicate func(field string) (string, error)
+}
+
+var exportTypes = map[string]exportType{
+	"Actor": {
+		nodes: func(ctx context.Context, client *movies.Client, filter string, pageSize int) iter.Seq2[any, error] {
+			return anySeq(client.Actor.Query(ctx).Filter(filter).First(pageSize).Iter())
+		},
+		predicate: fieldPredicate[movies.Actor],
+	},
+	"ContentRating": {
+		nodes: func(ctx context.Context, client *movies.Client, filter string, pageSize int) iter.Seq2[any, error] {
+			return anySeq(client.ContentRating.Query(ctx).Filter(filter).First(pageSize).Iter())
+		},
+		predicate: fieldPredicate[movies.ContentRating],
+	},
+	"Country": {
+		nodes: func(ctx context.Context, client *movies.Client, filter string, pageSize int) iter.Seq2[any, error] {
+			return anySeq(client.Country.Query(ctx).Filter(filter).First(pageSize).Iter())
+		},
+		predicate: fieldPredicate[movies.Country],
+	},
+	"Director": {
+		nodes: func(ctx context.Context, client *movies.Client, filter string, pageSize int) iter.Seq2[any, error] {
+			return anySeq(client.Director.Query(ctx).Filter(filter).First(pageSize).Iter())
+		},
+		predicate: fieldPredicate[movies.Director],
+	},
+	"Film": {
+		nodes: func(ctx context.Context, client *movies.Client, filter string, pageSize int) iter.Seq2[any, error] {
+			return anySeq(client.Film.Query(ctx).Filter(filter).First(pageSize).Iter())
+		},
+		predicate: fieldPredicate[movies.Film],
+	},
+	"Genre": {
+		nodes: func(ctx context.Context, client *movies.Client, filter string, pageSize int) iter.Seq2[any, error] {
+			return anySeq(client.Genre.Query(ctx).Filter(filter).First(pageSize).Iter())
+		},
+		predicate: fieldPredicate[movies.Genre],
+	},
+	"Location": {
+		nodes: func(ctx context.Context, client *movies.Client, filter string, pageSize int) iter.Seq2[any, error] {
+			return anySeq(client.Location.Query(ctx).Filter(filter).First(pageSize).Iter())
+		},
+		predicate: fieldPredicate[movies.Location],
+	},
+	"Performance": {
+		nodes: func(ctx context.Context, client *movies.Client, filter string, pageSize int) iter.Seq2[any, error] {
+			return anySeq(client.Performance.Query(ctx).Filter(filter).First(pageSize).Iter())
+		},
+		predicate: fieldPredicate[movies.Performance],
+	},
+	"Rating": {
+		nodes: func(ctx context.Context, client *movies.Client, filter string, pageSize int) iter.Seq2[any, error] {
+			return anySeq(client.Rating.Query(ctx).Filter(filter).First(pageSize).Iter())
+		},
+		predicate: fieldPredicate[movies.Rating],
+	},
+}
+
+func anySeq[E any](seq iter.Seq2[E, error]) iter.Seq2[any, error] {
+	return func(yield func(any, error) bool) {
+		for v, err := range seq {
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
+
+// fieldPredicate returns the Dgraph predicate of a field of T.
+func fieldPredicate[T any](field string) (string, error) {
+	f, err := movies.ParseField[T](field)
+	if err != nil {
+		return "", err
+	}
+	return f.Predicate(), nil
+}
+
+// exporter writes nodes in one export format.
+type exporter struct {
+	w      *bufio.Writer
+	format string
+	blank  bool
+	nodes  int
+	// records counts the JSON objects written, which includes the nodes
+	// linked over reverse edges.
+	records int
+	anon    int
+	seen    map[string]bool // triples already written for this record
+}
+
+// write exports one top-level node. typeName is used to map JSON field
+// names to predicates when the node does not carry its dgraph.type.
+func (e *exporter) write(v any, typeName string) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	doc, err := decodeOrdered(raw)
+	if err != nil {
+		return err
+	}
+	node, ok := doc.(*object)
+	if !ok {
+		return fmt.Errorf("unexpected export value %s", raw)
+	}
+	e.nodes++
+	if e.format == "rdf" {
+		e.seen = make(map[string]bool)
+		e.quads(node, typeName)
+		return nil
+	}
+	// Nodes reached over a reverse edge are written after the node, as
+	// records of their own that link back to it.
+	node, linked := e.mutation(node, typeName)
+	for _, n := range append([]*object{node}, linked...) {
+		if err := e.record(e.clean(n)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record writes one JSON object, as an NDJSON line or a JSON array element.
+func (e *exporter) record(v any) error {
+	e.records++
+	if e.format == "json" {
+		if e.records == 1 {
+			e.w.WriteString("[\n  ")
+		} else {
+			e.w.WriteString(",\n  ")
+		}
+		b, err := json.MarshalIndent(v, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = e.w.Write(b)
+		return err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.w.Write(b)
+	return e.w.WriteByte('\n')
+}
+
+// mutation rewrites a node as a Dgraph JSON mutation: keys become
+// predicates, as quads writes them. A mutation cannot set a reverse edge
+// (~genre), so the nodes under one are returned separately, each setting
+// the forward edge to this node.
+func (e *exporter) mutation(node *object, typeName string) (*object, []*object) {
+	if types, ok := node.values["dgraph.type"].([]any); ok && len(types) > 0 {
+		typeName, _ = types[0].(string)
+	}
+	out := &object{values: make(map[string]any, len(node.keys))}
+	var linked []*object
+	set := func(k string, v any) {
+		if _, ok := out.values[k]; !ok {
+			out.keys = append(out.keys, k)
+		}
+		out.values[k] = v
+	}
+	for _, k := range node.keys {
+		pred, facet, hasFacet := strings.Cut(k, "|")
+		if pred != "uid" && pred != "dgraph.type" {
+			if t, ok := exportTypes[typeName]; ok {
+				if p, err := t.predicate(pred); err == nil {
+					pred = p
+				}
+			}
+		}
+		rev, reverse := strings.CutPrefix(pred, "~")
+		if !reverse {
+			if hasFacet {
+				pred += "|" + facet
+			}
+			set(pred, e.children(node.values[k], &linked))
+			continue
+		}
+		if hasFacet {
+			continue
+		}
+		if _, ok := node.values["uid"]; !ok {
+			e.anon++
+			set("uid", "_:anon"+strconv.Itoa(e.anon))
+		}
+		vals, ok := node.values[k].([]any)
+		if !ok {
+			vals = []any{node.values[k]}
+		}
+		for _, v := range vals {
+			child, ok := v.(*object)
+			if !ok {
+				continue
+			}
+			c, more := e.mutation(child, "")
+			c.keys = append(c.keys, rev)
+			c.values[rev] = &object{keys: []string{"uid"}, values: map[string]any{"uid": out.values["uid"]}}
+			linked = append(append(linked, c), more...)
+		}
+	}
+	return out, linked
+}
+
+// children rewrites the nodes in an edge's value with mutation, adding the
+// nodes they link over reverse edges to linked.
+func (e *exporter) children(v any, linked *[]*object) any {
+	switch v := v.(type) {
+	case *object:
+		if isGeoJSON(v) {
+			return v
+		}
+		c, more := e.mutation(v, "")
+		*linked = append(*linked, more...)
+		return c
+	case []any:
+		out := make([]any, len(v))
+		for i, elem := range v {
+			out[i] = e.children(elem, linked)
+		}
+		return out
+	}
+	return v
+}
+
+func (e *exporter) close() error {
+	if e.format == "json" {
+		if e.records == 0 {
+			e.w.WriteString("[]\n")
+		} else {
+			e.w.WriteString("\n]\n")
+		}
+	}
+	return e.w.Flush()
+}
+
+// clean prepares a node for JSON output: UIDs become blank nodes when
+// requested and the zero time of unset dates is dropped.
+func (e *exporter) clean(v any) any {
+	switch v := v.(type) {
+	case *object:
+		out := &object{values: make(map[string]any, len(v.keys))}
+		for _, k := range v.keys {
+			val := v.values[k]
+			if k == "uid" {
+				if s, ok := val.(string); ok {
+					val = e.uid(s)
+				}
+			} else if isZeroTime(val) {
+				continue
+			} else {
+				val = e.clean(val)
+			}
+			out.keys = append(out.keys, k)
+			out.values[k] = val
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, elem := range v {
+			out[i] = e.clean(elem)
+		}
+		return out
+	}
+	return v
+}
+
+func (e *exporter) uid(uid string) string {
+	if e.blank && !strings.HasPrefix(uid, "_:") {
+		return "_:" + uid
+	}
+	return uid
+}
+
+// quads writes the triples of a node and everything nested in it, and
+// returns the node's subject. Reverse edges (~genre) are written the right
+// way round, from the linked node to this one.
+func (e *exporter) quads(node *object, typeName string) string {
+	var subject string
+	if uid, ok := node.values["uid"].(string); ok {
+		subject = e.uid(uid)
+		if !e.blank {
+			subject = "<" + subject + ">"
+		}
+	} else {
+		e.anon++
+		subject = "_:anon" + strconv.Itoa(e.anon)
+	}
+	if types, ok := node.values["dgraph.type"].([]any); ok {
+		for i, t := range types {
+			s, _ := t.(string)
+			if i == 0 {
+				typeName = s
+			}
+			e.quad(subject, "dgraph.type", quoteLiteral(s))
+		}
+	}
+	for _, k := range node.keys {
+		if k == "uid" || k == "dgraph.type" || strings.Contains(k, "|") {
+			continue
+		}
+		pred := k
+		if t, ok := exportTypes[typeName]; ok {
+			if p, err := t.predicate(k); err == nil {
+				pred = p
+			}
+		}
+		vals, ok := node.values[k].([]any)
+		if !ok {
+			vals = []any{node.values[k]}
+		}
+		for _, v := range vals {
+			child, ok := v.(*object)
+			if !ok || isGeoJSON(child) {
+				if lit, ok := rdfLiteral(v); ok {
+					e.quad(subject, pred, lit)
+				}
+				continue
+			}
+			target := e.quads(child, "")
+			if rev, ok := strings.CutPrefix(pred, "~"); ok {
+				e.quad(target, rev, subject)
+			} else {
+				e.quad(subject, pred, target)
+			}
+		}
+	}
+	return subject
+}
+
+// quad writes one triple. A subgraph can reach the same node along several
+// paths, so triples already written for the current record are skipped.
+func (e *exporter) quad(subject, predicate, object string) {
+	line := subject + " <" + predicate + "> " + object + " .\n"
+	if e.seen[line] {
+		return
+	}
+	e.seen[line] = true
+	e.w.WriteString(line)
+}
+
+func isGeoJSON(o *object) bool {
+	_, hasType := o.values["type"]
+	_, hasCoords := o.values["coordinates"]
+	return hasType && hasCoords && len(o.keys) == 2
+}
+
+func isZeroTime(v any) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return err == nil && t.IsZero()
+}
+
+// rdfLiteral formats a scalar as a typed N-Quad literal. Zero times are
+// skipped.
+func rdfLiteral(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			if t.IsZero() {
+				return "", false
+			}
+			return quoteLiteral(v) + "^^<xs:dateTime>", true
+		}
+		return quoteLiteral(v), true
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return quoteLiteral(v.String()) + "^^<xs:int>", true
+		}
+		return quoteLiteral(v.String()) + "^^<xs:float>", true
+	case bool:
+		return quoteLiteral(strconv.FormatBool(v)) + "^^<xs:boolean>", true
+	case *object:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", false
+		}
+		return quoteLiteral(string(b)) + "^^<geo:geojson>", true
+	}
+	return "", false
+}
+
+// quoteLiteral quotes s using the N-Quads string escapes.
+func quoteLiteral(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+func TestExportMutations(t *testing.T) {
+	genre := movies.Genre{
+		UID:   "0x1",
+		DType: []string{"Genre"},
+		Name:  "Crime",
+		Films: []movies.Film{{
+			UID:                "0x2",
+			DType:              []string{"Film"},
+			Name:               "Heat",
+			InitialReleaseDate: time.Date(1995, 12, 15, 0, 0, 0, 0, time.UTC),
+			Countries:          []movies.Country{{UID: "0x3", DType: []string{"Country"}, Name: "USA"}},
+		}},
+	}
+	tests := []struct {
+		format string
+		blank  bool
+		want   string
+	}{
+		{"ndjson", true, `{"uid":"_:0x1","dgraph.type":["Genre"],"name":"Crime"}
+{"uid":"_:0x2","dgraph.type":["Film"],"name":"Heat","initial_release_date":"1995-12-15T00:00:00Z","country":[{"uid":"_:0x3","dgraph.type":["Country"],"name":"USA"}],"genre":{"uid":"_:0x1"}}
+`},
+		{"ndjson", false, `{"uid":"0x1","dgraph.type":["Genre"],"name":"Crime"}
+{"uid":"0x2","dgraph.type":["Film"],"name":"Heat","initial_release_date":"1995-12-15T00:00:00Z","country":[{"uid":"0x3","dgraph.type":["Country"],"name":"USA"}],"genre":{"uid":"0x1"}}
+`},
+		{"json", true, `[
+  {
+    "uid": "_:0x1",
+    "dgraph.type": [
+      "Genre"
+    ],
+    "name": "Crime"
+  },
+  {
+    "uid": "_:0x2",
+    "dgraph.type": [
+      "Film"
+    ],
+    "name": "Heat",
+    "initial_release_date": "1995-12-15T00:00:00Z",
+    "country": [
+      {
+        "uid": "_:0x3",
+        "dgraph.type": [
+          "Country"
+        ],
+        "name": "USA"
+      }
+    ],
+    "genre": {
+      "uid": "_:0x1"
+    }
+  }
+]
+`},
+		{"rdf", true, `_:0x1 <dgraph.type> "Genre" .
+_:0x1 <name> "Crime" .
+_:0x2 <dgraph.type> "Film" .
+_:0x2 <name> "Heat" .
+_:0x2 <initial_release_date> "1995-12-15T00:00:00Z"^^<xs:dateTime> .
+_:0x3 <dgraph.type> "Country" .
+_:0x3 <name> "USA" .
+_:0x2 <country> _:0x3 .
+_:0x2 <genre> _:0x1 .
+`},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		e := &exporter{w: bufio.NewWriter(&out), format: tt.format, blank: tt.blank}
+		if err := e.write(genre, "Genre"); err != nil {
+			t.Fatalf("%s: write: %v", tt.format, err)
+		}
+		if err := e.close(); err != nil {
+			t.Fatalf("%s: close: %v", tt.format, err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%s (blank nodes %v):\n%s\nwant:\n%s", tt.format, tt.blank, out.String(), tt.want)
+		}
+	}
+}
+
+func TestExportAnonymousReverse(t *testing.T) {
+	// A node without a UID that has reverse edges is given a blank node,
+	// so the nodes linking to it can name it.
+	var out strings.Builder
+	e := &exporter{w: bufio.NewWriter(&out), format: "ndjson", blank: true}
+	node := map[string]any{
+		"dgraph.type": []string{"Genre"},
+		"name":        "Noir",
+		"~genre":      []any{map[string]any{"uid": "0x9", "name": "Laura"}},
+	}
+	if err := e.write(node, ""); err != nil {
+		t.Fatal(err)
+	}
+	e.close()
+	want := `{"dgraph.type":["Genre"],"name":"Noir","uid":"_:anon1"}
+{"name":"Laura","uid":"_:0x9","genre":{"uid":"_:anon1"}}
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
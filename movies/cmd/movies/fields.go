@@ -0,0 +1,20 @@
+package main
+
+import movies "github.com/mlwelles/modusGraphMoviesProject/movies"
+
+// selectFields returns the client to read with: a Select view of client
+// when --fields was given.
+func selectFields[T any, C any](client C, fields []string, sel func(...movies.Field[T]) C) (C, error) {
+	if len(fields) == 0 {
+		return client, nil
+	}
+	parsed := make([]movies.Field[T], len(fields))
+	for i, name := range fields {
+		f, err := movies.ParseField[T](name)
+		if err != nil {
+			return client, err
+		}
+		parsed[i] = f
+	}
+	return sel(parsed...), nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/graphql"
+)
+
+// GraphQLCmd serves the entity clients over GraphQL.
+type GraphQLCmd struct {
+	Run    GraphQLRunCmd    `cmd:"" default:"withargs" help:"Serve the GraphQL API (the default)."`
+	Schema GraphQLSchemaCmd `cmd:"" help:"Print the GraphQL schema."`
+}
+
+// GraphQLRunCmd runs the GraphQL server until interrupted.
+type GraphQLRunCmd struct {
+	Listen     string `help:"Address to listen on." default:":8082" env:"MOVIES_GRAPHQL_LISTEN"`
+	Playground bool   `help:"Serve a GraphiQL playground page at /."`
+	Quiet      bool   `help:"Do not log requests to stderr."`
+}
+
+func (c *GraphQLRunCmd) Run(client *movies.Client) error {
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", graphql.Handler(client))
+	if c.Playground {
+		mux.Handle("/{$}", graphql.Playground("/graphql"))
+	}
+	var handler http.Handler = mux
+	if !c.Quiet {
+		handler = logRequests(handler)
+	}
+	return listenAndServe(c.Listen, handler)
+}
+
+// GraphQLSchemaCmd prints the schema in SDL.
+type GraphQLSchemaCmd struct{}
+
+func (c *GraphQLSchemaCmd) Run() error {
+	fmt.Print(graphql.Schema())
+	return nil
+}
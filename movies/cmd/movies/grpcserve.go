@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/grpcapi"
+)
+
+// GRPCServeCmd serves the entity clients over gRPC.
+type GRPCServeCmd struct {
+	Run   GRPCServeRunCmd   `cmd:"" default:"withargs" help:"Serve the gRPC API (the default)."`
+	Proto GRPCServeProtoCmd `cmd:"" help:"Print the services as a .proto file."`
+}
+
+// GRPCServeRunCmd runs the gRPC server until interrupted.
+type GRPCServeRunCmd struct {
+	Listen string `help:"Address to listen on." default:":50051" env:"MOVIES_GRPC_LISTEN"`
+	Quiet  bool   `help:"Do not log calls to stderr."`
+}
+
+func (c *GRPCServeRunCmd) Run(client *movies.Client) error {
+	var opts []grpc.ServerOption
+	if !c.Quiet {
+		opts = append(opts, grpc.UnaryInterceptor(logUnary), grpc.StreamInterceptor(logStream))
+	}
+	srv := grpc.NewServer(opts...)
+	grpcapi.Register(srv, client)
+	reflection.Register(srv)
+
+	lis, err := net.Listen("tcp", c.Listen)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(lis) }()
+	fmt.Fprintf(os.Stderr, "listening on %s\n", c.Listen)
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	// Let calls in flight finish, but not for longer than the HTTP servers do.
+	timer := time.AfterFunc(10*time.Second, srv.Stop)
+	defer timer.Stop()
+	srv.GracefulStop()
+	return <-errc
+}
+
+// logUnary writes one line per unary call to stderr.
+func logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(info.FullMethod, err, start)
+	return resp, err
+}
+
+// logStream writes one line per streaming call to stderr.
+func logStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	logCall(info.FullMethod, err, start)
+	return err
+}
+
+func logCall(method string, err error, start time.Time) {
+	fmt.Fprintf(os.Stderr, "%s %s %s\n", method, status.Code(err), time.Since(start).Round(time.Microsecond))
+}
+
+// GRPCServeProtoCmd prints the .proto file describing the services.
+type GRPCServeProtoCmd struct{}
+
+func (c *GRPCServeProtoCmd) Run() error {
+	fmt.Print(grpcapi.Proto())
+	return nil
+}
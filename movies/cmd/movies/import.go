@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"go.yaml.in/yaml/v3"
+	"golang.org/x/term"
+)
+
+// ImportCmd loads entities from a CSV, TSV, JSON or NDJSON file.
+type ImportCmd struct {
+	Entity    string `arg:"" enum:"actor,content-rating,country,director,film,genre,location,performance,rating" help:"Entity to import: ${enum}."`
+	File      string `arg:"" help:"File to import (- for stdin)."`
+	Format    string `help:"Input format: auto, csv, tsv, json or ndjson." default:"auto" enum:"auto,csv,tsv,json,ndjson"`
+	Mapping   string `help:"YAML or JSON file mapping input columns to fields." type:"existingfile"`
+	Separator string `help:"Separator between values in multi-valued cells such as genres." default:"|"`
+	Match     bool   `help:"Update existing entities with the same name instead of adding duplicates." default:"true" negatable:""`
+	DryRun    bool   `help:"Validate every row, including edge lookups, without writing."`
+	Errors    string `help:"Write rejected rows with their error to this file."`
+}
+
+// ImportSummary reports the outcome of an import.
+type ImportSummary struct {
+	Rows     int  `json:"rows"`
+	Added    int  `json:"added"`
+	Updated  int  `json:"updated"`
+	Rejected int  `json:"rejected"`
+	DryRun   bool `json:"dryRun,omitempty"`
+}
+
+// importTarget adapts one entity's add/update flags to the importer.
+type importTarget struct {
+	typeName  string
+	named     bool
+	newFields func() any
+	// save builds the entity from fields and adds it, or updates uid when
+	// it is not empty. With dryRun it only builds.
+	save func(ctx context.Context, client *movies.Client, fields any, uid string, dryRun bool) error
+}
+
+type entityFields[E any] interface {
+	build(ctx context.Context, client *movies.Client) (*E, []link, error)
+}
+
+func newImportTarget[E any, F any, PF interface {
+	*F
+	entityFields[E]
+}](typeName string, named bool,
+	add, update func(*movies.Client) func(context.Context, *E) error,
+	uidOf func(*E) *string,
+) importTarget {
+	return importTarget{
+		typeName:  typeName,
+		named:     named,
+		newFields: func() any { return PF(new(F)) },
+		save: func(ctx context.Context, client *movies.Client, fields any, uid string, dryRun bool) error {
+			v, links, err := fields.(PF).build(ctx, client)
+			if err != nil || dryRun {
+				return err
+			}
+			if uid != "" {
+				*uidOf(v) = uid
+				err = update(client)(ctx, v)
+			} else {
+				err = add(client)(ctx, v)
+			}
+			if err != nil {
+				return err
+			}
+			for _, l := range links {
+				if err := l(ctx, *uidOf(v)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+var importTargets = map[string]importTarget{
+	"actor": newImportTarget[movies.Actor, ActorFields]("Actor", true,
+		func(c *movies.Client) func(context.Context, *movies.Actor) error { return c.Actor.Add },
+		func(c *movies.Client) func(context.Context, *movies.Actor) error { return c.Actor.Update },
+		func(v *movies.Actor) *string { return &v.UID }),
+	"content-rating": newImportTarget[movies.ContentRating, ContentRatingFields]("ContentRating", true,
+		func(c *movies.Client) func(context.Context, *movies.ContentRating) error { return c.ContentRating.Add },
+		func(c *movies.Client) func(context.Context, *movies.ContentRating) error {
+			return c.ContentRating.Update
+		},
+		func(v *movies.ContentRating) *string { return &v.UID }),
+	"country": newImportTarget[movies.Country, CountryFields]("Country", true,
+		func(c *movies.Client) func(context.Context, *movies.Country) error { return c.Country.Add },
+		func(c *movies.Client) func(context.Context, *movies.Country) error { return c.Country.Update },
+		func(v *movies.Country) *string { return &v.UID }),
+	"director": newImportTarget[movies.Director, DirectorFields]("Director", true,
+		func(c *movies.Client) func(context.Context, *movies.Director) error { return c.Director.Add },
+		func(c *movies.Client) func(context.Context, *movies.Director) error { return c.Director.Update },
+		func(v *movies.Director) *string { return &v.UID }),
+	"film": newImportTarget[movies.Film, FilmFields]("Film", true,
+		func(c *movies.Client) func(context.Context, *movies.Film) error { return c.Film.Add },
+		func(c *movies.Client) func(context.Context, *movies.Film) error { return c.Film.Update },
+		func(v *movies.Film) *string { return &v.UID }),
+	"genre": newImportTarget[movies.Genre, GenreFields]("Genre", true,
+		func(c *movies.Client) func(context.Context, *movies.Genre) error { return c.Genre.Add },
+		func(c *movies.Client) func(context.Context, *movies.Genre) error { return c.Genre.Update },
+		func(v *movies.Genre) *string { return &v.UID }),
+	"location": newImportTarget[movies.Location, LocationFields]("Location", true,
+		func(c *movies.Client) func(context.Context, *movies.Location) error { return c.Location.Add },
+		func(c *movies.Client) func(context.Context, *movies.Location) error { return c.Location.Update },
+		func(v *movies.Location) *string { return &v.UID }),
+	"performance": newImportTarget[movies.Performance, PerformanceFields]("Performance", false,
+		func(c *movies.Client) func(context.Context, *movies.Performance) error { return c.Performance.Add },
+		func(c *movies.Client) func(context.Context, *movies.Performance) error { return c.Performance.Update },
+		func(v *movies.Performance) *string { return &v.UID }),
+	"rating": newImportTarget[movies.Rating, RatingFields]("Rating", true,
+		func(c *movies.Client) func(context.Context, *movies.Rating) error { return c.Rating.Add },
+		func(c *movies.Client) func(context.Context, *movies.Rating) error { return c.Rating.Update },
+		func(v *movies.Rating) *string { return &v.UID }),
+}
+
+// importRecord is one input row. Columns keep their input order so that the
+// error report mirrors the input.
+type importRecord struct {
+	line int
+	cols []string
+	vals []any
+}
+
+func (c *ImportCmd) Run(client *movies.Client) error {
+	target := importTargets[c.Entity]
+	ctx := context.Background()
+
+	data, err := readInput(c.File)
+	if err != nil {
+		return err
+	}
+	format := c.Format
+	if format == "auto" {
+		format = detectFormat(c.File, data)
+	}
+	records, err := parseRecords(format, data)
+	if err != nil {
+		return err
+	}
+	mapping, err := loadMapping(c.Mapping)
+	if err != nil {
+		return err
+	}
+	setters := fieldSetters(reflect.TypeOf(target.newFields()).Elem())
+
+	var rejected []rejectedRow
+	summary := ImportSummary{DryRun: c.DryRun}
+	progress := newProgress(len(records))
+	for _, rec := range records {
+		summary.Rows++
+		progress.step(summary.Rows)
+		updated, err := c.importRow(ctx, client, target, setters, mapping, rec)
+		switch {
+		case err != nil:
+			summary.Rejected++
+			rejected = append(rejected, rejectedRow{rec, err})
+		case updated:
+			summary.Updated++
+		default:
+			summary.Added++
+		}
+	}
+	progress.done()
+
+	if c.Errors != "" && len(rejected) > 0 {
+		if err := writeRejected(c.Errors, format, rejected); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range rejected {
+			fmt.Fprintf(os.Stderr, "row %d: %v\n", r.line, r.err)
+		}
+	}
+	return printResult(summary)
+}
+
+// importRow reports whether the row updated an existing entity.
+func (c *ImportCmd) importRow(ctx context.Context, client *movies.Client, target importTarget,
+	setters map[string]fieldSetter, mapping map[string]string, rec importRecord) (bool, error) {
+	fields := target.newFields()
+	fv := reflect.ValueOf(fields).Elem()
+	for i, col := range rec.cols {
+		if strings.HasPrefix(col, "_") {
+			continue
+		}
+		name := col
+		if m, ok := mapping[col]; ok {
+			if m == "-" || m == "" {
+				continue
+			}
+			name = m
+		}
+		s, ok := setters[normalizeColumn(name)]
+		if !ok {
+			return false, fmt.Errorf("column %q does not match a %s field; map it with --mapping or map it to -", col, target.typeName)
+		}
+		vals := cellStrings(rec.vals[i], s.multi, c.Separator)
+		f := fv.Field(s.index)
+		if s.multi {
+			f.Set(reflect.AppendSlice(f, reflect.ValueOf(vals)))
+		} else if len(vals) > 0 {
+			f.SetString(vals[0])
+		}
+	}
+
+	var uid string
+	if c.Match && target.named {
+		name := fv.FieldByName("Name").String()
+		if name == "" {
+			return false, fmt.Errorf("missing name")
+		}
+		uids, err := lookupByName(ctx, client, target.typeName, name)
+		if err != nil {
+			return false, err
+		}
+		switch len(uids) {
+		case 0:
+		case 1:
+			uid = uids[0]
+		default:
+			return false, fmt.Errorf("more than one %s is named %q", target.typeName, name)
+		}
+	}
+	if err := target.save(ctx, client, fields, uid, c.DryRun); err != nil {
+		return false, err
+	}
+	return uid != "", nil
+}
+
+type fieldSetter struct {
+	index int
+	multi bool
+}
+
+// fieldSetters indexes the settable fields of an <Entity>Fields struct by
+// normalized flag name and Go field name, so "genre", "genres" and
+// "Genres" all find Genres and "initial_release_date" finds
+// InitialReleaseDate.
+func fieldSetters(t reflect.Type) map[string]fieldSetter {
+	out := make(map[string]fieldSetter)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Name == "FromJSON" {
+			continue
+		}
+		s := fieldSetter{index: i, multi: f.Type.Kind() == reflect.Slice}
+		out[normalizeColumn(f.Name)] = s
+		if flag := f.Tag.Get("name"); flag != "" {
+			out[normalizeColumn(flag)] = s
+		}
+	}
+	return out
+}
+
+func normalizeColumn(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// cellStrings converts an input value to flag strings. Multi-valued CSV
+// cells are split on sep; JSON arrays may hold names, UIDs or objects with
+// a uid or name. A GeoJSON point becomes "lat,lng".
+func cellStrings(v any, multi bool, sep string) []string {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if !multi {
+			if v = strings.TrimSpace(v); v == "" {
+				return nil
+			}
+			return []string{v}
+		}
+		var out []string
+		for _, part := range strings.Split(v, sep) {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+		return out
+	case []any:
+		var out []string
+		for _, e := range v {
+			out = append(out, cellStrings(e, false, sep)...)
+		}
+		return out
+	case map[string]any:
+		if coords, ok := v["coordinates"].([]any); ok && len(coords) == 2 {
+			return []string{fmt.Sprintf("%v,%v", coords[1], coords[0])}
+		}
+		for _, k := range []string{"uid", "name"} {
+			if s, ok := v[k].(string); ok && s != "" {
+				return []string{s}
+			}
+		}
+		return nil
+	}
+	return []string{fmt.Sprint(v)}
+}
+
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// detectFormat picks a format from the file extension, falling back to the
+// first non-blank character of the content.
+func detectFormat(path string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv"
+	case ".tsv", ".tab":
+		return "tsv"
+	case ".json":
+		return "json"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	}
+	trimmed := bytes.TrimLeftFunc(data, unicode.IsSpace)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		return "json"
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return "ndjson"
+	}
+	line, _, _ := bytes.Cut(trimmed, []byte("\n"))
+	if bytes.Count(line, []byte("\t")) > bytes.Count(line, []byte(",")) {
+		return "tsv"
+	}
+	return "csv"
+}
+
+func parseRecords(format string, data []byte) ([]importRecord, error) {
+	switch format {
+	case "csv", "tsv":
+		r := csv.NewReader(bytes.NewReader(data))
+		if format == "tsv" {
+			r.Comma = '\t'
+			r.LazyQuotes = true
+		}
+		rows, err := r.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", format, err)
+		}
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		header := rows[0]
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+		records := make([]importRecord, 0, len(rows)-1)
+		for i, row := range rows[1:] {
+			rec := importRecord{line: i + 2, cols: header, vals: make([]any, len(row))}
+			for j, v := range row {
+				rec.vals[j] = v
+			}
+			records = append(records, rec)
+		}
+		return records, nil
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		var objs []json.RawMessage
+		if err := dec.Decode(&objs); err != nil {
+			return nil, fmt.Errorf("reading json: expected an array of objects: %w", err)
+		}
+		records := make([]importRecord, 0, len(objs))
+		for i, raw := range objs {
+			rec, err := jsonRecord(i+1, raw)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, rec)
+		}
+		return records, nil
+	case "ndjson":
+		var records []importRecord
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for n := 1; sc.Scan(); n++ {
+			line := bytes.TrimSpace(sc.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			rec, err := jsonRecord(n, line)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, rec)
+		}
+		return records, sc.Err()
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// jsonRecord decodes one object, keeping its key order.
+func jsonRecord(n int, raw []byte) (importRecord, error) {
+	doc, err := decodeOrdered(raw)
+	if err != nil {
+		return importRecord{}, fmt.Errorf("record %d: %w", n, err)
+	}
+	o, ok := doc.(*object)
+	if !ok {
+		return importRecord{}, fmt.Errorf("record %d: expected an object", n)
+	}
+	rec := importRecord{line: n, cols: o.keys}
+	for _, k := range o.keys {
+		rec.vals = append(rec.vals, plain(o.values[k]))
+	}
+	return rec, nil
+}
+
+// loadMapping reads a column-to-field map such as:
+//
+//	Title: name
+//	Year: initialreleasedate
+//	Genre list: genres
+//	Notes: "-"   # ignored
+func loadMapping(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]string
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("reading mapping %s: %w", path, err)
+	}
+	return m, nil
+}
+
+type rejectedRow struct {
+	importRecord
+	err error
+}
+
+// writeRejected writes rejected rows in the input format with leading _row
+// and _error columns. Import skips columns starting with an underscore, so
+// the file can be fixed and imported again.
+func writeRejected(path, format string, rows []rejectedRow) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch format {
+	case "csv", "tsv":
+		w := csv.NewWriter(f)
+		if format == "tsv" {
+			w.Comma = '\t'
+		}
+		if err := w.Write(append([]string{"_row", "_error"}, rows[0].cols...)); err != nil {
+			return err
+		}
+		for _, r := range rows {
+			rec := []string{fmt.Sprint(r.line), r.err.Error()}
+			for _, v := range r.vals {
+				rec = append(rec, fmt.Sprint(v))
+			}
+			if err := w.Write(rec); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	default:
+		objs := make([]*object, len(rows))
+		for j, r := range rows {
+			o := &object{
+				keys:   append([]string{"_row", "_error"}, r.cols...),
+				values: map[string]any{"_row": r.line, "_error": r.err.Error()},
+			}
+			for i, k := range r.cols {
+				o.values[k] = r.vals[i]
+			}
+			objs[j] = o
+		}
+		enc := json.NewEncoder(f)
+		if format == "json" {
+			// JSON input is an array of objects, so the report is one too.
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(objs); err != nil {
+				return err
+			}
+			break
+		}
+		for _, o := range objs {
+			if err := enc.Encode(o); err != nil {
+				return err
+			}
+		}
+	}
+	return f.Close()
+}
+
+// progress reports rows processed on stderr: a live counter on a terminal,
+// otherwise a line every progressEvery rows.
+type progress struct {
+	total int
+	tty   bool
+}
+
+const progressEvery = 500
+
+func newProgress(total int) *progress {
+	return &progress{total: total, tty: term.IsTerminal(int(os.Stderr.Fd()))}
+}
+
+func (p *progress) step(n int) {
+	switch {
+	case p.tty:
+		fmt.Fprintf(os.Stderr, "\rimporting %d/%d", n, p.total)
+	case n%progressEvery == 0:
+		fmt.Fprintf(os.Stderr, "imported %d/%d rows\n", n, p.total)
+	}
+}
+
+func (p *progress) done() {
+	if p.tty && p.total > 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		path, data, want string
+	}{
+		{"films.csv", "{", "csv"},
+		{"films.TSV", "", "tsv"},
+		{"films.tab", "", "tsv"},
+		{"films.json", "", "json"},
+		{"films.jsonl", "", "ndjson"},
+		{"-", "  \n[{}]", "json"},
+		{"-", `{"name":"a"}`, "ndjson"},
+		{"-", "name\tgenres\nHeat\tCrime, Drama\n", "tsv"},
+		{"-", "name,genres\nHeat,Crime\n", "csv"},
+	}
+	for _, tt := range tests {
+		if got := detectFormat(tt.path, []byte(tt.data)); got != tt.want {
+			t.Errorf("detectFormat(%q, %q) = %q, want %q", tt.path, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestParseRecords(t *testing.T) {
+	tests := []struct {
+		format, data string
+		want         []importRecord
+	}{
+		{"csv", "\ufeffname,genres\nHeat,Crime|Drama\n\"Thief, The\",\n", []importRecord{
+			{line: 2, cols: []string{"name", "genres"}, vals: []any{"Heat", "Crime|Drama"}},
+			{line: 3, cols: []string{"name", "genres"}, vals: []any{"Thief, The", ""}},
+		}},
+		{"tsv", "name\ttagline\nHeat\ta \"quoted\" line\n", []importRecord{
+			{line: 2, cols: []string{"name", "tagline"}, vals: []any{"Heat", `a "quoted" line`}},
+		}},
+		{"csv", "", nil},
+		{"json", `[{"name":"Heat","year":1995,"genres":["Crime",{"uid":"0x2"}]},{"tagline":null,"name":"Thief"}]`, []importRecord{
+			{line: 1, cols: []string{"name", "year", "genres"}, vals: []any{"Heat", json.Number("1995"), []any{"Crime", map[string]any{"uid": "0x2"}}}},
+			{line: 2, cols: []string{"tagline", "name"}, vals: []any{nil, "Thief"}},
+		}},
+		{"ndjson", "{\"name\":\"Heat\"}\n\n{\"name\":\"Thief\"}\n", []importRecord{
+			{line: 1, cols: []string{"name"}, vals: []any{"Heat"}},
+			{line: 3, cols: []string{"name"}, vals: []any{"Thief"}},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := parseRecords(tt.format, []byte(tt.data))
+		if err != nil {
+			t.Errorf("%s %q: %v", tt.format, tt.data, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %q:\n got %#v\nwant %#v", tt.format, tt.data, got, tt.want)
+		}
+	}
+
+	for _, bad := range []struct{ format, data string }{
+		{"json", `{"name":"Heat"}`},
+		{"json", `[1]`},
+		{"ndjson", "{\"name\":\"Heat\"}\n[1]\n"},
+		{"csv", "name,genres\nHeat\n"},
+		{"xml", "<film/>"},
+	} {
+		if _, err := parseRecords(bad.format, []byte(bad.data)); err == nil {
+			t.Errorf("%s %q: expected an error", bad.format, bad.data)
+		}
+	}
+}
+
+func TestCellStrings(t *testing.T) {
+	tests := []struct {
+		v     any
+		multi bool
+		want  []string
+	}{
+		{nil, false, nil},
+		{"  Heat ", false, []string{"Heat"}},
+		{"  ", false, nil},
+		{"Crime| Drama ||", true, []string{"Crime", "Drama"}},
+		{[]any{"Crime", map[string]any{"uid": "0x2", "name": "Drama"}, map[string]any{"name": "Noir"}}, true, []string{"Crime", "0x2", "Noir"}},
+		{map[string]any{"type": "Point", "coordinates": []any{-122.28, 37.77}}, false, []string{"37.77,-122.28"}},
+		{float64(1995), false, []string{"1995"}},
+		{true, false, []string{"true"}},
+	}
+	for _, tt := range tests {
+		if got := cellStrings(tt.v, tt.multi, "|"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("cellStrings(%#v, %v) = %q, want %q", tt.v, tt.multi, got, tt.want)
+		}
+	}
+}
+
+func TestFieldSetters(t *testing.T) {
+	setters := fieldSetters(reflect.TypeOf(FilmFields{}))
+	for _, col := range []string{"name", "Name", "genre", "Genres", "initial_release_date", "InitialReleaseDate", "director"} {
+		if _, ok := setters[normalizeColumn(col)]; !ok {
+			t.Errorf("column %q has no setter", col)
+		}
+	}
+	if _, ok := setters[normalizeColumn("from-json")]; ok {
+		t.Error("--from-json is not settable from a column")
+	}
+	if !setters["genres"].multi || setters["name"].multi {
+		t.Error("genres is multi-valued, name is not")
+	}
+}
+
+func TestImportRow(t *testing.T) {
+	// Without --match and with --dry-run a row without edges is only built,
+	// so no client is needed.
+	target := importTargets["film"]
+	setters := fieldSetters(reflect.TypeOf(target.newFields()).Elem())
+	c := &ImportCmd{Separator: "|", DryRun: true}
+	ctx := context.Background()
+
+	rec := importRecord{
+		cols: []string{"_row", "_error", "Title", "Year", "Notes"},
+		vals: []any{"3", "bad date", "Heat", "1995-12-15", "ignored"},
+	}
+	mapping := map[string]string{"Title": "name", "Year": "initialreleasedate", "Notes": "-"}
+	if _, err := c.importRow(ctx, nil, target, setters, mapping, rec); err != nil {
+		t.Errorf("mapped row: %v", err)
+	}
+
+	rec = importRecord{cols: []string{"name", "year"}, vals: []any{"Heat", "1995"}}
+	if _, err := c.importRow(ctx, nil, target, setters, nil, rec); err == nil || !strings.Contains(err.Error(), `column "year"`) {
+		t.Errorf("err = %v, want an unknown column error", err)
+	}
+
+	rec = importRecord{cols: []string{"name", "initialreleasedate"}, vals: []any{"Heat", "someday"}}
+	if _, err := c.importRow(ctx, nil, target, setters, nil, rec); err == nil || !strings.Contains(err.Error(), "--initialreleasedate") {
+		t.Errorf("err = %v, want an invalid date error", err)
+	}
+}
+
+func TestWriteRejected(t *testing.T) {
+	tests := []struct {
+		format, data, want string
+	}{
+		{"csv", "name,initialreleasedate\nHeat,1995\n\"Thief, The\",someday\n",
+			"_row,_error,name,initialreleasedate\n" +
+				"3,bad date,\"Thief, The\",someday\n"},
+		{"tsv", "name\tinitialreleasedate\nHeat\t1995\nThief\tsomeday\n",
+			"_row\t_error\tname\tinitialreleasedate\n" +
+				"3\tbad date\tThief\tsomeday\n"},
+		{"json", `[{"name":"Heat"},{"name":"Thief","genres":["Crime"]}]`, `[
+  {
+    "_row": 2,
+    "_error": "bad date",
+    "name": "Thief",
+    "genres": [
+      "Crime"
+    ]
+  }
+]
+`},
+		{"ndjson", "{\"name\":\"Heat\"}\n{\"name\":\"Thief\"}\n",
+			`{"_row":2,"_error":"bad date","name":"Thief"}` + "\n"},
+	}
+	for _, tt := range tests {
+		records, err := parseRecords(tt.format, []byte(tt.data))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		path := filepath.Join(t.TempDir(), "rejected."+tt.format)
+		if err := writeRejected(path, tt.format, []rejectedRow{{records[1], errors.New("bad date")}}); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("%s:\n%s\nwant:\n%s", tt.format, data, tt.want)
+		}
+
+		// The report is read back in the same format, with the rejected
+		// row's own columns unchanged after _row and _error.
+		again, err := parseRecords(detectFormat(path, data), data)
+		if err != nil {
+			t.Fatalf("%s: re-reading: %v", tt.format, err)
+		}
+		if len(again) != 1 {
+			t.Fatalf("%s: re-read %d records, want 1", tt.format, len(again))
+		}
+		got := importRecord{cols: again[0].cols[2:], vals: again[0].vals[2:]}
+		want := importRecord{cols: records[1].cols, vals: records[1].vals}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: re-read %#v, want %#v", tt.format, got, want)
+		}
+	}
+}
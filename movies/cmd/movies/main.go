@@ -1,16 +1,10 @@
-// Code generated by modusGraphGen. DO NOT EDIT.
-
 package main
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/alecthomas/kong"
@@ -20,10 +14,37 @@ import (
 
 // CLI is the root command parsed by Kong.
 var CLI struct {
-	Addr string `help:"Dgraph gRPC address." default:"dgraph://localhost:9080" env:"DGRAPH_ADDR"`
+	Addr string `help:"Dgraph gRPC address (default dgraph://localhost:9080)." env:"DGRAPH_ADDR"`
 	Dir  string `help:"Local database directory (embedded mode, mutually exclusive with --addr)." env:"DGRAPH_DIR"`
 
-	Query         QueryCmd         `cmd:"" help:"Execute a raw DQL query."`
+	Profile    string `help:"Connection profile from the config file (default: its current profile)." env:"MOVIES_PROFILE"`
+	ConfigFile string `name:"config" help:"Config file (default ~/.config/movies/config.yaml)." env:"MOVIES_CONFIG"`
+
+	Output   string `short:"o" help:"Output format: json, ndjson, table, csv, tsv, yaml or template." default:"json" enum:"json,ndjson,table,csv,tsv,yaml,template" env:"MOVIES_OUTPUT"`
+	Template string `help:"Go text/template applied to each result with --output=template."`
+
+	NoAutoSchema bool `help:"Never alter the schema implicitly; writes then need 'movies schema apply' first." env:"MOVIES_NO_AUTO_SCHEMA"`
+	Explain      bool `help:"Print the DQL of each query, its variables and Dgraph's timings to stderr." env:"MOVIES_EXPLAIN"`
+	RecordOutbox bool `name:"outbox" help:"Record every change in the outbox, for 'movies outbox relay' to deliver." env:"MOVIES_OUTBOX"`
+	Remember     bool `help:"Remember the UIDs and names a command prints, for shell completion." env:"MOVIES_REMEMBER"`
+
+	CacheSize int           `help:"Cache up to this many reads per entity type in memory, for the servers; 0 disables caching." default:"0" env:"MOVIES_CACHE_SIZE"`
+	CacheTTL  time.Duration `name:"cache-ttl" help:"How long a cached read is served." default:"1m" env:"MOVIES_CACHE_TTL"`
+
+	Query         QueryCmd         `cmd:"" help:"Execute raw DQL queries and saved queries."`
+	Shell         ShellCmd         `cmd:"" help:"Start an interactive DQL shell."`
+	Import        ImportCmd        `cmd:"" help:"Import entities from a CSV, TSV, JSON or NDJSON file."`
+	Export        ExportCmd        `cmd:"" help:"Export entities or a subgraph as NDJSON, JSON or RDF."`
+	Schema        SchemaCmd        `cmd:"" help:"Inspect, compare and apply the database schema."`
+	Serve         ServeCmd         `cmd:"" help:"Serve the entity clients over HTTP as a REST API."`
+	GraphQL       GraphQLCmd       `cmd:"" name:"graphql" help:"Serve the entity clients over HTTP as a GraphQL API."`
+	GRPCServe     GRPCServeCmd     `cmd:"" name:"grpc-serve" help:"Serve the entity clients over gRPC."`
+	RPC           RPCCmd           `cmd:"" name:"rpc" help:"Serve JSON-RPC tools over the entity clients to scripts and agents."`
+	Outbox        OutboxCmd        `cmd:"" help:"Deliver recorded changes to webhooks and inspect the outbox."`
+	Stats         StatsCmd         `cmd:"" help:"Report node, predicate and edge counts and missing data."`
+	Config        ConfigCmd        `cmd:"" help:"Manage connection profiles."`
+	Completion    CompletionCmd    `cmd:"" help:"Print a shell completion script for bash, zsh or fish."`
+	Complete      CompleteCmd      `cmd:"" name:"__complete" hidden:"" help:"Print completions for a partial command line."`
 	Actor         ActorCmd         `cmd:"" help:"Manage Actor entities."`
 	ContentRating ContentRatingCmd `cmd:"" help:"Manage ContentRating entities."`
 	Country       CountryCmd       `cmd:"" help:"Manage Country entities."`
@@ -35,64 +56,16 @@ var CLI struct {
 	Rating        RatingCmd        `cmd:"" help:"Manage Rating entities."`
 }
 
-// QueryCmd executes a raw DQL query against the database.
-type QueryCmd struct {
-	Query   string        `arg:"" optional:"" help:"DQL query string (reads stdin if omitted)."`
-	Pretty  bool          `help:"Pretty-print JSON output." default:"true" negatable:""`
-	Timeout time.Duration `help:"Query timeout." default:"30s"`
-}
-
-func (c *QueryCmd) Run(client *movies.Client) error {
-	query := c.Query
-	if query == "" {
-		// Read from stdin.
-		reader := bufio.NewReader(os.Stdin)
-		var sb strings.Builder
-		for {
-			line, err := reader.ReadString('\n')
-			sb.WriteString(line)
-			if err != nil {
-				if err != io.EOF {
-					return fmt.Errorf("reading stdin: %w", err)
-				}
-				break
-			}
-		}
-		query = strings.TrimSpace(sb.String())
-	}
-
-	if query == "" {
-		return fmt.Errorf("empty query: provide a DQL query as an argument or via stdin")
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
-	defer cancel()
-
-	resp, err := client.QueryRaw(ctx, query, nil)
-	if err != nil {
-		return err
-	}
-
-	if c.Pretty {
-		var data any
-		if err := json.Unmarshal(resp, &data); err != nil {
-			return fmt.Errorf("parsing response: %w", err)
-		}
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		return enc.Encode(data)
-	}
-	_, err = fmt.Println(string(resp))
-	return err
-}
-
 // ActorCmd groups subcommands for Actor.
 type ActorCmd struct {
-	Get    ActorGetCmd    `cmd:"" help:"Get a Actor by UID."`
-	List   ActorListCmd   `cmd:"" help:"List Actor entities."`
-	Add    ActorAddCmd    `cmd:"" help:"Add a new Actor."`
-	Delete ActorDeleteCmd `cmd:"" help:"Delete a Actor by UID."`
-	Search ActorSearchCmd `cmd:"" help:"Search Actor by Name."`
+	Get     ActorGetCmd     `cmd:"" help:"Get a Actor by UID."`
+	Show    ActorShowCmd    `cmd:"" help:"Show an Actor and their roles."`
+	List    ActorListCmd    `cmd:"" help:"List Actor entities."`
+	Add     ActorAddCmd     `cmd:"" help:"Add a new Actor."`
+	Update  ActorUpdateCmd  `cmd:"" help:"Update a Actor by UID."`
+	Delete  ActorDeleteCmd  `cmd:"" help:"Delete a Actor by UID or filter."`
+	Search  ActorSearchCmd  `cmd:"" help:"Search Actor by Name."`
+	CoStars ActorCoStarsCmd `cmd:"" name:"costars" help:"List an Actor's co-stars ranked by shared films."`
 }
 
 type ActorGetCmd struct {
@@ -104,58 +77,102 @@ func (c *ActorGetCmd) Run(client *movies.Client) error {
 	if err != nil {
 		return err
 	}
-	return printJSON(result)
+	return printResult(result)
 }
 
 type ActorListCmd struct {
-	First  int `help:"Maximum results to return." default:"10"`
-	Offset int `help:"Number of results to skip." default:"0"`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *ActorListCmd) Run(client *movies.Client) error {
-	results, err := client.Actor.List(context.Background(),
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Actor, c.Fields, client.Actor.Select)
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	results, err := view.List(context.Background(), movies.First(c.First), movies.Offset(c.Offset))
+	if err != nil {
+		return err
+	}
+	return printResult(results)
 }
 
-type ActorAddCmd struct {
-	Name string `help:"Set Name." name:"name"`
+// ActorFields are the flags shared by actor add and actor update.
+type ActorFields struct {
+	Name     string   `help:"Set Name." name:"name"`
+	Films    []string `help:"Link Performances (Films) by UID. Repeatable." name:"performance" sep:"none"`
+	FromJSON string   `help:"Read a full Actor document from a JSON file (- for stdin)." name:"from-json"`
 }
 
-func (c *ActorAddCmd) Run(client *movies.Client) error {
-	v := &movies.Actor{
-		Name: c.Name,
+// build returns the Actor described by the flags, with edge references
+// resolved to UIDs, and the links to apply on other nodes.
+func (f *ActorFields) build(ctx context.Context, client *movies.Client) (*movies.Actor, []link, error) {
+	v := &movies.Actor{}
+	if f.FromJSON != "" {
+		if err := readJSONDoc(f.FromJSON, v); err != nil {
+			return nil, nil, err
+		}
 	}
-	if err := client.Actor.Add(context.Background(), v); err != nil {
-		return err
+	if f.Name != "" {
+		v.Name = f.Name
+	}
+	{
+		uids, err := resolveRefs(ctx, client, "Performance", false, f.Films)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--performance: %w", err)
+		}
+		for _, uid := range uids {
+			v.Films = append(v.Films, movies.Performance{UID: uid})
+		}
 	}
-	return printJSON(v)
+	return v, nil, nil
+}
+
+type ActorAddCmd struct {
+	ActorFields `embed:""`
+}
+
+func (c *ActorAddCmd) Run(client *movies.Client) error {
+	return addEntity(client, "Actor", &c.ActorFields, client.Actor, func(v *movies.Actor) *string { return &v.UID })
+}
+
+type ActorUpdateCmd struct {
+	UID         string `arg:"" required:"" help:"The UID of the Actor."`
+	ActorFields `embed:""`
+}
+
+func (c *ActorUpdateCmd) Run(client *movies.Client) error {
+	return updateEntity(client, c.UID, &c.ActorFields, client.Actor, func(v *movies.Actor) *string { return &v.UID })
 }
 
 type ActorDeleteCmd struct {
-	UID string `arg:"" required:"" help:"The UID to delete."`
+	UID    string `arg:"" optional:"" help:"The UID to delete."`
+	Search string `help:"Delete the nodes whose Name matches this fulltext search instead of one UID."`
+	deleteFlags
 }
 
 func (c *ActorDeleteCmd) Run(client *movies.Client) error {
-	return client.Actor.Delete(context.Background(), c.UID)
+	return c.run(client, "Actor", c.UID, c.Search, client.Actor.Delete, client.Actor.DeleteWhere)
 }
 
 type ActorSearchCmd struct {
-	Term   string `arg:"" required:"" help:"The search term."`
-	First  int    `help:"Maximum results to return." default:"10"`
-	Offset int    `help:"Number of results to skip." default:"0"`
+	Term   string   `arg:"" required:"" help:"The search term."`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *ActorSearchCmd) Run(client *movies.Client) error {
-	results, err := client.Actor.Search(context.Background(), c.Term,
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Actor, c.Fields, client.Actor.Select)
+	if err != nil {
+		return err
+	}
+	results, err := view.Find(context.Background(), c.Term, movies.First(c.First), movies.Offset(c.Offset))
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	return printResult(results)
 }
 
 // ContentRatingCmd groups subcommands for ContentRating.
@@ -163,7 +180,8 @@ type ContentRatingCmd struct {
 	Get    ContentRatingGetCmd    `cmd:"" help:"Get a ContentRating by UID."`
 	List   ContentRatingListCmd   `cmd:"" help:"List ContentRating entities."`
 	Add    ContentRatingAddCmd    `cmd:"" help:"Add a new ContentRating."`
-	Delete ContentRatingDeleteCmd `cmd:"" help:"Delete a ContentRating by UID."`
+	Update ContentRatingUpdateCmd `cmd:"" help:"Update a ContentRating by UID."`
+	Delete ContentRatingDeleteCmd `cmd:"" help:"Delete a ContentRating by UID or filter."`
 	Search ContentRatingSearchCmd `cmd:"" help:"Search ContentRating by Name."`
 }
 
@@ -176,58 +194,105 @@ func (c *ContentRatingGetCmd) Run(client *movies.Client) error {
 	if err != nil {
 		return err
 	}
-	return printJSON(result)
+	return printResult(result)
 }
 
 type ContentRatingListCmd struct {
-	First  int `help:"Maximum results to return." default:"10"`
-	Offset int `help:"Number of results to skip." default:"0"`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *ContentRatingListCmd) Run(client *movies.Client) error {
-	results, err := client.ContentRating.List(context.Background(),
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.ContentRating, c.Fields, client.ContentRating.Select)
+	if err != nil {
+		return err
+	}
+	results, err := view.List(context.Background(), movies.First(c.First), movies.Offset(c.Offset))
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	return printResult(results)
 }
 
-type ContentRatingAddCmd struct {
-	Name string `help:"Set Name." name:"name"`
+// ContentRatingFields are the flags shared by contentrating add and contentrating update.
+type ContentRatingFields struct {
+	Name     string   `help:"Set Name." name:"name"`
+	Films    []string `help:"Link Films by UID or name. Repeatable." name:"film" sep:"none"`
+	FromJSON string   `help:"Read a full ContentRating document from a JSON file (- for stdin)." name:"from-json"`
 }
 
-func (c *ContentRatingAddCmd) Run(client *movies.Client) error {
-	v := &movies.ContentRating{
-		Name: c.Name,
+// build returns the ContentRating described by the flags, with edge references
+// resolved to UIDs, and the links to apply on other nodes.
+func (f *ContentRatingFields) build(ctx context.Context, client *movies.Client) (*movies.ContentRating, []link, error) {
+	v := &movies.ContentRating{}
+	if f.FromJSON != "" {
+		if err := readJSONDoc(f.FromJSON, v); err != nil {
+			return nil, nil, err
+		}
 	}
-	if err := client.ContentRating.Add(context.Background(), v); err != nil {
-		return err
+	if f.Name != "" {
+		v.Name = f.Name
+	}
+	var links []link
+	{
+		uids, err := resolveRefs(ctx, client, "Film", true, f.Films)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--film: %w", err)
+		}
+		for _, owner := range uids {
+			links = append(links, func(ctx context.Context, uid string) error {
+				return client.Film.Update(ctx, &movies.Film{UID: owner, ContentRatings: []movies.ContentRating{{UID: uid}}})
+			})
+		}
 	}
-	return printJSON(v)
+	return v, links, nil
+}
+
+type ContentRatingAddCmd struct {
+	ContentRatingFields `embed:""`
+}
+
+func (c *ContentRatingAddCmd) Run(client *movies.Client) error {
+	return addEntity(client, "ContentRating", &c.ContentRatingFields, client.ContentRating, func(v *movies.ContentRating) *string { return &v.UID })
+}
+
+type ContentRatingUpdateCmd struct {
+	UID                 string `arg:"" required:"" help:"The UID of the ContentRating."`
+	ContentRatingFields `embed:""`
+}
+
+func (c *ContentRatingUpdateCmd) Run(client *movies.Client) error {
+	return updateEntity(client, c.UID, &c.ContentRatingFields, client.ContentRating, func(v *movies.ContentRating) *string { return &v.UID })
 }
 
 type ContentRatingDeleteCmd struct {
-	UID string `arg:"" required:"" help:"The UID to delete."`
+	UID    string `arg:"" optional:"" help:"The UID to delete."`
+	Search string `help:"Delete the nodes whose Name matches this fulltext search instead of one UID."`
+	deleteFlags
 }
 
 func (c *ContentRatingDeleteCmd) Run(client *movies.Client) error {
-	return client.ContentRating.Delete(context.Background(), c.UID)
+	return c.run(client, "ContentRating", c.UID, c.Search, client.ContentRating.Delete, client.ContentRating.DeleteWhere)
 }
 
 type ContentRatingSearchCmd struct {
-	Term   string `arg:"" required:"" help:"The search term."`
-	First  int    `help:"Maximum results to return." default:"10"`
-	Offset int    `help:"Number of results to skip." default:"0"`
+	Term   string   `arg:"" required:"" help:"The search term."`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *ContentRatingSearchCmd) Run(client *movies.Client) error {
-	results, err := client.ContentRating.Search(context.Background(), c.Term,
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.ContentRating, c.Fields, client.ContentRating.Select)
+	if err != nil {
+		return err
+	}
+	results, err := view.Find(context.Background(), c.Term, movies.First(c.First), movies.Offset(c.Offset))
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	return printResult(results)
 }
 
 // CountryCmd groups subcommands for Country.
@@ -235,7 +300,8 @@ type CountryCmd struct {
 	Get    CountryGetCmd    `cmd:"" help:"Get a Country by UID."`
 	List   CountryListCmd   `cmd:"" help:"List Country entities."`
 	Add    CountryAddCmd    `cmd:"" help:"Add a new Country."`
-	Delete CountryDeleteCmd `cmd:"" help:"Delete a Country by UID."`
+	Update CountryUpdateCmd `cmd:"" help:"Update a Country by UID."`
+	Delete CountryDeleteCmd `cmd:"" help:"Delete a Country by UID or filter."`
 	Search CountrySearchCmd `cmd:"" help:"Search Country by Name."`
 }
 
@@ -248,67 +314,117 @@ func (c *CountryGetCmd) Run(client *movies.Client) error {
 	if err != nil {
 		return err
 	}
-	return printJSON(result)
+	return printResult(result)
 }
 
 type CountryListCmd struct {
-	First  int `help:"Maximum results to return." default:"10"`
-	Offset int `help:"Number of results to skip." default:"0"`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *CountryListCmd) Run(client *movies.Client) error {
-	results, err := client.Country.List(context.Background(),
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Country, c.Fields, client.Country.Select)
+	if err != nil {
+		return err
+	}
+	results, err := view.List(context.Background(), movies.First(c.First), movies.Offset(c.Offset))
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	return printResult(results)
 }
 
-type CountryAddCmd struct {
-	Name string `help:"Set Name." name:"name"`
+// CountryFields are the flags shared by country add and country update.
+type CountryFields struct {
+	Name     string   `help:"Set Name." name:"name"`
+	Films    []string `help:"Link Films by UID or name. Repeatable." name:"film" sep:"none"`
+	FromJSON string   `help:"Read a full Country document from a JSON file (- for stdin)." name:"from-json"`
 }
 
-func (c *CountryAddCmd) Run(client *movies.Client) error {
-	v := &movies.Country{
-		Name: c.Name,
+// build returns the Country described by the flags, with edge references
+// resolved to UIDs, and the links to apply on other nodes.
+func (f *CountryFields) build(ctx context.Context, client *movies.Client) (*movies.Country, []link, error) {
+	v := &movies.Country{}
+	if f.FromJSON != "" {
+		if err := readJSONDoc(f.FromJSON, v); err != nil {
+			return nil, nil, err
+		}
 	}
-	if err := client.Country.Add(context.Background(), v); err != nil {
-		return err
+	if f.Name != "" {
+		v.Name = f.Name
 	}
-	return printJSON(v)
+	var links []link
+	{
+		uids, err := resolveRefs(ctx, client, "Film", true, f.Films)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--film: %w", err)
+		}
+		for _, owner := range uids {
+			links = append(links, func(ctx context.Context, uid string) error {
+				return client.Film.Update(ctx, &movies.Film{UID: owner, Countries: []movies.Country{{UID: uid}}})
+			})
+		}
+	}
+	return v, links, nil
+}
+
+type CountryAddCmd struct {
+	CountryFields `embed:""`
+}
+
+func (c *CountryAddCmd) Run(client *movies.Client) error {
+	return addEntity(client, "Country", &c.CountryFields, client.Country, func(v *movies.Country) *string { return &v.UID })
+}
+
+type CountryUpdateCmd struct {
+	UID           string `arg:"" required:"" help:"The UID of the Country."`
+	CountryFields `embed:""`
+}
+
+func (c *CountryUpdateCmd) Run(client *movies.Client) error {
+	return updateEntity(client, c.UID, &c.CountryFields, client.Country, func(v *movies.Country) *string { return &v.UID })
 }
 
 type CountryDeleteCmd struct {
-	UID string `arg:"" required:"" help:"The UID to delete."`
+	UID    string `arg:"" optional:"" help:"The UID to delete."`
+	Search string `help:"Delete the nodes whose Name matches this fulltext search instead of one UID."`
+	deleteFlags
 }
 
 func (c *CountryDeleteCmd) Run(client *movies.Client) error {
-	return client.Country.Delete(context.Background(), c.UID)
+	return c.run(client, "Country", c.UID, c.Search, client.Country.Delete, client.Country.DeleteWhere)
 }
 
 type CountrySearchCmd struct {
-	Term   string `arg:"" required:"" help:"The search term."`
-	First  int    `help:"Maximum results to return." default:"10"`
-	Offset int    `help:"Number of results to skip." default:"0"`
+	Term   string   `arg:"" required:"" help:"The search term."`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *CountrySearchCmd) Run(client *movies.Client) error {
-	results, err := client.Country.Search(context.Background(), c.Term,
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Country, c.Fields, client.Country.Select)
+	if err != nil {
+		return err
+	}
+	results, err := view.Find(context.Background(), c.Term, movies.First(c.First), movies.Offset(c.Offset))
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	return printResult(results)
 }
 
 // DirectorCmd groups subcommands for Director.
 type DirectorCmd struct {
-	Get    DirectorGetCmd    `cmd:"" help:"Get a Director by UID."`
-	List   DirectorListCmd   `cmd:"" help:"List Director entities."`
-	Add    DirectorAddCmd    `cmd:"" help:"Add a new Director."`
-	Delete DirectorDeleteCmd `cmd:"" help:"Delete a Director by UID."`
-	Search DirectorSearchCmd `cmd:"" help:"Search Director by Name."`
+	Get           DirectorGetCmd           `cmd:"" help:"Get a Director by UID."`
+	Show          DirectorShowCmd          `cmd:"" help:"Show a Director and their filmography."`
+	List          DirectorListCmd          `cmd:"" help:"List Director entities."`
+	Add           DirectorAddCmd           `cmd:"" help:"Add a new Director."`
+	Update        DirectorUpdateCmd        `cmd:"" help:"Update a Director by UID."`
+	Delete        DirectorDeleteCmd        `cmd:"" help:"Delete a Director by UID or filter."`
+	Search        DirectorSearchCmd        `cmd:"" help:"Search Director by Name."`
+	Collaborators DirectorCollaboratorsCmd `cmd:"" help:"List the actors who appear most often in a Director's films."`
 }
 
 type DirectorGetCmd struct {
@@ -320,66 +436,112 @@ func (c *DirectorGetCmd) Run(client *movies.Client) error {
 	if err != nil {
 		return err
 	}
-	return printJSON(result)
+	return printResult(result)
 }
 
 type DirectorListCmd struct {
-	First  int `help:"Maximum results to return." default:"10"`
-	Offset int `help:"Number of results to skip." default:"0"`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *DirectorListCmd) Run(client *movies.Client) error {
-	results, err := client.Director.List(context.Background(),
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Director, c.Fields, client.Director.Select)
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	results, err := view.List(context.Background(), movies.First(c.First), movies.Offset(c.Offset))
+	if err != nil {
+		return err
+	}
+	return printResult(results)
 }
 
-type DirectorAddCmd struct {
-	Name string `help:"Set Name." name:"name"`
+// DirectorFields are the flags shared by director add and director update.
+type DirectorFields struct {
+	Name     string   `help:"Set Name." name:"name"`
+	Films    []string `help:"Link Films by UID or name. Repeatable." name:"film" sep:"none"`
+	FromJSON string   `help:"Read a full Director document from a JSON file (- for stdin)." name:"from-json"`
 }
 
-func (c *DirectorAddCmd) Run(client *movies.Client) error {
-	v := &movies.Director{
-		Name: c.Name,
+// build returns the Director described by the flags, with edge references
+// resolved to UIDs, and the links to apply on other nodes.
+func (f *DirectorFields) build(ctx context.Context, client *movies.Client) (*movies.Director, []link, error) {
+	v := &movies.Director{}
+	if f.FromJSON != "" {
+		if err := readJSONDoc(f.FromJSON, v); err != nil {
+			return nil, nil, err
+		}
 	}
-	if err := client.Director.Add(context.Background(), v); err != nil {
-		return err
+	if f.Name != "" {
+		v.Name = f.Name
+	}
+	{
+		uids, err := resolveRefs(ctx, client, "Film", true, f.Films)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--film: %w", err)
+		}
+		for _, uid := range uids {
+			v.Films = append(v.Films, movies.Film{UID: uid})
+		}
 	}
-	return printJSON(v)
+	return v, nil, nil
+}
+
+type DirectorAddCmd struct {
+	DirectorFields `embed:""`
+}
+
+func (c *DirectorAddCmd) Run(client *movies.Client) error {
+	return addEntity(client, "Director", &c.DirectorFields, client.Director, func(v *movies.Director) *string { return &v.UID })
+}
+
+type DirectorUpdateCmd struct {
+	UID            string `arg:"" required:"" help:"The UID of the Director."`
+	DirectorFields `embed:""`
+}
+
+func (c *DirectorUpdateCmd) Run(client *movies.Client) error {
+	return updateEntity(client, c.UID, &c.DirectorFields, client.Director, func(v *movies.Director) *string { return &v.UID })
 }
 
 type DirectorDeleteCmd struct {
-	UID string `arg:"" required:"" help:"The UID to delete."`
+	UID    string `arg:"" optional:"" help:"The UID to delete."`
+	Search string `help:"Delete the nodes whose Name matches this fulltext search instead of one UID."`
+	deleteFlags
 }
 
 func (c *DirectorDeleteCmd) Run(client *movies.Client) error {
-	return client.Director.Delete(context.Background(), c.UID)
+	return c.run(client, "Director", c.UID, c.Search, client.Director.Delete, client.Director.DeleteWhere)
 }
 
 type DirectorSearchCmd struct {
-	Term   string `arg:"" required:"" help:"The search term."`
-	First  int    `help:"Maximum results to return." default:"10"`
-	Offset int    `help:"Number of results to skip." default:"0"`
+	Term   string   `arg:"" required:"" help:"The search term."`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *DirectorSearchCmd) Run(client *movies.Client) error {
-	results, err := client.Director.Search(context.Background(), c.Term,
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Director, c.Fields, client.Director.Select)
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	results, err := view.Find(context.Background(), c.Term, movies.First(c.First), movies.Offset(c.Offset))
+	if err != nil {
+		return err
+	}
+	return printResult(results)
 }
 
 // FilmCmd groups subcommands for Film.
 type FilmCmd struct {
 	Get    FilmGetCmd    `cmd:"" help:"Get a Film by UID."`
+	Show   FilmShowCmd   `cmd:"" help:"Show a Film as a readable card."`
 	List   FilmListCmd   `cmd:"" help:"List Film entities."`
 	Add    FilmAddCmd    `cmd:"" help:"Add a new Film."`
-	Delete FilmDeleteCmd `cmd:"" help:"Delete a Film by UID."`
+	Update FilmUpdateCmd `cmd:"" help:"Update a Film by UID."`
+	Delete FilmDeleteCmd `cmd:"" help:"Delete a Film by UID or filter."`
 	Search FilmSearchCmd `cmd:"" help:"Search Film by Name."`
 }
 
@@ -392,69 +554,177 @@ func (c *FilmGetCmd) Run(client *movies.Client) error {
 	if err != nil {
 		return err
 	}
-	return printJSON(result)
+	return printResult(result)
 }
 
 type FilmListCmd struct {
-	First  int `help:"Maximum results to return." default:"10"`
-	Offset int `help:"Number of results to skip." default:"0"`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *FilmListCmd) Run(client *movies.Client) error {
-	results, err := client.Film.List(context.Background(),
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Film, c.Fields, client.Film.Select)
+	if err != nil {
+		return err
+	}
+	results, err := view.List(context.Background(), movies.First(c.First), movies.Offset(c.Offset))
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	return printResult(results)
+}
+
+// FilmFields are the flags shared by film add and film update.
+type FilmFields struct {
+	Name               string   `help:"Set Name." name:"name"`
+	InitialReleaseDate string   `help:"Set InitialReleaseDate (RFC 3339, YYYY-MM-DD, YYYY-MM or YYYY)." name:"initialreleasedate"`
+	Tagline            string   `help:"Set Tagline." name:"tagline"`
+	Genres             []string `help:"Link Genres by UID or name. Repeatable." name:"genre" sep:"none"`
+	Countries          []string `help:"Link Countries by UID or name. Repeatable." name:"country" sep:"none"`
+	Ratings            []string `help:"Link Ratings by UID or name. Repeatable." name:"rating" sep:"none"`
+	ContentRatings     []string `help:"Link ContentRatings by UID or name. Repeatable." name:"contentrating" sep:"none"`
+	Starring           []string `help:"Link Starring Performances by UID. Repeatable." name:"starring" sep:"none"`
+	Directors          []string `help:"Link Directors by UID or name. Repeatable." name:"director" sep:"none"`
+	FromJSON           string   `help:"Read a full Film document from a JSON file (- for stdin)." name:"from-json"`
+}
+
+// build returns the Film described by the flags, with edge references
+// resolved to UIDs, and the links to apply on other nodes.
+func (f *FilmFields) build(ctx context.Context, client *movies.Client) (*movies.Film, []link, error) {
+	v := &movies.Film{}
+	if f.FromJSON != "" {
+		if err := readJSONDoc(f.FromJSON, v); err != nil {
+			return nil, nil, err
+		}
+	}
+	if f.Name != "" {
+		v.Name = f.Name
+	}
+	if f.InitialReleaseDate != "" {
+		t, err := parseDate(f.InitialReleaseDate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--initialreleasedate: %w", err)
+		}
+		v.InitialReleaseDate = t
+	}
+	if f.Tagline != "" {
+		v.Tagline = f.Tagline
+	}
+	{
+		uids, err := resolveRefs(ctx, client, "Genre", true, f.Genres)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--genre: %w", err)
+		}
+		for _, uid := range uids {
+			v.Genres = append(v.Genres, movies.Genre{UID: uid})
+		}
+	}
+	{
+		uids, err := resolveRefs(ctx, client, "Country", true, f.Countries)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--country: %w", err)
+		}
+		for _, uid := range uids {
+			v.Countries = append(v.Countries, movies.Country{UID: uid})
+		}
+	}
+	{
+		uids, err := resolveRefs(ctx, client, "Rating", true, f.Ratings)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--rating: %w", err)
+		}
+		for _, uid := range uids {
+			v.Ratings = append(v.Ratings, movies.Rating{UID: uid})
+		}
+	}
+	{
+		uids, err := resolveRefs(ctx, client, "ContentRating", true, f.ContentRatings)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--contentrating: %w", err)
+		}
+		for _, uid := range uids {
+			v.ContentRatings = append(v.ContentRatings, movies.ContentRating{UID: uid})
+		}
+	}
+	{
+		uids, err := resolveRefs(ctx, client, "Performance", false, f.Starring)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--starring: %w", err)
+		}
+		for _, uid := range uids {
+			v.Starring = append(v.Starring, movies.Performance{UID: uid})
+		}
+	}
+	var links []link
+	{
+		uids, err := resolveRefs(ctx, client, "Director", true, f.Directors)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--director: %w", err)
+		}
+		for _, owner := range uids {
+			links = append(links, func(ctx context.Context, uid string) error {
+				return client.Director.Update(ctx, &movies.Director{UID: owner, Films: []movies.Film{{UID: uid}}})
+			})
+		}
+	}
+	return v, links, nil
 }
 
 type FilmAddCmd struct {
-	Name               string `help:"Set Name." name:"name"`
-	InitialReleaseDate string `help:"Set InitialReleaseDate." name:"initialreleasedate"`
-	Tagline            string `help:"Set Tagline." name:"tagline"`
+	FilmFields `embed:""`
 }
 
 func (c *FilmAddCmd) Run(client *movies.Client) error {
-	v := &movies.Film{
-		Name:    c.Name,
-		Tagline: c.Tagline,
-	}
-	if err := client.Film.Add(context.Background(), v); err != nil {
-		return err
-	}
-	return printJSON(v)
+	return addEntity(client, "Film", &c.FilmFields, client.Film, func(v *movies.Film) *string { return &v.UID })
+}
+
+type FilmUpdateCmd struct {
+	UID        string `arg:"" required:"" help:"The UID of the Film."`
+	FilmFields `embed:""`
+}
+
+func (c *FilmUpdateCmd) Run(client *movies.Client) error {
+	return updateEntity(client, c.UID, &c.FilmFields, client.Film, func(v *movies.Film) *string { return &v.UID })
 }
 
 type FilmDeleteCmd struct {
-	UID string `arg:"" required:"" help:"The UID to delete."`
+	UID    string `arg:"" optional:"" help:"The UID to delete."`
+	Search string `help:"Delete the nodes whose Name matches this fulltext search instead of one UID."`
+	deleteFlags
 }
 
 func (c *FilmDeleteCmd) Run(client *movies.Client) error {
-	return client.Film.Delete(context.Background(), c.UID)
+	return c.run(client, "Film", c.UID, c.Search, client.Film.Delete, client.Film.DeleteWhere)
 }
 
 type FilmSearchCmd struct {
-	Term   string `arg:"" required:"" help:"The search term."`
-	First  int    `help:"Maximum results to return." default:"10"`
-	Offset int    `help:"Number of results to skip." default:"0"`
+	Term   string   `arg:"" required:"" help:"The search term."`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *FilmSearchCmd) Run(client *movies.Client) error {
-	results, err := client.Film.Search(context.Background(), c.Term,
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Film, c.Fields, client.Film.Select)
+	if err != nil {
+		return err
+	}
+	results, err := view.Find(context.Background(), c.Term, movies.First(c.First), movies.Offset(c.Offset))
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	return printResult(results)
 }
 
 // GenreCmd groups subcommands for Genre.
 type GenreCmd struct {
 	Get    GenreGetCmd    `cmd:"" help:"Get a Genre by UID."`
+	Show   GenreShowCmd   `cmd:"" help:"Show a Genre with its film count and recent films."`
 	List   GenreListCmd   `cmd:"" help:"List Genre entities."`
 	Add    GenreAddCmd    `cmd:"" help:"Add a new Genre."`
-	Delete GenreDeleteCmd `cmd:"" help:"Delete a Genre by UID."`
+	Update GenreUpdateCmd `cmd:"" help:"Update a Genre by UID."`
+	Delete GenreDeleteCmd `cmd:"" help:"Delete a Genre by UID or filter."`
 	Search GenreSearchCmd `cmd:"" help:"Search Genre by Name."`
 }
 
@@ -467,58 +737,105 @@ func (c *GenreGetCmd) Run(client *movies.Client) error {
 	if err != nil {
 		return err
 	}
-	return printJSON(result)
+	return printResult(result)
 }
 
 type GenreListCmd struct {
-	First  int `help:"Maximum results to return." default:"10"`
-	Offset int `help:"Number of results to skip." default:"0"`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *GenreListCmd) Run(client *movies.Client) error {
-	results, err := client.Genre.List(context.Background(),
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Genre, c.Fields, client.Genre.Select)
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	results, err := view.List(context.Background(), movies.First(c.First), movies.Offset(c.Offset))
+	if err != nil {
+		return err
+	}
+	return printResult(results)
 }
 
-type GenreAddCmd struct {
-	Name string `help:"Set Name." name:"name"`
+// GenreFields are the flags shared by genre add and genre update.
+type GenreFields struct {
+	Name     string   `help:"Set Name." name:"name"`
+	Films    []string `help:"Link Films by UID or name. Repeatable." name:"film" sep:"none"`
+	FromJSON string   `help:"Read a full Genre document from a JSON file (- for stdin)." name:"from-json"`
 }
 
-func (c *GenreAddCmd) Run(client *movies.Client) error {
-	v := &movies.Genre{
-		Name: c.Name,
+// build returns the Genre described by the flags, with edge references
+// resolved to UIDs, and the links to apply on other nodes.
+func (f *GenreFields) build(ctx context.Context, client *movies.Client) (*movies.Genre, []link, error) {
+	v := &movies.Genre{}
+	if f.FromJSON != "" {
+		if err := readJSONDoc(f.FromJSON, v); err != nil {
+			return nil, nil, err
+		}
 	}
-	if err := client.Genre.Add(context.Background(), v); err != nil {
-		return err
+	if f.Name != "" {
+		v.Name = f.Name
+	}
+	var links []link
+	{
+		uids, err := resolveRefs(ctx, client, "Film", true, f.Films)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--film: %w", err)
+		}
+		for _, owner := range uids {
+			links = append(links, func(ctx context.Context, uid string) error {
+				return client.Film.Update(ctx, &movies.Film{UID: owner, Genres: []movies.Genre{{UID: uid}}})
+			})
+		}
 	}
-	return printJSON(v)
+	return v, links, nil
+}
+
+type GenreAddCmd struct {
+	GenreFields `embed:""`
+}
+
+func (c *GenreAddCmd) Run(client *movies.Client) error {
+	return addEntity(client, "Genre", &c.GenreFields, client.Genre, func(v *movies.Genre) *string { return &v.UID })
+}
+
+type GenreUpdateCmd struct {
+	UID         string `arg:"" required:"" help:"The UID of the Genre."`
+	GenreFields `embed:""`
+}
+
+func (c *GenreUpdateCmd) Run(client *movies.Client) error {
+	return updateEntity(client, c.UID, &c.GenreFields, client.Genre, func(v *movies.Genre) *string { return &v.UID })
 }
 
 type GenreDeleteCmd struct {
-	UID string `arg:"" required:"" help:"The UID to delete."`
+	UID    string `arg:"" optional:"" help:"The UID to delete."`
+	Search string `help:"Delete the nodes whose Name matches this fulltext search instead of one UID."`
+	deleteFlags
 }
 
 func (c *GenreDeleteCmd) Run(client *movies.Client) error {
-	return client.Genre.Delete(context.Background(), c.UID)
+	return c.run(client, "Genre", c.UID, c.Search, client.Genre.Delete, client.Genre.DeleteWhere)
 }
 
 type GenreSearchCmd struct {
-	Term   string `arg:"" required:"" help:"The search term."`
-	First  int    `help:"Maximum results to return." default:"10"`
-	Offset int    `help:"Number of results to skip." default:"0"`
+	Term   string   `arg:"" required:"" help:"The search term."`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *GenreSearchCmd) Run(client *movies.Client) error {
-	results, err := client.Genre.Search(context.Background(), c.Term,
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Genre, c.Fields, client.Genre.Select)
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	results, err := view.Find(context.Background(), c.Term, movies.First(c.First), movies.Offset(c.Offset))
+	if err != nil {
+		return err
+	}
+	return printResult(results)
 }
 
 // LocationCmd groups subcommands for Location.
@@ -526,7 +843,8 @@ type LocationCmd struct {
 	Get    LocationGetCmd    `cmd:"" help:"Get a Location by UID."`
 	List   LocationListCmd   `cmd:"" help:"List Location entities."`
 	Add    LocationAddCmd    `cmd:"" help:"Add a new Location."`
-	Delete LocationDeleteCmd `cmd:"" help:"Delete a Location by UID."`
+	Update LocationUpdateCmd `cmd:"" help:"Update a Location by UID."`
+	Delete LocationDeleteCmd `cmd:"" help:"Delete a Location by UID or filter."`
 	Search LocationSearchCmd `cmd:"" help:"Search Location by Name."`
 }
 
@@ -539,61 +857,104 @@ func (c *LocationGetCmd) Run(client *movies.Client) error {
 	if err != nil {
 		return err
 	}
-	return printJSON(result)
+	return printResult(result)
 }
 
 type LocationListCmd struct {
-	First  int `help:"Maximum results to return." default:"10"`
-	Offset int `help:"Number of results to skip." default:"0"`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *LocationListCmd) Run(client *movies.Client) error {
-	results, err := client.Location.List(context.Background(),
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Location, c.Fields, client.Location.Select)
+	if err != nil {
+		return err
+	}
+	results, err := view.List(context.Background(), movies.First(c.First), movies.Offset(c.Offset))
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	return printResult(results)
 }
 
-type LocationAddCmd struct {
-	Name  string `help:"Set Name." name:"name"`
-	Loc   string `help:"Set Loc." name:"loc"`
-	Email string `help:"Set Email." name:"email"`
+// LocationFields are the flags shared by location add and location update.
+type LocationFields struct {
+	Name     string `help:"Set Name." name:"name"`
+	Loc      string `help:"Set Loc as lat,lng." name:"loc"`
+	Email    string `help:"Set Email." name:"email"`
+	FromJSON string `help:"Read a full Location document from a JSON file (- for stdin)." name:"from-json"`
 }
 
-func (c *LocationAddCmd) Run(client *movies.Client) error {
-	v := &movies.Location{
-		Name:  c.Name,
-		Email: c.Email,
+// build returns the Location described by the flags, with edge references
+// resolved to UIDs, and the links to apply on other nodes.
+func (f *LocationFields) build(ctx context.Context, client *movies.Client) (*movies.Location, []link, error) {
+	v := &movies.Location{}
+	if f.FromJSON != "" {
+		if err := readJSONDoc(f.FromJSON, v); err != nil {
+			return nil, nil, err
+		}
 	}
-	if err := client.Location.Add(context.Background(), v); err != nil {
-		return err
+	if f.Name != "" {
+		v.Name = f.Name
+	}
+	if f.Loc != "" {
+		p, err := parseGeoPoint(f.Loc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--loc: %w", err)
+		}
+		v.Loc = p
 	}
-	return printJSON(v)
+	if f.Email != "" {
+		v.Email = f.Email
+	}
+	return v, nil, nil
+}
+
+type LocationAddCmd struct {
+	LocationFields `embed:""`
+}
+
+func (c *LocationAddCmd) Run(client *movies.Client) error {
+	return addEntity(client, "Location", &c.LocationFields, client.Location, func(v *movies.Location) *string { return &v.UID })
+}
+
+type LocationUpdateCmd struct {
+	UID            string `arg:"" required:"" help:"The UID of the Location."`
+	LocationFields `embed:""`
+}
+
+func (c *LocationUpdateCmd) Run(client *movies.Client) error {
+	return updateEntity(client, c.UID, &c.LocationFields, client.Location, func(v *movies.Location) *string { return &v.UID })
 }
 
 type LocationDeleteCmd struct {
-	UID string `arg:"" required:"" help:"The UID to delete."`
+	UID    string `arg:"" optional:"" help:"The UID to delete."`
+	Search string `help:"Delete the nodes whose Name matches this fulltext search instead of one UID."`
+	deleteFlags
 }
 
 func (c *LocationDeleteCmd) Run(client *movies.Client) error {
-	return client.Location.Delete(context.Background(), c.UID)
+	return c.run(client, "Location", c.UID, c.Search, client.Location.Delete, client.Location.DeleteWhere)
 }
 
 type LocationSearchCmd struct {
-	Term   string `arg:"" required:"" help:"The search term."`
-	First  int    `help:"Maximum results to return." default:"10"`
-	Offset int    `help:"Number of results to skip." default:"0"`
+	Term   string   `arg:"" required:"" help:"The search term."`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *LocationSearchCmd) Run(client *movies.Client) error {
-	results, err := client.Location.Search(context.Background(), c.Term,
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Location, c.Fields, client.Location.Select)
+	if err != nil {
+		return err
+	}
+	results, err := view.Find(context.Background(), c.Term, movies.First(c.First), movies.Offset(c.Offset))
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	return printResult(results)
 }
 
 // PerformanceCmd groups subcommands for Performance.
@@ -601,7 +962,8 @@ type PerformanceCmd struct {
 	Get    PerformanceGetCmd    `cmd:"" help:"Get a Performance by UID."`
 	List   PerformanceListCmd   `cmd:"" help:"List Performance entities."`
 	Add    PerformanceAddCmd    `cmd:"" help:"Add a new Performance."`
-	Delete PerformanceDeleteCmd `cmd:"" help:"Delete a Performance by UID."`
+	Update PerformanceUpdateCmd `cmd:"" help:"Update a Performance by UID."`
+	Delete PerformanceDeleteCmd `cmd:"" help:"Delete a Performance by UID or filter."`
 }
 
 type PerformanceGetCmd struct {
@@ -613,43 +975,97 @@ func (c *PerformanceGetCmd) Run(client *movies.Client) error {
 	if err != nil {
 		return err
 	}
-	return printJSON(result)
+	return printResult(result)
 }
 
 type PerformanceListCmd struct {
-	First  int `help:"Maximum results to return." default:"10"`
-	Offset int `help:"Number of results to skip." default:"0"`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *PerformanceListCmd) Run(client *movies.Client) error {
-	results, err := client.Performance.List(context.Background(),
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Performance, c.Fields, client.Performance.Select)
+	if err != nil {
+		return err
+	}
+	results, err := view.List(context.Background(), movies.First(c.First), movies.Offset(c.Offset))
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	return printResult(results)
 }
 
-type PerformanceAddCmd struct {
-	CharacterNote string `help:"Set CharacterNote." name:"characternote"`
+// PerformanceFields are the flags shared by performance add and performance update.
+type PerformanceFields struct {
+	CharacterNote string   `help:"Set CharacterNote." name:"characternote"`
+	Actors        []string `help:"Link Actors by UID or name. Repeatable." name:"actor" sep:"none"`
+	Films         []string `help:"Link Films by UID or name. Repeatable." name:"film" sep:"none"`
+	FromJSON      string   `help:"Read a full Performance document from a JSON file (- for stdin)." name:"from-json"`
 }
 
-func (c *PerformanceAddCmd) Run(client *movies.Client) error {
-	v := &movies.Performance{
-		CharacterNote: c.CharacterNote,
+// build returns the Performance described by the flags, with edge references
+// resolved to UIDs, and the links to apply on other nodes.
+func (f *PerformanceFields) build(ctx context.Context, client *movies.Client) (*movies.Performance, []link, error) {
+	v := &movies.Performance{}
+	if f.FromJSON != "" {
+		if err := readJSONDoc(f.FromJSON, v); err != nil {
+			return nil, nil, err
+		}
 	}
-	if err := client.Performance.Add(context.Background(), v); err != nil {
-		return err
+	if f.CharacterNote != "" {
+		v.CharacterNote = f.CharacterNote
+	}
+	var links []link
+	{
+		uids, err := resolveRefs(ctx, client, "Actor", true, f.Actors)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--actor: %w", err)
+		}
+		for _, owner := range uids {
+			links = append(links, func(ctx context.Context, uid string) error {
+				return client.Actor.Update(ctx, &movies.Actor{UID: owner, Films: []movies.Performance{{UID: uid}}})
+			})
+		}
 	}
-	return printJSON(v)
+	{
+		uids, err := resolveRefs(ctx, client, "Film", true, f.Films)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--film: %w", err)
+		}
+		for _, owner := range uids {
+			links = append(links, func(ctx context.Context, uid string) error {
+				return client.Film.Update(ctx, &movies.Film{UID: owner, Starring: []movies.Performance{{UID: uid}}})
+			})
+		}
+	}
+	return v, links, nil
+}
+
+type PerformanceAddCmd struct {
+	PerformanceFields `embed:""`
+}
+
+func (c *PerformanceAddCmd) Run(client *movies.Client) error {
+	return addEntity(client, "Performance", &c.PerformanceFields, client.Performance, func(v *movies.Performance) *string { return &v.UID })
+}
+
+type PerformanceUpdateCmd struct {
+	UID               string `arg:"" required:"" help:"The UID of the Performance."`
+	PerformanceFields `embed:""`
+}
+
+func (c *PerformanceUpdateCmd) Run(client *movies.Client) error {
+	return updateEntity(client, c.UID, &c.PerformanceFields, client.Performance, func(v *movies.Performance) *string { return &v.UID })
 }
 
 type PerformanceDeleteCmd struct {
-	UID string `arg:"" required:"" help:"The UID to delete."`
+	UID string `arg:"" optional:"" help:"The UID to delete."`
+	deleteFlags
 }
 
 func (c *PerformanceDeleteCmd) Run(client *movies.Client) error {
-	return client.Performance.Delete(context.Background(), c.UID)
+	return c.run(client, "Performance", c.UID, "", client.Performance.Delete, client.Performance.DeleteWhere)
 }
 
 // RatingCmd groups subcommands for Rating.
@@ -657,7 +1073,8 @@ type RatingCmd struct {
 	Get    RatingGetCmd    `cmd:"" help:"Get a Rating by UID."`
 	List   RatingListCmd   `cmd:"" help:"List Rating entities."`
 	Add    RatingAddCmd    `cmd:"" help:"Add a new Rating."`
-	Delete RatingDeleteCmd `cmd:"" help:"Delete a Rating by UID."`
+	Update RatingUpdateCmd `cmd:"" help:"Update a Rating by UID."`
+	Delete RatingDeleteCmd `cmd:"" help:"Delete a Rating by UID or filter."`
 	Search RatingSearchCmd `cmd:"" help:"Search Rating by Name."`
 }
 
@@ -670,97 +1087,171 @@ func (c *RatingGetCmd) Run(client *movies.Client) error {
 	if err != nil {
 		return err
 	}
-	return printJSON(result)
+	return printResult(result)
 }
 
 type RatingListCmd struct {
-	First  int `help:"Maximum results to return." default:"10"`
-	Offset int `help:"Number of results to skip." default:"0"`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *RatingListCmd) Run(client *movies.Client) error {
-	results, err := client.Rating.List(context.Background(),
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Rating, c.Fields, client.Rating.Select)
+	if err != nil {
+		return err
+	}
+	results, err := view.List(context.Background(), movies.First(c.First), movies.Offset(c.Offset))
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
+	return printResult(results)
 }
 
-type RatingAddCmd struct {
-	Name string `help:"Set Name." name:"name"`
+// RatingFields are the flags shared by rating add and rating update.
+type RatingFields struct {
+	Name     string   `help:"Set Name." name:"name"`
+	Films    []string `help:"Link Films by UID or name. Repeatable." name:"film" sep:"none"`
+	FromJSON string   `help:"Read a full Rating document from a JSON file (- for stdin)." name:"from-json"`
 }
 
-func (c *RatingAddCmd) Run(client *movies.Client) error {
-	v := &movies.Rating{
-		Name: c.Name,
+// build returns the Rating described by the flags, with edge references
+// resolved to UIDs, and the links to apply on other nodes.
+func (f *RatingFields) build(ctx context.Context, client *movies.Client) (*movies.Rating, []link, error) {
+	v := &movies.Rating{}
+	if f.FromJSON != "" {
+		if err := readJSONDoc(f.FromJSON, v); err != nil {
+			return nil, nil, err
+		}
 	}
-	if err := client.Rating.Add(context.Background(), v); err != nil {
-		return err
+	if f.Name != "" {
+		v.Name = f.Name
 	}
-	return printJSON(v)
+	var links []link
+	{
+		uids, err := resolveRefs(ctx, client, "Film", true, f.Films)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--film: %w", err)
+		}
+		for _, owner := range uids {
+			links = append(links, func(ctx context.Context, uid string) error {
+				return client.Film.Update(ctx, &movies.Film{UID: owner, Ratings: []movies.Rating{{UID: uid}}})
+			})
+		}
+	}
+	return v, links, nil
+}
+
+type RatingAddCmd struct {
+	RatingFields `embed:""`
+}
+
+func (c *RatingAddCmd) Run(client *movies.Client) error {
+	return addEntity(client, "Rating", &c.RatingFields, client.Rating, func(v *movies.Rating) *string { return &v.UID })
+}
+
+type RatingUpdateCmd struct {
+	UID          string `arg:"" required:"" help:"The UID of the Rating."`
+	RatingFields `embed:""`
+}
+
+func (c *RatingUpdateCmd) Run(client *movies.Client) error {
+	return updateEntity(client, c.UID, &c.RatingFields, client.Rating, func(v *movies.Rating) *string { return &v.UID })
 }
 
 type RatingDeleteCmd struct {
-	UID string `arg:"" required:"" help:"The UID to delete."`
+	UID    string `arg:"" optional:"" help:"The UID to delete."`
+	Search string `help:"Delete the nodes whose Name matches this fulltext search instead of one UID."`
+	deleteFlags
 }
 
 func (c *RatingDeleteCmd) Run(client *movies.Client) error {
-	return client.Rating.Delete(context.Background(), c.UID)
+	return c.run(client, "Rating", c.UID, c.Search, client.Rating.Delete, client.Rating.DeleteWhere)
 }
 
 type RatingSearchCmd struct {
-	Term   string `arg:"" required:"" help:"The search term."`
-	First  int    `help:"Maximum results to return." default:"10"`
-	Offset int    `help:"Number of results to skip." default:"0"`
+	Term   string   `arg:"" required:"" help:"The search term."`
+	First  int      `help:"Maximum results to return." default:"10"`
+	Offset int      `help:"Number of results to skip." default:"0"`
+	Fields []string `help:"Only fetch these fields (JSON names)." sep:","`
 }
 
 func (c *RatingSearchCmd) Run(client *movies.Client) error {
-	results, err := client.Rating.Search(context.Background(), c.Term,
-		movies.First(c.First), movies.Offset(c.Offset))
+	view, err := selectFields(client.Rating, c.Fields, client.Rating.Select)
 	if err != nil {
 		return err
 	}
-	return printJSON(results)
-}
-
-func printJSON(v any) error {
-	enc := json.NewEncoder(os.Stdout)
-	enc.SetIndent("", "  ")
-	return enc.Encode(v)
+	results, err := view.Find(context.Background(), c.Term, movies.First(c.First), movies.Offset(c.Offset))
+	if err != nil {
+		return err
+	}
+	return printResult(results)
 }
 
 func connectString() (string, error) {
-	if CLI.Dir != "" {
-		if CLI.Addr != "dgraph://localhost:9080" {
-			return "", fmt.Errorf("--addr and --dir are mutually exclusive")
-		}
+	switch {
+	case CLI.Addr != "" && CLI.Dir != "":
+		return "", fmt.Errorf("--addr and --dir are mutually exclusive")
+	case CLI.Dir != "":
 		return fmt.Sprintf("file://%s", filepath.Clean(CLI.Dir)), nil
+	case CLI.Addr != "":
+		return CLI.Addr, nil
 	}
-	return CLI.Addr, nil
+	return defaultAddr, nil
 }
 
 func main() {
+	profiles := &profileResolver{}
 	ctx := kong.Parse(&CLI,
 		kong.Name("movies"),
 		kong.Description("CLI for the movies data model."),
+		kong.Resolvers(profiles),
 	)
+	ctx.FatalIfErrorf(profiles.err)
 
-	connStr, err := connectString()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
-	}
-
-	client, err := movies.New(connStr,
-		modusgraph.WithAutoSchema(true),
-	)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "connect: %v\n", err)
-		os.Exit(1)
-	}
-	defer client.Close()
+	// The client is only opened for commands that take one.
+	var client *movies.Client
+	defer func() {
+		if client != nil {
+			client.Close()
+		}
+	}()
+	err := ctx.BindToProvider(func() (*movies.Client, error) {
+		connStr, err := connectString()
+		if err != nil {
+			return nil, err
+		}
+		opts := []movies.Option{
+			movies.WithClientOptions(modusgraph.WithAutoSchema(!CLI.NoAutoSchema)),
+		}
+		// A profile's timeout also bounds the requests of commands that
+		// have no --timeout of their own.
+		p := profiles.load(ctx)
+		if profiles.err != nil {
+			return nil, profiles.err
+		}
+		if p != nil && p.Timeout > 0 {
+			opts = append(opts, movies.WithTimeout(p.Timeout))
+		}
+		if CLI.CacheSize > 0 {
+			opts = append(opts, movies.WithCache(movies.NewLRUCache(movies.LRUConfig{Size: CLI.CacheSize, TTL: CLI.CacheTTL})))
+		}
+		client, err = movies.New(connStr, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("connect: %w", err)
+		}
+		if CLI.Explain {
+			client.SetDebug(os.Stderr)
+		}
+		if CLI.RecordOutbox {
+			if err := client.EnableOutbox(context.Background()); err != nil {
+				return nil, fmt.Errorf("outbox: %w", err)
+			}
+		}
+		return client, nil
+	})
+	ctx.FatalIfErrorf(err)
 
-	err = ctx.Run(client)
+	err = ctx.Run()
 	ctx.FatalIfErrorf(err)
 }
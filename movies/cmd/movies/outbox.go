@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/outbox"
+)
+
+// OutboxCmd groups the outbox subcommands. Changes are recorded while
+// --outbox is given.
+type OutboxCmd struct {
+	Relay OutboxRelayCmd `cmd:"" help:"Deliver outbox records to webhooks until interrupted."`
+	List  OutboxListCmd  `cmd:"" help:"List outbox records with a status, oldest first."`
+	Dead  OutboxDeadCmd  `cmd:"" help:"List the records that ran out of delivery attempts."`
+	Retry OutboxRetryCmd `cmd:"" help:"Queue dead records for delivery again."`
+}
+
+// OutboxRelayCmd runs an outbox.Relay.
+type OutboxRelayCmd struct {
+	Webhook     []string      `help:"Webhook URL to deliver to. Repeatable." required:"" env:"MOVIES_OUTBOX_WEBHOOKS"`
+	Secret      string        `help:"Secret for the X-Movies-Signature HMAC; unsigned when empty." env:"MOVIES_OUTBOX_SECRET"`
+	Interval    time.Duration `help:"How often to look for due records." default:"1s"`
+	MaxAttempts int           `help:"Attempts before a record is set dead." default:"8"`
+	Backoff     time.Duration `help:"Wait after the first failed attempt, doubling after each." default:"1s"`
+	MaxBackoff  time.Duration `help:"Longest wait between attempts." default:"10m"`
+	Once        bool          `help:"Make one pass over the due records and exit."`
+	Quiet       bool          `help:"Do not log attempts to stderr."`
+}
+
+func (c *OutboxRelayCmd) Run(client *movies.Client) error {
+	r := &outbox.Relay{
+		Client:      client,
+		Webhooks:    c.Webhook,
+		Secret:      []byte(c.Secret),
+		MaxAttempts: c.MaxAttempts,
+		Backoff:     c.Backoff,
+		MaxBackoff:  c.MaxBackoff,
+	}
+	if !c.Quiet {
+		r.Logf = func(format string, args ...any) {
+			fmt.Fprintf(os.Stderr, format+"\n", args...)
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if c.Once {
+		_, err := r.Deliver(ctx)
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "relaying to %d webhook(s)\n", len(c.Webhook))
+	return r.Run(ctx, c.Interval)
+}
+
+// OutboxListCmd lists records by status.
+type OutboxListCmd struct {
+	Status string `help:"Status to list: pending, delivered or dead." default:"pending" enum:"pending,delivered,dead"`
+	First  int    `help:"Maximum records to return." default:"100"`
+}
+
+func (c *OutboxListCmd) Run(client *movies.Client) error {
+	records, err := client.OutboxRecords(context.Background(), movies.OutboxStatus(c.Status), c.First)
+	if err != nil {
+		return err
+	}
+	return printResult(records)
+}
+
+// OutboxDeadCmd lists the dead letters.
+type OutboxDeadCmd struct {
+	First int `help:"Maximum records to return." default:"100"`
+}
+
+func (c *OutboxDeadCmd) Run(client *movies.Client) error {
+	records, err := client.OutboxRecords(context.Background(), movies.OutboxDead, c.First)
+	if err != nil {
+		return err
+	}
+	return printResult(records)
+}
+
+// OutboxRetryCmd sets dead records pending again, with fresh attempts.
+type OutboxRetryCmd struct {
+	UIDs []string `arg:"" optional:"" name:"uid" help:"Dead records to retry."`
+	All  bool     `help:"Retry every dead record."`
+}
+
+func (c *OutboxRetryCmd) Run(client *movies.Client) error {
+	ctx := context.Background()
+	if len(c.UIDs) == 0 && !c.All {
+		return fmt.Errorf("give the UIDs of the records to retry, or --all")
+	}
+	// Dead records are few; read them all to find those named.
+	dead, err := client.OutboxRecords(ctx, movies.OutboxDead, 10000)
+	if err != nil {
+		return err
+	}
+	byUID := make(map[string]*movies.OutboxRecord, len(dead))
+	for i := range dead {
+		byUID[dead[i].UID] = &dead[i]
+	}
+	var retry []*movies.OutboxRecord
+	if c.All {
+		for i := range dead {
+			retry = append(retry, &dead[i])
+		}
+	} else {
+		for _, uid := range c.UIDs {
+			r, ok := byUID[uid]
+			if !ok {
+				return fmt.Errorf("%s is not a dead outbox record", uid)
+			}
+			retry = append(retry, r)
+		}
+	}
+	var uids []string
+	for _, r := range retry {
+		r.Status, r.Attempts, r.NextAttempt = movies.OutboxPending, 0, time.Now()
+		if err := client.SaveOutboxRecord(ctx, r); err != nil {
+			return err
+		}
+		uids = append(uids, r.UID)
+	}
+	return printResult(map[string]any{"retried": uids})
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// printResult writes v to stdout in the format selected by --output.
+func printResult(v any) error {
+	if err := render(os.Stdout, CLI.Output, CLI.Template, v); err != nil {
+		return err
+	}
+	if CLI.Remember {
+		if err := rememberNodes(v); err != nil {
+			fmt.Fprintf(os.Stderr, "completion cache: %v\n", err)
+		}
+	}
+	return nil
+}
+
+func render(w io.Writer, format, tmpl string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "", "json":
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, raw, "", "  "); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		_, err := buf.WriteTo(w)
+		return err
+	case "yaml":
+		doc, err := decodeOrdered(raw)
+		if err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(yamlValue(doc)); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	doc, err := decodeOrdered(raw)
+	if err != nil {
+		return err
+	}
+	rows := toRows(doc)
+	if len(rows) == 0 && format != "template" {
+		return nil
+	}
+	switch format {
+	case "ndjson":
+		for _, r := range rows {
+			line, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s\n", line); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "table":
+		return writeTable(w, rows)
+	case "csv":
+		return writeDelimited(w, rows, ',')
+	case "tsv":
+		return writeDelimited(w, rows, '\t')
+	case "template":
+		return writeTemplate(w, tmpl, rows)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// object is a decoded JSON object that remembers its key order, so columns
+// and YAML keys follow the struct field order rather than sorting.
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		val, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (o *object) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, k := range o.keys {
+		var val yaml.Node
+		if err := val.Encode(yamlValue(o.values[k])); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: k}, &val)
+	}
+	return node, nil
+}
+
+// yamlValue turns the json.Numbers decodeOrdered keeps into ints, or
+// floats when they have a fraction or overflow, which YAML would otherwise
+// write as quoted strings.
+func yamlValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = yamlValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+// plain converts the decoded value back to maps and slices for templates.
+func plain(v any) any {
+	switch v := v.(type) {
+	case *object:
+		m := make(map[string]any, len(v.keys))
+		for _, k := range v.keys {
+			m[k] = plain(v.values[k])
+		}
+		return m
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = plain(e)
+		}
+		return out
+	}
+	return v
+}
+
+func decodeOrdered(raw []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return decodeValue(dec)
+}
+
+func decodeValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		o := &object{values: make(map[string]any)}
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			k := kt.(string)
+			v, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := o.values[k]; !dup {
+				o.keys = append(o.keys, k)
+			}
+			o.values[k] = v
+		}
+		_, err := dec.Token()
+		return o, err
+	case json.Delim('['):
+		arr := []any{}
+		for dec.More() {
+			v, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
+
+// toRows turns a result into the records shown by the row formats. Lists
+// yield one row per element; a raw query response with a single block is
+// unwrapped to that block's nodes.
+func toRows(doc any) []*object {
+	if o, ok := doc.(*object); ok && len(o.keys) == 1 {
+		if arr, ok := o.values[o.keys[0]].([]any); ok {
+			doc = arr
+		}
+	}
+	var rows []*object
+	switch d := doc.(type) {
+	case []any:
+		for _, e := range d {
+			if o, ok := e.(*object); ok {
+				rows = append(rows, o)
+			} else {
+				rows = append(rows, &object{keys: []string{"value"}, values: map[string]any{"value": e}})
+			}
+		}
+	case *object:
+		rows = append(rows, d)
+	case nil:
+	default:
+		rows = append(rows, &object{keys: []string{"value"}, values: map[string]any{"value": d}})
+	}
+	return rows
+}
+
+// columns returns the union of row keys in first-seen order. The dgraph.type
+// bookkeeping predicate is left out.
+func columns(rows []*object) []string {
+	var cols []string
+	seen := map[string]bool{"dgraph.type": true}
+	for _, r := range rows {
+		for _, k := range r.keys {
+			if !seen[k] {
+				seen[k] = true
+				cols = append(cols, k)
+			}
+		}
+	}
+	return cols
+}
+
+// cell flattens a value to a single string. Edges show the linked nodes'
+// names (or UIDs when unnamed) joined by commas, and the zero time that
+// unset dates serialize to is left blank.
+func cell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			switch {
+			case t.IsZero():
+				return ""
+			case t.Equal(t.Truncate(24 * time.Hour)):
+				return t.Format(time.DateOnly)
+			}
+		}
+		return v
+	case *object:
+		for _, k := range []string{"name", "uid"} {
+			if s, ok := v.values[k].(string); ok {
+				return s
+			}
+		}
+		b, _ := json.Marshal(v)
+		return string(b)
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, e := range v {
+			if s := cell(e); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+	return fmt.Sprint(v)
+}
+
+func cells(rows []*object, cols []string) [][]string {
+	out := make([][]string, len(rows))
+	for i, r := range rows {
+		out[i] = make([]string, len(cols))
+		for j, c := range cols {
+			out[i][j] = cell(r.values[c])
+		}
+	}
+	return out
+}
+
+func writeTable(w io.Writer, rows []*object) error {
+	cols := columns(rows)
+	data := cells(rows, cols)
+
+	// Drop columns that are blank in every row, e.g. unset dates.
+	var keep []int
+	for j := range cols {
+		for _, r := range data {
+			if r[j] != "" {
+				keep = append(keep, j)
+				break
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := make([]string, len(keep))
+	for i, j := range keep {
+		header[i] = strings.ToUpper(cols[j])
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, r := range data {
+		line := make([]string, len(keep))
+		for i, j := range keep {
+			line[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(r[j])
+		}
+		fmt.Fprintln(tw, strings.Join(line, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeDelimited(w io.Writer, rows []*object, sep rune) error {
+	cols := columns(rows)
+	data := cells(rows, cols)
+	if sep == '\t' {
+		// TSV has no quoting, so embedded tabs and newlines become spaces.
+		clean := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+		if _, err := fmt.Fprintln(w, strings.Join(cols, "\t")); err != nil {
+			return err
+		}
+		for _, r := range data {
+			for i := range r {
+				r[i] = clean.Replace(r[i])
+			}
+			if _, err := fmt.Fprintln(w, strings.Join(r, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(cols); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(data); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func writeTemplate(w io.Writer, text string, rows []*object) error {
+	if text == "" {
+		return fmt.Errorf("--output=template requires --template")
+	}
+	t, err := template.New("output").Funcs(template.FuncMap{
+		// join flattens an edge the way table cells do, e.g.
+		// {{join ", " .genres}} lists genre names.
+		"join": func(sep string, v any) string {
+			list, ok := v.([]any)
+			if !ok {
+				return cell(toOrdered(v))
+			}
+			parts := make([]string, 0, len(list))
+			for _, e := range list {
+				if s := cell(toOrdered(e)); s != "" {
+					parts = append(parts, s)
+				}
+			}
+			return strings.Join(parts, sep)
+		},
+	}).Parse(text)
+	if err != nil {
+		return fmt.Errorf("parsing template: %w", err)
+	}
+	for _, r := range rows {
+		if err := t.Execute(w, plain(r)); err != nil {
+			return err
+		}
+		if !strings.HasSuffix(text, "\n") {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// toOrdered re-wraps template data so cell can flatten it.
+func toOrdered(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		o := &object{values: make(map[string]any, len(v))}
+		for k, e := range v {
+			o.keys = append(o.keys, k)
+			o.values[k] = toOrdered(e)
+		}
+		return o
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = toOrdered(e)
+		}
+		return out
+	}
+	return v
+}
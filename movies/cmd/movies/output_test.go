@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+func TestRender(t *testing.T) {
+	films := []movies.Film{
+		{
+			UID:                "0x1",
+			DType:              []string{"Film"},
+			Name:               "Heat",
+			InitialReleaseDate: time.Date(1995, 12, 15, 0, 0, 0, 0, time.UTC),
+			Genres:             []movies.Genre{{UID: "0x2", Name: "Crime"}, {UID: "0x3", Name: "Drama"}},
+		},
+		{UID: "0x4", DType: []string{"Film"}, Name: "Thief, The", Tagline: "tab\there"},
+	}
+	tests := []struct {
+		format, tmpl string
+		v            any
+		want         string
+	}{
+		{"json", "", films[1:], `[
+  {
+    "uid": "0x4",
+    "dgraph.type": [
+      "Film"
+    ],
+    "name": "Thief, The",
+    "initialReleaseDate": "0001-01-01T00:00:00Z",
+    "tagline": "tab\there"
+  }
+]
+`},
+		{"ndjson", "", films, `{"uid":"0x1","dgraph.type":["Film"],"name":"Heat","initialReleaseDate":"1995-12-15T00:00:00Z","genres":[{"uid":"0x2","name":"Crime"},{"uid":"0x3","name":"Drama"}]}
+{"uid":"0x4","dgraph.type":["Film"],"name":"Thief, The","initialReleaseDate":"0001-01-01T00:00:00Z","tagline":"tab\there"}
+`},
+		{"table", "", films, "UID  NAME        INITIALRELEASEDATE  GENRES        TAGLINE\n" +
+			"0x1  Heat        1995-12-15          Crime, Drama  \n" +
+			"0x4  Thief, The                                    tab here\n"},
+		{"csv", "", films, `uid,name,initialReleaseDate,genres,tagline
+0x1,Heat,1995-12-15,"Crime, Drama",
+0x4,"Thief, The",,,tab	here
+`},
+		{"tsv", "", films, "uid\tname\tinitialReleaseDate\tgenres\ttagline\n" +
+			"0x1\tHeat\t1995-12-15\tCrime, Drama\t\n" +
+			"0x4\tThief, The\t\t\ttab here\n"},
+		{"yaml", "", films[0], `uid: "0x1"
+dgraph.type:
+  - Film
+name: Heat
+initialReleaseDate: "1995-12-15T00:00:00Z"
+genres:
+  - uid: "0x2"
+    name: Crime
+  - uid: "0x3"
+    name: Drama
+`},
+		{"yaml", "", map[string]any{"films": 42, "score": 1.5, "big": json.RawMessage("1e400"), "years": []any{1995, 2004}},
+			"big: 1e400\nfilms: 42\nscore: 1.5\nyears:\n  - 1995\n  - 2004\n"},
+		{"yaml", "", []any{7, 0.25}, "- 7\n- 0.25\n"},
+		{"template", `{{.name}}: {{join "/" .genres}}`, films, "Heat: Crime/Drama\nThief, The: \n"},
+		{"table", "", []movies.Film{}, ""},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		if err := render(&out, tt.format, tt.tmpl, tt.v); err != nil {
+			t.Errorf("%s: %v", tt.format, err)
+			continue
+		}
+		if out.String() != tt.want {
+			t.Errorf("%s:\n%q\nwant:\n%q", tt.format, out.String(), tt.want)
+		}
+	}
+
+	if err := render(&strings.Builder{}, "template", "", films); err == nil {
+		t.Error("expected --output=template without --template to fail")
+	}
+	if err := render(&strings.Builder{}, "xml", "", films); err == nil {
+		t.Error("expected an unknown format to fail")
+	}
+}
+
+func TestToRows(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want []string // each row's keys, joined
+	}{
+		{"list", `[{"uid":"0x1","name":"a"},{"uid":"0x2"}]`, []string{"uid,name", "uid"}},
+		{"object", `{"uid":"0x1","name":"a"}`, []string{"uid,name"}},
+		{"single block", `{"films":[{"uid":"0x1"},{"uid":"0x2"}]}`, []string{"uid", "uid"}},
+		{"several blocks", `{"a":[{"uid":"0x1"}],"b":[]}`, []string{"a,b"}},
+		{"scalars", `[1,"two"]`, []string{"value", "value"}},
+		{"scalar", `3`, []string{"value"}},
+		{"null", `null`, nil},
+	}
+	for _, tt := range tests {
+		doc, err := decodeOrdered([]byte(tt.json))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, r := range toRows(doc) {
+			got = append(got, strings.Join(r.keys, ","))
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: rows %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCell(t *testing.T) {
+	tests := []struct {
+		json string
+		want string
+	}{
+		{`null`, ""},
+		{`"plain"`, "plain"},
+		{`42`, "42"},
+		{`true`, "true"},
+		{`"0001-01-01T00:00:00Z"`, ""},
+		{`"1995-12-15T00:00:00Z"`, "1995-12-15"},
+		{`"1995-12-15T10:30:00Z"`, "1995-12-15T10:30:00Z"},
+		{`{"uid":"0x1","name":"Crime"}`, "Crime"},
+		{`{"uid":"0x1"}`, "0x1"},
+		{`{"type":"Point","coordinates":[1,2]}`, `{"type":"Point","coordinates":[1,2]}`},
+		{`[{"name":"Crime"},{"uid":"0x3"},{"name":""}]`, "Crime, 0x3"},
+		{`[]`, ""},
+	}
+	for _, tt := range tests {
+		v, err := decodeOrdered([]byte(tt.json))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.json, err)
+		}
+		if got := cell(v); got != tt.want {
+			t.Errorf("cell(%s) = %q, want %q", tt.json, got, tt.want)
+		}
+	}
+}
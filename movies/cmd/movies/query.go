@@ -0,0 +1,528 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+// QueryCmd executes DQL queries: ad hoc ones given on the command line, in a
+// file or on stdin, and saved ones from the query library.
+type QueryCmd struct {
+	Exec QueryExecCmd `cmd:"" default:"withargs" help:"Execute a DQL query (the default)."`
+	Run  QueryRunCmd  `cmd:"" help:"Run a saved query from the library."`
+	List QueryListCmd `cmd:"" help:"List saved queries with their parameters."`
+	Show QueryShowCmd `cmd:"" help:"Print a saved query."`
+}
+
+// QueryExecCmd executes a raw DQL query against the database.
+type QueryExecCmd struct {
+	Query   string        `arg:"" optional:"" help:"DQL query string (reads --file or stdin if omitted)."`
+	File    string        `short:"f" type:"existingfile" help:"Read the query from a file."`
+	Var     []string      `short:"V" sep:"none" placeholder:"NAME[:TYPE]=VALUE" help:"Bind a query variable, a string unless TYPE is int, float or bool; repeatable."`
+	Pretty  bool          `help:"Pretty-print JSON output." default:"true" negatable:""`
+	Timeout time.Duration `help:"Query timeout." default:"30s"`
+}
+
+func (c *QueryExecCmd) Run(client *movies.Client) error {
+	if c.Query != "" && c.File != "" {
+		return fmt.Errorf("give the query as an argument or with --file, not both")
+	}
+	query := c.Query
+	switch {
+	case c.File != "":
+		data, err := os.ReadFile(c.File)
+		if err != nil {
+			return err
+		}
+		query = strings.TrimSpace(string(data))
+	case query == "":
+		// Read from stdin.
+		reader := bufio.NewReader(os.Stdin)
+		var sb strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			sb.WriteString(line)
+			if err != nil {
+				if err != io.EOF {
+					return fmt.Errorf("reading stdin: %w", err)
+				}
+				break
+			}
+		}
+		query = strings.TrimSpace(sb.String())
+	}
+
+	if query == "" {
+		return fmt.Errorf("empty query: provide a DQL query as an argument, with --file or via stdin")
+	}
+
+	values, types, err := parseVars(c.Var)
+	if err != nil {
+		return err
+	}
+	query, vars, unbound := bindVars("query", query, values, types)
+	if len(unbound) > 0 {
+		return fmt.Errorf("unbound variable %s (use --var %s=value)", unbound[0], unbound[0][1:])
+	}
+	return execQuery(client, query, vars, c.Pretty, c.Timeout)
+}
+
+func execQuery(client *movies.Client, query string, vars map[string]string, pretty bool, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resp, err := client.QueryRaw(ctx, query, vars)
+	if err != nil {
+		return err
+	}
+
+	if pretty || CLI.Output != "json" {
+		if !json.Valid(resp) {
+			return fmt.Errorf("parsing response: invalid JSON")
+		}
+		return printResult(json.RawMessage(resp))
+	}
+	_, err = fmt.Println(string(resp))
+	return err
+}
+
+// parseVars parses NAME=VALUE and NAME:TYPE=VALUE pairs into DQL variable
+// bindings and their declared types, both keyed by "$NAME". The leading $ is
+// optional on the command line.
+func parseVars(pairs []string) (values, types map[string]string, err error) {
+	values = make(map[string]string, len(pairs))
+	types = make(map[string]string)
+	for _, pair := range pairs {
+		decl, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid --var %q: want NAME=VALUE or NAME:TYPE=VALUE", pair)
+		}
+		name, typ, err := parseVarDecl(decl)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --var %q: %w", pair, err)
+		}
+		if err := checkVarType(typ, value); err != nil {
+			return nil, nil, fmt.Errorf("invalid --var %q: %w", pair, err)
+		}
+		values[name] = value
+		if typ != "" {
+			types[name] = typ
+		}
+	}
+	return values, types, nil
+}
+
+// varTypes are the types a DQL query variable can be declared with.
+var varTypes = []string{"string", "int", "float", "bool"}
+
+// parseVarDecl splits NAME or NAME:TYPE into "$NAME" and the type, which is
+// empty when not given.
+func parseVarDecl(decl string) (name, typ string, err error) {
+	name, typ, _ = strings.Cut(decl, ":")
+	name = "$" + strings.TrimPrefix(strings.TrimSpace(name), "$")
+	typ = strings.TrimSpace(typ)
+	if varRef.FindString(name) != name {
+		return "", "", fmt.Errorf("invalid variable name %q", name[1:])
+	}
+	if typ != "" && !slices.Contains(varTypes, typ) {
+		return "", "", fmt.Errorf("unknown type %q; use one of %s", typ, strings.Join(varTypes, ", "))
+	}
+	return name, typ, nil
+}
+
+// checkVarType reports whether value parses as typ.
+func checkVarType(typ, value string) error {
+	var err error
+	switch typ {
+	case "int":
+		_, err = strconv.ParseInt(value, 10, 64)
+	case "float":
+		_, err = strconv.ParseFloat(value, 64)
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return fmt.Errorf("want %s, got %q", typ, value)
+	}
+	return nil
+}
+
+var (
+	varRef = regexp.MustCompile(`\$[A-Za-z_][A-Za-z0-9_]*`)
+	varAt  = regexp.MustCompile(`^` + varRef.String())
+)
+
+// bindVars returns stmt with a header declaring the variables it uses, named
+// name, and the values to send, along with any variables that have no value.
+// Each variable is declared with its type from types, or as a string.
+// Queries that already declare a "query" header are sent as written; Dgraph
+// checks their variables.
+func bindVars(name, stmt string, values, types map[string]string) (query string, vars map[string]string, unbound []string) {
+	used := queryVars(stmt)
+	if len(used) == 0 {
+		return stmt, nil, nil
+	}
+	vars = make(map[string]string, len(used))
+	if hasQueryHeader(stmt) {
+		for _, v := range used {
+			if value, ok := values[v]; ok {
+				vars[v] = value
+			}
+		}
+		return stmt, vars, nil
+	}
+	var decls []string
+	for _, v := range used {
+		value, ok := values[v]
+		if !ok {
+			unbound = append(unbound, v)
+			continue
+		}
+		vars[v] = value
+		typ := types[v]
+		if typ == "" {
+			typ = "string"
+		}
+		decls = append(decls, v+": "+typ)
+	}
+	if len(unbound) > 0 {
+		return "", nil, unbound
+	}
+	return "query " + name + "(" + strings.Join(decls, ", ") + ") " + strings.TrimSpace(stmt), vars, nil
+}
+
+// queryVars returns the variables stmt uses, in order of first use,
+// ignoring # comments and string literals.
+func queryVars(stmt string) []string {
+	var used []string
+	inString, inComment := false, false
+	for i := 0; i < len(stmt); i++ {
+		ch := stmt[i]
+		switch {
+		case inComment:
+			inComment = ch != '\n'
+		case inString:
+			if ch == '\\' {
+				i++
+			} else if ch == '"' {
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+		case ch == '#':
+			inComment = true
+		case ch == '$':
+			v := varAt.FindString(stmt[i:])
+			if v == "" {
+				continue
+			}
+			if !slices.Contains(used, v) {
+				used = append(used, v)
+			}
+			i += len(v) - 1
+		}
+	}
+	return used
+}
+
+// hasQueryHeader reports whether stmt, after any comment lines, starts with a
+// "query" block header.
+func hasQueryHeader(stmt string) bool {
+	for _, line := range strings.Split(stmt, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return strings.HasPrefix(line, "query")
+	}
+	return false
+}
+
+// libraryFlags locates the saved-query library.
+type libraryFlags struct {
+	Library string `help:"Saved-query directory (default: queries/ next to the config file)." env:"MOVIES_QUERIES" type:"path"`
+}
+
+func (f libraryFlags) dir() (string, error) {
+	if f.Library != "" {
+		return expandHome(f.Library)
+	}
+	path, err := configPath(CLI.ConfigFile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "queries"), nil
+}
+
+// savedQuery is a .dql file in the library. Comment lines before the query
+// describe it, and "# $name: text" lines describe its parameters, which are
+// declared in the query header:
+//
+//	# Directors with the most films in a genre.
+//	# $genre: genre name, e.g. Action
+//	query top_directors($genre: string, $first: int = 10) { ... }
+type savedQuery struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Params      []queryParam `json:"params"`
+	Path        string       `json:"-"`
+	Text        string       `json:"-"`
+}
+
+// queryParam is a variable declared in a saved query's header. A parameter
+// without a default is required. Type is empty for queries without a header.
+type queryParam struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+var queryHeader = regexp.MustCompile(`(?m)^\s*query\s*[A-Za-z0-9_]*\s*\(([^)]*)\)`)
+
+// loadSavedQuery reads and parses the saved query at path.
+func loadSavedQuery(path string) (*savedQuery, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	q := &savedQuery{
+		Name: strings.TrimSuffix(filepath.Base(path), ".dql"),
+		Path: path,
+		Text: strings.TrimSpace(string(data)),
+	}
+	notes := map[string]string{}
+	var desc []string
+	for _, line := range strings.Split(q.Text, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") {
+			if line == "" {
+				continue
+			}
+			break
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		if name, text, ok := strings.Cut(line, ":"); ok && varRef.FindString(name) == name {
+			notes[name] = strings.TrimSpace(text)
+			continue
+		}
+		desc = append(desc, line)
+	}
+	q.Description = strings.TrimSpace(strings.Join(desc, " "))
+
+	q.Params = []queryParam{}
+	m := queryHeader.FindStringSubmatch(q.Text)
+	if m == nil {
+		// Without a header, every variable the query uses is a required
+		// parameter, a string unless --var gives it a type.
+		for _, name := range queryVars(q.Text) {
+			q.Params = append(q.Params, queryParam{Name: name[1:], Required: true, Description: notes[name]})
+		}
+		return q, nil
+	}
+	for _, decl := range splitDecls(m[1]) {
+		name, rest, ok := strings.Cut(decl, ":")
+		name = strings.TrimSpace(name)
+		if !ok || varRef.FindString(name) != name {
+			return nil, fmt.Errorf("%s: bad parameter declaration %q", path, decl)
+		}
+		typ, def, hasDefault := strings.Cut(rest, "=")
+		typ = strings.TrimSpace(typ)
+		p := queryParam{
+			Name:        name[1:],
+			Type:        strings.TrimSuffix(typ, "!"),
+			Required:    !hasDefault || strings.HasSuffix(typ, "!"),
+			Description: notes[name],
+		}
+		if hasDefault {
+			p.Default = strings.Trim(strings.TrimSpace(def), `"`)
+		}
+		q.Params = append(q.Params, p)
+	}
+	return q, nil
+}
+
+// splitDecls splits a header's variable declarations on commas outside
+// quoted defaults.
+func splitDecls(s string) []string {
+	var out []string
+	var quoted bool
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	if strings.TrimSpace(s[start:]) != "" {
+		out = append(out, s[start:])
+	}
+	return out
+}
+
+// findSavedQuery loads the named query from the library.
+func findSavedQuery(lib libraryFlags, name string) (*savedQuery, error) {
+	dir, err := lib.dir()
+	if err != nil {
+		return nil, err
+	}
+	q, err := loadSavedQuery(filepath.Join(dir, strings.TrimSuffix(name, ".dql")+".dql"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no saved query %q in %s (see 'movies query list')", name, dir)
+	}
+	return q, err
+}
+
+// bind checks values against the query's parameters and returns the
+// variables to send.
+func (q *savedQuery) bind(values, types map[string]string) (map[string]string, error) {
+	vars := make(map[string]string, len(values))
+	for name, value := range values {
+		i := slices.IndexFunc(q.Params, func(p queryParam) bool { return "$"+p.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("%s has no parameter %s", q.Name, name[1:])
+		}
+		p := q.Params[i]
+		if typ := types[name]; typ != "" && p.Type != "" && typ != p.Type {
+			return nil, fmt.Errorf("%s: parameter %s is declared %s, not %s", q.Name, p.Name, p.Type, typ)
+		}
+		if err := checkParam(p, value); err != nil {
+			return nil, fmt.Errorf("%s: %w", q.Name, err)
+		}
+		vars[name] = value
+	}
+	for _, p := range q.Params {
+		if _, ok := vars["$"+p.Name]; !ok && p.Required {
+			return nil, fmt.Errorf("%s: missing required parameter %s (use --var %s=value)", q.Name, p.Name, p.Name)
+		}
+	}
+	return vars, nil
+}
+
+// checkParam reports whether value is valid for the parameter's type.
+func checkParam(p queryParam, value string) error {
+	if err := checkVarType(p.Type, value); err != nil {
+		return fmt.Errorf("parameter %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// QueryRunCmd runs a saved query.
+type QueryRunCmd struct {
+	libraryFlags
+	Name    string        `arg:"" help:"Saved query name."`
+	Var     []string      `short:"V" sep:"none" placeholder:"NAME[:TYPE]=VALUE" help:"Set a query parameter; repeatable."`
+	Pretty  bool          `help:"Pretty-print JSON output." default:"true" negatable:""`
+	Timeout time.Duration `help:"Query timeout." default:"30s"`
+}
+
+func (c *QueryRunCmd) Run(client *movies.Client) error {
+	q, err := findSavedQuery(c.libraryFlags, c.Name)
+	if err != nil {
+		return err
+	}
+	values, types, err := parseVars(c.Var)
+	if err != nil {
+		return err
+	}
+	vars, err := q.bind(values, types)
+	if err != nil {
+		return err
+	}
+	query, vars, unbound := bindVars(strings.ReplaceAll(q.Name, "-", "_"), q.Text, vars, types)
+	if len(unbound) > 0 {
+		return fmt.Errorf("%s: unbound variable %s (use --var %s=value)", q.Name, unbound[0], unbound[0][1:])
+	}
+	return execQuery(client, query, vars, c.Pretty, c.Timeout)
+}
+
+// QueryListCmd lists the saved queries in the library.
+type QueryListCmd struct {
+	libraryFlags
+}
+
+func (c *QueryListCmd) Run() error {
+	dir, err := c.dir()
+	if err != nil {
+		return err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.dql"))
+	if err != nil {
+		return err
+	}
+	queries := []savedQuery{}
+	for _, path := range paths {
+		q, err := loadSavedQuery(path)
+		if err != nil {
+			return err
+		}
+		queries = append(queries, *q)
+	}
+	if CLI.Output == "table" {
+		return printQueryTable(queries)
+	}
+	return printResult(queries)
+}
+
+// printQueryTable lists one query per line with its parameters in the
+// header's order, marking optional ones with their defaults.
+func printQueryTable(queries []savedQuery) error {
+	type row struct {
+		Name        string `json:"name"`
+		Params      string `json:"params"`
+		Description string `json:"description"`
+	}
+	rows := make([]row, 0, len(queries))
+	for _, q := range queries {
+		rows = append(rows, row{q.Name, q.paramList(), q.Description})
+	}
+	return printResult(rows)
+}
+
+// paramList lists the query's parameters in the header's order, with their
+// types, marking optional ones with their defaults.
+func (q *savedQuery) paramList() string {
+	var params []string
+	for _, p := range q.Params {
+		s := p.Name
+		if p.Type != "" {
+			s += ": " + p.Type
+		}
+		if !p.Required {
+			s += " = " + p.Default
+		}
+		params = append(params, s)
+	}
+	return strings.Join(params, ", ")
+}
+
+// QueryShowCmd prints a saved query.
+type QueryShowCmd struct {
+	libraryFlags
+	Name string `arg:"" help:"Saved query name."`
+}
+
+func (c *QueryShowCmd) Run() error {
+	q, err := findSavedQuery(c.libraryFlags, c.Name)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(q.Text)
+	return err
+}
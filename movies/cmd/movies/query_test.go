@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseVars(t *testing.T) {
+	values, types, err := parseVars([]string{"name=The Matrix", "$n:int=5", "ratio:float=0.5", "ok:bool=true", "eq=a=b", "empty="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantValues := map[string]string{"$name": "The Matrix", "$n": "5", "$ratio": "0.5", "$ok": "true", "$eq": "a=b", "$empty": ""}
+	if !reflect.DeepEqual(values, wantValues) {
+		t.Errorf("values = %q, want %q", values, wantValues)
+	}
+	wantTypes := map[string]string{"$n": "int", "$ratio": "float", "$ok": "bool"}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Errorf("types = %q, want %q", types, wantTypes)
+	}
+
+	for _, bad := range []string{"name", "=x", "1st=x", "n:int=five", "n:float=x", "b:bool=maybe", "d:date=1999", "a b=c"} {
+		if _, _, err := parseVars([]string{bad}); err == nil {
+			t.Errorf("parseVars(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestBindVars(t *testing.T) {
+	values := map[string]string{"$name": "Heat", "$n": "5", "$year": "1995"}
+	types := map[string]string{"$n": "int"}
+	tests := []struct {
+		stmt, want string
+		unbound    []string
+	}{
+		{`{ q(func: has(name)) { uid } }`, `{ q(func: has(name)) { uid } }`, nil},
+		// Numbers are strings unless typed, so string predicates match them.
+		{`{ q(func: eq(name, $year), first: $n) { uid } }`,
+			`query q($year: string, $n: int) { q(func: eq(name, $year), first: $n) { uid } }`, nil},
+		{`{ a(func: eq(name, $name)) { uid } b(func: eq(name, $name)) { uid } }`,
+			`query q($name: string) { a(func: eq(name, $name)) { uid } b(func: eq(name, $name)) { uid } }`, nil},
+		{"# header below\nquery q($n: int) { q(func: has(name), first: $n) { uid } }",
+			"# header below\nquery q($n: int) { q(func: has(name), first: $n) { uid } }", nil},
+		{`{ q(func: eq(name, $other), first: $missing) { uid } }`, "", []string{"$other", "$missing"}},
+		// Variables in comments and string literals are not variables.
+		{"# uses $x\n{ q(func: eq(name, \"$x costs $5\")) { uid } }",
+			"# uses $x\n{ q(func: eq(name, \"$x costs $5\")) { uid } }", nil},
+		{"{ q(func: eq(name, \"say \\\"$x\\\"\"), first: $n) { uid } } # then $y",
+			"query q($n: int) { q(func: eq(name, \"say \\\"$x\\\"\"), first: $n) { uid } } # then $y", nil},
+	}
+	for _, tt := range tests {
+		query, vars, unbound := bindVars("q", tt.stmt, values, types)
+		if query != tt.want || !reflect.DeepEqual(unbound, tt.unbound) {
+			t.Errorf("bindVars(%q) = %q, unbound %q; want %q, unbound %q", tt.stmt, query, unbound, tt.want, tt.unbound)
+		}
+		for name, v := range vars {
+			if values[name] != v {
+				t.Errorf("bindVars(%q) sends %s = %q, want %q", tt.stmt, name, v, values[name])
+			}
+		}
+	}
+}
+
+func TestSavedQueryBind(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) *savedQuery {
+		t.Helper()
+		path := filepath.Join(dir, name+".dql")
+		if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		q, err := loadSavedQuery(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return q
+	}
+	header := write("top", "# Top films.\n# $genre: genre name\nquery top($genre: string, $first: int = 10) {\n  q(func: eq(name, $genre), first: $first) { uid }\n}\n")
+	if _, err := header.bind(map[string]string{"$genre": "Action", "$first": "5"}, map[string]string{"$first": "int"}); err != nil {
+		t.Errorf("typed as declared: %v", err)
+	}
+	if _, err := header.bind(map[string]string{"$genre": "Action", "$first": "5"}, map[string]string{"$first": "string"}); err == nil || !strings.Contains(err.Error(), "declared int") {
+		t.Errorf("err = %v, want a declared type mismatch", err)
+	}
+	if _, err := header.bind(map[string]string{"$genre": "Action", "$first": "five"}, nil); err == nil {
+		t.Error("expected a value that is not an int to fail")
+	}
+	if _, err := header.bind(map[string]string{"$first": "5"}, nil); err == nil || !strings.Contains(err.Error(), "missing required parameter genre") {
+		t.Errorf("err = %v, want a missing parameter", err)
+	}
+
+	bare := write("by-year", "{ q(func: eq(initial_release_date, $year), first: $n) { uid } }")
+	vars, err := bare.bind(map[string]string{"$year": "1999", "$n": "3"}, map[string]string{"$n": "int"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, _, _ := bindVars("by_year", bare.Text, vars, map[string]string{"$n": "int"})
+	if want := "query by_year($year: string, $n: int) {"; !strings.HasPrefix(query, want) {
+		t.Errorf("query = %q, want it to start %q", query, want)
+	}
+
+	literal := write("priced", "# Films named for a price.\n# $n: how many\n{ q(func: eq(name, \"$x for $5\"), first: $n) { uid } }")
+	if got := literal.paramList(); got != "n" {
+		t.Errorf("params = %q, want only n", got)
+	}
+}
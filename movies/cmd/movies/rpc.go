@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/jsonrpc"
+)
+
+// RPCCmd serves the JSON-RPC tools.
+type RPCCmd struct {
+	libraryFlags
+	Stdio bool `help:"Serve requests on stdin and responses on stdout, one JSON message per line."`
+}
+
+func (c *RPCCmd) Run(client *movies.Client) error {
+	if !c.Stdio {
+		return fmt.Errorf("give --stdio: stdin and stdout are the only transport so far")
+	}
+	tools := append(jsonrpc.Tools(client), c.savedQueryTool(client))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return jsonrpc.NewServer(tools...).Serve(ctx, os.Stdin, os.Stdout)
+}
+
+type runSavedQueryParams struct {
+	Name   string            `json:"name" required:"" help:"The saved query's name."`
+	Params map[string]string `json:"params" help:"The query's parameters by name, without the $. Give a query without a header a typed parameter as name:type, where type is int, float or bool."`
+}
+
+// savedQueryTool returns the run_saved_query tool, which runs queries from
+// the library. The library is read on each call, so queries added while
+// the server runs can be used, but the name enum lists those there at
+// startup.
+func (c *RPCCmd) savedQueryTool(client *movies.Client) jsonrpc.Tool {
+	t := jsonrpc.NewTool("run_saved_query", "Run a saved DQL query from the query library. Returns the query's result as Dgraph returns it.",
+		func(ctx context.Context, p runSavedQueryParams) (any, error) {
+			q, err := findSavedQuery(c.libraryFlags, p.Name)
+			if err != nil {
+				return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%v", err)
+			}
+			values := make(map[string]string, len(p.Params))
+			types := make(map[string]string)
+			for decl, value := range p.Params {
+				name, typ, err := parseVarDecl(decl)
+				if err != nil {
+					return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "params: %v", err)
+				}
+				if err := checkVarType(typ, value); err != nil {
+					return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "params: %s: %v", name[1:], err)
+				}
+				values[name] = value
+				if typ != "" {
+					types[name] = typ
+				}
+			}
+			for _, qp := range q.Params {
+				if _, ok := values["$"+qp.Name]; !ok && qp.Required {
+					return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%s: missing required parameter %s", q.Name, qp.Name)
+				}
+			}
+			vars, err := q.bind(values, types)
+			if err != nil {
+				return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%v", err)
+			}
+			query, vars, unbound := bindVars(strings.ReplaceAll(q.Name, "-", "_"), q.Text, vars, types)
+			if len(unbound) > 0 {
+				return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%s: unbound variable %s", q.Name, unbound[0])
+			}
+			resp, err := client.QueryRaw(ctx, query, vars)
+			if err != nil {
+				return nil, err
+			}
+			return json.RawMessage(resp), nil
+		})
+	// List the library's queries in the description, as query list does.
+	dir, err := c.dir()
+	if err != nil {
+		return t
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.dql"))
+	var lines []string
+	for _, path := range paths {
+		q, err := loadSavedQuery(path)
+		if err != nil {
+			continue
+		}
+		t.InputSchema.Properties["name"].Enum = append(t.InputSchema.Properties["name"].Enum, q.Name)
+		line := "- " + q.Name + "(" + q.paramList() + ")"
+		if q.Description != "" {
+			line += ": " + q.Description
+		}
+		lines = append(lines, line)
+	}
+	if lines != nil {
+		t.Description += " The queries are:\n" + strings.Join(lines, "\n")
+	}
+	return t
+}
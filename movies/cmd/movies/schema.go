@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+// SchemaCmd inspects the database schema and brings it in line with the
+// entity structs.
+type SchemaCmd struct {
+	Show  SchemaShowCmd  `cmd:"" help:"Show the live schema."`
+	Gen   SchemaGenCmd   `cmd:"" help:"Show the schema derived from the entity structs."`
+	Diff  SchemaDiffCmd  `cmd:"" help:"Compare the live schema with the entity structs."`
+	Apply SchemaApplyCmd `cmd:"" help:"Update the live schema to match the entity structs."`
+}
+
+// SchemaShowCmd prints the live schema.
+type SchemaShowCmd struct {
+	JSON    bool          `help:"Print the schema as JSON instead of DQL."`
+	Timeout time.Duration `help:"Query timeout." default:"30s"`
+}
+
+func (c *SchemaShowCmd) Run(client *movies.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	schema, err := client.Schema(ctx)
+	if err != nil {
+		return err
+	}
+	return printSchema(schema, c.JSON)
+}
+
+// SchemaGenCmd prints the schema derived from the entity structs.
+type SchemaGenCmd struct {
+	JSON bool `help:"Print the schema as JSON instead of DQL."`
+}
+
+func (c *SchemaGenCmd) Run() error {
+	return printSchema(movies.EntitySchema(), c.JSON)
+}
+
+func printSchema(schema movies.Schema, asJSON bool) error {
+	if asJSON {
+		return render(os.Stdout, "json", "", schema)
+	}
+	_, err := fmt.Print(schema)
+	return err
+}
+
+// SchemaDiffCmd compares the live schema with the entity structs and exits
+// non-zero when they differ.
+type SchemaDiffCmd struct {
+	Timeout time.Duration `help:"Query timeout." default:"30s"`
+}
+
+func (c *SchemaDiffCmd) Run(client *movies.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	live, err := client.Schema(ctx)
+	if err != nil {
+		return err
+	}
+	changes := diffSchema(movies.EntitySchema(), live)
+	for _, ch := range changes {
+		fmt.Println(ch)
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("schema differs from the entity structs in %d places", len(changes))
+	}
+	fmt.Println("schema matches the entity structs")
+	return nil
+}
+
+// SchemaApplyCmd alters the live schema to match the entity structs.
+type SchemaApplyCmd struct {
+	DryRun  bool          `help:"Show the changes without applying them."`
+	Timeout time.Duration `help:"Timeout." default:"1m"`
+}
+
+func (c *SchemaApplyCmd) Run(client *movies.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	live, err := client.Schema(ctx)
+	if err != nil {
+		return err
+	}
+	changes := diffSchema(movies.EntitySchema(), live)
+	for _, ch := range changes {
+		if ch.kind == extra {
+			fmt.Printf("%s (kept)\n", ch)
+		} else {
+			fmt.Println(ch)
+		}
+	}
+	if c.DryRun {
+		pending := movies.PendingSchema(live)
+		if len(pending.Predicates) == 0 && len(pending.Types) == 0 {
+			fmt.Println("schema is up to date")
+			return nil
+		}
+		fmt.Printf("\ndry run, would alter:\n\n%s", pending)
+		return nil
+	}
+	applied, err := client.ApplySchema(ctx)
+	if err != nil {
+		return fmt.Errorf("applying schema: %w", err)
+	}
+	if len(applied.Predicates) == 0 && len(applied.Types) == 0 {
+		fmt.Println("schema is up to date")
+		return nil
+	}
+	fmt.Printf("altered %d predicates and %d types\n", len(applied.Predicates), len(applied.Types))
+	return nil
+}
+
+type changeKind byte
+
+const (
+	missing changeKind = '+' // in the structs, not the database
+	extra   changeKind = '-' // in the database, not the structs
+	changed changeKind = '~' // in both, defined differently
+)
+
+// schemaChange is one difference between the struct and live schemas.
+type schemaChange struct {
+	kind   changeKind
+	what   string // "predicate" or "type"
+	name   string
+	detail string
+}
+
+func (c schemaChange) String() string {
+	s := fmt.Sprintf("%c %s %s", c.kind, c.what, c.name)
+	if c.detail != "" {
+		s += ": " + c.detail
+	}
+	return s
+}
+
+// diffSchema lists how have differs from want: missing and extra
+// predicates and types, type mismatches, index tokenizers and directives.
+func diffSchema(want, have movies.Schema) []schemaChange {
+	var changes []schemaChange
+	for _, w := range want.Predicates {
+		h, ok := have.Predicate(w.Predicate)
+		if !ok {
+			changes = append(changes, schemaChange{kind: missing, what: "predicate", name: w.String()})
+			continue
+		}
+		if d := predicateDiff(w, h); len(d) > 0 {
+			changes = append(changes, schemaChange{changed, "predicate", w.Predicate, strings.Join(d, "; ")})
+		}
+	}
+	for _, h := range have.Predicates {
+		if _, ok := want.Predicate(h.Predicate); ok {
+			continue
+		}
+		name := h.String()
+		// A near miss such as initialReleaseDate for initial_release_date
+		// usually means data was written under the JSON field name.
+		for _, w := range want.Predicates {
+			if normalizeColumn(w.Predicate) == normalizeColumn(h.Predicate) {
+				name += " (the structs use " + w.Predicate + ")"
+			}
+		}
+		changes = append(changes, schemaChange{kind: extra, what: "predicate", name: name})
+	}
+
+	for _, w := range want.Types {
+		h, ok := have.Type(w.Name)
+		if !ok {
+			changes = append(changes, schemaChange{kind: missing, what: "type", name: w.Name})
+			continue
+		}
+		var d []string
+		if m := fieldsMissing(w, h); len(m) > 0 {
+			d = append(d, "missing "+strings.Join(m, ", "))
+		}
+		if x := fieldsMissing(h, w); len(x) > 0 {
+			d = append(d, "not in the structs "+strings.Join(x, ", "))
+		}
+		if len(d) > 0 {
+			changes = append(changes, schemaChange{changed, "type", w.Name, strings.Join(d, "; ")})
+		}
+	}
+	for _, h := range have.Types {
+		if _, ok := want.Type(h.Name); !ok {
+			changes = append(changes, schemaChange{kind: extra, what: "type", name: h.Name})
+		}
+	}
+	return changes
+}
+
+// predicateDiff describes how the live predicate h differs from w.
+func predicateDiff(w, h movies.PredicateSchema) []string {
+	var d []string
+	wt, ht := w.Type, h.Type
+	if w.List {
+		wt = "[" + wt + "]"
+	}
+	if h.List {
+		ht = "[" + ht + "]"
+	}
+	if wt != ht {
+		d = append(d, fmt.Sprintf("type is %s, structs want %s", ht, wt))
+	}
+	wantTok, haveTok := w.Tokenizer, h.Tokenizer
+	if !w.Index {
+		wantTok = nil
+	}
+	if !h.Index {
+		haveTok = nil
+	}
+	if m := without(wantTok, haveTok); len(m) > 0 {
+		d = append(d, "missing index "+strings.Join(m, ", "))
+	}
+	if x := without(haveTok, wantTok); len(x) > 0 {
+		d = append(d, "index not in the structs "+strings.Join(x, ", "))
+	}
+	for _, dir := range []struct {
+		name       string
+		want, have bool
+	}{
+		{"@reverse", w.Reverse, h.Reverse},
+		{"@count", w.Count, h.Count},
+		{"@upsert", w.Upsert, h.Upsert},
+		{"@unique", w.Unique, h.Unique},
+		{"@lang", w.Lang, h.Lang},
+	} {
+		switch {
+		case dir.want && !dir.have:
+			d = append(d, "missing "+dir.name)
+		case dir.have && !dir.want:
+			d = append(d, dir.name+" not in the structs")
+		}
+	}
+	return d
+}
+
+// fieldsMissing returns the fields of a that b lacks.
+func fieldsMissing(a, b movies.TypeSchema) []string {
+	var out []string
+	for _, f := range a.Fields {
+		if !slices.ContainsFunc(b.Fields, func(g movies.TypeField) bool { return g.Name == f.Name }) {
+			out = append(out, f.Name)
+		}
+	}
+	return out
+}
+
+// without returns the elements of a not in b.
+func without(a, b []string) []string {
+	var out []string
+	for _, s := range a {
+		if !slices.Contains(b, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/rest"
+)
+
+// ServeCmd serves the entity clients over HTTP.
+type ServeCmd struct {
+	Run     ServeRunCmd     `cmd:"" default:"withargs" help:"Serve the REST API (the default)."`
+	OpenAPI ServeOpenAPICmd `cmd:"" name:"openapi" help:"Print the REST API's OpenAPI 3 document."`
+}
+
+// ServeRunCmd runs the REST server until interrupted.
+type ServeRunCmd struct {
+	Listen string `help:"Address to listen on." default:":8081" env:"MOVIES_LISTEN"`
+	Quiet  bool   `help:"Do not log requests to stderr."`
+}
+
+func (c *ServeRunCmd) Run(client *movies.Client) error {
+	var handler http.Handler = rest.Handler(client)
+	if !c.Quiet {
+		handler = logRequests(handler)
+	}
+	return listenAndServe(c.Listen, handler)
+}
+
+// listenAndServe serves handler on addr until SIGINT or SIGTERM, then lets
+// requests in flight finish.
+func listenAndServe(addr string, handler http.Handler) error {
+	srv := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	fmt.Fprintf(os.Stderr, "listening on %s\n", addr)
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdown); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// statusRecorder notes the status a handler writes.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests writes one line per request to stderr.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		fmt.Fprintf(os.Stderr, "%s %s %d %s\n", r.Method, r.URL.RequestURI(), rec.status, time.Since(start).Round(time.Microsecond))
+	})
+}
+
+// ServeOpenAPICmd prints the OpenAPI document.
+type ServeOpenAPICmd struct{}
+
+func (c *ServeOpenAPICmd) Run() error {
+	return render(os.Stdout, "json", "", rest.OpenAPI())
+}
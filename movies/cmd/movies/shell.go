@@ -0,0 +1,563 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"golang.org/x/term"
+)
+
+// ShellCmd runs an interactive DQL shell.
+type ShellCmd struct {
+	History string        `help:"History file." default:"~/.movies_history" type:"path"`
+	Timeout time.Duration `help:"Query timeout." default:"30s"`
+}
+
+const shellHelp = `Enter DQL queries; a query runs once its braces balance.
+Meta-commands:
+  \schema [Type]           show the schema, or one type and its predicates
+  \output FORMAT [TMPL]    set the output format (json, ndjson, table, csv,
+                           tsv, yaml, template)
+  \timing [on|off]         show how long each query takes
+  \page N|off              page output longer than N lines
+  \set [$name[:type] value]
+                           bind a query variable, or list bindings; a
+                           type is int, float or bool, and string if absent
+  \unset $name             remove a binding
+  \c                       discard the query being typed
+  \?                       show this help
+  \q                       quit
+Variables bound with \set are declared automatically when a query uses them
+without a query header. Tab completes predicates, types, functions and
+variables.
+`
+
+func (c *ShellCmd) Run(client *movies.Client) error {
+	s := &shell{
+		client:  client,
+		timeout: c.Timeout,
+		format:  CLI.Output,
+		tmpl:    CLI.Template,
+		vars:    make(map[string]string),
+		types:   make(map[string]string),
+	}
+	if err := s.loadSchema(); err != nil {
+		fmt.Fprintf(os.Stderr, "loading schema for completion: %v\n", err)
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		s.out = os.Stdout
+		r := bufio.NewReader(os.Stdin)
+		return s.loop(func(string) (string, error) {
+			line, err := r.ReadString('\n')
+			if err == io.EOF && line != "" {
+				err = nil
+			}
+			return strings.TrimRight(line, "\r\n"), err
+		})
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "")
+	if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 && h > 1 {
+		_ = t.SetSize(w, h)
+		s.page = h - 1
+	}
+	hist, err := openHistory(c.History)
+	if err != nil {
+		fmt.Fprintf(t, "history disabled: %v\n", err)
+	} else {
+		defer hist.Close()
+		t.History = hist
+	}
+	comp := &completer{shell: s}
+	t.AutoCompleteCallback = comp.complete
+	s.out = t
+	s.term = t
+
+	fmt.Fprintf(t, "movies shell. Type \\? for help, \\q to quit.\n")
+	return s.loop(func(prompt string) (string, error) {
+		t.SetPrompt(prompt)
+		line, err := t.ReadLine()
+		if errors.Is(err, term.ErrPasteIndicator) {
+			err = nil
+		}
+		return line, err
+	})
+}
+
+type shell struct {
+	client  *movies.Client
+	out     io.Writer
+	term    *term.Terminal // nil when reading from a pipe
+	timeout time.Duration
+	format  string
+	tmpl    string
+	timing  bool
+	page    int // lines per page; 0 disables paging
+	vars    map[string]string
+	types   map[string]string // declared types of vars; string when absent
+
+	schema movies.Schema
+}
+
+// loadSchema fetches the schema for completion and \schema.
+func (s *shell) loadSchema() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	schema, err := s.client.Schema(ctx)
+	if err != nil {
+		return err
+	}
+	s.schema = schema
+	return nil
+}
+
+func (s *shell) loop(readLine func(prompt string) (string, error)) error {
+	var buf []string
+	for {
+		prompt := "dql> "
+		if len(buf) > 0 {
+			prompt = "...> "
+		}
+		line, err := readLine(prompt)
+		if err == io.EOF {
+			if len(buf) > 0 {
+				s.exec(strings.Join(buf, "\n"))
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, `\`) {
+			if trimmed == `\c` {
+				buf = nil
+				continue
+			}
+			if quit := s.meta(trimmed); quit {
+				return nil
+			}
+			continue
+		}
+		if len(buf) == 0 && trimmed == "" {
+			continue
+		}
+		buf = append(buf, line)
+		stmt := strings.Join(buf, "\n")
+		if !statementComplete(stmt) {
+			continue
+		}
+		buf = nil
+		s.exec(strings.TrimSuffix(strings.TrimSpace(stmt), ";"))
+	}
+}
+
+// statementComplete reports whether stmt has at least one block and all of
+// its braces are closed, ignoring braces in strings and # comments. A
+// trailing semicolon also ends a statement.
+func statementComplete(stmt string) bool {
+	if strings.HasSuffix(strings.TrimSpace(stmt), ";") {
+		return true
+	}
+	depth, opened := 0, false
+	inString, inComment := false, false
+	for i := 0; i < len(stmt); i++ {
+		ch := stmt[i]
+		switch {
+		case inComment:
+			inComment = ch != '\n'
+		case inString:
+			if ch == '\\' {
+				i++
+			} else if ch == '"' {
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+		case ch == '#':
+			inComment = true
+		case ch == '{':
+			depth++
+			opened = true
+		case ch == '}':
+			depth--
+		}
+	}
+	return opened && depth <= 0
+}
+
+// bind returns the query with a header declaring any bound variables it uses,
+// and the values to send.
+func (s *shell) bind(stmt string) (string, map[string]string, error) {
+	query, vars, unbound := bindVars("shell", stmt, s.vars, s.types)
+	if len(unbound) > 0 {
+		return "", nil, fmt.Errorf("unbound variable %s (use \\set %s value)", unbound[0], unbound[0])
+	}
+	return query, vars, nil
+}
+
+func (s *shell) exec(stmt string) {
+	query, vars, err := s.bind(stmt)
+	if err != nil {
+		fmt.Fprintf(s.out, "error: %v\n", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	start := time.Now()
+	resp, err := s.client.QueryRaw(ctx, query, vars)
+	elapsed := time.Since(start)
+	if err != nil {
+		fmt.Fprintf(s.out, "error: %v\n", err)
+		return
+	}
+	var out bytes.Buffer
+	if err := render(&out, s.format, s.tmpl, json.RawMessage(resp)); err != nil {
+		fmt.Fprintf(s.out, "error: %v\n", err)
+		return
+	}
+	s.pageOut(out.String())
+	if s.timing {
+		fmt.Fprintf(s.out, "Time: %s\n", elapsed.Round(time.Microsecond))
+	}
+}
+
+// pageOut writes text a screenful at a time when paging is on and the shell
+// is interactive.
+func (s *shell) pageOut(text string) {
+	lines := strings.SplitAfter(strings.TrimSuffix(text, "\n"), "\n")
+	lines[len(lines)-1] += "\n"
+	if s.term == nil || s.page <= 0 || len(lines) <= s.page {
+		io.WriteString(s.out, text)
+		return
+	}
+	for len(lines) > 0 {
+		n := min(s.page, len(lines))
+		io.WriteString(s.out, strings.Join(lines[:n], ""))
+		lines = lines[n:]
+		if len(lines) == 0 {
+			return
+		}
+		// ReadPassword neither echoes nor records the answer in history.
+		answer, err := s.term.ReadPassword(fmt.Sprintf("-- More (%d lines left; Enter to continue, q to stop) -- ", len(lines)))
+		if err != nil || strings.HasPrefix(strings.TrimSpace(answer), "q") {
+			return
+		}
+	}
+}
+
+// meta runs a backslash command and reports whether the shell should exit.
+func (s *shell) meta(line string) bool {
+	fields := strings.Fields(line)
+	cmd, args := fields[0], fields[1:]
+	switch cmd {
+	case `\q`, `\quit`:
+		return true
+	case `\?`, `\h`, `\help`:
+		io.WriteString(s.out, shellHelp)
+	case `\schema`:
+		if err := s.loadSchema(); err != nil {
+			fmt.Fprintf(s.out, "error: %v\n", err)
+			return false
+		}
+		var typeName string
+		if len(args) > 0 {
+			typeName = args[0]
+		}
+		s.pageOut(s.schemaText(typeName))
+	case `\timing`:
+		switch {
+		case len(args) == 0:
+			s.timing = !s.timing
+		case args[0] == "on" || args[0] == "off":
+			s.timing = args[0] == "on"
+		default:
+			fmt.Fprintf(s.out, "usage: \\timing [on|off]\n")
+			return false
+		}
+		fmt.Fprintf(s.out, "Timing is %s.\n", onOff(s.timing))
+	case `\output`, `\o`:
+		if len(args) == 0 {
+			fmt.Fprintf(s.out, "Output format is %s.\n", s.format)
+			return false
+		}
+		if !slices.Contains(outputFormats, args[0]) {
+			fmt.Fprintf(s.out, "unknown format %q; use one of %s\n", args[0], strings.Join(outputFormats, ", "))
+			return false
+		}
+		s.format = args[0]
+		if s.format == "template" {
+			_, tmpl, _ := strings.Cut(strings.TrimSpace(line[len(cmd):]), " ")
+			if tmpl = strings.TrimSpace(tmpl); tmpl != "" {
+				s.tmpl = tmpl
+			}
+		}
+		fmt.Fprintf(s.out, "Output format is %s.\n", s.format)
+	case `\page`:
+		if len(args) != 1 {
+			fmt.Fprintf(s.out, "usage: \\page N|off\n")
+			return false
+		}
+		if args[0] == "off" {
+			s.page = 0
+		} else if n, err := strconv.Atoi(args[0]); err == nil && n > 0 {
+			s.page = n
+		} else {
+			fmt.Fprintf(s.out, "usage: \\page N|off\n")
+		}
+	case `\set`:
+		if len(args) == 0 {
+			names := make([]string, 0, len(s.vars))
+			for name := range s.vars {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				decl := name
+				if typ := s.types[name]; typ != "" {
+					decl += ":" + typ
+				}
+				fmt.Fprintf(s.out, "%s = %s\n", decl, s.vars[name])
+			}
+			return false
+		}
+		name, typ, err := parseVarDecl(args[0])
+		if err != nil || len(args) < 2 {
+			fmt.Fprintf(s.out, "usage: \\set $name[:type] value\n")
+			return false
+		}
+		// Keep the value's inner spacing: it is everything after the name.
+		rest := strings.TrimSpace(line[len(cmd):])
+		value := strings.TrimSpace(rest[len(args[0]):])
+		if err := checkVarType(typ, value); err != nil {
+			fmt.Fprintf(s.out, "error: %s: %v\n", name, err)
+			return false
+		}
+		s.vars[name] = value
+		delete(s.types, name)
+		if typ != "" {
+			s.types[name] = typ
+		}
+	case `\unset`:
+		for _, a := range args {
+			name := "$" + strings.TrimPrefix(a, "$")
+			delete(s.vars, name)
+			delete(s.types, name)
+		}
+	default:
+		fmt.Fprintf(s.out, "unknown command %s; type \\? for help\n", cmd)
+	}
+	return false
+}
+
+var outputFormats = []string{"json", "ndjson", "table", "csv", "tsv", "yaml", "template"}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// schemaText formats the schema the way it is written in DQL. With a type
+// name, only that type and its predicates are shown.
+func (s *shell) schemaText(typeName string) string {
+	if typeName == "" {
+		return s.schema.String()
+	}
+	t, ok := s.schema.Type(typeName)
+	if !ok {
+		return fmt.Sprintf("no type named %s\n", typeName)
+	}
+	sub := movies.Schema{Types: []movies.TypeSchema{t}}
+	for _, f := range t.Fields {
+		if p, ok := s.schema.Predicate(f.Name); ok {
+			sub.Predicates = append(sub.Predicates, p)
+		}
+	}
+	return sub.String()
+}
+
+// historyFile is a terminal History that appends each entry to a file so
+// that it survives between sessions.
+type historyFile struct {
+	entries []string // oldest first
+	f       *os.File
+}
+
+const maxHistory = 1000
+
+func openHistory(path string) (*historyFile, error) {
+	h := &historyFile{}
+	if data, err := os.ReadFile(path); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line != "" {
+				h.entries = append(h.entries, line)
+			}
+		}
+		if len(h.entries) > maxHistory {
+			h.entries = h.entries[len(h.entries)-maxHistory:]
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	h.f = f
+	return h, nil
+}
+
+func (h *historyFile) Add(entry string) {
+	if strings.TrimSpace(entry) == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry) {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[1:]
+	}
+	fmt.Fprintln(h.f, entry)
+}
+
+func (h *historyFile) Len() int { return len(h.entries) }
+
+func (h *historyFile) At(idx int) string { return h.entries[len(h.entries)-1-idx] }
+
+func (h *historyFile) Close() error { return h.f.Close() }
+
+var dqlWords = []string{
+	"func:", "first:", "offset:", "after:", "orderasc:", "orderdesc:",
+	"uid", "eq", "ge", "gt", "le", "lt", "between", "has", "type",
+	"allofterms", "anyofterms", "alloftext", "anyoftext", "regexp", "match",
+	"near", "within", "contains", "intersects", "uid_in", "count", "val",
+	"expand", "_all_", "dgraph.type", "var", "query", "schema",
+	"@filter", "@cascade", "@normalize", "@recurse", "@facets",
+	"AND", "OR", "NOT",
+}
+
+var metaCommands = []string{`\schema`, `\output`, `\timing`, `\page`, `\set`, `\unset`, `\c`, `\help`, `\quit`}
+
+// completer implements tab completion. An ambiguous word is completed to the
+// longest common prefix; pressing tab again cycles through the candidates.
+type completer struct {
+	shell *shell
+	cycle *completionCycle
+}
+
+type completionCycle struct {
+	line, before, after string
+	pos                 int
+	matches             []string
+	idx                 int
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b == '.' || b == '~' || b == '@' || b == '$' || b == '\\' ||
+		('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
+
+func (c *completer) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		c.cycle = nil
+		return "", 0, false
+	}
+	if cy := c.cycle; cy != nil && cy.line == line && cy.pos == pos {
+		cy.idx = (cy.idx + 1) % len(cy.matches)
+		cy.line = cy.before + cy.matches[cy.idx] + cy.after
+		cy.pos = len(cy.before) + len(cy.matches[cy.idx])
+		return cy.line, cy.pos, true
+	}
+	c.cycle = nil
+
+	start := pos
+	for start > 0 && isWordByte(line[start-1]) {
+		start--
+	}
+	word := line[start:pos]
+	if word == "" {
+		return line, pos, true
+	}
+	matches := c.candidates(word, strings.TrimSpace(line[:start]) == "")
+	if len(matches) == 0 {
+		return line, pos, true
+	}
+	before, after := line[:start], line[pos:]
+	if prefix := commonPrefix(matches); len(prefix) > len(word) || len(matches) == 1 {
+		return before + prefix + after, start + len(prefix), true
+	}
+	c.cycle = &completionCycle{before: before, after: after, matches: matches}
+	c.cycle.line = before + matches[0] + after
+	c.cycle.pos = start + len(matches[0])
+	return c.cycle.line, c.cycle.pos, true
+}
+
+func (c *completer) candidates(word string, lineStart bool) []string {
+	var pool []string
+	switch {
+	case strings.HasPrefix(word, `\`):
+		if lineStart {
+			pool = metaCommands
+		}
+	case strings.HasPrefix(word, "$"):
+		for name := range c.shell.vars {
+			pool = append(pool, name)
+		}
+	case strings.HasPrefix(word, "~"):
+		for _, p := range c.shell.schema.Predicates {
+			if p.Reverse {
+				pool = append(pool, "~"+p.Predicate)
+			}
+		}
+	default:
+		for _, p := range c.shell.schema.Predicates {
+			pool = append(pool, p.Predicate)
+		}
+		for _, t := range c.shell.schema.Types {
+			pool = append(pool, t.Name)
+		}
+		pool = append(pool, dqlWords...)
+	}
+	var out []string
+	for _, cand := range pool {
+		if strings.HasPrefix(cand, word) && !slices.Contains(out, cand) {
+			out = append(out, cand)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+func TestStatementComplete(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want bool
+	}{
+		{"", false},
+		{"{", false},
+		{"{ q(func: has(name)) { uid } }", true},
+		{"{\n  q(func: has(name)) {\n    uid\n", false},
+		{"{\n  q(func: has(name)) {\n    uid\n  }\n}", true},
+		{`{ q(func: eq(name, "}")) {`, false},
+		{`{ q(func: eq(name, "a \"}\" b")) { uid } }`, true},
+		{"{ q(func: has(name)) { # }\n", false},
+		{"{ q(func: has(name)) { # }\n uid } }", true},
+		{"schema {}", true},
+		{"schema(pred: name);", true},
+		{"no braces", false},
+	}
+	for _, tt := range tests {
+		if got := statementComplete(tt.stmt); got != tt.want {
+			t.Errorf("statementComplete(%q) = %v, want %v", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestShellBind(t *testing.T) {
+	s := &shell{vars: map[string]string{"$name": "Heat", "$n": "2"}, types: map[string]string{"$n": "int"}}
+	query, vars, err := s.bind(`{ q(func: eq(name, $name), first: $n) { uid } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `query shell($name: string, $n: int) { q(func: eq(name, $name), first: $n) { uid } }`; query != want {
+		t.Errorf("query = %q, want %q", query, want)
+	}
+	if vars["$name"] != "Heat" {
+		t.Errorf("vars = %v", vars)
+	}
+
+	stmt := `query q($name: string) { q(func: eq(name, $name)) { uid } }`
+	if query, _, err := s.bind(stmt); err != nil || query != stmt {
+		t.Errorf("a query with a header is sent as written, got %q, %v", query, err)
+	}
+
+	if _, _, err := s.bind(`{ q(func: eq(name, $other)) { uid } }`); err == nil || !strings.Contains(err.Error(), `\set $other`) {
+		t.Errorf("err = %v, want an unbound variable hint", err)
+	}
+}
+
+// runShell feeds lines to a shell without a client and returns its output.
+// Only meta-commands and queries that fail before reaching Dgraph can be run.
+func runShell(s *shell, lines ...string) string {
+	var out strings.Builder
+	s.out = &out
+	if s.vars == nil {
+		s.vars = make(map[string]string)
+		s.types = make(map[string]string)
+	}
+	s.loop(func(string) (string, error) {
+		if len(lines) == 0 {
+			return "", io.EOF
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return line, nil
+	})
+	return out.String()
+}
+
+func TestShellMeta(t *testing.T) {
+	s := &shell{format: "json"}
+	out := runShell(s,
+		`\set $name  The  Matrix `,
+		`\set year:int 1999`,
+		`\set`,
+		`\unset $year`,
+		`\set`,
+		`\set $bad`,
+		`\set $n:int five`,
+		`\set $n:date 1999`,
+		`\timing`,
+		`\timing off`,
+		`\timing maybe`,
+		`\output table`,
+		`\output xml`,
+		`\output template {{.name}} ({{.uid}})`,
+		`\page 20`,
+		`\page zero`,
+		`\nope`,
+	)
+	want := "$name = The  Matrix\n" +
+		"$year:int = 1999\n" +
+		"$name = The  Matrix\n" +
+		"usage: \\set $name[:type] value\n" +
+		"error: $n: want int, got \"five\"\n" +
+		"usage: \\set $name[:type] value\n" +
+		"Timing is on.\n" +
+		"Timing is off.\n" +
+		"usage: \\timing [on|off]\n" +
+		"Output format is table.\n" +
+		"unknown format \"xml\"; use one of json, ndjson, table, csv, tsv, yaml, template\n" +
+		"Output format is template.\n" +
+		"usage: \\page N|off\n" +
+		"unknown command \\nope; type \\? for help\n"
+	if out != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out, want)
+	}
+	if s.tmpl != "{{.name}} ({{.uid}})" {
+		t.Errorf("tmpl = %q", s.tmpl)
+	}
+	if s.page != 20 {
+		t.Errorf("page = %d, want 20", s.page)
+	}
+}
+
+func TestShellLoop(t *testing.T) {
+	// \c discards the partial query; the unbound variable shows the query
+	// that did run was joined across lines and ended at its closing brace.
+	out := runShell(&shell{},
+		"{ q(func: has(name)) {",
+		`\c`,
+		"",
+		"{ q(func: eq(name, $a))",
+		"{ uid } }",
+		`\q`,
+		"{ never run }",
+	)
+	if want := "error: unbound variable $a (use \\set $a value)\n"; out != want {
+		t.Errorf("output %q, want %q", out, want)
+	}
+
+	// A query still open at end of input is run.
+	out = runShell(&shell{}, "{ q(func: eq(name, $b)) {")
+	if want := "error: unbound variable $b (use \\set $b value)\n"; out != want {
+		t.Errorf("output %q, want %q", out, want)
+	}
+}
+
+var testSchema = movies.Schema{
+	Predicates: []movies.PredicateSchema{
+		{Predicate: "genre", Type: "uid", List: true, Reverse: true},
+		{Predicate: "name", Type: "string", Index: true, Tokenizer: []string{"hash", "term"}},
+		{Predicate: "tagline", Type: "string"},
+	},
+	Types: []movies.TypeSchema{
+		{Name: "Film", Fields: []movies.TypeField{{Name: "name"}, {Name: "genre"}}},
+		{Name: "Genre", Fields: []movies.TypeField{{Name: "name"}}},
+	},
+}
+
+func TestShellSchemaText(t *testing.T) {
+	s := &shell{schema: testSchema}
+	want := "name: string @index(hash, term) .\n" +
+		"genre: [uid] @reverse .\n" +
+		"\n" +
+		"type Film {\n  name\n  genre\n}\n"
+	if got := s.schemaText("Film"); got != want {
+		t.Errorf("schemaText(Film):\n%s\nwant:\n%s", got, want)
+	}
+	if got := s.schemaText("Nope"); got != "no type named Nope\n" {
+		t.Errorf("schemaText(Nope) = %q", got)
+	}
+	if got := s.schemaText(""); got != testSchema.String() {
+		t.Errorf("schemaText() = %q", got)
+	}
+}
+
+func TestCompleter(t *testing.T) {
+	c := &completer{shell: &shell{schema: testSchema, vars: map[string]string{"$name": "x", "$year": "1"}}}
+	tests := []struct {
+		line     string
+		pos      int
+		wantLine string
+		wantPos  int
+	}{
+		{"{ q(func: has(tag", 17, "{ q(func: has(tagline", 21},
+		{"{ q(func: has(na)) }", 16, "{ q(func: has(name)) }", 18},
+		{"{ q(func: has(~g", 16, "{ q(func: has(~genre", 20},
+		{"{ q(func: eq(name, $y", 21, "{ q(func: eq(name, $year", 24},
+		{"Fi", 2, "Film", 4},
+		{`\sc`, 3, `\schema`, 7},
+		{`{ \sc`, 5, `{ \sc`, 5},
+		{"zzz", 3, "zzz", 3},
+		{"{ ", 2, "{ ", 2},
+	}
+	for _, tt := range tests {
+		c.cycle = nil
+		line, pos, ok := c.complete(tt.line, tt.pos, '\t')
+		if !ok || line != tt.wantLine || pos != tt.wantPos {
+			t.Errorf("complete(%q, %d) = %q, %d, %v; want %q, %d", tt.line, tt.pos, line, pos, ok, tt.wantLine, tt.wantPos)
+		}
+	}
+
+	// An ambiguous word cycles through its candidates on repeated tabs.
+	c.cycle = nil
+	line, pos, _ := c.complete("{ q(func: a", 11, '\t')
+	var seen []string
+	for range 3 {
+		seen = append(seen, line[len("{ q(func: "):pos])
+		line, pos, _ = c.complete(line, pos, '\t')
+	}
+	if got := strings.Join(seen, " "); got != "after: allofterms alloftext" {
+		t.Errorf("cycled through %q", got)
+	}
+	if _, _, ok := c.complete("x", 1, 'a'); ok {
+		t.Error("keys other than tab are not handled")
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  string
+	}{
+		{[]string{"name"}, "name"},
+		{[]string{"allofterms", "alloftext"}, "allofte"},
+		{[]string{"eq", "expand"}, "e"},
+		{[]string{"uid", "val"}, ""},
+	}
+	for _, tt := range tests {
+		if got := commonPrefix(tt.words); got != tt.want {
+			t.Errorf("commonPrefix(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h, err := openHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []string{"first", "  ", "second", "second", "third"} {
+		h.Add(e)
+	}
+	if h.Len() != 3 || h.At(0) != "third" || h.At(2) != "first" {
+		t.Errorf("entries %q, want first, second, third", h.entries)
+	}
+	h.Close()
+
+	h, err = openHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if h.Len() != 3 || h.At(0) != "third" {
+		t.Errorf("reopened entries %q, want first, second, third", h.entries)
+	}
+
+	lines := make([]string, maxHistory+5)
+	for i := range lines {
+		lines[i] = "q" + string(rune('a'+i%26))
+	}
+	os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600)
+	h2, err := openHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h2.Close()
+	if h2.Len() != maxHistory {
+		t.Errorf("Len = %d, want history capped at %d", h2.Len(), maxHistory)
+	}
+}
@@ -0,0 +1,541 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"golang.org/x/term"
+)
+
+// showFlags are the display options of the show commands.
+type showFlags struct {
+	Width int    `help:"Wrap text at this many columns (default: the terminal width, or 80)."`
+	Color string `help:"Colorize output: auto, always or never. auto colors a terminal unless NO_COLOR is set." enum:"auto,always,never" default:"auto" env:"MOVIES_COLOR"`
+}
+
+// FilmShowCmd renders a Film as a readable card.
+type FilmShowCmd struct {
+	UIDOrName string        `arg:"" required:"" help:"The UID or exact name of the Film."`
+	Cast      int           `help:"Number of cast members to list." default:"10"`
+	Timeout   time.Duration `help:"Query timeout." default:"30s"`
+	showFlags
+}
+
+func (c *FilmShowCmd) Run(client *movies.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	var data struct {
+		Films []struct {
+			Name      string         `json:"name"`
+			Released  time.Time      `json:"initial_release_date"`
+			Tagline   string         `json:"tagline"`
+			Directors []showNode     `json:"directors"`
+			Genres    []showNode     `json:"genre"`
+			Countries []showNode     `json:"country"`
+			Ratings   []showNode     `json:"rating"`
+			Rated     []showNode     `json:"rated"`
+			CastSize  int            `json:"castSize"`
+			Cast      []showRoleNode `json:"starring"`
+		} `json:"film"`
+	}
+	err := showQuery(ctx, client, "Film", c.UIDOrName, func(edge func(pred, clause string) string) string {
+		return `query film($uid: string) {
+	film(func: uid($uid)) @filter(type(Film)) {
+		uid dgraph.type name initial_release_date tagline
+		` + edge("director.film", `directors: ~director.film (orderasc: name) { name }`) + `
+		` + edge("genre", `genre (orderasc: name) { name }`) + `
+		` + edge("country", `country (orderasc: name) { name }`) + `
+		` + edge("rating", `rating (orderasc: name) { name }`) + `
+		` + edge("rated", `rated (orderasc: name) { name }`) + `
+		` + edge("starring", `castSize: count(starring)
+		starring (first: `+fmt.Sprint(max(c.Cast, 0))+`) {
+			performance.character_note
+			`+edge("actor.film", `actors: ~actor.film { name }`)+`
+		}`) + `
+	}
+}`
+	}, &data)
+	if err != nil {
+		return err
+	}
+	f := data.Films[0]
+	cd := c.card()
+	cd.title(f.Name, year(f.Released))
+	if f.Tagline != "" {
+		cd.para(cd.dim(strings.TrimSpace(f.Tagline)))
+	}
+	cd.blank()
+	cd.field("Directed by", names(f.Directors))
+	cd.field("Genres", names(f.Genres))
+	cd.field("Countries", names(f.Countries))
+	cd.field("Ratings", names(f.Ratings))
+	cd.field("Rated", names(f.Rated))
+	if f.CastSize > 0 && c.Cast > 0 {
+		cd.heading("Cast")
+		rows := make([][]string, len(f.Cast))
+		for i, p := range f.Cast {
+			rows[i] = []string{strings.Join(names(p.Actors), ", "), p.Character}
+		}
+		cd.table(rows)
+		if more := f.CastSize - len(f.Cast); more > 0 {
+			cd.table([][]string{{cd.dim(fmt.Sprintf("and %d more", more))}})
+		}
+	}
+	return cd.flush()
+}
+
+// DirectorShowCmd renders a Director and their filmography.
+type DirectorShowCmd struct {
+	UIDOrName string        `arg:"" required:"" help:"The UID or exact name of the Director."`
+	Timeout   time.Duration `help:"Query timeout." default:"30s"`
+	showFlags
+}
+
+func (c *DirectorShowCmd) Run(client *movies.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	var data struct {
+		Directors []struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+			Films []struct {
+				Name     string     `json:"name"`
+				Released time.Time  `json:"initial_release_date"`
+				Genres   []showNode `json:"genre"`
+			} `json:"director.film"`
+		} `json:"director"`
+	}
+	err := showQuery(ctx, client, "Director", c.UIDOrName, func(edge func(pred, clause string) string) string {
+		return `query director($uid: string) {
+	director(func: uid($uid)) @filter(type(Director)) {
+		uid dgraph.type name
+		` + edge("director.film", `count: count(director.film)
+		director.film (orderasc: initial_release_date) {
+			name initial_release_date
+			`+edge("genre", `genre (orderasc: name) { name }`)+`
+		}`) + `
+	}
+}`
+	}, &data)
+	if err != nil {
+		return err
+	}
+	d := data.Directors[0]
+	cd := c.card()
+	cd.title(d.Name, plural(d.Count, "film"))
+	if len(d.Films) > 0 {
+		cd.heading("Filmography")
+		rows := make([][]string, len(d.Films))
+		for i, f := range d.Films {
+			rows[i] = []string{year(f.Released), f.Name, cd.dim(strings.Join(names(f.Genres), ", "))}
+		}
+		cd.table(rows)
+	}
+	return cd.flush()
+}
+
+// ActorShowCmd renders an Actor and their roles.
+type ActorShowCmd struct {
+	UIDOrName string        `arg:"" required:"" help:"The UID or exact name of the Actor."`
+	Timeout   time.Duration `help:"Query timeout." default:"30s"`
+	showFlags
+}
+
+func (c *ActorShowCmd) Run(client *movies.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	var data struct {
+		Actors []struct {
+			Name  string         `json:"name"`
+			Roles []showRoleNode `json:"actor.film"`
+		} `json:"actor"`
+	}
+	err := showQuery(ctx, client, "Actor", c.UIDOrName, func(edge func(pred, clause string) string) string {
+		return `query actor($uid: string) {
+	actor(func: uid($uid)) @filter(type(Actor)) {
+		uid dgraph.type name
+		` + edge("actor.film", `actor.film {
+			performance.character_note
+			`+edge("starring", `films: ~starring { name initial_release_date }`)+`
+		}`) + `
+	}
+}`
+	}, &data)
+	if err != nil {
+		return err
+	}
+	a := data.Actors[0]
+	type role struct {
+		released  time.Time
+		film      string
+		character string
+	}
+	var roles []role
+	for _, p := range a.Roles {
+		for _, f := range p.Films {
+			roles = append(roles, role{f.Released, f.Name, p.Character})
+		}
+	}
+	slices.SortStableFunc(roles, func(a, b role) int { return a.released.Compare(b.released) })
+	cd := c.card()
+	cd.title(a.Name, plural(len(roles), "role"))
+	if len(roles) > 0 {
+		cd.heading("Roles")
+		rows := make([][]string, len(roles))
+		for i, r := range roles {
+			rows[i] = []string{year(r.released), r.film, ""}
+			if r.character != "" {
+				rows[i][2] = cd.dim("as " + r.character)
+			}
+		}
+		cd.table(rows)
+	}
+	return cd.flush()
+}
+
+// GenreShowCmd renders a Genre with its film count and recent films.
+type GenreShowCmd struct {
+	UIDOrName string        `arg:"" required:"" help:"The UID or exact name of the Genre."`
+	Sample    int           `help:"Number of films to list, most recent first." default:"10"`
+	Timeout   time.Duration `help:"Query timeout." default:"30s"`
+	showFlags
+}
+
+func (c *GenreShowCmd) Run(client *movies.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	var data struct {
+		Genres []struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+			Films []struct {
+				Name     string    `json:"name"`
+				Released time.Time `json:"initial_release_date"`
+			} `json:"films"`
+		} `json:"genre"`
+	}
+	err := showQuery(ctx, client, "Genre", c.UIDOrName, func(edge func(pred, clause string) string) string {
+		return `query genre($uid: string) {
+	genre(func: uid($uid)) @filter(type(Genre)) {
+		uid dgraph.type name
+		` + edge("genre", `count: count(~genre)
+		films: ~genre (orderdesc: initial_release_date, first: `+fmt.Sprint(max(c.Sample, 0))+`) {
+			name initial_release_date
+		}`) + `
+	}
+}`
+	}, &data)
+	if err != nil {
+		return err
+	}
+	g := data.Genres[0]
+	cd := c.card()
+	cd.title(g.Name, plural(g.Count, "film"))
+	if len(g.Films) > 0 && c.Sample > 0 {
+		cd.heading("Recent films")
+		rows := make([][]string, len(g.Films))
+		for i, f := range g.Films {
+			rows[i] = []string{year(f.Released), f.Name}
+		}
+		cd.table(rows)
+	}
+	return cd.flush()
+}
+
+type showNode struct {
+	Name string `json:"name"`
+}
+
+// showRoleNode is a performance seen from either end: with the actors
+// playing it or the films it is in.
+type showRoleNode struct {
+	Character string     `json:"performance.character_note"`
+	Actors    []showNode `json:"actors"`
+	Films     []struct {
+		Name     string    `json:"name"`
+		Released time.Time `json:"initial_release_date"`
+	} `json:"films"`
+}
+
+// showQuery resolves ref to a node of typeName and runs the query build
+// returns for it, decoding the response into v. build wraps each edge
+// clause in edge, which drops clauses on predicates the database has never
+// seen, since embedded Dgraph panics on those. The query's root block must
+// hold exactly the one node; an empty block means ref is not a typeName.
+func showQuery(ctx context.Context, client *movies.Client, typeName, ref string, build func(edge func(pred, clause string) string) string, v any) error {
+	uids, err := resolveRefs(ctx, client, typeName, true, []string{ref})
+	if err != nil {
+		return err
+	}
+	fresh := showSchema == nil
+	if fresh {
+		if err := loadShowSchema(ctx, client); err != nil {
+			return err
+		}
+	}
+	missing := false
+	edge := func(pred, clause string) string {
+		if _, ok := showSchema.Predicate(pred); ok {
+			return clause
+		}
+		missing = true
+		return ""
+	}
+	query := build(edge)
+	if missing && !fresh {
+		// The predicate may have been added since the schema was fetched.
+		if err := loadShowSchema(ctx, client); err != nil {
+			return err
+		}
+		query = build(edge)
+	}
+	resp, err := client.QueryRaw(ctx, query, map[string]string{"$uid": uids[0]})
+	if err != nil {
+		return err
+	}
+	var blocks map[string][]json.RawMessage
+	if err := json.Unmarshal(resp, &blocks); err != nil {
+		return fmt.Errorf("parsing %s: %w", typeName, err)
+	}
+	for _, nodes := range blocks {
+		if len(nodes) == 0 {
+			return fmt.Errorf("no %s with UID %s", typeName, uids[0])
+		}
+	}
+	if err := json.Unmarshal(resp, v); err != nil {
+		return fmt.Errorf("parsing %s: %w", typeName, err)
+	}
+	if CLI.Remember {
+		if err := rememberNodes(json.RawMessage(resp)); err != nil {
+			fmt.Fprintf(os.Stderr, "completion cache: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// showSchema is the live schema as last fetched by showQuery. Predicates
+// are only ever added, so it is fetched once per process, and again only
+// when a show needs one it lacks.
+var showSchema *movies.Schema
+
+func loadShowSchema(ctx context.Context, client *movies.Client) error {
+	schema, err := client.Schema(ctx)
+	if err != nil {
+		return err
+	}
+	showSchema = &schema
+	return nil
+}
+
+func names(nodes []showNode) []string {
+	out := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		if n.Name != "" {
+			out = append(out, n.Name)
+		}
+	}
+	return out
+}
+
+func year(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return fmt.Sprint(t.Year())
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// card accumulates a show view. Text is wrapped to width, and styled with
+// ANSI escapes only when color is on.
+type card struct {
+	sb    strings.Builder
+	width int
+	color bool
+}
+
+// labelWidth is the column in which field values start.
+const labelWidth = 13
+
+func (f showFlags) card() *card {
+	fd := int(os.Stdout.Fd())
+	tty := term.IsTerminal(fd)
+	c := &card{
+		width: f.Width,
+		color: f.Color == "always" || f.Color == "auto" && tty && os.Getenv("NO_COLOR") == "",
+	}
+	if c.width <= 0 {
+		c.width = 80
+		if w, _, err := term.GetSize(fd); tty && err == nil && w > 0 {
+			c.width = w
+		}
+	}
+	c.width = max(c.width, labelWidth+20)
+	return c
+}
+
+func (c *card) style(code, s string) string {
+	if !c.color || s == "" {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+func (c *card) bold(s string) string { return c.style("1", s) }
+func (c *card) dim(s string) string  { return c.style("2", s) }
+
+func (c *card) title(name, sub string) {
+	if sub != "" {
+		sub = c.dim("(" + sub + ")")
+	}
+	c.para(strings.TrimSpace(c.bold(name) + " " + sub))
+}
+
+func (c *card) heading(s string) {
+	c.blank()
+	c.para(c.style("1;4", s))
+}
+
+func (c *card) blank() {
+	if c.sb.Len() > 0 && !strings.HasSuffix(c.sb.String(), "\n\n") {
+		c.sb.WriteByte('\n')
+	}
+}
+
+// para writes text wrapped to the card width.
+func (c *card) para(text string) {
+	c.sb.WriteString(strings.Join(wrap(text, c.width), "\n") + "\n")
+}
+
+// field writes a label and its comma-separated values, wrapped with the
+// values aligned. A field without values is left out.
+func (c *card) field(label string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	lines := wrap(strings.Join(values, ", "), c.width-labelWidth)
+	pad := strings.Repeat(" ", labelWidth)
+	for i, line := range lines {
+		if i == 0 {
+			c.sb.WriteString(c.dim(label) + strings.Repeat(" ", max(labelWidth-visibleLen(label), 1)))
+		} else {
+			c.sb.WriteString(pad)
+		}
+		c.sb.WriteString(line + "\n")
+	}
+}
+
+// table writes indented rows with every column but the last padded to its
+// widest cell, truncating lines to the card width.
+func (c *card) table(rows [][]string) {
+	var widths []int
+	for _, r := range rows {
+		for i, cell := range r {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], visibleLen(cell))
+		}
+	}
+	for _, r := range rows {
+		line := " "
+		for i, cell := range r {
+			line += " "
+			if i < len(r)-1 {
+				cell += strings.Repeat(" ", widths[i]-visibleLen(cell)+1)
+			}
+			line += cell
+		}
+		c.sb.WriteString(strings.TrimRight(truncate(line, c.width), " ") + "\n")
+	}
+}
+
+func (c *card) flush() error {
+	_, err := os.Stdout.WriteString(c.sb.String())
+	return err
+}
+
+// wrap breaks text into lines of at most width visible characters at
+// spaces. Words longer than width get a line of their own.
+func wrap(text string, width int) []string {
+	var lines []string
+	line, n := "", 0
+	for _, word := range strings.Fields(text) {
+		w := visibleLen(word)
+		if n > 0 && n+1+w > width {
+			lines = append(lines, line)
+			line, n = "", 0
+		}
+		if n > 0 {
+			line += " "
+			n++
+		}
+		line += word
+		n += w
+	}
+	return append(lines, line)
+}
+
+// visibleLen counts the characters of s that take up a column, skipping
+// ANSI escape sequences.
+func visibleLen(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			if j := strings.IndexByte(s[i:], 'm'); j >= 0 {
+				i += j + 1
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		n++
+	}
+	return n
+}
+
+// truncate shortens s to width visible characters, ending it with an
+// ellipsis and resetting any style it cuts through.
+func truncate(s string, width int) string {
+	if visibleLen(s) <= width {
+		return s
+	}
+	var sb strings.Builder
+	n := 0
+	styled := false
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			if j := strings.IndexByte(s[i:], 'm'); j >= 0 {
+				esc := s[i : i+j+1]
+				styled = esc != "\x1b[0m"
+				sb.WriteString(esc)
+				i += j + 1
+				continue
+			}
+		}
+		if n == width-1 {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		sb.WriteRune(r)
+		i += size
+		n++
+	}
+	sb.WriteString("…")
+	if styled {
+		sb.WriteString("\x1b[0m")
+	}
+	return sb.String()
+}
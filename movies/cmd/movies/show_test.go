@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{"", 10, []string{""}},
+		{"one two three", 13, []string{"one two three"}},
+		{"one two three", 12, []string{"one two", "three"}},
+		{"one   two\nthree", 7, []string{"one two", "three"}},
+		{"a extraordinarily long word", 8, []string{"a", "extraordinarily", "long", "word"}},
+		{"\x1b[1mbold\x1b[0m text", 9, []string{"\x1b[1mbold\x1b[0m text"}},
+		{"héllo wörld", 5, []string{"héllo", "wörld"}},
+	}
+	for _, tt := range tests {
+		if got := wrap(tt.text, tt.width); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("wrap(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestVisibleLen(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"plain", 5},
+		{"\x1b[1;4mtitle\x1b[0m", 5},
+		{"naïve", 5},
+		{"\x1b[2m(1995)\x1b[0m …", 8},
+	}
+	for _, tt := range tests {
+		if got := visibleLen(tt.s); got != tt.want {
+			t.Errorf("visibleLen(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"too long", 5, "too …"},
+		{"\x1b[1mstyled text\x1b[0m", 6, "\x1b[1mstyle…\x1b[0m"},
+		{"\x1b[1mab\x1b[0m cdefgh", 6, "\x1b[1mab\x1b[0m cd…"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.width); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestCard(t *testing.T) {
+	c := &card{width: 30}
+	c.title("Heat", "1995")
+	c.field("Genres", []string{"Crime", "Drama", "Thriller", "Action"})
+	c.field("Empty", nil)
+	c.heading("Cast")
+	c.table([][]string{
+		{"Al Pacino", "Vincent Hanna"},
+		{"Robert De Niro", "Neil McCauley, a professional thief"},
+	})
+	want := "Heat (1995)\n" +
+		"Genres       Crime, Drama,\n" +
+		"             Thriller, Action\n" +
+		"\n" +
+		"Cast\n" +
+		"  Al Pacino       Vincent Han…\n" +
+		"  Robert De Niro  Neil McCaul…\n"
+	if got := c.sb.String(); got != want {
+		t.Errorf("without color:\n%s\nwant:\n%s", got, want)
+	}
+
+	c = &card{width: 30, color: true}
+	c.title("Heat", "")
+	c.field("Genres", []string{"Crime"})
+	want = "\x1b[1mHeat\x1b[0m\n" +
+		"\x1b[2mGenres\x1b[0m       Crime\n"
+	if got := c.sb.String(); got != want {
+		t.Errorf("with color:\n%q\nwant:\n%q", got, want)
+	}
+}
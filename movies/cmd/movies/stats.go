@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+// StatsCmd reports what is loaded: nodes per type, edges per predicate,
+// edge fan-out and nodes missing required predicates.
+type StatsCmd struct {
+	Section string        `help:"Report only one section: types, predicates, fanout or missing." enum:"all,types,predicates,fanout,missing" default:"all"`
+	Timeout time.Duration `help:"Query timeout." default:"60s"`
+}
+
+func (c *StatsCmd) Run(client *movies.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	stats, err := client.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	sections := []struct {
+		name string
+		rows any
+	}{
+		{"types", stats.Types},
+		{"predicates", stats.Predicates},
+		{"fanout", stats.FanOut},
+		{"missing", stats.Missing},
+	}
+	if c.Section != "all" {
+		for _, s := range sections {
+			if s.name == c.Section {
+				return printResult(s.rows)
+			}
+		}
+	}
+	switch CLI.Output {
+	case "json", "yaml":
+		return printResult(stats)
+	}
+	// The row formats show one list at a time, so each section is printed
+	// in turn under a heading.
+	for i, s := range sections {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("# %s\n", s.name)
+		if err := render(os.Stdout, CLI.Output, CLI.Template, s.rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
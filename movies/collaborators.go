@@ -0,0 +1,149 @@
+package movies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Collaborator is an actor ranked by the number of films they share with
+// another actor or director.
+type Collaborator struct {
+	Actor Actor  `json:"actor"`
+	Count int    `json:"count"`
+	Films []Film `json:"films"`
+}
+
+// CoStars returns the actors who appear in films alongside the actor with the
+// given UID, ranked by the number of shared films.
+func (c *ActorClient) CoStars(ctx context.Context, uid string, opts ...PageOption) ([]Collaborator, error) {
+	const query = `query costars($uid: string) {
+	root(func: uid($uid)) {
+		actor.film {
+			films: ~starring {
+				uid name initial_release_date
+				starring { actors: ~actor.film { uid name } }
+			}
+		}
+	}
+}`
+	resp, err := c.conn.QueryRaw(ctx, query, map[string]string{"$uid": uid})
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Root []struct {
+			Performances []struct {
+				Films []collabFilm `json:"films"`
+			} `json:"actor.film"`
+		} `json:"root"`
+	}
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return nil, fmt.Errorf("parsing costars response: %w", err)
+	}
+	var films []collabFilm
+	for _, r := range data.Root {
+		for _, p := range r.Performances {
+			films = append(films, p.Films...)
+		}
+	}
+	return rankCollaborators(films, uid, opts), nil
+}
+
+// FrequentCollaborators returns the actors who appear in films made by the
+// director with the given UID, ranked by the number of those films.
+func (c *DirectorClient) FrequentCollaborators(ctx context.Context, uid string, opts ...PageOption) ([]Collaborator, error) {
+	const query = `query collaborators($uid: string) {
+	root(func: uid($uid)) {
+		films: director.film {
+			uid name initial_release_date
+			starring { actors: ~actor.film { uid name } }
+		}
+	}
+}`
+	resp, err := c.conn.QueryRaw(ctx, query, map[string]string{"$uid": uid})
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Root []struct {
+			Films []collabFilm `json:"films"`
+		} `json:"root"`
+	}
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return nil, fmt.Errorf("parsing collaborators response: %w", err)
+	}
+	var films []collabFilm
+	for _, r := range data.Root {
+		films = append(films, r.Films...)
+	}
+	return rankCollaborators(films, uid, opts), nil
+}
+
+type collabFilm struct {
+	UID                string    `json:"uid"`
+	Name               string    `json:"name"`
+	InitialReleaseDate time.Time `json:"initial_release_date"`
+	Starring           []struct {
+		Actors []struct {
+			UID  string `json:"uid"`
+			Name string `json:"name"`
+		} `json:"actors"`
+	} `json:"starring"`
+}
+
+// rankCollaborators counts the distinct films each actor appears in, skipping
+// the subject itself, and returns the requested page ordered by count.
+func rankCollaborators(films []collabFilm, subject string, opts []PageOption) []Collaborator {
+	byActor := make(map[string]*Collaborator)
+	seen := make(map[[2]string]bool)
+	for _, f := range films {
+		for _, p := range f.Starring {
+			for _, a := range p.Actors {
+				if a.UID == subject || seen[[2]string{a.UID, f.UID}] {
+					continue
+				}
+				seen[[2]string{a.UID, f.UID}] = true
+				col, ok := byActor[a.UID]
+				if !ok {
+					col = &Collaborator{Actor: Actor{UID: a.UID, Name: a.Name}}
+					byActor[a.UID] = col
+				}
+				col.Count++
+				col.Films = append(col.Films, Film{UID: f.UID, Name: f.Name, InitialReleaseDate: f.InitialReleaseDate})
+			}
+		}
+	}
+
+	ranked := make([]Collaborator, 0, len(byActor))
+	for _, col := range byActor {
+		sort.Slice(col.Films, func(i, j int) bool {
+			return col.Films[i].InitialReleaseDate.Before(col.Films[j].InitialReleaseDate)
+		})
+		ranked = append(ranked, *col)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Actor.Name < ranked[j].Actor.Name
+	})
+
+	cfg := pageConfig{first: defaultPageSize}
+	for _, opt := range opts {
+		opt.applyPage(&cfg)
+	}
+	// A negative offset starts at the first, as it does for List and the
+	// query builders.
+	offset := max(cfg.offset, 0)
+	if offset >= len(ranked) {
+		return nil
+	}
+	ranked = ranked[offset:]
+	if cfg.first > 0 && len(ranked) > cfg.first {
+		ranked = ranked[:cfg.first]
+	}
+	return ranked
+}
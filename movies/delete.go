@@ -0,0 +1,148 @@
+package movies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/matthewmcneely/modusgraph"
+)
+
+// DefaultDeleteLimit is the most nodes DeleteWhere deletes unless given
+// DeleteLimit.
+const DefaultDeleteLimit = 100
+
+// errNoFilter is returned by DeleteWhere for an empty filter.
+var errNoFilter = errors.New("DeleteWhere needs a filter")
+
+// DeleteOption configures DeleteWhere.
+type DeleteOption interface {
+	applyDelete(cfg *deleteConfig)
+}
+
+type deleteConfig struct {
+	limit  int
+	dryRun bool
+}
+
+type deleteOptionFunc func(cfg *deleteConfig)
+
+func (f deleteOptionFunc) applyDelete(cfg *deleteConfig) { f(cfg) }
+
+// DeleteLimit makes DeleteWhere refuse, with a *TooManyMatchesError and
+// without deleting anything, when more than n nodes match. Zero removes
+// the limit.
+func DeleteLimit(n int) DeleteOption {
+	return deleteOptionFunc(func(cfg *deleteConfig) { cfg.limit = n })
+}
+
+// DryRun makes DeleteWhere return the UIDs it would delete, and delete
+// nothing.
+func DryRun() DeleteOption {
+	return deleteOptionFunc(func(cfg *deleteConfig) { cfg.dryRun = true })
+}
+
+// TooManyMatchesError is returned by DeleteWhere when more nodes match its
+// filter than its limit.
+type TooManyMatchesError struct {
+	Type    string
+	Matches int
+	Limit   int
+}
+
+func (e *TooManyMatchesError) Error() string {
+	return fmt.Sprintf("%d %s nodes match, more than the limit of %d", e.Matches, e.Type, e.Limit)
+}
+
+// deleteWhere deletes the T nodes matching filter, in one mutation.
+func deleteWhere[T any](ctx context.Context, conn modusgraph.Client, filter string, opts []DeleteOption) ([]string, error) {
+	if strings.TrimSpace(filter) == "" {
+		return nil, errNoFilter
+	}
+	cfg := deleteConfig{limit: DefaultDeleteLimit}
+	for _, opt := range opts {
+		opt.applyDelete(&cfg)
+	}
+	var model T
+	q := conn.Query(ctx, model).Filter(filter).Query("{\n\t\tuid\n\t}")
+	if cfg.limit > 0 {
+		// One more than the limit tells whether it is exceeded.
+		q = q.First(cfg.limit + 1)
+	}
+	var matches []T
+	total, err := q.NodesAndCount(&matches)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.limit > 0 && total > cfg.limit {
+		return nil, &TooManyMatchesError{Type: typeName(model), Matches: total, Limit: cfg.limit}
+	}
+	uids := make([]string, len(matches))
+	for i := range matches {
+		uids[i] = uidOf(&matches[i])
+	}
+	if cfg.dryRun || len(uids) == 0 {
+		return uids, nil
+	}
+	if err := conn.Delete(ctx, uids); err != nil {
+		return nil, err
+	}
+	return uids, nil
+}
+
+// DeleteWhere removes every Actor matching the DQL filter expression, as
+// taken by Query().Filter, and returns the UIDs it removed. An empty filter
+// is refused rather than deleting every Actor, as is matching more than
+// DefaultDeleteLimit nodes unless DeleteLimit says otherwise.
+func (c *ActorClient) DeleteWhere(ctx context.Context, filter string, opts ...DeleteOption) ([]string, error) {
+	return deleteWhere[Actor](ctx, c.conn, filter, opts)
+}
+
+// DeleteWhere removes every ContentRating matching the DQL filter
+// expression; see ActorClient.DeleteWhere.
+func (c *ContentRatingClient) DeleteWhere(ctx context.Context, filter string, opts ...DeleteOption) ([]string, error) {
+	return deleteWhere[ContentRating](ctx, c.conn, filter, opts)
+}
+
+// DeleteWhere removes every Country matching the DQL filter expression; see
+// ActorClient.DeleteWhere.
+func (c *CountryClient) DeleteWhere(ctx context.Context, filter string, opts ...DeleteOption) ([]string, error) {
+	return deleteWhere[Country](ctx, c.conn, filter, opts)
+}
+
+// DeleteWhere removes every Director matching the DQL filter expression;
+// see ActorClient.DeleteWhere.
+func (c *DirectorClient) DeleteWhere(ctx context.Context, filter string, opts ...DeleteOption) ([]string, error) {
+	return deleteWhere[Director](ctx, c.conn, filter, opts)
+}
+
+// DeleteWhere removes every Film matching the DQL filter expression; see
+// ActorClient.DeleteWhere.
+func (c *FilmClient) DeleteWhere(ctx context.Context, filter string, opts ...DeleteOption) ([]string, error) {
+	return deleteWhere[Film](ctx, c.conn, filter, opts)
+}
+
+// DeleteWhere removes every Genre matching the DQL filter expression; see
+// ActorClient.DeleteWhere.
+func (c *GenreClient) DeleteWhere(ctx context.Context, filter string, opts ...DeleteOption) ([]string, error) {
+	return deleteWhere[Genre](ctx, c.conn, filter, opts)
+}
+
+// DeleteWhere removes every Location matching the DQL filter expression;
+// see ActorClient.DeleteWhere.
+func (c *LocationClient) DeleteWhere(ctx context.Context, filter string, opts ...DeleteOption) ([]string, error) {
+	return deleteWhere[Location](ctx, c.conn, filter, opts)
+}
+
+// DeleteWhere removes every Performance matching the DQL filter expression;
+// see ActorClient.DeleteWhere.
+func (c *PerformanceClient) DeleteWhere(ctx context.Context, filter string, opts ...DeleteOption) ([]string, error) {
+	return deleteWhere[Performance](ctx, c.conn, filter, opts)
+}
+
+// DeleteWhere removes every Rating matching the DQL filter expression; see
+// ActorClient.DeleteWhere.
+func (c *RatingClient) DeleteWhere(ctx context.Context, filter string, opts ...DeleteOption) ([]string, error) {
+	return deleteWhere[Rating](ctx, c.conn, filter, opts)
+}
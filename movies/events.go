@@ -0,0 +1,166 @@
+package movies
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Op is the kind of change an Event reports.
+type Op int
+
+const (
+	OpAdd Op = iota + 1
+	OpUpdate
+	OpDelete
+)
+
+var opNames = map[Op]string{OpAdd: "add", OpUpdate: "update", OpDelete: "delete"}
+
+func (o Op) String() string {
+	if s, ok := opNames[o]; ok {
+		return s
+	}
+	return fmt.Sprintf("Op(%d)", int(o))
+}
+
+// MarshalText encodes the op as its name, e.g. "update".
+func (o Op) MarshalText() ([]byte, error) {
+	if _, ok := opNames[o]; !ok {
+		return nil, fmt.Errorf("unknown op %d", int(o))
+	}
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText decodes an op from its name.
+func (o *Op) UnmarshalText(b []byte) error {
+	for op, name := range opNames {
+		if name == string(b) {
+			*o = op
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown op %q", b)
+}
+
+// Event reports a change a client committed to one node. Before and After
+// are pointers to the entity struct, e.g. *Film: Before is the node as it
+// was, for updates and deletes, and After as it is, for adds and updates.
+// Before is nil when the node could not be read before the change.
+type Event struct {
+	Op     Op     `json:"op"`
+	Type   string `json:"type"`
+	UID    string `json:"uid"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// Subscribe registers handler to be called after every change the client
+// commits through the sub-clients' Add, Update and Delete, and so also
+// DeleteWhere. It returns a function that unregisters handler.
+//
+// Handlers are called one at a time, in the order the changes were
+// committed, normally by the goroutine that made the change before its
+// method returns. When another goroutine is already calling handlers, that
+// goroutine calls them for the change instead, so a handler may itself
+// change the graph. A handler that panics leaves its remaining events to be
+// delivered with the next change.
+//
+// Reading the snapshots costs a Get before and after each update, and
+// before each delete, while any handler is registered for the type; while
+// any handler is registered, deletes also look up the types of the nodes.
+func (c *Client) Subscribe(handler func(ctx context.Context, e Event)) (unsubscribe func()) {
+	return c.ext().hooks.subscribe("", handler)
+}
+
+// hooks holds a client's handlers and delivers events to them.
+type hooks struct {
+	mu         sync.Mutex
+	subs       []*subscription
+	queue      []delivery
+	delivering bool
+}
+
+// subscription is a registered handler, for events of typ or, when typ is
+// empty, every event.
+type subscription struct {
+	typ string
+	fn  func(context.Context, Event)
+}
+
+type delivery struct {
+	ctx context.Context
+	e   Event
+}
+
+func (h *hooks) subscribe(typ string, fn func(context.Context, Event)) func() {
+	s := &subscription{typ: typ, fn: fn}
+	h.mu.Lock()
+	// Copy on write, as delivery reads subs without the lock.
+	h.subs = append(slices.Clip(h.subs), s)
+	h.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			h.mu.Lock()
+			h.subs = slices.DeleteFunc(slices.Clone(h.subs), func(t *subscription) bool { return t == s })
+			h.mu.Unlock()
+		})
+	}
+}
+
+// active reports whether any handler wants events of typ, or of any type
+// when typ is empty, so that snapshots are read only when they are needed.
+func (h *hooks) active(typ string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.ContainsFunc(h.subs, func(s *subscription) bool { return typ == "" || s.typ == "" || s.typ == typ })
+}
+
+// publish queues e and, unless another goroutine is delivering already,
+// delivers the queue.
+func (h *hooks) publish(ctx context.Context, e Event) {
+	h.mu.Lock()
+	h.queue = append(h.queue, delivery{ctx, e})
+	if h.delivering {
+		h.mu.Unlock()
+		return
+	}
+	h.delivering = true
+	h.mu.Unlock()
+
+	done := false
+	defer func() {
+		// Let the next change deliver, should a handler panic.
+		if !done {
+			h.mu.Lock()
+			h.delivering = false
+			h.mu.Unlock()
+		}
+	}()
+	for {
+		h.mu.Lock()
+		if len(h.queue) == 0 {
+			h.delivering, done = false, true
+			h.mu.Unlock()
+			return
+		}
+		d := h.queue[0]
+		h.queue[0] = delivery{}
+		h.queue = h.queue[1:]
+		subs := h.subs
+		h.mu.Unlock()
+		for _, s := range subs {
+			if s.typ == "" || s.typ == d.e.Type {
+				s.fn(d.ctx, d.e)
+			}
+		}
+	}
+}
+
+// snapshotOf returns the entity an Event's Before or After holds, or nil.
+func snapshotOf[T any](v any) *T {
+	t, _ := v.(*T)
+	return t
+}
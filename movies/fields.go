@@ -0,0 +1,172 @@
+package movies
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Field is a predicate of the entity type T. It is implemented by
+// SortField, ScalarField and EdgeField, whose values come from functions
+// such as FilmFieldName and from ParseField.
+type Field[T any] interface {
+	// Predicate returns the Dgraph predicate name.
+	Predicate() string
+	selectClause() string
+	field(T)
+}
+
+// SortField is a scalar predicate of T with an index Dgraph can order by.
+// Only these fields are accepted by the query builders' SortAsc and
+// SortDesc.
+type SortField[T any] struct{ predicate string }
+
+// ScalarField is a scalar predicate of T that cannot be ordered by.
+type ScalarField[T any] struct{ predicate string }
+
+// EdgeField is a predicate of T linking to other nodes.
+type EdgeField[T any] struct{ predicate string }
+
+// Predicate returns the Dgraph predicate name.
+func (f SortField[T]) Predicate() string { return f.predicate }
+
+func (f SortField[T]) selectClause() string { return f.predicate }
+
+func (SortField[T]) field(T) {}
+
+// Predicate returns the Dgraph predicate name.
+func (f ScalarField[T]) Predicate() string { return f.predicate }
+
+func (f ScalarField[T]) selectClause() string { return f.predicate }
+
+func (ScalarField[T]) field(T) {}
+
+// Predicate returns the Dgraph predicate name.
+func (f EdgeField[T]) Predicate() string { return f.predicate }
+
+func (f EdgeField[T]) selectClause() string {
+	return f.predicate + " { uid dgraph.type expand(_all_) }"
+}
+
+func (EdgeField[T]) field(T) {}
+
+// ParseField returns the field of T with the given JSON field name or
+// Dgraph predicate name, classified from the struct's tags: a field
+// holding other entities is an EdgeField, and a scalar with a non-geo index
+// is a SortField.
+//
+//	f, err := movies.ParseField[movies.Film]("initialReleaseDate")
+func ParseField[T any](name string) (Field[T], error) {
+	t := reflect.TypeFor[T]()
+	if entityTypes()[t.Name()] != t {
+		return nil, fmt.Errorf("%s is not an entity type", t)
+	}
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Name == "UID" || f.Name == "DType" {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		predicate, index := jsonName, ""
+		for d := range strings.FieldsSeq(f.Tag.Get("dgraph")) {
+			if p, ok := strings.CutPrefix(d, "predicate="); ok {
+				predicate = p
+			} else if i, ok := strings.CutPrefix(d, "index="); ok {
+				index = i
+			}
+		}
+		if name != jsonName && name != predicate {
+			continue
+		}
+		elem := f.Type
+		for elem.Kind() == reflect.Pointer || elem.Kind() == reflect.Slice {
+			elem = elem.Elem()
+		}
+		switch {
+		case entityTypes()[elem.Name()] == elem:
+			return EdgeField[T]{predicate}, nil
+		case index != "" && !strings.Contains(index, "geo"):
+			return SortField[T]{predicate}, nil
+		}
+		return ScalarField[T]{predicate}, nil
+	}
+	return nil, fmt.Errorf("unknown %s field %q", t.Name(), name)
+}
+
+// ActorFieldName returns the name predicate of Actor.
+func ActorFieldName() SortField[Actor] { return SortField[Actor]{"name"} }
+
+// ActorFieldFilms returns the actor.film predicate of Actor.
+func ActorFieldFilms() EdgeField[Actor] { return EdgeField[Actor]{"actor.film"} }
+
+// ContentRatingFieldName returns the name predicate of ContentRating.
+func ContentRatingFieldName() SortField[ContentRating] { return SortField[ContentRating]{"name"} }
+
+// ContentRatingFieldFilms returns the ~rated predicate of ContentRating.
+func ContentRatingFieldFilms() EdgeField[ContentRating] { return EdgeField[ContentRating]{"~rated"} }
+
+// CountryFieldName returns the name predicate of Country.
+func CountryFieldName() SortField[Country] { return SortField[Country]{"name"} }
+
+// CountryFieldFilms returns the ~country predicate of Country.
+func CountryFieldFilms() EdgeField[Country] { return EdgeField[Country]{"~country"} }
+
+// DirectorFieldName returns the name predicate of Director.
+func DirectorFieldName() SortField[Director] { return SortField[Director]{"name"} }
+
+// DirectorFieldFilms returns the director.film predicate of Director.
+func DirectorFieldFilms() EdgeField[Director] { return EdgeField[Director]{"director.film"} }
+
+// FilmFieldName returns the name predicate of Film.
+func FilmFieldName() SortField[Film] { return SortField[Film]{"name"} }
+
+// FilmFieldInitialReleaseDate returns the initial_release_date predicate of
+// Film.
+func FilmFieldInitialReleaseDate() SortField[Film] {
+	return SortField[Film]{"initial_release_date"}
+}
+
+// FilmFieldTagline returns the tagline predicate of Film.
+func FilmFieldTagline() ScalarField[Film] { return ScalarField[Film]{"tagline"} }
+
+// FilmFieldGenres returns the genre predicate of Film.
+func FilmFieldGenres() EdgeField[Film] { return EdgeField[Film]{"genre"} }
+
+// FilmFieldCountries returns the country predicate of Film.
+func FilmFieldCountries() EdgeField[Film] { return EdgeField[Film]{"country"} }
+
+// FilmFieldRatings returns the rating predicate of Film.
+func FilmFieldRatings() EdgeField[Film] { return EdgeField[Film]{"rating"} }
+
+// FilmFieldContentRatings returns the rated predicate of Film.
+func FilmFieldContentRatings() EdgeField[Film] { return EdgeField[Film]{"rated"} }
+
+// FilmFieldStarring returns the starring predicate of Film.
+func FilmFieldStarring() EdgeField[Film] { return EdgeField[Film]{"starring"} }
+
+// GenreFieldName returns the name predicate of Genre.
+func GenreFieldName() SortField[Genre] { return SortField[Genre]{"name"} }
+
+// GenreFieldFilms returns the ~genre predicate of Genre.
+func GenreFieldFilms() EdgeField[Genre] { return EdgeField[Genre]{"~genre"} }
+
+// LocationFieldName returns the name predicate of Location.
+func LocationFieldName() SortField[Location] { return SortField[Location]{"name"} }
+
+// LocationFieldLoc returns the loc predicate of Location. Its geo index
+// cannot order.
+func LocationFieldLoc() ScalarField[Location] { return ScalarField[Location]{"loc"} }
+
+// LocationFieldEmail returns the email predicate of Location.
+func LocationFieldEmail() SortField[Location] { return SortField[Location]{"email"} }
+
+// PerformanceFieldCharacterNote returns the performance.character_note
+// predicate of Performance.
+func PerformanceFieldCharacterNote() ScalarField[Performance] {
+	return ScalarField[Performance]{"performance.character_note"}
+}
+
+// RatingFieldName returns the name predicate of Rating.
+func RatingFieldName() SortField[Rating] { return SortField[Rating]{"name"} }
+
+// RatingFieldFilms returns the ~rating predicate of Rating.
+func RatingFieldFilms() EdgeField[Rating] { return EdgeField[Rating]{"~rating"} }
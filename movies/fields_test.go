@@ -0,0 +1,87 @@
+package movies_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  movies.Field[movies.Film]
+	}{
+		{[]string{"name"}, movies.FilmFieldName()},
+		{[]string{"initialReleaseDate", "initial_release_date"}, movies.FilmFieldInitialReleaseDate()},
+		{[]string{"tagline"}, movies.FilmFieldTagline()},
+		{[]string{"genres", "genre"}, movies.FilmFieldGenres()},
+		{[]string{"contentRatings", "rated"}, movies.FilmFieldContentRatings()},
+		{[]string{"starring"}, movies.FilmFieldStarring()},
+	}
+	for _, tt := range tests {
+		for _, name := range tt.names {
+			if got, err := movies.ParseField[movies.Film](name); err != nil || got != tt.want {
+				t.Errorf("ParseField[Film](%q) = %#v, %v; want %#v", name, got, err, tt.want)
+			}
+		}
+	}
+
+	other := []struct {
+		name string
+		got  func() (any, error)
+		want any
+	}{
+		{"loc", func() (any, error) { return movies.ParseField[movies.Location]("loc") }, movies.LocationFieldLoc()},
+		{"email", func() (any, error) { return movies.ParseField[movies.Location]("email") }, movies.LocationFieldEmail()},
+		{"films", func() (any, error) { return movies.ParseField[movies.Actor]("films") }, movies.ActorFieldFilms()},
+		{"~genre", func() (any, error) { return movies.ParseField[movies.Genre]("~genre") }, movies.GenreFieldFilms()},
+		{"characterNote", func() (any, error) { return movies.ParseField[movies.Performance]("characterNote") }, movies.PerformanceFieldCharacterNote()},
+	}
+	for _, tt := range other {
+		if got, err := tt.got(); err != nil || got != tt.want {
+			t.Errorf("ParseField(%q) = %#v, %v; want %#v", tt.name, got, err, tt.want)
+		}
+	}
+
+	for _, name := range []string{"", "uid", "dgraph.type", "Name", "no_such_field"} {
+		if _, err := movies.ParseField[movies.Film](name); err == nil {
+			t.Errorf("ParseField[Film](%q): expected an error", name)
+		}
+	}
+	if _, err := movies.ParseField[movies.GeoPoint]("type"); err == nil {
+		t.Error("ParseField[GeoPoint]: expected an error for a non-entity type")
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	server := &sentQueries{}
+	c := movies.NewFromClient(&depthConn{server: server})
+	ctx := context.Background()
+
+	tests := []struct {
+		q    *movies.FilmQuery
+		want string
+	}{
+		{c.Film.Query(ctx).SortAsc(movies.FilmFieldName()), ", orderasc: name)"},
+		{c.Film.Query(ctx).SortDesc(movies.FilmFieldInitialReleaseDate()).SortAsc(movies.FilmFieldName()),
+			", orderdesc: initial_release_date, orderasc: name)"},
+		{c.Film.Query(ctx).SortAsc(movies.FilmFieldName()).SortDesc(movies.FilmFieldInitialReleaseDate()),
+			", orderasc: name, orderdesc: initial_release_date)"},
+		{c.Film.Query(ctx).SortAsc(movies.FilmFieldName()).OrderDesc("tagline"), ", orderdesc: tagline)"},
+		{c.Film.Query(ctx).OrderDesc("tagline").SortAsc(movies.FilmFieldName()), ", orderdesc: tagline, orderasc: name)"},
+		{c.Film.Query(ctx).SortAsc(movies.SortField[movies.Film]{}), "first: 50)"},
+	}
+	for i, tt := range tests {
+		if dql := tt.q.ToDQL(); !strings.Contains(dql, tt.want) {
+			t.Errorf("expected %q in:\n%s", tt.want, dql)
+		}
+		if err := tt.q.Exec(&[]movies.Film{}); err != nil {
+			t.Fatalf("Exec: %v", err)
+		}
+		if sent := server.queries[i]; !strings.Contains(sent, tt.want) {
+			t.Errorf("expected Exec to send %q, sent:\n%s", tt.want, sent)
+		}
+	}
+}
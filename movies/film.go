@@ -12,5 +12,5 @@ type Film struct {
 	Countries          []Country       `json:"countries,omitempty" dgraph:"predicate=country reverse"`
 	Ratings            []Rating        `json:"ratings,omitempty" dgraph:"predicate=rating reverse"`
 	ContentRatings     []ContentRating `json:"contentRatings,omitempty" dgraph:"predicate=rated reverse"`
-	Starring           []Performance   `json:"starring,omitempty" dgraph:"count"`
+	Starring           []Performance   `json:"starring,omitempty" dgraph:"reverse count"`
 }
@@ -0,0 +1,216 @@
+package movies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FacetBucket counts the matching films linked to one Genre, Country or
+// ContentRating.
+type FacetBucket struct {
+	UID   string `json:"uid"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// DecadeBucket counts the matching films first released in a decade. Decade
+// is the first year of the decade, e.g. 1990.
+type DecadeBucket struct {
+	Decade int `json:"decade"`
+	Count  int `json:"count"`
+}
+
+// FilmFacets holds the facet buckets computed for a faceted film search.
+type FilmFacets struct {
+	Genres         []FacetBucket  `json:"genres"`
+	Countries      []FacetBucket  `json:"countries"`
+	ContentRatings []FacetBucket  `json:"contentRatings"`
+	Decades        []DecadeBucket `json:"decades"`
+}
+
+// FacetSelection narrows a faceted search to previously returned bucket
+// values. Values within one facet are ORed; facets are ANDed together.
+type FacetSelection struct {
+	Genres         []string // Genre UIDs
+	Countries      []string // Country UIDs
+	ContentRatings []string // ContentRating UIDs
+	Decades        []int    // first year of each decade
+}
+
+// FacetedFilms is a page of search results plus facet counts over every
+// match, not just the page.
+type FacetedFilms struct {
+	Films  []Film     `json:"films"`
+	Total  int        `json:"total"`
+	Facets FilmFacets `json:"facets"`
+}
+
+// SearchFaceted finds Film entities whose Name matches term using fulltext
+// search, narrowed by sel, and returns the requested page together with
+// Genre, Country, ContentRating and release-decade facet counts. Everything
+// is computed in a single DQL request.
+func (c *FilmClient) SearchFaceted(ctx context.Context, term string, sel FacetSelection, opts ...PageOption) (*FacetedFilms, error) {
+	cfg := pageConfig{first: defaultPageSize}
+	for _, opt := range opts {
+		opt.applyPage(&cfg)
+	}
+	query, err := facetedQuery(sel, cfg)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.conn.QueryRaw(ctx, query, map[string]string{"$term": term})
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Films []Film `json:"films"`
+		Total []struct {
+			N int `json:"n"`
+		} `json:"total"`
+		Genres         []FacetBucket `json:"genres"`
+		Countries      []FacetBucket `json:"countries"`
+		ContentRatings []FacetBucket `json:"contentRatings"`
+	}
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return nil, fmt.Errorf("parsing faceted search response: %w", err)
+	}
+	var counts map[string]json.RawMessage
+	if err := json.Unmarshal(resp, &counts); err != nil {
+		return nil, fmt.Errorf("parsing faceted search response: %w", err)
+	}
+
+	out := &FacetedFilms{
+		Films: data.Films,
+		Facets: FilmFacets{
+			Genres:         sortBuckets(data.Genres),
+			Countries:      sortBuckets(data.Countries),
+			ContentRatings: sortBuckets(data.ContentRatings),
+		},
+	}
+	if len(data.Total) > 0 {
+		out.Total = data.Total[0].N
+	}
+	for _, decade := range facetDecades() {
+		var n []struct {
+			N int `json:"n"`
+		}
+		if raw, ok := counts[fmt.Sprintf("decade%d", decade)]; ok {
+			if err := json.Unmarshal(raw, &n); err != nil {
+				return nil, fmt.Errorf("parsing faceted search response: %w", err)
+			}
+		}
+		if len(n) > 0 && n[0].N > 0 {
+			out.Facets.Decades = append(out.Facets.Decades, DecadeBucket{Decade: decade, Count: n[0].N})
+		}
+	}
+	return out, nil
+}
+
+// firstFacetDecade is the earliest decade counted in the decade facet,
+// that of the first films.
+const firstFacetDecade = 1880
+
+// facetDecades returns the decades the decade facet counts, from
+// firstFacetDecade to the current one.
+func facetDecades() []int {
+	var decades []int
+	for d := firstFacetDecade; d <= time.Now().Year(); d += 10 {
+		decades = append(decades, d)
+	}
+	return decades
+}
+
+// sortBuckets drops empty buckets and orders the rest by count, then name.
+func sortBuckets(in []FacetBucket) []FacetBucket {
+	out := in[:0]
+	for _, b := range in {
+		if b.Count > 0 {
+			out = append(out, b)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func facetedQuery(sel FacetSelection, cfg pageConfig) (string, error) {
+	conds := []string{"type(Film)"}
+	for _, f := range []struct {
+		predicate string
+		uids      []string
+	}{
+		{"genre", sel.Genres},
+		{"country", sel.Countries},
+		{"rated", sel.ContentRatings},
+	} {
+		if len(f.uids) == 0 {
+			continue
+		}
+		var or []string
+		for _, uid := range f.uids {
+			if !IsUID(uid) {
+				return "", fmt.Errorf("invalid %s facet UID %q", f.predicate, uid)
+			}
+			or = append(or, fmt.Sprintf("uid_in(%s, %s)", f.predicate, uid))
+		}
+		conds = append(conds, "("+strings.Join(or, " OR ")+")")
+	}
+	if len(sel.Decades) > 0 {
+		var or []string
+		for _, d := range sel.Decades {
+			or = append(or, fmt.Sprintf(`(ge(initial_release_date, "%04d-01-01") AND lt(initial_release_date, "%04d-01-01"))`, d, d+10))
+		}
+		conds = append(conds, "("+strings.Join(or, " OR ")+")")
+	}
+
+	page := ""
+	if cfg.first > 0 {
+		page += fmt.Sprintf(", first: %d", cfg.first)
+	}
+	if cfg.offset > 0 {
+		page += fmt.Sprintf(", offset: %d", cfg.offset)
+	}
+
+	return `query faceted($term: string) {
+	matches as var(func: alloftext(name, $term)) @filter(` + strings.Join(conds, " AND ") + `)
+	var(func: uid(matches)) {
+		g as genre
+		co as country
+		cr as rated
+	}
+	films(func: uid(matches)` + page + `) {
+		uid
+		dgraph.type
+		name
+		initialReleaseDate: initial_release_date
+		tagline
+		genres: genre { uid name }
+		countries: country { uid name }
+		ratings: rating { uid name }
+		contentRatings: rated { uid name }
+	}
+	total(func: uid(matches)) { n: count(uid) }
+	genres(func: uid(g)) { uid name count: count(~genre @filter(uid(matches))) }
+	countries(func: uid(co)) { uid name count: count(~country @filter(uid(matches))) }
+	contentRatings(func: uid(cr)) { uid name count: count(~rated @filter(uid(matches))) }
+` + decadeBlocks() + `}`, nil
+}
+
+// decadeBlocks counts the matches released in each facet decade, one block
+// per decade, so that no release dates are sent back.
+func decadeBlocks() string {
+	var b strings.Builder
+	for _, d := range facetDecades() {
+		fmt.Fprintf(&b, "\tdecade%d(func: uid(matches)) @filter(ge(initial_release_date, \"%04d-01-01\") AND lt(initial_release_date, \"%04d-01-01\")) { n: count(uid) }\n", d, d, d+10)
+	}
+	return b.String()
+}
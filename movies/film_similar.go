@@ -0,0 +1,314 @@
+package movies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NeighborKind identifies the kind of graph neighbor two films share.
+type NeighborKind string
+
+const (
+	NeighborGenre    NeighborKind = "genre"
+	NeighborDirector NeighborKind = "director"
+	NeighborCast     NeighborKind = "cast"
+	NeighborCountry  NeighborKind = "country"
+)
+
+// SimilarWeights controls how much each shared neighbor contributes to a
+// similarity score.
+type SimilarWeights struct {
+	Genre    float64
+	Director float64
+	Cast     float64
+	Country  float64
+}
+
+// DefaultSimilarWeights favors shared directors and cast over broad
+// neighbors like genre and country.
+var DefaultSimilarWeights = SimilarWeights{
+	Genre:    1,
+	Director: 3,
+	Cast:     2,
+	Country:  0.5,
+}
+
+func (w SimilarWeights) weight(kind NeighborKind) float64 {
+	switch kind {
+	case NeighborGenre:
+		return w.Genre
+	case NeighborDirector:
+		return w.Director
+	case NeighborCast:
+		return w.Cast
+	case NeighborCountry:
+		return w.Country
+	}
+	return 0
+}
+
+// SharedNeighbor is a genre, director, cast member or country that a similar
+// film has in common with the source film.
+type SharedNeighbor struct {
+	Kind   NeighborKind `json:"kind"`
+	UID    string       `json:"uid"`
+	Name   string       `json:"name,omitempty"`
+	Weight float64      `json:"weight"`
+}
+
+// SimilarFilm is a film ranked by FilmClient.Similar.
+type SimilarFilm struct {
+	Film   Film             `json:"film"`
+	Score  float64          `json:"score"`
+	Shared []SharedNeighbor `json:"shared"`
+}
+
+const (
+	defaultSimilarLimit  = 10
+	defaultSimilarFanout = 1000
+)
+
+type similarConfig struct {
+	weights         SimilarWeights
+	limit           int
+	fanout          int
+	excludeDirector bool
+	fromYear        int
+	toYear          int
+}
+
+// SimilarOption configures FilmClient.Similar.
+type SimilarOption func(*similarConfig)
+
+// WithSimilarWeights overrides DefaultSimilarWeights.
+func WithSimilarWeights(w SimilarWeights) SimilarOption {
+	return func(cfg *similarConfig) {
+		cfg.weights = w
+	}
+}
+
+// WithSimilarLimit sets the maximum number of films returned.
+func WithSimilarLimit(n int) SimilarOption {
+	return func(cfg *similarConfig) {
+		cfg.limit = n
+	}
+}
+
+// WithSimilarFanout caps how many films are followed from each shared
+// neighbor. Large genres otherwise pull in most of the graph.
+func WithSimilarFanout(n int) SimilarOption {
+	return func(cfg *similarConfig) {
+		cfg.fanout = n
+	}
+}
+
+// ExcludeSameDirector drops films made by any director of the source film.
+func ExcludeSameDirector() SimilarOption {
+	return func(cfg *similarConfig) {
+		cfg.excludeDirector = true
+	}
+}
+
+// ReleasedBetween restricts results to films first released within the
+// inclusive year range. A zero bound is left open.
+func ReleasedBetween(fromYear, toYear int) SimilarOption {
+	return func(cfg *similarConfig) {
+		cfg.fromYear = fromYear
+		cfg.toYear = toYear
+	}
+}
+
+// Similar ranks other films by their weighted overlap with the genres,
+// directors, cast members and countries of the film with the given UID.
+// Each result lists the shared neighbors that contributed to its score.
+func (c *FilmClient) Similar(ctx context.Context, uid string, opts ...SimilarOption) ([]SimilarFilm, error) {
+	cfg := similarConfig{
+		weights: DefaultSimilarWeights,
+		limit:   defaultSimilarLimit,
+		fanout:  defaultSimilarFanout,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	resp, err := c.conn.QueryRaw(ctx, similarQuery(cfg), map[string]string{"$uid": uid})
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Source []similarSource `json:"source"`
+	}
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return nil, fmt.Errorf("parsing similar response: %w", err)
+	}
+	if len(data.Source) == 0 {
+		return nil, nil
+	}
+	src := data.Source[0]
+
+	excluded := map[string]bool{uid: true}
+	if cfg.excludeDirector {
+		for _, d := range src.Directors {
+			for _, f := range d.Filmography {
+				excluded[f.UID] = true
+			}
+		}
+	}
+
+	scores := make(map[string]*SimilarFilm)
+	credit := func(kind NeighborKind, n similarNeighbor, films []similarRef) {
+		w := cfg.weights.weight(kind)
+		if w == 0 {
+			return
+		}
+		seen := make(map[string]bool, len(films))
+		for _, f := range films {
+			if excluded[f.UID] || seen[f.UID] {
+				continue
+			}
+			seen[f.UID] = true
+			sf, ok := scores[f.UID]
+			if !ok {
+				sf = &SimilarFilm{Film: Film{UID: f.UID}}
+				scores[f.UID] = sf
+			}
+			sf.Score += w
+			sf.Shared = append(sf.Shared, SharedNeighbor{Kind: kind, UID: n.UID, Name: n.Name, Weight: w})
+		}
+	}
+	for _, g := range src.Genres {
+		credit(NeighborGenre, g.similarNeighbor, g.Films)
+	}
+	for _, co := range src.Countries {
+		credit(NeighborCountry, co.similarNeighbor, co.Films)
+	}
+	for _, d := range src.Directors {
+		credit(NeighborDirector, d.similarNeighbor, d.Films)
+	}
+	// An actor with several roles in the source film is one neighbor, and
+	// each lists all of its performances, so it is credited once.
+	actors := make(map[string]bool)
+	for _, p := range src.Starring {
+		for _, a := range p.Actors {
+			if actors[a.UID] {
+				continue
+			}
+			actors[a.UID] = true
+			var films []similarRef
+			for _, ap := range a.Performances {
+				films = append(films, ap.Films...)
+			}
+			credit(NeighborCast, a.similarNeighbor, films)
+		}
+	}
+
+	ranked := make([]*SimilarFilm, 0, len(scores))
+	for _, sf := range scores {
+		ranked = append(ranked, sf)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Film.UID < ranked[j].Film.UID
+	})
+	if cfg.limit > 0 && len(ranked) > cfg.limit {
+		ranked = ranked[:cfg.limit]
+	}
+	if len(ranked) == 0 {
+		return nil, nil
+	}
+
+	uids := make([]string, len(ranked))
+	for i, sf := range ranked {
+		uids[i] = sf.Film.UID
+	}
+	var films []Film
+	if err := c.conn.Query(ctx, Film{}).UID(strings.Join(uids, ", ")).Nodes(&films); err != nil {
+		return nil, err
+	}
+	byUID := make(map[string]Film, len(films))
+	for _, f := range films {
+		byUID[f.UID] = f
+	}
+
+	results := make([]SimilarFilm, 0, len(ranked))
+	for _, sf := range ranked {
+		if f, ok := byUID[sf.Film.UID]; ok {
+			sf.Film = f
+		}
+		results = append(results, *sf)
+	}
+	return results, nil
+}
+
+type similarRef struct {
+	UID string `json:"uid"`
+}
+
+type similarNeighbor struct {
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+}
+
+type similarEdge struct {
+	similarNeighbor
+	Films []similarRef `json:"films"`
+}
+
+type similarSource struct {
+	Genres    []similarEdge `json:"genres"`
+	Countries []similarEdge `json:"countries"`
+	Directors []struct {
+		similarEdge
+		Filmography []similarRef `json:"filmography"`
+	} `json:"directors"`
+	Starring []struct {
+		Actors []struct {
+			similarNeighbor
+			Performances []struct {
+				Films []similarRef `json:"films"`
+			} `json:"performances"`
+		} `json:"actors"`
+	} `json:"starring"`
+}
+
+// similarQuery builds the neighborhood query for Similar. Candidate films are
+// filtered by release year where they are reached so that scoring only sees
+// eligible films.
+func similarQuery(cfg similarConfig) string {
+	var conds []string
+	if cfg.fromYear > 0 {
+		conds = append(conds, fmt.Sprintf(`ge(initial_release_date, "%04d-01-01")`, cfg.fromYear))
+	}
+	if cfg.toYear > 0 {
+		conds = append(conds, fmt.Sprintf(`le(initial_release_date, "%04d-12-31T23:59:59")`, cfg.toYear))
+	}
+	films := func(edge string) string {
+		s := fmt.Sprintf("films: %s (first: %d)", edge, cfg.fanout)
+		if len(conds) > 0 {
+			s += " @filter(" + strings.Join(conds, " AND ") + ")"
+		}
+		return s + " { uid }"
+	}
+	return `query similar($uid: string) {
+	source(func: uid($uid)) {
+		genres: genre { uid name ` + films("~genre") + ` }
+		countries: country { uid name ` + films("~country") + ` }
+		directors: ~director.film {
+			uid name
+			` + films("director.film") + `
+			filmography: director.film (first: ` + fmt.Sprint(cfg.fanout) + `) { uid }
+		}
+		starring {
+			actors: ~actor.film {
+				uid name
+				performances: actor.film (first: ` + fmt.Sprint(cfg.fanout) + `) { ` + films("~starring") + ` }
+			}
+		}
+	}
+}`
+}
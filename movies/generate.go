@@ -1,3 +1,8 @@
 package movies
 
-//go:generate go run github.com/matthewmcneely/modusgraph/cmd/modusgraph-gen
+// The generator also writes a CLI; cmd/movies/main.go is maintained by hand,
+// so its output goes to the ignored _cli directory. New and NewFromClient
+// take this package's options, so client.go defines them and trimclient
+// removes the generated ones.
+//go:generate go run github.com/matthewmcneely/modusgraph/cmd/modusgraph-gen -cli-dir=_cli
+//go:generate go run trimclient.go
@@ -0,0 +1,819 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dgraph-io/gqlparser/v2/ast"
+	"github.com/dgraph-io/gqlparser/v2/gqlerror"
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+type rootOp int
+
+const (
+	opGet rootOp = iota
+	opList
+	opCount
+	opAdd
+	opUpdate
+	opDelete
+	opDeleteWhere
+)
+
+// root is what a Query or Mutation field does, and to which entity.
+type root struct {
+	e  *entity
+	op rootOp
+}
+
+func roots(ents []*entity) map[string]root {
+	m := map[string]root{}
+	for _, e := range ents {
+		m[e.one] = root{e, opGet}
+		m[e.many] = root{e, opList}
+		m[e.many+"Count"] = root{e, opCount}
+		m["add"+e.name] = root{e, opAdd}
+		m["update"+e.name] = root{e, opUpdate}
+		m["delete"+e.name] = root{e, opDelete}
+		m["delete"+upperFirst(e.many)] = root{e, opDeleteWhere}
+	}
+	return m
+}
+
+// executor runs one operation. Each query operation is compiled to a single
+// DQL request: every root field becomes a block, and nested edges are
+// fetched inside their parent's block, so a query costs one round trip
+// however deep it goes.
+type executor struct {
+	*server
+	ctx  context.Context
+	vars map[string]any
+	errs gqlerror.List
+	// db is the database schema, loaded on first use and dropped after
+	// each mutation. Embedded Dgraph panics on a predicate it has never
+	// seen, so predicates missing from it are never queried.
+	db *movies.Schema
+}
+
+func (x *executor) fail(f *ast.Field, path ast.Path, err error) {
+	e := &gqlerror.Error{Message: err.Error(), Path: path}
+	if f.Position != nil {
+		e.Locations = []gqlerror.Location{{Line: f.Position.Line, Column: f.Position.Column}}
+	}
+	x.errs = append(x.errs, e)
+}
+
+// known reports whether the database has pred, and a reverse index for
+// a reverse edge.
+func (x *executor) known(pred string) (bool, error) {
+	if x.db == nil {
+		s, err := x.client.Schema(x.ctx)
+		if err != nil {
+			return false, err
+		}
+		x.db = &s
+	}
+	name, reverse := strings.CutPrefix(pred, "~")
+	ps, ok := x.db.Predicate(name)
+	return ok && (!reverse || ps.Reverse), nil
+}
+
+// collected is the fields of a selection set sharing a response key.
+type collected struct {
+	key    string
+	fields []*ast.Field
+}
+
+// selection returns the merged selection sets of c's fields.
+func (c *collected) selection() ast.SelectionSet {
+	var set ast.SelectionSet
+	for _, f := range c.fields {
+		set = append(set, f.SelectionSet...)
+	}
+	return set
+}
+
+// collect groups the fields of set that apply to typeName by response
+// key, expanding fragments and honouring @skip and @include.
+func (x *executor) collect(set ast.SelectionSet, typeName string) []*collected {
+	var out []*collected
+	byKey := map[string]*collected{}
+	var walk func(ast.SelectionSet)
+	walk = func(set ast.SelectionSet) {
+		for _, s := range set {
+			switch s := s.(type) {
+			case *ast.Field:
+				if !x.included(s.Directives) {
+					continue
+				}
+				key := s.Alias
+				if key == "" {
+					key = s.Name
+				}
+				c := byKey[key]
+				if c == nil {
+					c = &collected{key: key}
+					byKey[key] = c
+					out = append(out, c)
+				}
+				c.fields = append(c.fields, s)
+			case *ast.FragmentSpread:
+				if x.included(s.Directives) && s.Definition != nil && s.Definition.TypeCondition == typeName {
+					walk(s.Definition.SelectionSet)
+				}
+			case *ast.InlineFragment:
+				if x.included(s.Directives) && (s.TypeCondition == "" || s.TypeCondition == typeName) {
+					walk(s.SelectionSet)
+				}
+			}
+		}
+	}
+	walk(set)
+	return out
+}
+
+func (x *executor) included(dirs ast.DirectiveList) bool {
+	if d := dirs.ForName("skip"); d != nil && d.ArgumentMap(x.vars)["if"] == true {
+		return false
+	}
+	if d := dirs.ForName("include"); d != nil && d.ArgumentMap(x.vars)["if"] == false {
+		return false
+	}
+	return true
+}
+
+type planKind int
+
+const (
+	planTypename planKind = iota
+	planUID
+	planScalar
+	planValue
+	planEdge
+	planCount
+)
+
+// plan is one selected field of an entity: how it is fetched and where
+// it goes in the response.
+type plan struct {
+	key   string // the response key
+	alias string // the DQL alias, safe from clashes with DQL keywords
+	kind  planKind
+	field *field
+	// missing is set when the database does not have the field's
+	// predicate yet, so it is left out of the DQL.
+	missing bool
+
+	params   []string // an edge's first, offset and order
+	filter   string   // an edge's filter
+	children []*plan  // an edge's selection
+	value    []*collected
+}
+
+// plan compiles the selection of e's fields in set.
+func (x *executor) plan(e *entity, set ast.SelectionSet) ([]*plan, error) {
+	var out []*plan
+	for i, c := range x.collect(set, e.name) {
+		f := c.fields[0]
+		p := &plan{key: c.key, alias: "f" + strconv.Itoa(i)}
+		out = append(out, p)
+		switch f.Name {
+		case "__typename":
+			p.kind = planTypename
+			continue
+		case "uid":
+			p.kind = planUID
+			continue
+		}
+		p.field = e.field(f.Name)
+		if p.field == nil {
+			p.field, p.kind = e.field(strings.TrimSuffix(f.Name, "Count")), planCount
+		}
+		known, err := x.known(p.field.predicate)
+		if err != nil {
+			return nil, err
+		}
+		p.missing = !known
+		switch {
+		case p.kind == planCount:
+		case p.field.kind == scalarField:
+			p.kind = planScalar
+		case p.field.kind == valueField:
+			p.kind = planValue
+			p.value = x.collect(c.selection(), p.field.typ)
+		default:
+			p.kind = planEdge
+			if p.missing {
+				continue
+			}
+			if p.params, p.filter, err = x.listArgs(p.field.target, f.ArgumentMap(x.vars)); err != nil {
+				return nil, err
+			}
+			if p.children, err = x.plan(p.field.target, c.selection()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// writeSelection writes the DQL selection of plans. uid is always fetched,
+// so that no node comes back empty.
+func writeSelection(b *strings.Builder, plans []*plan) {
+	b.WriteString("{ uid")
+	for _, p := range plans {
+		if p.missing {
+			continue
+		}
+		switch p.kind {
+		case planUID:
+			fmt.Fprintf(b, " %s: uid", p.alias)
+		case planScalar, planValue:
+			fmt.Fprintf(b, " %s: %s", p.alias, p.field.predicate)
+		case planCount:
+			fmt.Fprintf(b, " %s: count(%s)", p.alias, p.field.predicate)
+		case planEdge:
+			fmt.Fprintf(b, " %s: %s", p.alias, p.field.predicate)
+			if len(p.params) > 0 {
+				fmt.Fprintf(b, " (%s)", strings.Join(p.params, ", "))
+			}
+			if p.filter != "" {
+				fmt.Fprintf(b, " @filter(%s)", p.filter)
+			}
+			b.WriteString(" ")
+			writeSelection(b, p.children)
+		}
+	}
+	b.WriteString(" }")
+}
+
+// shape builds the response object of a node from its DQL result.
+func shape(e *entity, plans []*plan, node map[string]any) *object {
+	o := &object{}
+	for _, p := range plans {
+		v := node[p.alias]
+		switch p.kind {
+		case planTypename:
+			v = e.name
+		case planCount:
+			if v == nil {
+				v = 0
+			}
+		case planValue:
+			if m, ok := v.(map[string]any); ok {
+				value := &object{}
+				for _, c := range p.value {
+					if c.fields[0].Name == "__typename" {
+						value.set(c.key, p.field.typ)
+					} else {
+						value.set(c.key, m[c.fields[0].Name])
+					}
+				}
+				v = value
+			}
+		case planEdge:
+			items := []any{}
+			nodes, _ := v.([]any)
+			for _, n := range nodes {
+				if m, ok := n.(map[string]any); ok {
+					items = append(items, shape(p.field.target, p.children, m))
+				}
+			}
+			v = items
+		}
+		o.set(p.key, v)
+	}
+	return o
+}
+
+// never is a filter matching no typed node. A condition on a predicate the
+// database does not have yet compiles to it.
+const never = "NOT has(dgraph.type)"
+
+// listArgs compiles the first, offset, order and filter arguments of a
+// list of e.
+func (x *executor) listArgs(e *entity, args map[string]any) (params []string, filter string, err error) {
+	if v := args["first"]; v != nil {
+		n, err := toInt(v)
+		if err != nil || n < 0 || n > maxFirst {
+			return nil, "", fmt.Errorf("first must be between 0 and %d", maxFirst)
+		}
+		params = append(params, "first: "+strconv.Itoa(n))
+	}
+	if v := args["offset"]; v != nil {
+		n, err := toInt(v)
+		if err != nil || n < 0 {
+			return nil, "", errors.New("offset must not be negative")
+		}
+		if n > 0 {
+			params = append(params, "offset: "+strconv.Itoa(n))
+		}
+	}
+	for m, ok := args["order"].(map[string]any); ok; m, ok = m["then"].(map[string]any) {
+		for _, dir := range []string{"asc", "desc"} {
+			name, _ := m[dir].(string)
+			if name == "" {
+				continue
+			}
+			f := e.field(name)
+			known, err := x.known(f.predicate)
+			if err != nil {
+				return nil, "", err
+			}
+			if known {
+				params = append(params, "order"+dir+": "+f.predicate)
+			}
+		}
+	}
+	filter, err = x.filter(e, args["filter"])
+	return params, filter, err
+}
+
+// filter compiles an e filter input to a DQL filter; "" matches all.
+func (x *executor) filter(e *entity, v any) (string, error) {
+	m, _ := v.(map[string]any)
+	var terms []string
+	if ids, ok := m["uid"].([]any); ok {
+		uids := make([]string, len(ids))
+		for i, id := range ids {
+			if uids[i] = fmt.Sprint(id); !movies.IsUID(uids[i]) {
+				return "", fmt.Errorf("%q is not a UID", uids[i])
+			}
+		}
+		if len(uids) == 0 {
+			terms = append(terms, never)
+		} else {
+			terms = append(terms, "uid("+strings.Join(uids, ", ")+")")
+		}
+	}
+	for _, f := range e.fields {
+		ops, ok := m[f.name].(map[string]any)
+		if !ok {
+			continue
+		}
+		known, err := x.known(f.predicate)
+		if err != nil {
+			return "", err
+		}
+		for _, op := range sortedKeys(ops) {
+			if ops[op] == nil {
+				continue
+			}
+			if !known {
+				terms = append(terms, never)
+				continue
+			}
+			term, err := condition(f, op, ops[op])
+			if err != nil {
+				return "", fmt.Errorf("%s.%s: %w", f.name, op, err)
+			}
+			terms = append(terms, term)
+		}
+	}
+	has, _ := m["has"].([]any)
+	for _, name := range has {
+		f := e.field(fmt.Sprint(name))
+		known, err := x.known(f.predicate)
+		if err != nil {
+			return "", err
+		}
+		if known {
+			terms = append(terms, "has("+f.predicate+")")
+		} else {
+			terms = append(terms, never)
+		}
+	}
+	and, _ := m["and"].([]any)
+	for _, sub := range and {
+		s, err := x.filter(e, sub)
+		if err != nil {
+			return "", err
+		}
+		if s != "" {
+			terms = append(terms, "("+s+")")
+		}
+	}
+	if or, ok := m["or"].([]any); ok && len(or) > 0 {
+		var alts []string
+		for _, sub := range or {
+			s, err := x.filter(e, sub)
+			if err != nil {
+				return "", err
+			}
+			if s == "" {
+				// One alternative matches everything, so all of them do.
+				alts = nil
+				break
+			}
+			alts = append(alts, "("+s+")")
+		}
+		if len(alts) > 0 {
+			terms = append(terms, "("+strings.Join(alts, " OR ")+")")
+		}
+	}
+	if not, ok := m["not"].(map[string]any); ok {
+		s, err := x.filter(e, not)
+		if err != nil {
+			return "", err
+		}
+		if s == "" {
+			terms = append(terms, never)
+		} else {
+			terms = append(terms, "NOT ("+s+")")
+		}
+	}
+	return strings.Join(terms, " AND "), nil
+}
+
+// condition compiles one operator of a scalar filter. The operators are
+// named after the DQL functions they become.
+func condition(f *field, op string, v any) (string, error) {
+	var arg string
+	switch op {
+	case "regexp":
+		s := fmt.Sprint(v)
+		if utf8.RuneCountInString(s) < 3 {
+			return "", errors.New("a regular expression needs at least 3 characters")
+		}
+		lit, err := regexpLiteral(s)
+		if err != nil {
+			return "", err
+		}
+		arg = lit
+	case "in":
+		vs, _ := v.([]any)
+		if len(vs) == 0 {
+			return never, nil
+		}
+		items := make([]string, len(vs))
+		for i, item := range vs {
+			lit, err := literal(f.typ, item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = lit
+		}
+		op, arg = "eq", "["+strings.Join(items, ", ")+"]"
+	default:
+		lit, err := literal(f.typ, v)
+		if err != nil {
+			return "", err
+		}
+		arg = lit
+	}
+	return fmt.Sprintf("%s(%s, %s)", op, f.predicate, arg), nil
+}
+
+// regexpLiteral returns s between slashes as a DQL regular expression.
+// The lexer takes a backslash and the character after it as a pair, so
+// pairs are kept, which leaves \d and \/ to the expression, and only bare
+// slashes are escaped. A trailing backslash would escape the closing slash.
+func regexpLiteral(s string) (string, error) {
+	var b strings.Builder
+	b.WriteByte('/')
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 == len(s) {
+				return "", errors.New("a regular expression cannot end in a backslash")
+			}
+			b.WriteString(s[i : i+2])
+			i++
+		case '/':
+			b.WriteString(`\/`)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	b.WriteByte('/')
+	return b.String(), nil
+}
+
+// literal returns v as a DQL literal of the GraphQL scalar typ.
+func literal(typ string, v any) (string, error) {
+	switch typ {
+	case "DateTime":
+		t, err := parseDateTime(fmt.Sprint(v))
+		if err != nil {
+			return "", err
+		}
+		return movies.Quote(t.Format(time.RFC3339)), nil
+	case "Int", "Float", "Boolean":
+		return fmt.Sprint(v), nil
+	}
+	return movies.Quote(fmt.Sprint(v)), nil
+}
+
+// block is a root query field compiled to a DQL block.
+type block struct {
+	c     *collected
+	r     root
+	name  string
+	plans []*plan
+	// uid is the node to get, when it is not the field's uid argument,
+	// as for the result of a mutation.
+	uid string
+}
+
+// query runs a query operation.
+func (x *executor) query(set ast.SelectionSet) any {
+	data := &object{}
+	var q strings.Builder
+	var blocks []*block
+	nullData := false
+	for _, c := range x.collect(set, "Query") {
+		f := c.fields[0]
+		data.set(c.key, nil)
+		switch f.Name {
+		case "__typename":
+			data.set(c.key, "Query")
+			continue
+		case "__schema":
+			data.set(c.key, x.introspect(schemaIntro{x.schema}, c.selection()))
+			continue
+		case "__type":
+			if def := x.schema.Types[fmt.Sprint(f.ArgumentMap(x.vars)["name"])]; def != nil {
+				data.set(c.key, x.introspect(typeIntro{s: x.schema, def: def}, c.selection()))
+			}
+			continue
+		}
+		b := &block{c: c, r: x.roots[f.Name], name: "b" + strconv.Itoa(len(blocks))}
+		if err := x.writeBlock(&q, b); err != nil {
+			x.fail(f, ast.Path{ast.PathName(c.key)}, err)
+			nullData = nullData || b.r.op != opGet
+			continue
+		}
+		blocks = append(blocks, b)
+	}
+	if len(blocks) > 0 {
+		res, err := x.run("{\n" + q.String() + "}")
+		for _, b := range blocks {
+			if err != nil {
+				x.fail(b.c.fields[0], ast.Path{ast.PathName(b.c.key)}, err)
+				nullData = nullData || b.r.op != opGet
+				continue
+			}
+			data.set(b.c.key, b.result(res[b.name]))
+		}
+	}
+	if nullData {
+		// The failed field cannot be null, so neither can the data.
+		return nil
+	}
+	return data
+}
+
+// writeBlock compiles a root query field into a DQL block.
+func (x *executor) writeBlock(q *strings.Builder, b *block) error {
+	f, e := b.c.fields[0], b.r.e
+	args := f.ArgumentMap(x.vars)
+	if b.r.op == opGet {
+		uid := b.uid
+		if uid == "" {
+			uid = fmt.Sprint(args["uid"])
+		}
+		if !movies.IsUID(uid) {
+			return fmt.Errorf("%q is not a UID", uid)
+		}
+		plans, err := x.plan(e, b.c.selection())
+		if err != nil {
+			return err
+		}
+		b.plans = plans
+		fmt.Fprintf(q, "  %s(func: uid(%s)) @filter(type(%s)) ", b.name, uid, e.name)
+		writeSelection(q, plans)
+		q.WriteString("\n")
+		return nil
+	}
+
+	params, filter, err := x.listArgs(e, args)
+	if err != nil {
+		return err
+	}
+	fn, filters := "type("+e.name+")", []string{}
+	if search, _ := args["search"].(string); search != "" {
+		known, err := x.known("name")
+		if err != nil {
+			return err
+		}
+		if known {
+			fn = "alloftext(name, " + movies.Quote(search) + ")"
+			filters = append(filters, "type("+e.name+")")
+		} else {
+			filters = append(filters, never)
+		}
+	}
+	if filter != "" {
+		filters = append(filters, "("+filter+")")
+	}
+	fmt.Fprintf(q, "  %s(func: %s", b.name, fn)
+	for _, p := range params {
+		q.WriteString(", " + p)
+	}
+	q.WriteString(")")
+	if len(filters) > 0 {
+		fmt.Fprintf(q, " @filter(%s)", strings.Join(filters, " AND "))
+	}
+	if b.r.op == opCount {
+		q.WriteString(" { n: count(uid) }\n")
+		return nil
+	}
+	if b.plans, err = x.plan(e, b.c.selection()); err != nil {
+		return err
+	}
+	q.WriteString(" ")
+	writeSelection(q, b.plans)
+	q.WriteString("\n")
+	return nil
+}
+
+// result shapes a block's nodes as its field's value.
+func (b *block) result(nodes []map[string]any) any {
+	switch b.r.op {
+	case opGet:
+		if len(nodes) == 0 {
+			return nil
+		}
+		return shape(b.r.e, b.plans, nodes[0])
+	case opCount:
+		if len(nodes) == 0 {
+			return 0
+		}
+		return nodes[0]["n"]
+	}
+	items := make([]any, len(nodes))
+	for i, n := range nodes {
+		items[i] = shape(b.r.e, b.plans, n)
+	}
+	return items
+}
+
+// run executes a DQL query and returns its blocks.
+func (x *executor) run(query string) (map[string][]map[string]any, error) {
+	resp, err := x.client.QueryRaw(x.ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	var res map[string][]map[string]any
+	dec := json.NewDecoder(bytes.NewReader(resp))
+	dec.UseNumber()
+	if err := dec.Decode(&res); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	return res, nil
+}
+
+// mutation runs a mutation operation, one field after another.
+func (x *executor) mutation(set ast.SelectionSet) any {
+	data := &object{}
+	for _, c := range x.collect(set, "Mutation") {
+		f := c.fields[0]
+		if f.Name == "__typename" {
+			data.set(c.key, "Mutation")
+			continue
+		}
+		v, err := x.mutate(x.roots[f.Name], c)
+		if err != nil {
+			x.fail(f, ast.Path{ast.PathName(c.key)}, err)
+			v = nil
+		}
+		data.set(c.key, v)
+	}
+	return data
+}
+
+func (x *executor) mutate(r root, c *collected) (any, error) {
+	args := c.fields[0].ArgumentMap(x.vars)
+	input, _ := args["input"].(map[string]any)
+	uid := fmt.Sprint(args["uid"])
+	switch r.op {
+	case opAdd:
+		uid, err := r.e.add(x.ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		x.db = nil
+		return x.node(r.e, uid, c)
+	case opUpdate:
+		if err := x.exists(r.e, uid); err != nil {
+			return nil, err
+		}
+		if err := r.e.update(x.ctx, uid, input); err != nil {
+			return nil, err
+		}
+		x.db = nil
+		return x.node(r.e, uid, c)
+	case opDelete:
+		if err := x.exists(r.e, uid); err != nil {
+			return nil, err
+		}
+		if err := r.e.delete(x.ctx, uid); err != nil {
+			return nil, err
+		}
+		return uid, nil
+	case opDeleteWhere:
+		filter, err := x.filter(r.e, args["filter"])
+		if err != nil {
+			return nil, err
+		}
+		uids, err := r.e.deleteWhere(x.ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]any, len(uids))
+		for i, id := range uids {
+			ids[i] = id
+		}
+		return ids, nil
+	}
+	return nil, fmt.Errorf("%s is not a mutation", c.fields[0].Name)
+}
+
+// node fetches the selection c of the e node uid.
+func (x *executor) node(e *entity, uid string, c *collected) (any, error) {
+	var q strings.Builder
+	b := &block{c: c, r: root{e, opGet}, name: "b0", uid: uid}
+	if err := x.writeBlock(&q, b); err != nil {
+		return nil, err
+	}
+	res, err := x.run("{\n" + q.String() + "}")
+	if err != nil {
+		return nil, err
+	}
+	return b.result(res[b.name]), nil
+}
+
+// exists fails unless uid is an e node.
+func (x *executor) exists(e *entity, uid string) error {
+	if !movies.IsUID(uid) {
+		return fmt.Errorf("%q is not a UID", uid)
+	}
+	res, err := x.run(fmt.Sprintf("{ n(func: uid(%s)) @filter(type(%s)) { uid } }", uid, e.name))
+	if err != nil {
+		return err
+	}
+	if len(res["n"]) == 0 {
+		return fmt.Errorf("no %s with UID %s", e.name, uid)
+	}
+	return nil
+}
+
+func toInt(v any) (int, error) {
+	switch v := v.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		return int(v), nil
+	case json.Number:
+		n, err := v.Int64()
+		return int(n), err
+	}
+	return 0, fmt.Errorf("%v is not an integer", v)
+}
+
+// object is a JSON object keeping its keys in selection order, as GraphQL
+// responses do.
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+func (o *object) set(key string, v any) {
+	if o.values == nil {
+		o.values = map[string]any{}
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		v, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,108 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/dgraph/v25/dql"
+)
+
+// parseFilter parses cond as the filter of a query, as Dgraph does, and
+// returns the functions it holds.
+func parseFilter(t *testing.T, cond string) []string {
+	t.Helper()
+	res, err := dql.Parse(dql.Request{Str: "{ q(func: has(name)) @filter(" + cond + ") { uid } }"})
+	if err != nil {
+		t.Fatalf("parsing %s: %v", cond, err)
+	}
+	var fns []string
+	var walk func(*dql.FilterTree)
+	walk = func(ft *dql.FilterTree) {
+		if ft == nil {
+			return
+		}
+		if ft.Func != nil {
+			fns = append(fns, ft.Func.Name)
+		}
+		for _, c := range ft.Child {
+			walk(c)
+		}
+	}
+	walk(res.Query[0].Filter)
+	return fns
+}
+
+func TestConditionRegexp(t *testing.T) {
+	name := &field{name: "name", predicate: "name", typ: "String"}
+	tests := []struct {
+		in, want string
+	}{
+		{"^Star", "regexp(name, /^Star/)"},
+		{"a/b", `regexp(name, /a\/b/)`},
+		{`\d\d\d`, `regexp(name, /\d\d\d/)`},
+		{`a\/b`, `regexp(name, /a\/b/)`},
+		{`a\\/b`, `regexp(name, /a\\\/b/)`},
+		// Unescaped, the backslash pair would close the expression early.
+		{`a\/) OR has(x) OR regexp(name, /x`, `regexp(name, /a\/) OR has(x) OR regexp(name, \/x/)`},
+	}
+	for _, tt := range tests {
+		got, err := condition(name, "regexp", tt.in)
+		if err != nil {
+			t.Errorf("condition(regexp, %q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("condition(regexp, %q) = %s, want %s", tt.in, got, tt.want)
+		}
+		if fns := parseFilter(t, got); len(fns) != 1 || fns[0] != "regexp" {
+			t.Errorf("condition(regexp, %q) parses as %q, want one regexp", tt.in, fns)
+		}
+	}
+
+	for _, bad := range []string{`abc\`, `a\/) OR has(x) \`, "ab"} {
+		if got, err := condition(name, "regexp", bad); err == nil {
+			t.Errorf("condition(regexp, %q) = %s, want an error", bad, got)
+		}
+	}
+}
+
+func TestConditionQuotes(t *testing.T) {
+	name := &field{name: "name", predicate: "name", typ: "String"}
+	for _, in := range []string{
+		`Heat`,
+		`say "hi") OR has(x`,
+		`C:\films\`,
+		"bell\a and tab\t and nul\x00",
+		"emoji 😀 and é",
+	} {
+		for _, op := range []string{"eq", "anyofterms", "in"} {
+			var v any = in
+			if op == "in" {
+				v = []any{in, "other"}
+			}
+			got, err := condition(name, op, v)
+			if err != nil {
+				t.Errorf("condition(%s, %q): %v", op, in, err)
+				continue
+			}
+			fns := parseFilter(t, got)
+			want := op
+			if op == "in" {
+				want = "eq"
+			}
+			if len(fns) != 1 || fns[0] != want {
+				t.Errorf("condition(%s, %q) = %s parses as %q", op, in, got, fns)
+			}
+		}
+	}
+
+	// DateTimes are checked and written in RFC 3339.
+	released := &field{name: "initialReleaseDate", predicate: "initial_release_date", typ: "DateTime"}
+	got, err := condition(released, "ge", "1999-03-31")
+	if err != nil || !strings.Contains(got, `"1999-03-31T00:00:00Z"`) {
+		t.Errorf("condition(ge, 1999-03-31) = %s, %v", got, err)
+	}
+	if _, err := condition(released, "ge", `1999") OR has(x`); err == nil {
+		t.Error("expected an invalid DateTime to fail")
+	}
+}
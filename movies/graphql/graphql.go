@@ -0,0 +1,188 @@
+// Package graphql serves the movies data model over GraphQL. The schema is
+// derived from the entity structs, as Schema shows: every entity has a
+// type with its fields and edges, and the root fields
+//
+//	film(uid: ID!): Film
+//	films(search, filter, order, first, offset): [Film!]!
+//	filmsCount(search, filter): Int!
+//	addFilm(input), updateFilm(uid, input), deleteFilm(uid), deleteFilms(filter)
+//
+// where the mutations call the typed client's Add, Update, Delete and
+// DeleteWhere. Edges take filter, order, first and offset too, and have a
+// count, e.g. genresCount.
+//
+// Queries are compiled to DQL rather than resolved field by field: all the
+// root fields of an operation are fetched in one DQL request, and nested
+// edges are traversed inside it, so there are no per-node round trips.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+
+	"github.com/dgraph-io/gqlparser/v2"
+	"github.com/dgraph-io/gqlparser/v2/ast"
+	"github.com/dgraph-io/gqlparser/v2/gqlerror"
+	"github.com/dgraph-io/gqlparser/v2/validator"
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+// Request is a GraphQL request, as POSTed in JSON.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is a GraphQL response.
+type Response struct {
+	Data   any           `json:"data,omitempty"`
+	Errors gqlerror.List `json:"errors,omitempty"`
+}
+
+// server executes requests against a client.
+type server struct {
+	client *movies.Client
+	schema *ast.Schema
+	roots  map[string]root
+}
+
+// Handler returns the GraphQL endpoint for client. It takes POSTed JSON
+// requests, and GET requests with query, operationName and variables
+// parameters; GET cannot run mutations.
+func Handler(client *movies.Client) http.Handler {
+	ents := entities(client)
+	return &server{
+		client: client,
+		schema: gqlparser.MustLoadSchema(&ast.Source{Name: "movies.graphql", Input: schemaSDL(ents)}),
+		roots:  roots(ents),
+	}
+}
+
+// maxBody caps request bodies.
+const maxBody = 1 << 20
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := decode([]byte(v), &req.Variables); err != nil {
+				writeJSON(w, http.StatusBadRequest, errorResponse("invalid variables: "+err.Error()))
+				return
+			}
+		}
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+		switch {
+		case err != nil:
+			writeJSON(w, http.StatusBadRequest, errorResponse("reading body: "+err.Error()))
+			return
+		case len(body) > maxBody:
+			writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse("the body is too large"))
+			return
+		}
+		if err := decode(body, &req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse("invalid request: "+err.Error()))
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse(r.Method+" is not supported"))
+		return
+	}
+	resp, status := s.execute(r.Context(), req, r.Method == http.MethodGet)
+	writeJSON(w, status, resp)
+}
+
+// execute runs req, returning its response and HTTP status: 400 when the
+// request is invalid, and 200 once it runs, even if fields fail.
+func (s *server) execute(ctx context.Context, req Request, readOnly bool) (*Response, int) {
+	if req.Query == "" {
+		return errorResponse("the request has no query"), http.StatusBadRequest
+	}
+	doc, errs := gqlparser.LoadQuery(s.schema, req.Query)
+	if errs != nil {
+		return &Response{Errors: errs}, http.StatusBadRequest
+	}
+	op := doc.Operations.ForName(req.OperationName)
+	if op == nil {
+		return errorResponse("no operation named " + req.OperationName), http.StatusBadRequest
+	}
+	if readOnly && op.Operation != ast.Query {
+		return errorResponse("GET requests can only run queries"), http.StatusMethodNotAllowed
+	}
+	vars, err := validator.VariableValues(s.schema, op, req.Variables)
+	if err != nil {
+		return &Response{Errors: gqlerror.List{err}}, http.StatusBadRequest
+	}
+	x := &executor{server: s, ctx: ctx, vars: vars}
+	var data any
+	if op.Operation == ast.Mutation {
+		data = x.mutation(op.SelectionSet)
+	} else {
+		data = x.query(op.SelectionSet)
+	}
+	if data == nil {
+		// An operation that ran has data, null if a failure spread to it.
+		data = json.RawMessage("null")
+	}
+	return &Response{Data: data, Errors: x.errs}, http.StatusOK
+}
+
+func errorResponse(message string) *Response {
+	return &Response{Errors: gqlerror.List{{Message: message}}}
+}
+
+// decode unmarshals JSON keeping numbers exact, as variable coercion needs.
+func decode(b []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+var playground = template.Must(template.New("playground").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>movies GraphQL</title>
+<link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css">
+</head>
+<body style="margin: 0">
+<div id="graphiql" style="height: 100vh"></div>
+<script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+<script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+<script crossorigin src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+<script>
+const fetcher = GraphiQL.createFetcher({ url: {{.}} });
+ReactDOM.createRoot(document.getElementById("graphiql")).render(React.createElement(GraphiQL, { fetcher }));
+</script>
+</body>
+</html>
+`))
+
+// Playground returns a GraphiQL page querying endpoint. The page loads
+// GraphiQL from unpkg.com.
+func Playground(endpoint string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, r.Method+" is not supported", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = playground.Execute(w, endpoint)
+	})
+}
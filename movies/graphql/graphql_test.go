@@ -0,0 +1,225 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/graphql"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/internal/dgraphtest"
+)
+
+// response is a GraphQL response with its errors' messages.
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// post sends query to srv, fails t unless the response has wantStatus, and
+// decodes the body into v.
+func post(t *testing.T, srv *httptest.Server, query string, vars map[string]any, wantStatus int, v any) {
+	t.Helper()
+	body, err := json.Marshal(graphql.Request{Query: query, Variables: vars})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s: expected status %d, got %d", query, wantStatus, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+}
+
+// TestHandlerRefuses checks the requests the handler answers before
+// reaching the database, so a zero Client serves them.
+func TestHandlerRefuses(t *testing.T) {
+	srv := httptest.NewServer(graphql.Handler(&movies.Client{}))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		query  string
+		vars   map[string]any
+		status int
+	}{
+		{"", nil, http.StatusBadRequest},
+		{`{ films { nme } }`, nil, http.StatusBadRequest},
+		{`{ films(first: "ten") { name } }`, nil, http.StatusBadRequest},
+		{`query Q($uid: ID!) { film(uid: $uid) { name } }`, nil, http.StatusBadRequest},
+		{`query Q($first: Int) { films(first: $first) { name } }`, map[string]any{"first": "ten"}, http.StatusBadRequest},
+	} {
+		var resp response
+		post(t, srv, tt.query, tt.vars, tt.status, &resp)
+		if len(resp.Errors) == 0 {
+			t.Errorf("%s: expected an error", tt.query)
+		}
+	}
+
+	for _, tt := range []struct {
+		method, target, body string
+		status               int
+	}{
+		{"PUT", "/", `{"query": "{ __typename }"}`, http.StatusMethodNotAllowed},
+		{"POST", "/", `{"query": `, http.StatusBadRequest},
+		{"GET", "/?query=" + url.QueryEscape(`mutation { deleteGenre(uid: "0x1") }`), "", http.StatusMethodNotAllowed},
+		{"GET", "/?query=" + url.QueryEscape(`{ __typename }`) + "&variables=%7B", "", http.StatusBadRequest},
+		{"GET", "/?query=" + url.QueryEscape(`{ __typename }`) + "&operationName=Missing", "", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+		graphql.Handler(&movies.Client{}).ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.target, tt.status, rec.Code)
+		}
+	}
+}
+
+// TestIntrospection checks the schema derived from the entity structs,
+// which needs no database.
+func TestIntrospection(t *testing.T) {
+	srv := httptest.NewServer(graphql.Handler(&movies.Client{}))
+	defer srv.Close()
+
+	var schema struct {
+		Data struct {
+			Typename string `json:"__typename"`
+			Type     struct {
+				Fields []struct {
+					Name string `json:"name"`
+				} `json:"fields"`
+			} `json:"__type"`
+		} `json:"data"`
+	}
+	post(t, srv, `{ __typename __type(name: "Film") { fields { name } } }`, nil, http.StatusOK, &schema)
+	var fields []string
+	for _, f := range schema.Data.Type.Fields {
+		fields = append(fields, f.Name)
+	}
+	for _, want := range []string{"uid", "name", "initialReleaseDate", "genres", "genresCount"} {
+		if !slices.Contains(fields, want) {
+			t.Errorf("expected Film to have %s, got %v", want, fields)
+		}
+	}
+	if schema.Data.Typename != "Query" {
+		t.Errorf("expected __typename Query, got %q", schema.Data.Typename)
+	}
+
+	sdl := graphql.Schema()
+	for _, want := range []string{"type Film {", "films(", "filmsCount(", "addGenre(", "enum FilmOrderField {"} {
+		if !strings.Contains(sdl, want) {
+			t.Errorf("expected the schema to contain %q", want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	c := dgraphtest.Client(t)
+	ctx := context.Background()
+	action := &movies.Genre{Name: "GraphQL Test Action"}
+	scifi := &movies.Genre{Name: "GraphQL Test Sci-Fi"}
+	for _, g := range []*movies.Genre{scifi, action} {
+		if err := c.Genre.Add(ctx, g); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = c.Genre.Delete(ctx, g.UID) })
+	}
+	for _, f := range []*movies.Film{
+		{Name: "Quillon Dawn", InitialReleaseDate: time.Date(1999, 3, 31, 0, 0, 0, 0, time.UTC), Genres: []movies.Genre{*scifi, *action}},
+		{Name: "Quillon Dusk", InitialReleaseDate: time.Date(2003, 5, 15, 0, 0, 0, 0, time.UTC), Genres: []movies.Genre{*scifi, *action}},
+	} {
+		if err := c.Film.Add(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = c.Film.Delete(ctx, f.UID) })
+	}
+	srv := httptest.NewServer(graphql.Handler(c))
+	defer srv.Close()
+
+	var films struct {
+		Data struct {
+			Films []struct {
+				Name   string `json:"name"`
+				Genres []struct {
+					Name string `json:"name"`
+				} `json:"genres"`
+				GenresCount int `json:"genresCount"`
+			} `json:"films"`
+			Count int `json:"count"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	post(t, srv, `query Q($search: String) {
+		films(search: $search, filter: {initialReleaseDate: {lt: "2000-01-01"}}, order: {asc: name}, first: 1) {
+			name
+			genres(order: {asc: name}) { name }
+			genresCount
+		}
+		count: filmsCount(search: $search)
+	}`, map[string]any{"search": "Quillon"}, http.StatusOK, &films)
+	if len(films.Errors) > 0 {
+		t.Fatalf("unexpected errors %+v", films.Errors)
+	}
+	if len(films.Data.Films) != 1 || films.Data.Films[0].Name != "Quillon Dawn" {
+		t.Fatalf("expected Quillon Dawn, got %+v", films.Data.Films)
+	}
+	if f := films.Data.Films[0]; len(f.Genres) != 2 || f.Genres[0].Name != action.Name || f.GenresCount != 2 {
+		t.Errorf("expected %s and %s, got %+v", action.Name, scifi.Name, f)
+	}
+	if films.Data.Count != 2 {
+		t.Errorf("expected 2 Quillon films, got %d", films.Data.Count)
+	}
+
+	var added struct {
+		Data struct {
+			AddGenre struct {
+				UID  string `json:"uid"`
+				Name string `json:"name"`
+			} `json:"addGenre"`
+		} `json:"data"`
+	}
+	post(t, srv, `mutation { addGenre(input: {name: "GraphQL Genre"}) { uid name } }`, nil, http.StatusOK, &added)
+	uid := added.Data.AddGenre.UID
+	if uid == "" || added.Data.AddGenre.Name != "GraphQL Genre" {
+		t.Fatalf("unexpected added genre %+v", added.Data.AddGenre)
+	}
+	var updated struct {
+		Data struct {
+			UpdateGenre struct {
+				Name string `json:"name"`
+			} `json:"updateGenre"`
+			DeleteGenre string `json:"deleteGenre"`
+		} `json:"data"`
+	}
+	post(t, srv, `mutation M($uid: ID!) {
+		updateGenre(uid: $uid, input: {name: "GraphQL Genre Renamed"}) { name }
+		deleteGenre(uid: $uid)
+	}`, map[string]any{"uid": uid}, http.StatusOK, &updated)
+	if updated.Data.UpdateGenre.Name != "GraphQL Genre Renamed" || updated.Data.DeleteGenre != uid {
+		t.Errorf("unexpected update and delete %+v", updated.Data)
+	}
+	var got struct {
+		Data struct {
+			Genre *struct{} `json:"genre"`
+		} `json:"data"`
+	}
+	post(t, srv, `query Q($uid: ID!) { genre(uid: $uid) { name } }`, map[string]any{"uid": uid}, http.StatusOK, &got)
+	if got.Data.Genre != nil {
+		t.Error("expected the deleted genre to be gone")
+	}
+}
@@ -0,0 +1,286 @@
+package graphql
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/dgraph-io/gqlparser/v2/ast"
+)
+
+// introspected is a value of one of the introspection types, such as
+// __Schema, resolving its fields on demand.
+type introspected interface {
+	typeName() string
+	resolve(field string, args map[string]any) any
+}
+
+// introspect resolves the selection set of v.
+func (x *executor) introspect(v introspected, set ast.SelectionSet) any {
+	o := &object{}
+	for _, c := range x.collect(set, v.typeName()) {
+		f := c.fields[0]
+		if f.Name == "__typename" {
+			o.set(c.key, v.typeName())
+			continue
+		}
+		switch r := v.resolve(f.Name, f.ArgumentMap(x.vars)).(type) {
+		case introspected:
+			o.set(c.key, x.introspect(r, c.selection()))
+		case []introspected:
+			items := make([]any, len(r))
+			for i, item := range r {
+				items[i] = x.introspect(item, c.selection())
+			}
+			o.set(c.key, items)
+		default:
+			o.set(c.key, r)
+		}
+	}
+	return o
+}
+
+// optional returns s, or nil when it is empty.
+func optional(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+type schemaIntro struct{ s *ast.Schema }
+
+func (schemaIntro) typeName() string { return "__Schema" }
+
+func (i schemaIntro) resolve(field string, _ map[string]any) any {
+	switch field {
+	case "types":
+		var types []introspected
+		for _, name := range sortedKeys(i.s.Types) {
+			types = append(types, typeIntro{s: i.s, def: i.s.Types[name]})
+		}
+		return types
+	case "queryType":
+		return typeIntro{s: i.s, def: i.s.Query}
+	case "mutationType":
+		if i.s.Mutation != nil {
+			return typeIntro{s: i.s, def: i.s.Mutation}
+		}
+	case "directives":
+		var dirs []introspected
+		for _, name := range sortedKeys(i.s.Directives) {
+			dirs = append(dirs, directiveIntro{i.s, i.s.Directives[name]})
+		}
+		return dirs
+	}
+	return nil
+}
+
+// typeIntro is a named type, def, or a list or non-null wrapper, t.
+type typeIntro struct {
+	s   *ast.Schema
+	def *ast.Definition
+	t   *ast.Type
+}
+
+// typeOf returns the introspection type of t.
+func typeOf(s *ast.Schema, t *ast.Type) typeIntro {
+	if t.NonNull || t.Elem != nil {
+		return typeIntro{s: s, t: t}
+	}
+	return typeIntro{s: s, def: s.Types[t.NamedType]}
+}
+
+func (typeIntro) typeName() string { return "__Type" }
+
+func (i typeIntro) resolve(field string, _ map[string]any) any {
+	if i.t != nil {
+		switch field {
+		case "kind":
+			if i.t.NonNull {
+				return "NON_NULL"
+			}
+			return "LIST"
+		case "ofType":
+			if i.t.NonNull {
+				nullable := *i.t
+				nullable.NonNull = false
+				return typeOf(i.s, &nullable)
+			}
+			return typeOf(i.s, i.t.Elem)
+		}
+		return nil
+	}
+	d := i.def
+	switch field {
+	case "kind":
+		return string(d.Kind)
+	case "name":
+		return d.Name
+	case "description":
+		return optional(d.Description)
+	case "fields":
+		if d.Kind != ast.Object && d.Kind != ast.Interface {
+			return nil
+		}
+		fields := []introspected{}
+		for _, f := range d.Fields {
+			if !strings.HasPrefix(f.Name, "__") {
+				fields = append(fields, fieldIntro{i.s, f})
+			}
+		}
+		return fields
+	case "inputFields":
+		if d.Kind != ast.InputObject {
+			return nil
+		}
+		fields := []introspected{}
+		for _, f := range d.Fields {
+			fields = append(fields, inputValueIntro{i.s, f.Name, f.Description, f.Type, f.DefaultValue})
+		}
+		return fields
+	case "interfaces":
+		if d.Kind != ast.Object {
+			return nil
+		}
+		ifaces := []introspected{}
+		for _, name := range d.Interfaces {
+			ifaces = append(ifaces, typeIntro{s: i.s, def: i.s.Types[name]})
+		}
+		return ifaces
+	case "possibleTypes":
+		if !d.IsAbstractType() {
+			return nil
+		}
+		var types []introspected
+		for _, t := range i.s.GetPossibleTypes(d) {
+			types = append(types, typeIntro{s: i.s, def: t})
+		}
+		return types
+	case "enumValues":
+		if d.Kind != ast.Enum {
+			return nil
+		}
+		values := []introspected{}
+		for _, v := range d.EnumValues {
+			values = append(values, enumValueIntro{v})
+		}
+		return values
+	}
+	return nil
+}
+
+type fieldIntro struct {
+	s *ast.Schema
+	f *ast.FieldDefinition
+}
+
+func (fieldIntro) typeName() string { return "__Field" }
+
+func (i fieldIntro) resolve(field string, _ map[string]any) any {
+	switch field {
+	case "name":
+		return i.f.Name
+	case "description":
+		return optional(i.f.Description)
+	case "args":
+		return arguments(i.s, i.f.Arguments)
+	case "type":
+		return typeOf(i.s, i.f.Type)
+	case "isDeprecated":
+		return i.f.Directives.ForName("deprecated") != nil
+	case "deprecationReason":
+		return deprecationReason(i.f.Directives)
+	}
+	return nil
+}
+
+func arguments(s *ast.Schema, args ast.ArgumentDefinitionList) []introspected {
+	out := []introspected{}
+	for _, a := range args {
+		out = append(out, inputValueIntro{s, a.Name, a.Description, a.Type, a.DefaultValue})
+	}
+	return out
+}
+
+func deprecationReason(dirs ast.DirectiveList) any {
+	d := dirs.ForName("deprecated")
+	if d == nil {
+		return nil
+	}
+	if reason := d.Arguments.ForName("reason"); reason != nil {
+		return reason.Value.Raw
+	}
+	return "No longer supported"
+}
+
+type inputValueIntro struct {
+	s           *ast.Schema
+	name        string
+	description string
+	typ         *ast.Type
+	def         *ast.Value
+}
+
+func (inputValueIntro) typeName() string { return "__InputValue" }
+
+func (i inputValueIntro) resolve(field string, _ map[string]any) any {
+	switch field {
+	case "name":
+		return i.name
+	case "description":
+		return optional(i.description)
+	case "type":
+		return typeOf(i.s, i.typ)
+	case "defaultValue":
+		if i.def != nil {
+			return i.def.String()
+		}
+	}
+	return nil
+}
+
+type enumValueIntro struct{ v *ast.EnumValueDefinition }
+
+func (enumValueIntro) typeName() string { return "__EnumValue" }
+
+func (i enumValueIntro) resolve(field string, _ map[string]any) any {
+	switch field {
+	case "name":
+		return i.v.Name
+	case "description":
+		return optional(i.v.Description)
+	case "isDeprecated":
+		return i.v.Directives.ForName("deprecated") != nil
+	case "deprecationReason":
+		return deprecationReason(i.v.Directives)
+	}
+	return nil
+}
+
+type directiveIntro struct {
+	s *ast.Schema
+	d *ast.DirectiveDefinition
+}
+
+func (directiveIntro) typeName() string { return "__Directive" }
+
+func (i directiveIntro) resolve(field string, _ map[string]any) any {
+	switch field {
+	case "name":
+		return i.d.Name
+	case "description":
+		return optional(i.d.Description)
+	case "locations":
+		locs := make([]string, len(i.d.Locations))
+		for j, l := range i.d.Locations {
+			locs[j] = string(l)
+		}
+		slices.Sort(locs)
+		return locs
+	case "args":
+		return arguments(i.s, i.d.Arguments)
+	case "isRepeatable":
+		return false
+	}
+	return nil
+}
@@ -0,0 +1,468 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+// entity is one entity type: its GraphQL shape, derived from the struct, and
+// the typed sub-client calls its mutations map to.
+type entity struct {
+	name   string // the GraphQL type, and the Dgraph type
+	one    string // the root field getting one node, e.g. film
+	many   string // the root field listing nodes, e.g. films
+	model  reflect.Type
+	fields []*field
+	search bool
+
+	add         func(ctx context.Context, input map[string]any) (string, error)
+	update      func(ctx context.Context, uid string, input map[string]any) error
+	delete      func(ctx context.Context, uid string) error
+	deleteWhere func(ctx context.Context, filter string, opts ...movies.DeleteOption) ([]string, error)
+}
+
+type fieldKind int
+
+const (
+	scalarField fieldKind = iota
+	valueField            // a struct without a UID, such as GeoPoint
+	edgeField
+)
+
+// field is an entity struct field other than uid and dgraph.type.
+type field struct {
+	name      string // the JSON name, which is also the GraphQL name
+	predicate string
+	kind      fieldKind
+	typ       string // the GraphQL type of a scalar or value field
+	goType    reflect.Type
+	target    *entity // what an edge links to
+}
+
+func (f *field) reverse() bool { return strings.HasPrefix(f.predicate, "~") }
+
+func (e *entity) field(name string) *field {
+	for _, f := range e.fields {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// entities returns the served entity types. A zero Client is enough for
+// describing them, as Schema does.
+func entities(c *movies.Client) []*entity {
+	all := []*entity{
+		newEntity[movies.Actor]("Actor", "actor", "actors", c.Actor),
+		newEntity[movies.ContentRating]("ContentRating", "contentRating", "contentRatings", c.ContentRating),
+		newEntity[movies.Country]("Country", "country", "countries", c.Country),
+		newEntity[movies.Director]("Director", "director", "directors", c.Director),
+		newEntity[movies.Film]("Film", "film", "films", c.Film),
+		newEntity[movies.Genre]("Genre", "genre", "genres", c.Genre),
+		newEntity[movies.Location]("Location", "location", "locations", c.Location),
+		newEntity[movies.Performance]("Performance", "performance", "performances", c.Performance),
+		newEntity[movies.Rating]("Rating", "rating", "ratings", c.Rating),
+	}
+	for _, e := range all {
+		for _, f := range e.fields {
+			if f.kind != edgeField {
+				continue
+			}
+			for _, t := range all {
+				if t.model == f.goType {
+					f.target = t
+				}
+			}
+		}
+	}
+	return all
+}
+
+// entityClient is the method set of the generated sub-clients that
+// mutations use.
+type entityClient[T any] interface {
+	Add(ctx context.Context, v *T) error
+	Update(ctx context.Context, v *T) error
+	Delete(ctx context.Context, uid string) error
+	DeleteWhere(ctx context.Context, filter string, opts ...movies.DeleteOption) ([]string, error)
+}
+
+// searcher is implemented by the sub-clients of types with a name index.
+type searcher[T any] interface {
+	Search(ctx context.Context, term string, opts ...movies.PageOption) ([]T, error)
+}
+
+func newEntity[T any](name, one, many string, c entityClient[T]) *entity {
+	e := &entity{name: name, one: one, many: many, model: reflect.TypeFor[T]()}
+	_, e.search = c.(searcher[T])
+	for _, sf := range reflect.VisibleFields(e.model) {
+		jn := jsonName(sf)
+		if jn == "" || jn == "-" || jn == "uid" || jn == "dgraph.type" {
+			continue
+		}
+		f := &field{name: jn, predicate: predicateOf(sf), goType: sf.Type}
+		switch t := entityType(sf.Type); {
+		case t != nil:
+			f.kind, f.goType = edgeField, t
+		case valueType(sf.Type) != nil:
+			f.kind, f.goType = valueField, valueType(sf.Type)
+			f.typ = f.goType.Name()
+		default:
+			f.typ = scalarType(sf.Type)
+		}
+		e.fields = append(e.fields, f)
+	}
+
+	// decode turns a mutation input into a T: edges are given as UIDs.
+	decode := func(input map[string]any) (*T, error) {
+		m := map[string]any{}
+		for k, v := range input {
+			f := e.field(k)
+			switch {
+			case f == nil:
+				return nil, fmt.Errorf("%s has no field %s", name, k)
+			case v == nil:
+				continue
+			case f.kind == edgeField:
+				var links []map[string]string
+				for _, id := range v.([]any) {
+					uid := fmt.Sprint(id)
+					if !movies.IsUID(uid) {
+						return nil, fmt.Errorf("%s: %q is not a UID", k, uid)
+					}
+					links = append(links, map[string]string{"uid": uid})
+				}
+				m[k] = links
+			case f.typ == "DateTime":
+				t, err := parseDateTime(fmt.Sprint(v))
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", k, err)
+				}
+				m[k] = t
+			default:
+				m[k] = v
+			}
+		}
+		b, err := json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		v := new(T)
+		if err := json.Unmarshal(b, v); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		return v, nil
+	}
+	e.add = func(ctx context.Context, input map[string]any) (string, error) {
+		v, err := decode(input)
+		if err != nil {
+			return "", err
+		}
+		reflect.ValueOf(v).Elem().FieldByName("DType").Set(reflect.ValueOf([]string{name}))
+		if err := c.Add(ctx, v); err != nil {
+			return "", err
+		}
+		return reflect.ValueOf(v).Elem().FieldByName("UID").String(), nil
+	}
+	e.update = func(ctx context.Context, uid string, input map[string]any) error {
+		v, err := decode(input)
+		if err != nil {
+			return err
+		}
+		reflect.ValueOf(v).Elem().FieldByName("UID").SetString(uid)
+		return c.Update(ctx, v)
+	}
+	e.delete = c.Delete
+	e.deleteWhere = c.DeleteWhere
+	return e
+}
+
+// Schema returns the GraphQL schema Handler serves, in SDL, derived from
+// the entity structs.
+func Schema() string {
+	return schemaSDL(entities(&movies.Client{}))
+}
+
+const (
+	// defaultFirst is the page size of root lists.
+	defaultFirst = 20
+	// maxFirst caps the first argument of lists.
+	maxFirst = 1000
+)
+
+// scalarFilters are the filter inputs of each scalar type, by DQL function.
+var scalarFilters = map[string]string{
+	"String": `"""Matches string values; every given condition must hold."""
+input StringFilter {
+  "Equal to, on a hash or exact index."
+  eq: String
+  "Equal to one of, on a hash or exact index."
+  in: [String!]
+  "Has all of the terms, on a term index."
+  allofterms: String
+  "Has any of the terms, on a term index."
+  anyofterms: String
+  "Has all of the words, stemmed, on a fulltext index."
+  alloftext: String
+  "Has any of the words, stemmed, on a fulltext index."
+  anyoftext: String
+  "Matches the regular expression, of at least 3 characters, on a trigram index."
+  regexp: String
+}
+`,
+	"DateTime": `"""Matches date and time values; every given condition must hold."""
+input DateTimeFilter {
+  eq: DateTime
+  lt: DateTime
+  le: DateTime
+  gt: DateTime
+  ge: DateTime
+}
+`,
+	"Int": `"""Matches integer values; every given condition must hold."""
+input IntFilter {
+  eq: Int
+  lt: Int
+  le: Int
+  gt: Int
+  ge: Int
+}
+`,
+	"Float": `"""Matches float values; every given condition must hold."""
+input FloatFilter {
+  eq: Float
+  lt: Float
+  le: Float
+  gt: Float
+  ge: Float
+}
+`,
+	"Boolean": `"""Matches boolean values."""
+input BooleanFilter {
+  eq: Boolean
+}
+`,
+}
+
+// schemaSDL writes the schema of ents.
+func schemaSDL(ents []*entity) string {
+	var b strings.Builder
+	b.WriteString(`"""An RFC 3339 date and time. Inputs may also be a bare date such as 1999-03-31."""
+scalar DateTime
+
+`)
+	values := map[string]reflect.Type{}
+	filters := map[string]bool{}
+	for _, e := range ents {
+		for _, f := range e.fields {
+			switch f.kind {
+			case valueField:
+				values[f.typ] = f.goType
+			case scalarField:
+				filters[f.typ] = scalarFilters[f.typ] != ""
+			}
+		}
+	}
+	for _, name := range sortedKeys(filters) {
+		if filters[name] {
+			b.WriteString(scalarFilters[name] + "\n")
+		}
+	}
+	for _, name := range sortedKeys(values) {
+		writeValueTypes(&b, values[name])
+	}
+
+	for _, e := range ents {
+		fmt.Fprintf(&b, "\"\"\"%s %s node.\"\"\"\ntype %s {\n  uid: ID!\n", upperFirst(article(e.name)), e.name, e.name)
+		for _, f := range e.fields {
+			if f.kind != edgeField {
+				fmt.Fprintf(&b, "  %s: %s\n", f.name, f.typ)
+				continue
+			}
+			if f.reverse() {
+				fmt.Fprintf(&b, "  \"Reverse edge: the %s nodes linking here by %s.\"\n", f.target.name, strings.TrimPrefix(f.predicate, "~"))
+			}
+			fmt.Fprintf(&b, "  %s(filter: %[2]sFilter, order: %[2]sOrder, first: Int, offset: Int = 0): [%[2]s!]!\n", f.name, f.target.name)
+			fmt.Fprintf(&b, "  \"The number of %s.\"\n  %sCount: Int!\n", f.name, f.name)
+		}
+		b.WriteString("}\n\n")
+
+		fmt.Fprintf(&b, "\"\"\"Matches %s nodes; every given condition must hold.\"\"\"\ninput %[2]sFilter {\n  uid: [ID!]\n", e.name, e.name)
+		for _, f := range e.fields {
+			if f.kind == scalarField && filters[f.typ] {
+				fmt.Fprintf(&b, "  %s: %sFilter\n", f.name, f.typ)
+			}
+		}
+		fmt.Fprintf(&b, "  \"Has a value for each of these fields.\"\n  has: [%[1]sField!]\n  and: [%[1]sFilter!]\n  or: [%[1]sFilter!]\n  not: %[1]sFilter\n}\n\n", e.name)
+
+		fmt.Fprintf(&b, "enum %sField {\n", e.name)
+		for _, f := range e.fields {
+			fmt.Fprintf(&b, "  %s\n", f.name)
+		}
+		b.WriteString("}\n\n")
+
+		fmt.Fprintf(&b, "enum %sOrderField {\n", e.name)
+		for _, f := range e.fields {
+			if f.kind == scalarField {
+				fmt.Fprintf(&b, "  %s\n", f.name)
+			}
+		}
+		b.WriteString("}\n\n")
+		fmt.Fprintf(&b, "\"\"\"Sorts by asc or desc, then by then.\"\"\"\ninput %[1]sOrder {\n  asc: %[1]sOrderField\n  desc: %[1]sOrderField\n  then: %[1]sOrder\n}\n\n", e.name)
+
+		fmt.Fprintf(&b, "\"\"\"The fields of %s %s to set. Edges are given as UIDs, and updates add them.\"\"\"\ninput %[2]sInput {\n", article(e.name), e.name)
+		for _, f := range e.fields {
+			switch {
+			case f.kind == edgeField && !f.reverse():
+				fmt.Fprintf(&b, "  %s: [ID!]\n", f.name)
+			case f.kind == valueField:
+				fmt.Fprintf(&b, "  %s: %sInput\n", f.name, f.typ)
+			case f.kind == scalarField:
+				fmt.Fprintf(&b, "  %s: %s\n", f.name, f.typ)
+			}
+		}
+		b.WriteString("}\n\n")
+	}
+
+	b.WriteString("type Query {\n")
+	for _, e := range ents {
+		fmt.Fprintf(&b, "  \"The %s with this UID.\"\n  %s(uid: ID!): %s\n", e.name, e.one, e.name)
+		search, searchArg := "", ""
+		if e.search {
+			search, searchArg = " Search does a fulltext match on name.", "search: String, "
+		}
+		fmt.Fprintf(&b, "  \"%s nodes, %d by default and at most %d.%s\"\n", e.name, defaultFirst, maxFirst, search)
+		fmt.Fprintf(&b, "  %s(%sfilter: %sFilter, order: %[3]sOrder, first: Int = %[4]d, offset: Int = 0): [%[3]s!]!\n", e.many, searchArg, e.name, defaultFirst)
+		fmt.Fprintf(&b, "  \"The number of %s nodes matching.\"\n  %sCount(%sfilter: %sFilter): Int!\n", e.name, e.many, searchArg, e.name)
+	}
+	b.WriteString("}\n\ntype Mutation {\n")
+	for _, e := range ents {
+		fmt.Fprintf(&b, "  add%[1]s(input: %[1]sInput!): %[1]s\n", e.name)
+		fmt.Fprintf(&b, "  \"Sets the given fields and adds the given edges.\"\n  update%[1]s(uid: ID!, input: %[1]sInput!): %[1]s\n", e.name)
+		fmt.Fprintf(&b, "  \"Deletes the %[1]s and returns its UID.\"\n  delete%[1]s(uid: ID!): ID\n", e.name)
+		fmt.Fprintf(&b, "  \"Deletes every %s matching and returns their UIDs.\"\n  delete%s(filter: %[1]sFilter!): [ID!]\n", e.name, upperFirst(e.many))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// writeValueTypes writes the output and input types of a value struct.
+func writeValueTypes(b *strings.Builder, t reflect.Type) {
+	for _, kind := range []string{"type", "input"} {
+		name := t.Name()
+		if kind == "input" {
+			name += "Input"
+		}
+		fmt.Fprintf(b, "%s %s {\n", kind, name)
+		for _, sf := range reflect.VisibleFields(t) {
+			fmt.Fprintf(b, "  %s: %s!\n", jsonName(sf), scalarType(sf.Type))
+		}
+		b.WriteString("}\n\n")
+	}
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// scalarType returns the GraphQL type of a Go scalar, or of a slice of them.
+func scalarType(t reflect.Type) string {
+	switch {
+	case t == timeType:
+		return "DateTime"
+	case t.Kind() == reflect.String:
+		return "String"
+	case t.Kind() == reflect.Bool:
+		return "Boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return "Int"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return "Float"
+	case t.Kind() == reflect.Slice:
+		return "[" + scalarType(t.Elem()) + "!]"
+	}
+	return "String"
+}
+
+// parseDateTime accepts RFC 3339 and bare dates.
+func parseDateTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an RFC 3339 date and time or a date", s)
+	}
+	return t, nil
+}
+
+// jsonName returns the JSON field name of f.
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+// predicateOf returns the Dgraph predicate of f: its predicate= directive,
+// or else its JSON name.
+func predicateOf(f reflect.StructField) string {
+	for d := range strings.FieldsSeq(f.Tag.Get("dgraph")) {
+		if p, ok := strings.CutPrefix(d, "predicate="); ok {
+			return p
+		}
+	}
+	return jsonName(f)
+}
+
+// entityType returns the entity a field of type t links to, or nil when t
+// is not an edge. Entities are the structs with a UID.
+func entityType(t reflect.Type) reflect.Type {
+	if t.Kind() != reflect.Slice {
+		return nil
+	}
+	t = t.Elem()
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if _, ok := t.FieldByName("UID"); !ok {
+		return nil
+	}
+	return t
+}
+
+// valueType returns the struct a field of type t holds by value, or nil.
+func valueType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil
+	}
+	return t
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// article returns "a" or "an" for name.
+func article(name string) string {
+	if strings.ContainsRune("AEIOU", rune(name[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+func upperFirst(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
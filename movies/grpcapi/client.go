@@ -0,0 +1,153 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"reflect"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+// Client calls the movies services over a gRPC connection, with the same
+// entity structs as movies.Client.
+type Client struct {
+	Actor         *EntityClient[movies.Actor]
+	ContentRating *EntityClient[movies.ContentRating]
+	Country       *EntityClient[movies.Country]
+	Director      *EntityClient[movies.Director]
+	Film          *EntityClient[movies.Film]
+	Genre         *EntityClient[movies.Genre]
+	Location      *EntityClient[movies.Location]
+	Performance   *EntityClient[movies.Performance]
+	Rating        *EntityClient[movies.Rating]
+}
+
+// NewClient returns a client calling the services over conn, e.g. a
+// *grpc.ClientConn.
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{
+		Actor:         newEntityClient[movies.Actor](conn, "Actor"),
+		ContentRating: newEntityClient[movies.ContentRating](conn, "ContentRating"),
+		Country:       newEntityClient[movies.Country](conn, "Country"),
+		Director:      newEntityClient[movies.Director](conn, "Director"),
+		Film:          newEntityClient[movies.Film](conn, "Film"),
+		Genre:         newEntityClient[movies.Genre](conn, "Genre"),
+		Location:      newEntityClient[movies.Location](conn, "Location"),
+		Performance:   newEntityClient[movies.Performance](conn, "Performance"),
+		Rating:        newEntityClient[movies.Rating](conn, "Rating"),
+	}
+}
+
+// EntityClient calls the service of one entity type. Errors are gRPC
+// statuses; status.Code tells them apart.
+type EntityClient[T any] struct {
+	conn    grpc.ClientConnInterface
+	service string
+	entity  protoreflect.MessageDescriptor
+}
+
+func newEntityClient[T any](conn grpc.ClientConnInterface, name string) *EntityClient[T] {
+	return &EntityClient[T]{
+		conn:    conn,
+		service: "/" + protoPackage + "." + name + "Service/",
+		entity:  message(name),
+	}
+}
+
+// Get returns the node with the given UID.
+func (c *EntityClient[T]) Get(ctx context.Context, uid string) (*T, error) {
+	req := dynamicpb.NewMessage(message("GetRequest"))
+	req.Set(req.Descriptor().Fields().ByName("uid"), protoreflect.ValueOfString(uid))
+	resp := dynamicpb.NewMessage(c.entity)
+	if err := c.conn.Invoke(ctx, c.service+"Get", req, resp); err != nil {
+		return nil, err
+	}
+	return c.read(resp), nil
+}
+
+// Add inserts v, which must not have a UID, and sets v to the stored node.
+func (c *EntityClient[T]) Add(ctx context.Context, v *T) error {
+	return c.save(ctx, "Add", v)
+}
+
+// Update stores v over the node with its UID, and sets v to the result.
+func (c *EntityClient[T]) Update(ctx context.Context, v *T) error {
+	return c.save(ctx, "Update", v)
+}
+
+func (c *EntityClient[T]) save(ctx context.Context, method string, v *T) error {
+	resp := dynamicpb.NewMessage(c.entity)
+	if err := c.conn.Invoke(ctx, c.service+method, toMessage(v, c.entity), resp); err != nil {
+		return err
+	}
+	*v = *c.read(resp)
+	return nil
+}
+
+// Delete deletes the nodes with the given UIDs. Nothing is deleted if one
+// of them is not found.
+func (c *EntityClient[T]) Delete(ctx context.Context, uids ...string) error {
+	req := dynamicpb.NewMessage(message("DeleteRequest"))
+	list := req.Mutable(req.Descriptor().Fields().ByName("uids")).List()
+	for _, uid := range uids {
+		list.Append(protoreflect.ValueOfString(uid))
+	}
+	return c.conn.Invoke(ctx, c.service+"Delete", req, dynamicpb.NewMessage(message("DeleteResponse")))
+}
+
+// ListIter streams every node of the type. Breaking out of the loop ends
+// the stream.
+func (c *EntityClient[T]) ListIter(ctx context.Context) iter.Seq2[T, error] {
+	return c.stream(ctx, "List", dynamicpb.NewMessage(message("ListRequest")))
+}
+
+// SearchIter streams the nodes whose name matches term. The call fails
+// with codes.Unimplemented for types without a name index.
+func (c *EntityClient[T]) SearchIter(ctx context.Context, term string) iter.Seq2[T, error] {
+	req := dynamicpb.NewMessage(message("SearchRequest"))
+	req.Set(req.Descriptor().Fields().ByName("term"), protoreflect.ValueOfString(term))
+	return c.stream(ctx, "Search", req)
+}
+
+func (c *EntityClient[T]) stream(ctx context.Context, method string, req *dynamicpb.Message) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var zero T
+		s, err := c.conn.NewStream(ctx, &grpc.StreamDesc{StreamName: method, ServerStreams: true}, c.service+method)
+		if err == nil {
+			err = s.SendMsg(req)
+		}
+		if err == nil {
+			err = s.CloseSend()
+		}
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		for {
+			resp := dynamicpb.NewMessage(c.entity)
+			err := s.RecvMsg(resp)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(*c.read(resp), nil) {
+				return
+			}
+		}
+	}
+}
+
+func (c *EntityClient[T]) read(m *dynamicpb.Message) *T {
+	return fromMessage(m, reflect.TypeFor[T]()).(*T)
+}
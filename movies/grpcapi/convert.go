@@ -0,0 +1,124 @@
+package grpcapi
+
+import (
+	"reflect"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// toMessage copies the entity struct v points to into a message of desc.
+// Fields match by JSON name.
+func toMessage(v any, desc protoreflect.MessageDescriptor) *dynamicpb.Message {
+	m := dynamicpb.NewMessage(desc)
+	fill(m, reflect.ValueOf(v).Elem())
+	return m
+}
+
+func fill(m protoreflect.Message, v reflect.Value) {
+	fields := m.Descriptor().Fields()
+	for _, sf := range reflect.VisibleFields(v.Type()) {
+		fd := fields.ByJSONName(jsonName(sf))
+		if fd == nil {
+			continue
+		}
+		fv := v.FieldByIndex(sf.Index)
+		switch {
+		case fv.Kind() == reflect.Pointer && fv.IsNil():
+		case fd.IsList():
+			if fv.Len() == 0 {
+				continue
+			}
+			list := m.Mutable(fd).List()
+			for i := range fv.Len() {
+				list.Append(protoValue(fd, fv.Index(i), list.NewElement))
+			}
+		case fv.Type() == timeType && fv.Interface().(time.Time).IsZero():
+		default:
+			m.Set(fd, protoValue(fd, fv, func() protoreflect.Value { return m.NewField(fd) }))
+		}
+	}
+}
+
+// protoValue converts v to a value of field fd; newMessage makes the
+// message of a message field.
+func protoValue(fd protoreflect.FieldDescriptor, v reflect.Value, newMessage func() protoreflect.Value) protoreflect.Value {
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(v.Bool())
+	case protoreflect.Int64Kind:
+		return protoreflect.ValueOfInt64(v.Int())
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(v.Float())
+	case protoreflect.MessageKind:
+		mv := newMessage()
+		m := mv.Message()
+		if t, ok := v.Interface().(time.Time); ok {
+			m.Set(m.Descriptor().Fields().ByName("seconds"), protoreflect.ValueOfInt64(t.Unix()))
+			m.Set(m.Descriptor().Fields().ByName("nanos"), protoreflect.ValueOfInt32(int32(t.Nanosecond())))
+		} else {
+			fill(m, v)
+		}
+		return mv
+	}
+	return protoreflect.ValueOfString(v.String())
+}
+
+// fromMessage returns a new struct of type t, read from m.
+func fromMessage(m protoreflect.Message, t reflect.Type) any {
+	v := reflect.New(t)
+	read(v.Elem(), m)
+	return v.Interface()
+}
+
+func read(v reflect.Value, m protoreflect.Message) {
+	fields := m.Descriptor().Fields()
+	for _, sf := range reflect.VisibleFields(v.Type()) {
+		fd := fields.ByJSONName(jsonName(sf))
+		if fd == nil || !m.Has(fd) {
+			continue
+		}
+		fv := v.FieldByIndex(sf.Index)
+		if fd.IsList() {
+			list := m.Get(fd).List()
+			s := reflect.MakeSlice(fv.Type(), list.Len(), list.Len())
+			for i := range list.Len() {
+				goValue(s.Index(i), fd, list.Get(i))
+			}
+			fv.Set(s)
+			continue
+		}
+		goValue(fv, fd, m.Get(fd))
+	}
+}
+
+// goValue stores the value pv of field fd in v.
+func goValue(v reflect.Value, fd protoreflect.FieldDescriptor, pv protoreflect.Value) {
+	if v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		v.SetBool(pv.Bool())
+	case protoreflect.Int64Kind:
+		v.SetInt(pv.Int())
+	case protoreflect.DoubleKind:
+		v.SetFloat(pv.Float())
+	case protoreflect.StringKind:
+		v.SetString(pv.String())
+	case protoreflect.MessageKind:
+		m := pv.Message()
+		if v.Type() == timeType {
+			secs := m.Get(m.Descriptor().Fields().ByName("seconds")).Int()
+			nanos := m.Get(m.Descriptor().Fields().ByName("nanos")).Int()
+			v.Set(reflect.ValueOf(time.Unix(secs, nanos).UTC()))
+			return
+		}
+		read(v, m)
+	}
+}
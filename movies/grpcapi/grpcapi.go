@@ -0,0 +1,334 @@
+// Package grpcapi serves the movies data model over gRPC, with a service
+// per entity:
+//
+//	service FilmService {
+//	  rpc Get(GetRequest) returns (Film);
+//	  rpc Add(Film) returns (Film);
+//	  rpc Update(Film) returns (Film);
+//	  rpc Delete(DeleteRequest) returns (DeleteResponse);
+//	  rpc List(ListRequest) returns (stream Film);
+//	  rpc Search(SearchRequest) returns (stream Film);
+//	}
+//
+// The messages and services are derived from the entity structs, as File
+// and Proto show; movies.proto in this directory is Proto's output, for
+// generating clients in other languages. Register serves them from a
+// movies.Client, and NewClient calls them from Go with the entity structs.
+// Messages are handled as dynamicpb messages, in the protobuf wire format,
+// so no generated code is needed on either side.
+package grpcapi
+
+//go:generate sh -c "go run ../cmd/movies grpc-serve proto > movies.proto"
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+
+	dg "github.com/dolan-in/dgman/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+// service serves one entity type. Its functions close over the typed
+// sub-client.
+type service struct {
+	name   string
+	model  reflect.Type
+	search bool
+
+	get    func(ctx context.Context, uid string) (any, error)
+	add    func(ctx context.Context, v any) (any, error)
+	update func(ctx context.Context, v any) (any, error)
+	delete func(ctx context.Context, uids []string) error
+	list   func(ctx context.Context) iter.Seq2[any, error]
+	find   func(ctx context.Context, term string) iter.Seq2[any, error]
+}
+
+// services returns the served entity types. A zero Client is enough for
+// describing them, as File does.
+func services(c *movies.Client) []*service {
+	return []*service{
+		newService[movies.Actor]("Actor", c.Actor),
+		newService[movies.ContentRating]("ContentRating", c.ContentRating),
+		newService[movies.Country]("Country", c.Country),
+		newService[movies.Director]("Director", c.Director),
+		newService[movies.Film]("Film", c.Film),
+		newService[movies.Genre]("Genre", c.Genre),
+		newService[movies.Location]("Location", c.Location),
+		newService[movies.Performance]("Performance", c.Performance),
+		newService[movies.Rating]("Rating", c.Rating),
+	}
+}
+
+// method is an RPC of a service. An empty in or out is the entity message.
+type method struct {
+	name    string
+	in, out string
+	stream  bool
+}
+
+func (s *service) methods() []method {
+	m := []method{
+		{name: "Get", in: "GetRequest"},
+		{name: "Add"},
+		{name: "Update"},
+		{name: "Delete", in: "DeleteRequest", out: "DeleteResponse"},
+		{name: "List", in: "ListRequest", stream: true},
+	}
+	if s.search {
+		m = append(m, method{name: "Search", in: "SearchRequest", stream: true})
+	}
+	return m
+}
+
+// entityClient is the method set of the generated sub-clients the service
+// uses.
+type entityClient[T any] interface {
+	Get(ctx context.Context, uid string) (*T, error)
+	Add(ctx context.Context, v *T) error
+	Update(ctx context.Context, v *T) error
+	DeleteWhere(ctx context.Context, filter string, opts ...movies.DeleteOption) ([]string, error)
+	ListIter(ctx context.Context) iter.Seq2[T, error]
+}
+
+// searcher is implemented by the sub-clients of types with a name index.
+type searcher[T any] interface {
+	FindIter(ctx context.Context, term string) iter.Seq2[T, error]
+}
+
+func newService[T any](name string, c entityClient[T]) *service {
+	s := &service{name: name, model: reflect.TypeFor[T]()}
+	sc, searchable := c.(searcher[T])
+	s.search = searchable
+
+	get := func(ctx context.Context, uid string) (*T, error) {
+		if !movies.IsUID(uid) {
+			return nil, status.Errorf(codes.InvalidArgument, "%q is not a UID", uid)
+		}
+		v, err := c.Get(ctx, uid)
+		if errors.Is(err, dg.ErrNodeNotFound) || err == nil && !slices.Contains(field[[]string](v, "DType"), name) {
+			return nil, status.Errorf(codes.NotFound, "no %s with UID %s", name, uid)
+		}
+		return v, err
+	}
+	// check refuses reverse edges, which are stored on the other node.
+	check := func(v *T) error {
+		rv := reflect.ValueOf(v).Elem()
+		for _, sf := range reflect.VisibleFields(s.model) {
+			if strings.HasPrefix(predicateOf(sf), "~") && rv.FieldByIndex(sf.Index).Len() > 0 {
+				return status.Errorf(codes.InvalidArgument, "%s is a reverse edge; set it on the other node", jsonName(sf))
+			}
+		}
+		return nil
+	}
+	seq := func(items iter.Seq2[T, error]) iter.Seq2[any, error] {
+		return func(yield func(any, error) bool) {
+			for v, err := range items {
+				if !yield(&v, err) {
+					return
+				}
+			}
+		}
+	}
+
+	s.get = func(ctx context.Context, uid string) (any, error) {
+		return get(ctx, uid)
+	}
+	s.add = func(ctx context.Context, a any) (any, error) {
+		v := a.(*T)
+		if uid := field[string](v, "UID"); uid != "" {
+			return nil, status.Errorf(codes.InvalidArgument, "a new %s cannot have a uid", name)
+		}
+		if err := check(v); err != nil {
+			return nil, err
+		}
+		reflect.ValueOf(v).Elem().FieldByName("DType").Set(reflect.ValueOf([]string{name}))
+		if err := c.Add(ctx, v); err != nil {
+			return nil, err
+		}
+		return get(ctx, field[string](v, "UID"))
+	}
+	s.update = func(ctx context.Context, a any) (any, error) {
+		v := a.(*T)
+		uid := field[string](v, "UID")
+		if err := check(v); err != nil {
+			return nil, err
+		}
+		if _, err := get(ctx, uid); err != nil {
+			return nil, err
+		}
+		if err := c.Update(ctx, v); err != nil {
+			return nil, err
+		}
+		return get(ctx, uid)
+	}
+	s.delete = func(ctx context.Context, uids []string) error {
+		for _, uid := range uids {
+			if _, err := get(ctx, uid); err != nil {
+				return err
+			}
+		}
+		// One mutation deletes them all, or none.
+		_, err := c.DeleteWhere(ctx, "uid("+strings.Join(uids, ", ")+")", movies.DeleteLimit(0))
+		return err
+	}
+	s.list = func(ctx context.Context) iter.Seq2[any, error] {
+		return seq(c.ListIter(ctx))
+	}
+	if searchable {
+		s.find = func(ctx context.Context, term string) iter.Seq2[any, error] {
+			return seq(sc.FindIter(ctx, term))
+		}
+	}
+	return s
+}
+
+// Register registers the service of every entity on s, backed by client.
+func Register(s grpc.ServiceRegistrar, client *movies.Client) {
+	for _, svc := range services(client) {
+		s.RegisterService(svc.desc(), client)
+	}
+}
+
+// desc describes the service for grpc.
+func (s *service) desc() *grpc.ServiceDesc {
+	name := protoPackage + "." + s.name + "Service"
+	entity := message(s.name)
+	sd := &grpc.ServiceDesc{
+		ServiceName: name,
+		HandlerType: (*any)(nil),
+		Metadata:    protoFile,
+	}
+	unary := func(m string, in protoreflect.MessageDescriptor, h func(context.Context, *dynamicpb.Message) (proto.Message, error)) {
+		sd.Methods = append(sd.Methods, grpc.MethodDesc{
+			MethodName: m,
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				req := dynamicpb.NewMessage(in)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req any) (any, error) {
+					resp, err := h(ctx, req.(*dynamicpb.Message))
+					return resp, statusOf(err)
+				}
+				if interceptor == nil {
+					return handler(ctx, req)
+				}
+				return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + name + "/" + m}, handler)
+			},
+		})
+	}
+	stream := func(m string, in protoreflect.MessageDescriptor, h func(context.Context, *dynamicpb.Message) (iter.Seq2[any, error], error)) {
+		sd.Streams = append(sd.Streams, grpc.StreamDesc{
+			StreamName:    m,
+			ServerStreams: true,
+			Handler: func(_ any, ss grpc.ServerStream) error {
+				req := dynamicpb.NewMessage(in)
+				if err := ss.RecvMsg(req); err != nil {
+					return err
+				}
+				items, err := h(ss.Context(), req)
+				if err != nil {
+					return statusOf(err)
+				}
+				limit := int(req.Get(in.Fields().ByName("limit")).Int())
+				sent := 0
+				for v, err := range items {
+					if err != nil {
+						return statusOf(err)
+					}
+					if err := ss.SendMsg(toMessage(v, entity)); err != nil {
+						return err
+					}
+					if sent++; sent == limit {
+						break
+					}
+				}
+				return nil
+			},
+		})
+	}
+
+	unary("Get", message("GetRequest"), func(ctx context.Context, req *dynamicpb.Message) (proto.Message, error) {
+		v, err := s.get(ctx, stringField(req, "uid"))
+		if err != nil {
+			return nil, err
+		}
+		return toMessage(v, entity), nil
+	})
+	unary("Add", entity, func(ctx context.Context, req *dynamicpb.Message) (proto.Message, error) {
+		v, err := s.add(ctx, fromMessage(req, s.model))
+		if err != nil {
+			return nil, err
+		}
+		return toMessage(v, entity), nil
+	})
+	unary("Update", entity, func(ctx context.Context, req *dynamicpb.Message) (proto.Message, error) {
+		v, err := s.update(ctx, fromMessage(req, s.model))
+		if err != nil {
+			return nil, err
+		}
+		return toMessage(v, entity), nil
+	})
+	unary("Delete", message("DeleteRequest"), func(ctx context.Context, req *dynamicpb.Message) (proto.Message, error) {
+		list := req.Get(req.Descriptor().Fields().ByName("uids")).List()
+		uids := make([]string, list.Len())
+		for i := range uids {
+			uids[i] = list.Get(i).String()
+		}
+		if len(uids) == 0 {
+			return nil, status.Error(codes.InvalidArgument, "give the UIDs to delete")
+		}
+		if err := s.delete(ctx, uids); err != nil {
+			return nil, err
+		}
+		return dynamicpb.NewMessage(message("DeleteResponse")), nil
+	})
+	stream("List", message("ListRequest"), func(ctx context.Context, _ *dynamicpb.Message) (iter.Seq2[any, error], error) {
+		return s.list(ctx), nil
+	})
+	if s.search {
+		stream("Search", message("SearchRequest"), func(ctx context.Context, req *dynamicpb.Message) (iter.Seq2[any, error], error) {
+			term := stringField(req, "term")
+			if term == "" {
+				return nil, status.Error(codes.InvalidArgument, "give a term to search for")
+			}
+			return s.find(ctx, term), nil
+		})
+	}
+	return sd
+}
+
+// statusOf turns an error into a gRPC status: errors that are statuses
+// already are kept, and context errors get their codes.
+func statusOf(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func stringField(m protoreflect.Message, name string) string {
+	return m.Get(m.Descriptor().Fields().ByName(protoreflect.Name(name))).String()
+}
+
+// field returns the named field of the struct v points to.
+func field[V any](v any, name string) V {
+	return reflect.ValueOf(v).Elem().FieldByName(name).Interface().(V)
+}
@@ -0,0 +1,141 @@
+package grpcapi_test
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/grpcapi"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/internal/dgraphtest"
+)
+
+// serve registers the services of c on an in-memory server and returns a
+// client of it. Both are stopped when t ends.
+func serve(t *testing.T, c *movies.Client) *grpcapi.Client {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	grpcapi.Register(srv, c)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return grpcapi.NewClient(conn)
+}
+
+// TestServerRefuses checks the calls the services answer before reaching
+// the database, so a zero Client serves them.
+func TestServerRefuses(t *testing.T) {
+	gc := serve(t, &movies.Client{})
+	ctx := context.Background()
+
+	if _, err := gc.Film.Get(ctx, "matrix"); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Get of a bad UID: expected InvalidArgument, got %v", err)
+	}
+	if err := gc.Genre.Add(ctx, &movies.Genre{UID: "0x1", Name: "Noir"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Add with a UID: expected InvalidArgument, got %v", err)
+	}
+	err := gc.Genre.Add(ctx, &movies.Genre{Name: "Noir", Films: []movies.Film{{UID: "0x2"}}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Add with a reverse edge: expected InvalidArgument, got %v", err)
+	}
+	if err := gc.Genre.Update(ctx, &movies.Genre{UID: "0x1", Films: []movies.Film{{UID: "0x2"}}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Update of a reverse edge: expected InvalidArgument, got %v", err)
+	}
+	if err := gc.Genre.Delete(ctx, "matrix"); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Delete of a bad UID: expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	c := dgraphtest.Client(t)
+	ctx := context.Background()
+	noir := &movies.Genre{Name: "gRPC Test Noir"}
+	heist := &movies.Genre{Name: "gRPC Test Heist"}
+	for _, g := range []*movies.Genre{noir, heist} {
+		if err := c.Genre.Add(ctx, g); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = c.Genre.Delete(ctx, g.UID) })
+	}
+	rising := &movies.Film{Name: "Vextra Rising", InitialReleaseDate: time.Date(1999, 3, 31, 0, 0, 0, 0, time.UTC), Genres: []movies.Genre{*noir, *heist}}
+	returns := &movies.Film{Name: "Vextra Returns", Genres: []movies.Genre{*noir}}
+	for _, f := range []*movies.Film{rising, returns} {
+		if err := c.Film.Add(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = c.Film.Delete(ctx, f.UID) })
+	}
+	gc := serve(t, c)
+
+	var names []string
+	for f, err := range gc.Film.SearchIter(ctx, "Vextra") {
+		if err != nil {
+			t.Fatalf("SearchIter: %v", err)
+		}
+		names = append(names, f.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"Vextra Returns", "Vextra Rising"}) {
+		t.Fatalf("expected both Vextra films, got %v", names)
+	}
+
+	listed := 0
+	for g, err := range gc.Genre.ListIter(ctx) {
+		if err != nil {
+			t.Fatalf("ListIter: %v", err)
+		}
+		if g.UID == "" {
+			t.Errorf("expected listed genres to have UIDs, got %+v", g)
+		}
+		if listed++; listed == 2 {
+			break
+		}
+	}
+	if listed != 2 {
+		t.Errorf("expected ListIter to stream the genres, got %d", listed)
+	}
+
+	f, err := gc.Film.Get(ctx, rising.UID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !f.InitialReleaseDate.Equal(rising.InitialReleaseDate) || len(f.Genres) != 2 {
+		t.Errorf("unexpected film %+v", f)
+	}
+
+	g := &movies.Genre{Name: "gRPC Genre"}
+	if err := gc.Genre.Add(ctx, g); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if g.UID == "" {
+		t.Fatal("expected Add to set the UID")
+	}
+	g.Name = "gRPC Genre Renamed"
+	if err := gc.Genre.Update(ctx, g); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, err := c.Genre.Get(ctx, g.UID); err != nil || got.Name != "gRPC Genre Renamed" {
+		t.Errorf("expected the rename to be stored, got %+v, %v", got, err)
+	}
+	if err := gc.Genre.Delete(ctx, g.UID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := gc.Genre.Get(ctx, g.UID); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for the deleted genre, got %v", err)
+	}
+}
@@ -0,0 +1,315 @@
+package grpcapi
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+const (
+	// protoPackage is the proto package of the services and messages.
+	protoPackage = "movies.v1"
+	protoFile    = "movies.proto"
+	goPackage    = "github.com/mlwelles/modusGraphMoviesProject/movies/grpcapi"
+)
+
+var (
+	fileOnce sync.Once
+	file     protoreflect.FileDescriptor
+)
+
+// File returns the descriptor of movies.proto, derived from the entity
+// structs. It is registered in protoregistry.GlobalFiles, which server
+// reflection serves from.
+func File() protoreflect.FileDescriptor {
+	fileOnce.Do(func() {
+		fd, err := protodesc.NewFile(fileProto(services(&movies.Client{})), protoregistry.GlobalFiles)
+		if err != nil {
+			panic(fmt.Sprintf("grpcapi: building %s: %v", protoFile, err))
+		}
+		if err := protoregistry.GlobalFiles.RegisterFile(fd); err != nil {
+			panic(fmt.Sprintf("grpcapi: registering %s: %v", protoFile, err))
+		}
+		file = fd
+	})
+	return file
+}
+
+// message returns the descriptor of the named message in File.
+func message(name string) protoreflect.MessageDescriptor {
+	return File().Messages().ByName(protoreflect.Name(name))
+}
+
+var timestampName = "." + string((&timestamppb.Timestamp{}).ProtoReflect().Descriptor().FullName())
+
+// fileProto describes the messages and services of svcs. Entity fields are
+// numbered in struct order, so new fields must be added at the end of a
+// struct to keep the wire format compatible.
+func fileProto(svcs []*service) *descriptorpb.FileDescriptorProto {
+	f := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String(protoFile),
+		Package:    proto.String(protoPackage),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String(goPackage)},
+	}
+	values := map[string]reflect.Type{}
+	for _, s := range svcs {
+		f.MessageType = append(f.MessageType, messageProto(s.name, s.model, values))
+	}
+	for _, name := range sortedKeys(values) {
+		f.MessageType = append(f.MessageType, messageProto(name, values[name], values))
+	}
+	f.MessageType = append(f.MessageType,
+		&descriptorpb.DescriptorProto{Name: proto.String("GetRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+			scalarField("uid", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, false),
+		}},
+		&descriptorpb.DescriptorProto{Name: proto.String("ListRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+			scalarField("limit", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, false),
+		}},
+		&descriptorpb.DescriptorProto{Name: proto.String("SearchRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+			scalarField("term", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, false),
+			scalarField("limit", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, false),
+		}},
+		&descriptorpb.DescriptorProto{Name: proto.String("DeleteRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+			scalarField("uids", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, true),
+		}},
+		&descriptorpb.DescriptorProto{Name: proto.String("DeleteResponse")},
+	)
+	for _, s := range svcs {
+		entity := "." + protoPackage + "." + s.name
+		svc := &descriptorpb.ServiceDescriptorProto{Name: proto.String(s.name + "Service")}
+		for _, m := range s.methods() {
+			in, out := "."+protoPackage+"."+m.in, "."+protoPackage+"."+m.out
+			if m.in == "" {
+				in = entity
+			}
+			if m.out == "" {
+				out = entity
+			}
+			md := &descriptorpb.MethodDescriptorProto{Name: proto.String(m.name), InputType: proto.String(in), OutputType: proto.String(out)}
+			if m.stream {
+				md.ServerStreaming = proto.Bool(true)
+			}
+			svc.Method = append(svc.Method, md)
+		}
+		f.Service = append(f.Service, svc)
+	}
+	return f
+}
+
+// messageProto describes struct t as message name. Value structs it holds,
+// such as GeoPoint, are added to values.
+func messageProto(name string, t reflect.Type, values map[string]reflect.Type) *descriptorpb.DescriptorProto {
+	m := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+	n := int32(0)
+	for _, sf := range reflect.VisibleFields(t) {
+		jn := jsonName(sf)
+		if jn == "" || jn == "-" || jn == "dgraph.type" {
+			continue
+		}
+		n++
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		repeated := ft.Kind() == reflect.Slice
+		if repeated {
+			ft = ft.Elem()
+		}
+		fp := scalarField(jn, n, scalarProtoType(ft), repeated)
+		switch {
+		case ft == timeType:
+			fp.Type, fp.TypeName = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), proto.String(timestampName)
+		case ft.Kind() == reflect.Struct:
+			fp.Type, fp.TypeName = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), proto.String("."+protoPackage+"."+ft.Name())
+			if _, ok := ft.FieldByName("UID"); !ok {
+				values[ft.Name()] = ft
+			}
+		}
+		m.Field = append(m.Field, fp)
+	}
+	return m
+}
+
+func scalarField(jsonName string, n int32, typ descriptorpb.FieldDescriptorProto_Type, repeated bool) *descriptorpb.FieldDescriptorProto {
+	label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	if repeated {
+		label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	}
+	return &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(snake(jsonName)),
+		JsonName: proto.String(jsonName),
+		Number:   proto.Int32(n),
+		Label:    label.Enum(),
+		Type:     typ.Enum(),
+	}
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func scalarProtoType(t reflect.Type) descriptorpb.FieldDescriptorProto_Type {
+	switch {
+	case t.Kind() == reflect.Bool:
+		return descriptorpb.FieldDescriptorProto_TYPE_BOOL
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return descriptorpb.FieldDescriptorProto_TYPE_INT64
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return descriptorpb.FieldDescriptorProto_TYPE_DOUBLE
+	}
+	return descriptorpb.FieldDescriptorProto_TYPE_STRING
+}
+
+// Proto returns movies.proto, the source of File, for generating clients
+// in other languages.
+func Proto() string {
+	fd := File()
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by movies grpc-serve proto. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "syntax = \"proto3\";\n\npackage %s;\n\n", fd.Package())
+	for i := range fd.Imports().Len() {
+		fmt.Fprintf(&b, "import %q;\n", fd.Imports().Get(i).Path())
+	}
+	fmt.Fprintf(&b, "\noption go_package = %q;\n", goPackage)
+
+	for i := range fd.Messages().Len() {
+		m := fd.Messages().Get(i)
+		b.WriteString("\n")
+		if c := messageComments[string(m.Name())]; c != "" {
+			b.WriteString(c)
+		} else if fd.Services().ByName(m.Name()+"Service") != nil {
+			fmt.Fprintf(&b, "// %s %s node.\n", upperFirst(article(string(m.Name()))), m.Name())
+		}
+		fmt.Fprintf(&b, "message %s {\n", m.Name())
+		for j := range m.Fields().Len() {
+			f := m.Fields().Get(j)
+			if reverse[string(m.Name())+"."+f.JSONName()] {
+				b.WriteString("  // Reverse edge; set it on the other node.\n")
+			}
+			label := ""
+			if f.IsList() {
+				label = "repeated "
+			}
+			typ := f.Kind().String()
+			if f.Message() != nil {
+				typ = string(f.Message().FullName())
+				typ = strings.TrimPrefix(typ, protoPackage+".")
+			}
+			fmt.Fprintf(&b, "  %s%s %s = %d;\n", label, typ, f.Name(), f.Number())
+		}
+		b.WriteString("}\n")
+	}
+
+	for i := range fd.Services().Len() {
+		s := fd.Services().Get(i)
+		fmt.Fprintf(&b, "\nservice %s {\n", s.Name())
+		for j := range s.Methods().Len() {
+			m := s.Methods().Get(j)
+			stream := ""
+			if m.IsStreamingServer() {
+				stream = "stream "
+			}
+			fmt.Fprintf(&b, "  %s  rpc %s(%s) returns (%s%s);\n", methodComments[string(m.Name())], m.Name(), m.Input().Name(), stream, m.Output().Name())
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+var messageComments = map[string]string{
+	"GetRequest":     "// GetRequest names the node to get.\n",
+	"ListRequest":    "// ListRequest bounds a list; a limit of 0 streams every node.\n",
+	"SearchRequest":  "// SearchRequest is a fulltext search on name; a limit of 0 streams every match.\n",
+	"DeleteRequest":  "// DeleteRequest names the nodes to delete.\n",
+	"DeleteResponse": "// DeleteResponse is empty.\n",
+	"GeoPoint":       "// GeoPoint is a GeoJSON point: type \"Point\" and [longitude, latitude].\n",
+}
+
+var methodComments = map[string]string{
+	"Get":    "// Get returns the node with a UID.\n",
+	"Add":    "// Add stores a new node and returns it with its UID.\n",
+	"Update": "// Update sets the given fields of a node and adds the given edges.\n",
+	"Delete": "// Delete removes nodes by UID.\n",
+	"List":   "// List streams every node.\n",
+	"Search": "// Search streams the nodes whose name matches a term.\n",
+}
+
+// reverse holds the Message.jsonName of every reverse edge.
+var reverse = func() map[string]bool {
+	m := map[string]bool{}
+	for _, s := range services(&movies.Client{}) {
+		for _, sf := range reflect.VisibleFields(s.model) {
+			if strings.HasPrefix(predicateOf(sf), "~") {
+				m[s.name+"."+jsonName(sf)] = true
+			}
+		}
+	}
+	return m
+}()
+
+// jsonName returns the JSON field name of f.
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+// predicateOf returns the Dgraph predicate of f: its predicate= directive,
+// or else its JSON name.
+func predicateOf(f reflect.StructField) string {
+	for d := range strings.FieldsSeq(f.Tag.Get("dgraph")) {
+		if p, ok := strings.CutPrefix(d, "predicate="); ok {
+			return p
+		}
+	}
+	return jsonName(f)
+}
+
+// snake turns a JSON name such as initialReleaseDate into a proto field
+// name, initial_release_date.
+func snake(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// article returns "a" or "an" for name.
+func article(name string) string {
+	if strings.ContainsRune("AEIOU", rune(name[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+func upperFirst(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
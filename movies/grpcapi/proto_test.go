@@ -0,0 +1,146 @@
+package grpcapi
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+func TestProtoFile(t *testing.T) {
+	b, err := os.ReadFile("movies.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != Proto() {
+		t.Error("movies.proto is stale; run go generate ./grpcapi")
+	}
+}
+
+func TestFile(t *testing.T) {
+	film := message("Film")
+	for _, tt := range []struct {
+		name     string
+		number   int
+		kind     protoreflect.Kind
+		message  string
+		repeated bool
+	}{
+		{"uid", 1, protoreflect.StringKind, "", false},
+		{"name", 2, protoreflect.StringKind, "", false},
+		{"initial_release_date", 3, protoreflect.MessageKind, "google.protobuf.Timestamp", false},
+		{"tagline", 4, protoreflect.StringKind, "", false},
+		{"genres", 5, protoreflect.MessageKind, "movies.v1.Genre", true},
+		{"content_ratings", 8, protoreflect.MessageKind, "movies.v1.ContentRating", true},
+		{"starring", 9, protoreflect.MessageKind, "movies.v1.Performance", true},
+	} {
+		fd := film.Fields().ByName(protoreflect.Name(tt.name))
+		if fd == nil {
+			t.Errorf("Film has no field %s", tt.name)
+			continue
+		}
+		if int(fd.Number()) != tt.number || fd.Kind() != tt.kind || fd.IsList() != tt.repeated {
+			t.Errorf("Film.%s = %d %v list=%v, want %d %v list=%v", tt.name, fd.Number(), fd.Kind(), fd.IsList(), tt.number, tt.kind, tt.repeated)
+		}
+		if tt.message != "" && string(fd.Message().FullName()) != tt.message {
+			t.Errorf("Film.%s is a %s, want %s", tt.name, fd.Message().FullName(), tt.message)
+		}
+	}
+	if fd := film.Fields().ByJSONName("initialReleaseDate"); fd == nil || fd.Name() != "initial_release_date" {
+		t.Error("expected initialReleaseDate to be the JSON name of initial_release_date")
+	}
+	if film.Fields().ByName("dgraph_type") != nil {
+		t.Error("expected dgraph.type to be left out")
+	}
+
+	// Value structs become messages of their own.
+	geo := message("GeoPoint")
+	if geo == nil {
+		t.Fatal("expected a GeoPoint message")
+	}
+	if fd := geo.Fields().ByName("coordinates"); fd == nil || fd.Kind() != protoreflect.DoubleKind || !fd.IsList() {
+		t.Errorf("expected GeoPoint.coordinates to be repeated double, got %v", fd)
+	}
+	if fd := message("Location").Fields().ByName("loc"); fd == nil || fd.Message() != geo {
+		t.Errorf("expected Location.loc to be a GeoPoint, got %v", fd)
+	}
+
+	svcs := File().Services()
+	if svcs.Len() != len(services(&movies.Client{})) {
+		t.Errorf("expected a service per entity, got %d", svcs.Len())
+	}
+	films := svcs.ByName("FilmService")
+	if films == nil {
+		t.Fatal("expected a FilmService")
+	}
+	for _, tt := range []struct {
+		name, in, out string
+		stream        bool
+	}{
+		{"Get", "GetRequest", "Film", false},
+		{"Add", "Film", "Film", false},
+		{"Update", "Film", "Film", false},
+		{"Delete", "DeleteRequest", "DeleteResponse", false},
+		{"List", "ListRequest", "Film", true},
+		{"Search", "SearchRequest", "Film", true},
+	} {
+		m := films.Methods().ByName(protoreflect.Name(tt.name))
+		if m == nil {
+			t.Errorf("FilmService has no %s", tt.name)
+			continue
+		}
+		if string(m.Input().Name()) != tt.in || string(m.Output().Name()) != tt.out || m.IsStreamingServer() != tt.stream {
+			t.Errorf("FilmService.%s(%s) returns (%s, stream=%v), want (%s) returns (%s, stream=%v)",
+				tt.name, m.Input().Name(), m.Output().Name(), m.IsStreamingServer(), tt.in, tt.out, tt.stream)
+		}
+	}
+	// Performance has no name to search.
+	if svcs.ByName("PerformanceService").Methods().ByName("Search") != nil {
+		t.Error("expected PerformanceService to have no Search")
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	for _, v := range []any{
+		&movies.Film{
+			UID:                "0x1",
+			Name:               "Heat",
+			InitialReleaseDate: time.Date(1995, 12, 15, 10, 30, 0, 500, time.UTC),
+			Genres:             []movies.Genre{{UID: "0x2", Name: "Crime"}, {UID: "0x3"}},
+			Starring:           []movies.Performance{{CharacterNote: "Neil McCauley"}},
+		},
+		&movies.Film{Name: "Untitled"},
+		&movies.Location{Name: "Los Angeles", Loc: movies.NewGeoPoint(34.05, -118.24), Email: "la@example.com"},
+	} {
+		desc := message(reflect.TypeOf(v).Elem().Name())
+		b, err := proto.Marshal(toMessage(v, desc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := dynamicpb.NewMessage(desc)
+		if err := proto.Unmarshal(b, m); err != nil {
+			t.Fatal(err)
+		}
+		if got := fromMessage(m, reflect.TypeOf(v).Elem()); !reflect.DeepEqual(got, v) {
+			t.Errorf("round trip of %+v gave %+v", v, got)
+		}
+	}
+}
+
+func TestSnake(t *testing.T) {
+	for in, want := range map[string]string{
+		"name":               "name",
+		"initialReleaseDate": "initial_release_date",
+		"contentRatings":     "content_ratings",
+	} {
+		if got := snake(in); got != want {
+			t.Errorf("snake(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
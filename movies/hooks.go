@@ -0,0 +1,29 @@
+package movies
+
+import (
+	"context"
+	"reflect"
+)
+
+// On registers fn to be called after each change of kind op to an entity
+// of type T, with the node's UID and its snapshots: before is nil for adds,
+// and for changes whose node could not be read first; after is nil for
+// deletes. It returns a function that unregisters fn. See Subscribe for how
+// handlers are called.
+//
+//	stop := movies.On(client, movies.OpUpdate, func(ctx context.Context, uid string, before, after *movies.Film) {
+//		...
+//	})
+//
+// On panics if T is not one of the package's entity types.
+func On[T any](c *Client, op Op, fn func(ctx context.Context, uid string, before, after *T)) (unsubscribe func()) {
+	t := reflect.TypeFor[T]()
+	if entityTypes()[t.Name()] != t {
+		panic("movies: On: " + t.String() + " is not an entity type")
+	}
+	return c.ext().hooks.subscribe(t.Name(), func(ctx context.Context, e Event) {
+		if e.Op == op {
+			fn(ctx, e.UID, snapshotOf[T](e.Before), snapshotOf[T](e.After))
+		}
+	})
+}
@@ -1,43 +1,40 @@
 package movies_test
 
 import (
+	"bytes"
 	"context"
-	"os"
+	"errors"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/dgraph-io/dgo/v250"
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	dg "github.com/dolan-in/dgman/v2"
 	"github.com/matthewmcneely/modusgraph"
+	"google.golang.org/grpc"
 
 	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/internal/dgraphtest"
 )
 
 // testAddr returns the Dgraph gRPC address or empty if not set.
 func testAddr() string {
-	return os.Getenv("DGRAPH_TEST_ADDR")
+	return dgraphtest.Addr()
 }
 
 // skipIfNoDgraph skips the test if DGRAPH_TEST_ADDR is not set or -short is passed.
 func skipIfNoDgraph(t *testing.T) {
 	t.Helper()
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
-	}
-	if testAddr() == "" {
-		t.Skip("Skipping: DGRAPH_TEST_ADDR not set")
-	}
+	dgraphtest.Skip(t)
 }
 
 // newTestClient creates a movies.Client connected to the test Dgraph instance.
 func newTestClient(t *testing.T) *movies.Client {
 	t.Helper()
-	c, err := movies.New("dgraph://"+testAddr(), modusgraph.WithAutoSchema(true))
-	if err != nil {
-		t.Fatalf("movies.New: %v", err)
-	}
-	t.Cleanup(c.Close)
-	return c
+	return dgraphtest.Client(t)
 }
 
 // seedOnce ensures test data is seeded exactly once across all tests.
@@ -160,6 +157,90 @@ func doSeed(c *movies.Client) error {
 	return nil
 }
 
+// castOnce seeds the cast fixture, which the similarity and collaboration
+// tests share, once.
+var castOnce sync.Once
+var castErr error
+
+// seedCast inserts films linked to the actors who played in them, with
+// names of their own so that the seedData searches are unaffected. Robert
+// De Niro plays two parts in Heat.
+func seedCast(t *testing.T, c *movies.Client) {
+	t.Helper()
+	castOnce.Do(func() {
+		castErr = doSeedCast(c)
+	})
+	if castErr != nil {
+		t.Fatalf("seed cast: %v", castErr)
+	}
+}
+
+func doSeedCast(c *movies.Client) error {
+	ctx := context.Background()
+
+	heist := &movies.Genre{Name: "Heist"}
+	caper := &movies.Genre{Name: "Caper"}
+	for _, g := range []*movies.Genre{heist, caper} {
+		if err := c.Genre.Add(ctx, g); err != nil {
+			return err
+		}
+	}
+
+	heat := &movies.Film{
+		Name:               "Heat",
+		InitialReleaseDate: time.Date(1995, 12, 15, 0, 0, 0, 0, time.UTC),
+		Genres:             []movies.Genre{*heist, *caper},
+		Starring: []movies.Performance{
+			{CharacterNote: "Neil McCauley"}, {CharacterNote: "Narrator"}, {CharacterNote: "Chris Shiherlis"},
+		},
+	}
+	thief := &movies.Film{
+		Name:               "Thief",
+		InitialReleaseDate: time.Date(1981, 3, 27, 0, 0, 0, 0, time.UTC),
+		Genres:             []movies.Genre{*heist, *caper},
+		Starring:           []movies.Performance{{CharacterNote: "Frank"}},
+	}
+	collateral := &movies.Film{
+		Name:               "Collateral",
+		InitialReleaseDate: time.Date(2004, 8, 6, 0, 0, 0, 0, time.UTC),
+		Genres:             []movies.Genre{*caper},
+		Starring:           []movies.Performance{{CharacterNote: "Vincent"}, {CharacterNote: "Max"}},
+	}
+	ronin := &movies.Film{
+		Name:               "Ronin",
+		InitialReleaseDate: time.Date(1998, 9, 25, 0, 0, 0, 0, time.UTC),
+		Genres:             []movies.Genre{*heist},
+		Starring:           []movies.Performance{{CharacterNote: "Sam"}, {CharacterNote: "Larry"}},
+	}
+	for _, f := range []*movies.Film{heat, thief, collateral, ronin} {
+		if err := c.Film.Add(ctx, f); err != nil {
+			return err
+		}
+	}
+
+	mann := &movies.Director{Name: "Michael Mann", Films: []movies.Film{*heat, *thief, *collateral}}
+	frankenheimer := &movies.Director{Name: "John Frankenheimer", Films: []movies.Film{*ronin}}
+	for _, d := range []*movies.Director{mann, frankenheimer} {
+		if err := c.Director.Add(ctx, d); err != nil {
+			return err
+		}
+	}
+
+	actors := []*movies.Actor{
+		{Name: "Robert De Niro", Films: []movies.Performance{heat.Starring[0], heat.Starring[1], ronin.Starring[0]}},
+		{Name: "Val Kilmer", Films: []movies.Performance{heat.Starring[2], ronin.Starring[1]}},
+		{Name: "James Caan", Films: []movies.Performance{thief.Starring[0]}},
+		{Name: "Tom Cruise", Films: []movies.Performance{collateral.Starring[0]}},
+		{Name: "Jamie Foxx", Films: []movies.Performance{collateral.Starring[1]}},
+	}
+	for _, a := range actors {
+		if err := c.Actor.Add(ctx, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // --- Search tests ---
 
 func TestSearchFilmMatrix(t *testing.T) {
@@ -188,6 +269,37 @@ func TestSearchFilmMatrix(t *testing.T) {
 	}
 }
 
+func TestFindQuotedTerm(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	seedData(t, c)
+	ctx := context.Background()
+
+	// Spliced into the filter, the quote would end the term and the rest
+	// would match every film.
+	term := `Matrix") or has(name) or alloftext(name, "x`
+	results, err := c.Film.Find(ctx, term)
+	if err != nil {
+		t.Fatalf("Film.Find: %v", err)
+	}
+	for _, f := range results {
+		t.Errorf("the quoted term matched %q", f.Name)
+	}
+	for f, err := range c.Film.FindIter(ctx, term) {
+		if err != nil {
+			t.Fatalf("Film.FindIter: %v", err)
+		}
+		t.Errorf("FindIter matched %q", f.Name)
+	}
+	if _, err := c.Film.Find(ctx, `back\slash "quoted"`); err != nil {
+		t.Fatalf("Film.Find: %v", err)
+	}
+	results, err = c.Film.Find(ctx, `"Matrix"`, movies.First(1))
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Film.Find(\"Matrix\") = %d results, %v; want 1", len(results), err)
+	}
+}
+
 func TestSearchFilmStarWars(t *testing.T) {
 	skipIfNoDgraph(t)
 	c := newTestClient(t)
@@ -373,6 +485,44 @@ func TestQueryBuilderOrderDesc(t *testing.T) {
 	t.Logf("Found %d films, newest first", len(results))
 }
 
+func TestQueryBuilderMultiKeyOrder(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	// Two films share a name so the second sort key decides their order.
+	name := "Multi Key Order Test"
+	older := &movies.Film{Name: name, InitialReleaseDate: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)}
+	newer := &movies.Film{Name: name, InitialReleaseDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	other := &movies.Film{Name: "Multi Key Order Test Again", InitialReleaseDate: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	for _, f := range []*movies.Film{older, newer, other} {
+		if err := c.Film.Add(ctx, f); err != nil {
+			t.Fatalf("Film.Add: %v", err)
+		}
+		t.Cleanup(func() { _ = c.Film.Delete(ctx, f.UID) })
+	}
+
+	var results []movies.Film
+	err := c.Film.Query(ctx).
+		Filter(`allofterms(name, "Multi Key Order Test")`).
+		SortAsc(movies.FilmFieldName()).
+		SortDesc(movies.FilmFieldInitialReleaseDate()).
+		Exec(&results)
+	if err != nil {
+		t.Fatalf("FilmQuery.Exec: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 films, got %d", len(results))
+	}
+	want := []string{newer.UID, older.UID, other.UID}
+	for i, f := range results {
+		t.Logf("%d: %s (%d)", i, f.Name, f.InitialReleaseDate.Year())
+		if f.UID != want[i] {
+			t.Errorf("position %d: expected %s, got %s", i, want[i], f.UID)
+		}
+	}
+}
+
 // --- Iterator tests ---
 
 func TestFilmSearchIterator(t *testing.T) {
@@ -418,6 +568,38 @@ func TestGenreListIterator(t *testing.T) {
 	t.Logf("Genre iterator yielded %d genres total", count)
 }
 
+func TestFilmQueryIterator(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	seedData(t, c)
+	ctx := context.Background()
+
+	var want []movies.Film
+	if err := c.Film.Query(ctx).Filter(`anyoftext(name, "Matrix")`).First(100).Exec(&want); err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if len(want) < 2 {
+		t.Skipf("need at least 2 films matching 'Matrix' to page, got %d", len(want))
+	}
+
+	// A page size of 1 makes the iterator page once per film.
+	var got []string
+	for film, err := range c.Film.Query(ctx).Filter(`anyoftext(name, "Matrix")`).First(1).Iter() {
+		if err != nil {
+			t.Fatalf("Iter error: %v", err)
+		}
+		got = append(got, film.UID)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d films, got %d", len(want), len(got))
+	}
+	for i, f := range want {
+		if got[i] != f.UID {
+			t.Errorf("position %d: expected %s, got %s", i, f.UID, got[i])
+		}
+	}
+}
+
 // --- Mutation round-trip test ---
 
 func TestMutationRoundTrip(t *testing.T) {
@@ -729,85 +911,1181 @@ func TestDirectorWithFilms(t *testing.T) {
 	}
 }
 
-// --- Raw DQL query tests ---
+// --- Faceted search tests ---
 
-func TestQueryRaw(t *testing.T) {
+func TestFilmSearchFaceted(t *testing.T) {
 	skipIfNoDgraph(t)
 	c := newTestClient(t)
 	seedData(t, c)
 	ctx := context.Background()
 
-	// Query for films using raw DQL.
-	query := `{
-		q(func: type(Film), first: 5, orderasc: name) {
-			uid
-			name
+	res, err := c.Film.SearchFaceted(ctx, "Star Wars", movies.FacetSelection{})
+	if err != nil {
+		t.Fatalf("Film.SearchFaceted: %v", err)
+	}
+	if res.Total < 2 || len(res.Films) != res.Total {
+		t.Fatalf("expected at least 2 Star Wars films on one page, got %d of %d", len(res.Films), res.Total)
+	}
+	t.Logf("Facets: %+v", res.Facets)
+
+	var adventure movies.FacetBucket
+	for _, b := range res.Facets.Genres {
+		if b.Name == "Adventure" {
+			adventure = b
 		}
-	}`
-	resp, err := c.QueryRaw(ctx, query, nil)
+	}
+	if adventure.Count != res.Total {
+		t.Fatalf("expected Adventure bucket to count all %d matches, got %+v", res.Total, adventure)
+	}
+	decades := map[int]int{}
+	for _, d := range res.Facets.Decades {
+		decades[d.Decade] = d.Count
+	}
+	if decades[1970] == 0 || decades[1980] == 0 {
+		t.Fatalf("expected 1970s and 1980s decade buckets, got %+v", res.Facets.Decades)
+	}
+
+	// Passing a bucket back in narrows the results and the facets.
+	narrowed, err := c.Film.SearchFaceted(ctx, "Star Wars", movies.FacetSelection{
+		Genres:  []string{adventure.UID},
+		Decades: []int{1980},
+	})
 	if err != nil {
-		t.Fatalf("QueryRaw: %v", err)
+		t.Fatalf("Film.SearchFaceted narrowed: %v", err)
+	}
+	if narrowed.Total != decades[1980] {
+		t.Fatalf("expected %d films in the 1980s, got %d", decades[1980], narrowed.Total)
+	}
+	for _, f := range narrowed.Films {
+		if y := f.InitialReleaseDate.Year(); y < 1980 || y > 1989 {
+			t.Errorf("film %q from %d escaped the decade selection", f.Name, y)
+		}
+	}
+	if len(narrowed.Facets.Decades) != 1 {
+		t.Fatalf("expected a single decade bucket after narrowing, got %+v", narrowed.Facets.Decades)
 	}
 
-	// Response should be valid JSON containing film data.
-	if len(resp) == 0 {
-		t.Fatal("QueryRaw returned empty response")
+	page, err := c.Film.SearchFaceted(ctx, "Star Wars", movies.FacetSelection{}, movies.First(1))
+	if err != nil {
+		t.Fatalf("Film.SearchFaceted page: %v", err)
 	}
+	if len(page.Films) != 1 || page.Total != res.Total {
+		t.Fatalf("expected 1 film with total %d, got %d with total %d", res.Total, len(page.Films), page.Total)
+	}
+}
 
-	// Should contain at least one of our seeded film names.
-	body := string(resp)
-	if !strings.Contains(body, "The Matrix") && !strings.Contains(body, "Star Wars") {
-		t.Errorf("expected response to contain seeded film names, got: %s", body)
+// --- Similar films tests ---
+
+// filmByName returns the seeded film with the exact given name.
+func filmByName(t *testing.T, c *movies.Client, name string) movies.Film {
+	t.Helper()
+	var results []movies.Film
+	err := c.Film.Query(context.Background()).
+		Filter(`eq(name, "` + name + `")`).
+		First(1).
+		Exec(&results)
+	if err != nil {
+		t.Fatalf("FilmQuery.Exec %q: %v", name, err)
 	}
-	t.Logf("QueryRaw response: %s", body)
+	if len(results) == 0 {
+		t.Fatalf("expected seeded film %q", name)
+	}
+	return results[0]
 }
 
-func TestQueryRawWithVars(t *testing.T) {
+func TestFilmSimilar(t *testing.T) {
 	skipIfNoDgraph(t)
 	c := newTestClient(t)
-	seedData(t, c)
+	seedCast(t, c)
 	ctx := context.Background()
 
-	// Parameterized query using DQL variables.
-	query := `query films($term: string) {
-		q(func: alloftext(name, $term)) {
-			uid
-			name
+	heat := filmByName(t, c, "Heat")
+	results, err := c.Film.Similar(ctx, heat.UID)
+	if err != nil {
+		t.Fatalf("Film.Similar: %v", err)
+	}
+	if len(results) == 0 {
+		t.Fatal("expected similar films for Heat, got 0")
+	}
+	byName := map[string]movies.SimilarFilm{}
+	for _, r := range results {
+		t.Logf("Similar: %s score=%.1f shared=%d", r.Film.Name, r.Score, len(r.Shared))
+		if r.Film.UID == heat.UID {
+			t.Fatal("source film should not be among its similar films")
 		}
-	}`
-	vars := map[string]string{"$term": "Matrix"}
-	resp, err := c.QueryRaw(ctx, query, vars)
+		byName[r.Film.Name] = r
+	}
+
+	for i := 1; i < len(results); i++ {
+		if results[i].Score > results[i-1].Score {
+			t.Errorf("results not ranked by score: %.1f came after %.1f", results[i].Score, results[i-1].Score)
+		}
+	}
+
+	// Thief shares both genres and the director.
+	w := movies.DefaultSimilarWeights
+	thief := byName["Thief"]
+	if want := 2*w.Genre + w.Director; thief.Score != want {
+		t.Errorf("Thief scored %.1f, want %.1f: %+v", thief.Score, want, thief.Shared)
+	}
+	kinds := map[movies.NeighborKind]bool{}
+	for _, n := range thief.Shared {
+		kinds[n.Kind] = true
+	}
+	for _, k := range []movies.NeighborKind{movies.NeighborGenre, movies.NeighborDirector} {
+		if !kinds[k] {
+			t.Errorf("expected shared %s neighbor in explanation, got %+v", k, thief.Shared)
+		}
+	}
+
+	// De Niro plays two parts in Heat but is one shared cast member.
+	ronin, ok := byName["Ronin"]
+	if !ok {
+		t.Fatal("expected Ronin among the similar films")
+	}
+	if want := w.Genre + 2*w.Cast; ronin.Score != want {
+		t.Errorf("Ronin scored %.1f, want %.1f: %+v", ronin.Score, want, ronin.Shared)
+	}
+	cast := map[string]int{}
+	for _, n := range ronin.Shared {
+		if n.Kind == movies.NeighborCast {
+			cast[n.Name]++
+		}
+	}
+	if cast["Robert De Niro"] != 1 || cast["Val Kilmer"] != 1 {
+		t.Errorf("expected De Niro and Kilmer credited once each, got %v", cast)
+	}
+}
+
+func TestFilmSimilarFilters(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	seedCast(t, c)
+	ctx := context.Background()
+
+	heat := filmByName(t, c, "Heat")
+	results, err := c.Film.Similar(ctx, heat.UID,
+		movies.ExcludeSameDirector(),
+		movies.ReleasedBetween(1990, 1999),
+		movies.WithSimilarWeights(movies.SimilarWeights{Genre: 1}),
+	)
 	if err != nil {
-		t.Fatalf("QueryRaw with vars: %v", err)
+		t.Fatalf("Film.Similar: %v", err)
+	}
+	if len(results) == 0 {
+		t.Fatal("expected similar films from the 1990s, got 0")
+	}
+	for _, r := range results {
+		t.Logf("Similar: %s (%d) score=%.1f", r.Film.Name, r.Film.InitialReleaseDate.Year(), r.Score)
+		if y := r.Film.InitialReleaseDate.Year(); y < 1990 || y > 1999 {
+			t.Errorf("film %q released %d is outside the requested window", r.Film.Name, y)
+		}
+		if r.Film.Name == "Thief" || r.Film.Name == "Collateral" {
+			t.Errorf("film %q by the same director was not excluded", r.Film.Name)
+		}
+		for _, n := range r.Shared {
+			if n.Kind != movies.NeighborGenre {
+				t.Errorf("expected only genre contributions with zero weights elsewhere, got %s", n.Kind)
+			}
+		}
+	}
+}
+
+// --- Collaboration tests ---
+
+func TestActorCoStars(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	seedCast(t, c)
+	ctx := context.Background()
+
+	actors, err := c.Actor.Search(ctx, "De Niro")
+	if err != nil {
+		t.Fatalf("Actor.Search: %v", err)
+	}
+	if len(actors) == 0 {
+		t.Fatal("expected to find Robert De Niro")
 	}
 
-	body := string(resp)
-	if !strings.Contains(body, "Matrix") {
-		t.Errorf("expected response to contain 'Matrix', got: %s", body)
+	costars, err := c.Actor.CoStars(ctx, actors[0].UID)
+	if err != nil {
+		t.Fatalf("Actor.CoStars: %v", err)
+	}
+	if len(costars) == 0 {
+		t.Fatal("expected co-stars for Robert De Niro, got 0")
+	}
+	for _, cs := range costars {
+		t.Logf("Co-star: %s (%d shared films)", cs.Actor.Name, cs.Count)
+		if cs.Actor.UID == actors[0].UID {
+			t.Fatal("actor should not be listed as their own co-star")
+		}
+	}
+	top := costars[0]
+	if top.Actor.Name != "Val Kilmer" || top.Count != 2 || len(top.Films) != 2 {
+		t.Fatalf("expected Val Kilmer with 2 shared films, got %s with %d (%d films)",
+			top.Actor.Name, top.Count, len(top.Films))
 	}
-	t.Logf("QueryRaw with vars response: %s", body)
 }
 
-func TestQueryRawEmptyResult(t *testing.T) {
+func TestDirectorFrequentCollaborators(t *testing.T) {
 	skipIfNoDgraph(t)
 	c := newTestClient(t)
+	seedCast(t, c)
 	ctx := context.Background()
 
-	// Query for something that definitely doesn't exist.
-	query := `{
-		q(func: eq(name, "zzzNonExistentFilm99999")) {
-			uid
-			name
+	directors, err := c.Director.Search(ctx, "Michael Mann")
+	if err != nil {
+		t.Fatalf("Director.Search: %v", err)
+	}
+	if len(directors) == 0 {
+		t.Fatal("expected to find Michael Mann")
+	}
+
+	collaborators, err := c.Director.FrequentCollaborators(ctx, directors[0].UID)
+	if err != nil {
+		t.Fatalf("Director.FrequentCollaborators: %v", err)
+	}
+	if len(collaborators) < 2 {
+		t.Fatalf("expected at least 2 collaborators, got %d", len(collaborators))
+	}
+	for i, col := range collaborators {
+		t.Logf("Collaborator: %s (%d films)", col.Actor.Name, col.Count)
+		if i > 0 && col.Count > collaborators[i-1].Count {
+			t.Errorf("collaborators not ranked by count: %d came after %d", col.Count, collaborators[i-1].Count)
 		}
-	}`
-	resp, err := c.QueryRaw(ctx, query, nil)
+	}
+
+	page, err := c.Director.FrequentCollaborators(ctx, directors[0].UID, movies.First(1), movies.Offset(1))
 	if err != nil {
-		t.Fatalf("QueryRaw: %v", err)
+		t.Fatalf("Director.FrequentCollaborators page: %v", err)
+	}
+	if len(page) != 1 || page[0].Actor.UID != collaborators[1].Actor.UID {
+		t.Fatalf("expected second collaborator on page 2, got %+v", page)
 	}
 
-	// Should return valid JSON even with no results.
-	if len(resp) == 0 {
-		t.Fatal("QueryRaw returned empty response for no-match query")
+	// A negative offset is treated as none rather than panicking.
+	page, err = c.Director.FrequentCollaborators(ctx, directors[0].UID, movies.First(1), movies.Offset(-1))
+	if err != nil {
+		t.Fatalf("Director.FrequentCollaborators negative offset: %v", err)
+	}
+	if len(page) != 1 || page[0].Actor.UID != collaborators[0].Actor.UID {
+		t.Fatalf("expected the first collaborator for a negative offset, got %+v", page)
+	}
+}
+
+// --- Raw DQL query tests ---
+
+func TestFieldSelection(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	film := &movies.Film{
+		Name:    "Field Selection Test",
+		Tagline: "Only some of this comes back",
+		Genres:  []movies.Genre{{Name: "Field Selection Genre"}},
+	}
+	if err := c.Film.Add(ctx, film); err != nil {
+		t.Fatalf("Film.Add: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Film.Delete(ctx, film.UID) })
+
+	check := func(label string, f movies.Film) {
+		t.Helper()
+		if f.Name != film.Name {
+			t.Errorf("%s: expected name %q, got %q", label, film.Name, f.Name)
+		}
+		if f.Tagline != "" {
+			t.Errorf("%s: expected tagline to be skipped, got %q", label, f.Tagline)
+		}
+		if len(f.Genres) != 0 {
+			t.Errorf("%s: expected genres to be skipped, got %d", label, len(f.Genres))
+		}
+	}
+
+	sel := c.Film.Select(movies.FilmFieldName())
+	got, err := sel.Get(ctx, film.UID)
+	if err != nil {
+		t.Fatalf("Film.Get: %v", err)
+	}
+	check("Get", *got)
+
+	results, err := sel.Search(ctx, "Field Selection Test")
+	if err != nil {
+		t.Fatalf("Film.Search: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search: expected 1 film, got %d", len(results))
+	}
+	check("Search", results[0])
+
+	var queried []movies.Film
+	err = sel.Query(ctx).
+		Filter(`eq(name, "Field Selection Test")`).
+		Exec(&queried)
+	if err != nil {
+		t.Fatalf("FilmQuery.Exec: %v", err)
+	}
+	if len(queried) != 1 {
+		t.Fatalf("Query: expected 1 film, got %d", len(queried))
+	}
+	check("Query", queried[0])
+
+	// Edge fields bring back the linked nodes.
+	withGenres, err := c.Film.Select(movies.FilmFieldName(), movies.FilmFieldGenres()).Get(ctx, film.UID)
+	if err != nil {
+		t.Fatalf("Film.Get: %v", err)
+	}
+	if len(withGenres.Genres) != 1 || withGenres.Genres[0].Name != "Field Selection Genre" {
+		t.Errorf("expected the linked genre, got %+v", withGenres.Genres)
+	}
+	if len(withGenres.Genres) == 1 {
+		t.Cleanup(func() { _ = c.Genre.Delete(ctx, withGenres.Genres[0].UID) })
+	}
+
+	if _, err := movies.ParseField[movies.Film]("no_such_field"); err == nil {
+		t.Error("expected ParseField to reject an unknown field")
+	}
+	if f, err := movies.ParseField[movies.Film]("initial_release_date"); err != nil || f != movies.FilmFieldInitialReleaseDate() {
+		t.Errorf("expected predicate name to parse, got %v, %v", f, err)
+	}
+}
+
+func TestLocationGeoPoint(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	loc := &movies.Location{Name: "Geo Point Test", Loc: movies.NewGeoPoint(37.7749, -122.4194)}
+	if err := c.Location.Add(ctx, loc); err != nil {
+		t.Fatalf("Location.Add: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Location.Delete(ctx, loc.UID) })
+
+	got, err := c.Location.Get(ctx, loc.UID)
+	if err != nil {
+		t.Fatalf("Location.Get: %v", err)
+	}
+	if got.Loc == nil || got.Loc.Type != "Point" {
+		t.Fatalf("expected a Point, got %+v", got.Loc)
+	}
+	if len(got.Loc.Coordinates) != 2 || got.Loc.Coordinates[0] != -122.4194 || got.Loc.Coordinates[1] != 37.7749 {
+		t.Errorf("expected [lng, lat] coordinates, got %v", got.Loc.Coordinates)
+	}
+
+	var near []movies.Location
+	err = c.Location.Query(ctx).
+		Filter(`near(loc, [-122.42, 37.77], 1000)`).
+		Exec(&near)
+	if err != nil {
+		t.Fatalf("LocationQuery.Exec: %v", err)
+	}
+	found := false
+	for _, l := range near {
+		if l.UID == loc.UID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected near() to find the location, got %d results", len(near))
+	}
+}
+
+func TestSchemaApply(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	seedData(t, c)
+	ctx := context.Background()
+
+	want := movies.EntitySchema()
+	p, ok := want.Predicate("initial_release_date")
+	if !ok || p.Type != "datetime" || !slices.Contains(p.Tokenizer, "year") {
+		t.Fatalf("expected initial_release_date: datetime @index(year), got %v", p)
+	}
+	if _, ok := want.Type("Film"); !ok {
+		t.Fatal("expected a Film type in the entity schema")
+	}
+
+	if _, err := c.ApplySchema(ctx); err != nil {
+		t.Fatalf("ApplySchema: %v", err)
+	}
+	live, err := c.Schema(ctx)
+	if err != nil {
+		t.Fatalf("Schema: %v", err)
+	}
+	if pending := movies.PendingSchema(live); len(pending.Predicates) > 0 || len(pending.Types) > 0 {
+		t.Errorf("expected no pending changes after ApplySchema, got:\n%s", pending)
+	}
+	applied, err := c.ApplySchema(ctx)
+	if err != nil {
+		t.Fatalf("second ApplySchema: %v", err)
+	}
+	if len(applied.Predicates) > 0 || len(applied.Types) > 0 {
+		t.Errorf("expected a second ApplySchema to send nothing, got:\n%s", applied)
+	}
+}
+
+func TestPendingSchemaKeepsLiveExtras(t *testing.T) {
+	want := movies.EntitySchema()
+	name, _ := want.Predicate("name")
+	film, _ := want.Type("Film")
+
+	// A live schema with more than the structs ask for is up to date.
+	live := movies.EntitySchema()
+	for i, p := range live.Predicates {
+		if p.Predicate == "name" {
+			live.Predicates[i].Lang = true
+			live.Predicates[i].Tokenizer = append(slices.Clone(p.Tokenizer), "exact")
+		}
+	}
+	for i, ty := range live.Types {
+		if ty.Name == "Film" {
+			live.Types[i].Fields = append(slices.Clone(ty.Fields), movies.TypeField{Name: "note"})
+		}
+	}
+	live.Predicates = append(live.Predicates, movies.PredicateSchema{Predicate: "note", Type: "string"})
+	if pending := movies.PendingSchema(live); len(pending.Predicates) > 0 || len(pending.Types) > 0 {
+		t.Errorf("expected live extras to be compatible, got pending:\n%s", pending)
+	}
+
+	// When a predicate or type needs altering, its live extras are kept.
+	for i, p := range live.Predicates {
+		if p.Predicate == "name" {
+			live.Predicates[i].Tokenizer = []string{"exact"}
+		}
+	}
+	for i, ty := range live.Types {
+		if ty.Name == "Film" {
+			live.Types[i].Fields = []movies.TypeField{{Name: "name"}, {Name: "note"}}
+		}
+	}
+	pending := movies.PendingSchema(live)
+	got, ok := pending.Predicate("name")
+	if !ok || !got.Lang || !slices.Contains(got.Tokenizer, "exact") || len(got.Tokenizer) != len(name.Tokenizer)+1 {
+		t.Errorf("expected name with the struct tokenizers, exact and @lang, got %v", got)
+	}
+	gotFilm, ok := pending.Type("Film")
+	if !ok || len(gotFilm.Fields) != len(film.Fields)+1 {
+		t.Errorf("expected Film with the struct fields and note, got %v", gotFilm)
+	}
+	if len(pending.Predicates) != 1 || len(pending.Types) != 1 {
+		t.Errorf("expected only name and Film pending, got:\n%s", pending)
+	}
+}
+
+func TestQueryRaw(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	seedData(t, c)
+	ctx := context.Background()
+
+	// Query for films using raw DQL.
+	query := `{
+		q(func: type(Film), first: 5, orderasc: name) {
+			uid
+			name
+		}
+	}`
+	resp, err := c.QueryRaw(ctx, query, nil)
+	if err != nil {
+		t.Fatalf("QueryRaw: %v", err)
+	}
+
+	// Response should be valid JSON containing film data.
+	if len(resp) == 0 {
+		t.Fatal("QueryRaw returned empty response")
+	}
+
+	// Should contain at least one of our seeded film names.
+	body := string(resp)
+	if !strings.Contains(body, "The Matrix") && !strings.Contains(body, "Star Wars") {
+		t.Errorf("expected response to contain seeded film names, got: %s", body)
+	}
+	t.Logf("QueryRaw response: %s", body)
+}
+
+func TestQueryRawWithVars(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	seedData(t, c)
+	ctx := context.Background()
+
+	// Parameterized query using DQL variables.
+	query := `query films($term: string) {
+		q(func: alloftext(name, $term)) {
+			uid
+			name
+		}
+	}`
+	vars := map[string]string{"$term": "Matrix"}
+	resp, err := c.QueryRaw(ctx, query, vars)
+	if err != nil {
+		t.Fatalf("QueryRaw with vars: %v", err)
+	}
+
+	body := string(resp)
+	if !strings.Contains(body, "Matrix") {
+		t.Errorf("expected response to contain 'Matrix', got: %s", body)
+	}
+	t.Logf("QueryRaw with vars response: %s", body)
+}
+
+func TestQueryRawEmptyResult(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	// Query for something that definitely doesn't exist.
+	query := `{
+		q(func: eq(name, "zzzNonExistentFilm99999")) {
+			uid
+			name
+		}
+	}`
+	resp, err := c.QueryRaw(ctx, query, nil)
+	if err != nil {
+		t.Fatalf("QueryRaw: %v", err)
+	}
+
+	// Should return valid JSON even with no results.
+	if len(resp) == 0 {
+		t.Fatal("QueryRaw returned empty response for no-match query")
+	}
+	t.Logf("QueryRaw empty result response: %s", string(resp))
+}
+
+func TestQueryTrace(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	seedData(t, c)
+	tc, err := movies.New("dgraph://"+testAddr(), movies.WithClientOptions(modusgraph.WithAutoSchema(true)))
+	if err != nil {
+		t.Fatalf("movies.New: %v", err)
+	}
+	t.Cleanup(tc.Close)
+	ctx, trace := movies.WithTrace(context.Background())
+
+	q := tc.Film.Query(ctx).Filter(`anyoftext(name, "Matrix")`).First(5)
+	dql := q.ToDQL()
+	if !strings.Contains(dql, "type(Film), first: 5") || !strings.Contains(dql, `anyoftext(name, "Matrix")`) {
+		t.Errorf("unexpected DQL:\n%s", dql)
+	}
+	var films []movies.Film
+	if err := q.Exec(&films); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if len(films) == 0 {
+		t.Fatal("expected films matching 'Matrix'")
+	}
+	vars := map[string]string{"$term": "Matrix"}
+	if _, err := tc.QueryRaw(ctx, `query films($term: string) { q(func: alloftext(name, $term)) { uid } }`, vars); err != nil {
+		t.Fatalf("QueryRaw: %v", err)
+	}
+
+	queries := trace.Queries()
+	if len(queries) != 2 {
+		t.Fatalf("expected 2 traced queries, got %d", len(queries))
+	}
+	if queries[0].Query != dql {
+		t.Errorf("traced query differs from ToDQL:\n%s\nvs\n%s", queries[0].Query, dql)
+	}
+	if queries[1].Vars["$term"] != "Matrix" {
+		t.Errorf("expected traced vars, got %v", queries[1].Vars)
+	}
+	for _, m := range queries {
+		if m.Latency.Total <= 0 || m.Elapsed <= 0 {
+			t.Errorf("expected timings, got %+v", m)
+		}
+	}
+
+	var log strings.Builder
+	c.SetDebug(&log)
+	defer c.SetDebug(nil)
+	if err := c.Genre.Query(context.Background()).First(1).Exec(&[]movies.Genre{}); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if !strings.Contains(log.String(), "round trip") || !strings.Contains(log.String(), "type(Genre)") {
+		t.Errorf("unexpected debug log:\n%s", log.String())
+	}
+}
+
+func TestStats(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	seedData(t, c)
+	seedCast(t, c)
+
+	stats, err := c.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	films := 0
+	for _, ts := range stats.Types {
+		if ts.Type == "Film" {
+			films = ts.Nodes
+		}
+	}
+	if films < 6 {
+		t.Errorf("expected at least 6 films, got %d", films)
+	}
+	var genreEdges int
+	for _, p := range stats.Predicates {
+		if p.Predicate == "genre" {
+			genreEdges = p.Edges
+		}
+	}
+	if genreEdges < 14 {
+		t.Errorf("expected at least 14 genre edges, got %d", genreEdges)
+	}
+	var cast *movies.FanOutStats
+	for i, f := range stats.FanOut {
+		if f.Type == "Film" && f.Edge == "starring" {
+			cast = &stats.FanOut[i]
+		}
+	}
+	if cast == nil {
+		t.Fatal("expected cast size fan-out")
+	}
+	if cast.Nodes != films || cast.Max < 2 || cast.Median < float64(cast.Min) || cast.Median > float64(cast.Max) {
+		t.Errorf("unexpected cast size summary: %+v", *cast)
+	}
+	for _, m := range stats.Missing {
+		if m.Nodes <= 0 {
+			t.Errorf("expected only nonzero missing counts, got %+v", m)
+		}
+	}
+}
+
+func TestDeleteWhere(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	var keep movies.Genre
+	for _, name := range []string{"Obsolete Western", "Obsolete Noir", "Current Noir"} {
+		g := &movies.Genre{Name: name}
+		if err := c.Genre.Add(ctx, g); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		if name == "Current Noir" {
+			keep = *g
+		}
+	}
+	t.Cleanup(func() { _ = c.Genre.Delete(ctx, keep.UID) })
+
+	if _, err := c.Genre.DeleteWhere(ctx, ""); err == nil {
+		t.Error("expected an empty filter to be refused")
+	}
+	planned, err := c.Genre.DeleteWhere(ctx, `anyofterms(name, "Obsolete")`, movies.DryRun())
+	if err != nil || len(planned) != 2 {
+		t.Fatalf("DeleteWhere dry run: %v, %v", planned, err)
+	}
+	var tooMany *movies.TooManyMatchesError
+	_, err = c.Genre.DeleteWhere(ctx, `anyofterms(name, "Obsolete")`, movies.DeleteLimit(1))
+	if !errors.As(err, &tooMany) || tooMany.Matches != 2 || tooMany.Limit != 1 {
+		t.Fatalf("expected a TooManyMatchesError for 2 matches over a limit of 1, got %v", err)
+	}
+	uids, err := c.Genre.DeleteWhere(ctx, `anyofterms(name, "Obsolete")`)
+	if err != nil {
+		t.Fatalf("DeleteWhere: %v", err)
+	}
+	slices.Sort(planned)
+	slices.Sort(uids)
+	if !slices.Equal(planned, uids) {
+		t.Errorf("dry run planned %v, deleted %v", planned, uids)
+	}
+	if len(uids) != 2 {
+		t.Errorf("expected 2 deleted genres, got %v", uids)
+	}
+	var left []movies.Genre
+	if err := c.Genre.Query(ctx).Filter(`anyofterms(name, "Obsolete Noir")`).Exec(&left); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if len(left) != 1 || left[0].UID != keep.UID {
+		t.Errorf("expected only %q to remain, got %+v", keep.Name, left)
+	}
+}
+
+func TestMutationHooks(t *testing.T) {
+	skipIfNoDgraph(t)
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	var events []movies.Event
+	unsubscribe := c.Subscribe(func(ctx context.Context, e movies.Event) {
+		if e.Type == "Genre" {
+			events = append(events, e)
+		}
+	})
+	var renamed [][2]string
+	stop := movies.On(c, movies.OpUpdate, func(ctx context.Context, uid string, before, after *movies.Genre) {
+		renamed = append(renamed, [2]string{before.Name, after.Name})
+	})
+	var deleted []string
+	movies.On(c, movies.OpDelete, func(ctx context.Context, uid string, before, after *movies.Genre) {
+		deleted = append(deleted, before.Name)
+	})
+	filmAdded := false
+	movies.On(c, movies.OpAdd, func(ctx context.Context, uid string, before, after *movies.Film) { filmAdded = true })
+
+	g := &movies.Genre{Name: "Hooked Genre"}
+	if err := c.Genre.Add(ctx, g); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Genre.Update(ctx, &movies.Genre{UID: g.UID, Name: "Hooked Genre Renamed"}); err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	if err := c.Genre.Update(ctx, &movies.Genre{UID: g.UID, Name: "Hooked Genre Again"}); err != nil {
+		t.Fatal(err)
+	}
+	uids, err := c.Genre.DeleteWhere(ctx, `eq(name, "Hooked Genre Again")`)
+	if err != nil || len(uids) != 1 {
+		t.Fatalf("DeleteWhere: %v, %v", uids, err)
+	}
+
+	ops := make([]movies.Op, len(events))
+	for i, e := range events {
+		ops[i] = e.Op
+		if e.UID != g.UID {
+			t.Errorf("event %d: expected UID %s, got %s", i, g.UID, e.UID)
+		}
+	}
+	if !slices.Equal(ops, []movies.Op{movies.OpAdd, movies.OpUpdate, movies.OpUpdate, movies.OpDelete}) {
+		t.Fatalf("unexpected ops %v", ops)
+	}
+	if a := events[0].After.(*movies.Genre); a.Name != "Hooked Genre" || events[0].Before != nil {
+		t.Errorf("unexpected add snapshots %+v, %+v", events[0].Before, a)
+	}
+	if b, a := events[1].Before.(*movies.Genre), events[1].After.(*movies.Genre); b.Name != "Hooked Genre" || a.Name != "Hooked Genre Renamed" {
+		t.Errorf("unexpected update snapshots %+v, %+v", b, a)
+	}
+	if b := events[3].Before.(*movies.Genre); b.Name != "Hooked Genre Again" || events[3].After != nil {
+		t.Errorf("unexpected delete snapshots %+v, %+v", b, events[3].After)
+	}
+	if len(renamed) != 1 || renamed[0] != [2]string{"Hooked Genre", "Hooked Genre Renamed"} {
+		t.Errorf("expected one rename before unsubscribing, got %v", renamed)
+	}
+	if !slices.Equal(deleted, []string{"Hooked Genre Again"}) {
+		t.Errorf("unexpected deletes %v", deleted)
+	}
+	if filmAdded {
+		t.Error("expected no Film events")
+	}
+
+	// Handlers may change the graph; their events follow in order.
+	unsubscribe()
+	events = nil
+	c.Subscribe(func(ctx context.Context, e movies.Event) {
+		events = append(events, e)
+		if e.Type == "Genre" && e.Op == movies.OpAdd {
+			if err := c.Genre.Delete(ctx, e.UID); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	if err := c.Genre.Add(ctx, &movies.Genre{Name: "Short-lived Genre"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Op != movies.OpAdd || events[1].Op != movies.OpDelete {
+		t.Errorf("expected the add and then the handler's delete, got %+v", events)
+	}
+}
+
+// rejectRecords is a connection on which every request that writes outbox
+// records fails, and every other request goes through.
+type rejectRecords struct {
+	modusgraph.Client
+}
+
+func (r rejectRecords) DgraphClient() (*dgo.Dgraph, func(), error) {
+	client, cleanup, err := r.Client.DgraphClient()
+	if err != nil {
+		return nil, cleanup, err
+	}
+	return dgo.NewDgraphClient(&recordRejecter{dg: client}), cleanup, nil
+}
+
+type recordRejecter struct {
+	api.DgraphClient
+
+	dg *dgo.Dgraph
+}
+
+func (r *recordRejecter) Query(ctx context.Context, req *api.Request, _ ...grpc.CallOption) (*api.Response, error) {
+	for _, m := range req.Mutations {
+		if bytes.Contains(m.SetJson, []byte(`"outbox.event"`)) {
+			return nil, errors.New("outbox records rejected")
+		}
+	}
+	return r.dg.NewTxn().Do(ctx, req)
+}
+
+func (r *recordRejecter) CommitOrAbort(_ context.Context, tc *api.TxnContext, _ ...grpc.CallOption) (*api.TxnContext, error) {
+	return tc, nil
+}
+
+func TestOutboxAtomic(t *testing.T) {
+	skipIfNoDgraph(t)
+	ctx := context.Background()
+	if err := newTestClient(t).EnableOutbox(ctx); err != nil {
+		t.Fatalf("EnableOutbox: %v", err)
+	}
+	direct := newTestClient(t)
+	seedData(t, direct)
+	conn, err := modusgraph.NewClient("dgraph://"+testAddr(), modusgraph.WithAutoSchema(true))
+	if err != nil {
+		t.Fatalf("modusgraph.NewClient: %v", err)
+	}
+	c := movies.NewFromClient(rejectRecords{conn})
+	t.Cleanup(c.Close)
+	if err := c.EnableOutbox(ctx); err != nil {
+		t.Fatalf("EnableOutbox: %v", err)
+	}
+
+	// A change whose records fail is not committed either.
+	g := &movies.Genre{Name: "Unrecorded Genre"}
+	if err := c.Genre.Add(ctx, g); err == nil {
+		t.Fatal("expected the add to fail with its records")
+	}
+	if g.UID != "" {
+		t.Errorf("expected no UID for the failed add, got %q", g.UID)
+	}
+	if found, err := direct.Genre.Search(ctx, "Unrecorded"); err != nil || len(found) != 0 {
+		t.Errorf("expected no Genre added without its record, got %+v, %v", found, err)
+	}
+	kept := &movies.Genre{Name: "Recorded Genre Kept"}
+	if err := direct.Genre.Add(ctx, kept); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = direct.Genre.Delete(ctx, kept.UID) })
+	if err := c.Genre.Update(ctx, &movies.Genre{UID: kept.UID, Name: "Renamed Without Record"}); err == nil {
+		t.Error("expected the update to fail with its records")
+	}
+	if err := c.Genre.Delete(ctx, kept.UID); err == nil {
+		t.Error("expected the delete to fail with its records")
+	}
+	if got, err := direct.Genre.Get(ctx, kept.UID); err != nil || got.Name != "Recorded Genre Kept" {
+		t.Errorf("expected the Genre unchanged without records, got %+v, %v", got, err)
+	}
+}
+
+// mutationServer is a Dgraph server that records the requests sent to it
+// and fails each with err, or commits it, giving a new node UID 0x1.
+type mutationServer struct {
+	api.DgraphClient
+	err      error
+	requests []*api.Request
+}
+
+func (m *mutationServer) Query(_ context.Context, req *api.Request, _ ...grpc.CallOption) (*api.Response, error) {
+	m.requests = append(m.requests, req)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &api.Response{Uids: map[string]string{"outbox.node": "0x1"}}, nil
+}
+
+func (m *mutationServer) CommitOrAbort(_ context.Context, tc *api.TxnContext, _ ...grpc.CallOption) (*api.TxnContext, error) {
+	return tc, nil
+}
+
+// outboxConn is a connection whose schema has the outbox and whose
+// requests go to a mutationServer.
+type outboxConn struct {
+	modusgraph.Client
+	server *mutationServer
+}
+
+func (o *outboxConn) QueryRaw(context.Context, string, map[string]string) ([]byte, error) {
+	return []byte(`{"types": [{"name": "OutboxRecord", "fields": []}]}`), nil
+}
+
+func (o *outboxConn) DgraphClient() (*dgo.Dgraph, func(), error) {
+	return dgo.NewDgraphClient(o.server), func() {}, nil
+}
+
+func TestOutboxUniqueError(t *testing.T) {
+	ctx := context.Background()
+	server := &mutationServer{err: errors.New("could not insert duplicate value [Heat] for predicate [name]")}
+	c := movies.NewFromClient(&outboxConn{server: server})
+	if err := c.EnableOutbox(ctx); err != nil {
+		t.Fatalf("EnableOutbox: %v", err)
+	}
+	err := c.Film.Add(ctx, &movies.Film{Name: "Heat"})
+	var unique *modusgraph.UniqueError
+	if !errors.As(err, &unique) || unique.Field != "name" || unique.Value != "Heat" {
+		t.Fatalf("expected a UniqueError as modusgraph reports it, got %v", err)
+	}
+	if len(server.requests) != 1 || len(server.requests[0].Mutations) != 2 {
+		t.Fatalf("expected the change and its record in one request, got %v", server.requests)
+	}
+}
+
+// rejectAll is a validator that fails every struct.
+type rejectAll struct{}
+
+func (rejectAll) StructCtx(context.Context, any) error { return errors.New("rejected") }
+
+func TestOutboxSkipsValidator(t *testing.T) {
+	ctx := context.Background()
+	// modusgraph validates before it connects, so no server is needed.
+	conn, err := modusgraph.NewClient("dgraph://localhost:1", modusgraph.WithValidator(rejectAll{}))
+	if err != nil {
+		t.Fatalf("modusgraph.NewClient: %v", err)
+	}
+	if err := movies.NewFromClient(conn).Genre.Add(ctx, &movies.Genre{Name: "Validated"}); err == nil || err.Error() != "rejected" {
+		t.Fatalf("expected modusgraph's Insert to run the validator, got %v", err)
+	}
+
+	// With the outbox enabled, as EnableOutbox documents, it does not.
+	c := movies.NewFromClient(&outboxConn{Client: conn, server: &mutationServer{}})
+	if err := c.EnableOutbox(ctx); err != nil {
+		t.Fatalf("EnableOutbox: %v", err)
+	}
+	g := &movies.Genre{Name: "Unvalidated"}
+	if err := c.Genre.Add(ctx, g); err != nil || g.UID != "0x1" {
+		t.Fatalf("expected the outboxed add to skip the validator, got %q, %v", g.UID, err)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	lru := movies.NewLRUCache(movies.LRUConfig{Size: 2, Sizes: map[string]int{"Film": 3}, TTL: 50 * time.Millisecond})
+	for _, k := range []string{"a", "b", "c"} {
+		lru.Set("Genre", k, []byte(k))
+		lru.Set("Film", k, []byte(k))
+	}
+	if _, ok := lru.Get("Genre", "a"); ok {
+		t.Error("expected the least recently used Genre to be evicted")
+	}
+	if lru.Len("Genre") != 2 || lru.Len("Film") != 3 {
+		t.Errorf("expected 2 Genres and 3 Films, got %d and %d", lru.Len("Genre"), lru.Len("Film"))
+	}
+	// Reading b makes c the least recently used.
+	lru.Get("Genre", "b")
+	lru.Set("Genre", "d", []byte("d"))
+	if _, ok := lru.Get("Genre", "c"); ok {
+		t.Error("expected c to be evicted after b was read")
+	}
+	lru.Invalidate("Film")
+	if lru.Len("Film") != 0 || lru.Len("Genre") != 2 {
+		t.Error("expected Invalidate to drop only Films")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := lru.Get("Genre", "b"); ok {
+		t.Error("expected entries to expire after the TTL")
+	}
+}
+
+func TestCache(t *testing.T) {
+	skipIfNoDgraph(t)
+	direct := newTestClient(t)
+	seedData(t, direct)
+	ctx := context.Background()
+	c, err := movies.New("dgraph://"+testAddr(),
+		movies.WithClientOptions(modusgraph.WithAutoSchema(true)),
+		movies.WithCache(movies.NewLRUCache(movies.LRUConfig{Size: 100, TTL: time.Second})))
+	if err != nil {
+		t.Fatalf("movies.New: %v", err)
+	}
+	t.Cleanup(c.Close)
+	stats := func(typ string) movies.CacheStats {
+		for _, s := range c.CacheStats() {
+			if s.Type == typ {
+				return s
+			}
+		}
+		t.Fatalf("no cache stats for %s", typ)
+		return movies.CacheStats{}
+	}
+
+	g := &movies.Genre{Name: "Cached Genre"}
+	if err := c.Genre.Add(ctx, g); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = direct.Genre.Delete(ctx, g.UID) })
+	before := stats("Genre")
+	first, err := c.Genre.Get(ctx, g.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Name = "Changed By The Caller"
+	second, err := c.Genre.Get(ctx, g.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Name != "Cached Genre" {
+		t.Errorf("expected the cached Genre unchanged by its reader, got %q", second.Name)
+	}
+	if s := stats("Genre"); s.Hits-before.Hits != 1 || s.Misses-before.Misses != 1 || s.Entries == 0 {
+		t.Errorf("expected a miss and then a hit, got %+v after %+v", s, before)
+	}
+
+	// Changes through the client invalidate; a Film's change also drops
+	// Genres, which hold films, but not Locations.
+	if err := c.Genre.Update(ctx, &movies.Genre{UID: g.UID, Name: "Cached Genre Renamed"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.Genre.Get(ctx, g.UID); err != nil || got.Name != "Cached Genre Renamed" {
+		t.Errorf("expected the update to be read, got %+v, %v", got, err)
+	}
+	if _, err := c.Genre.List(ctx, movies.First(5)); err != nil {
+		t.Fatal(err)
+	}
+	genres, locations := stats("Genre"), stats("Location")
+	f := &movies.Film{Name: "Cached Film"}
+	if err := c.Film.Add(ctx, f); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = direct.Film.Delete(ctx, f.UID) })
+	if stats("Genre").Invalidations != genres.Invalidations+1 || stats("Genre").Entries != 0 {
+		t.Errorf("expected adding a Film to drop the Genres, got %+v", stats("Genre"))
+	}
+	if stats("Location").Invalidations != locations.Invalidations {
+		t.Error("expected adding a Film to keep the Locations")
+	}
+
+	// Changes by other clients are seen once the entry expires.
+	if _, err := c.Genre.Get(ctx, g.UID); err != nil {
+		t.Fatal(err)
+	}
+	if err := direct.Genre.Update(ctx, &movies.Genre{UID: g.UID, Name: "Renamed Elsewhere"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.Genre.Get(ctx, g.UID); got.Name != "Cached Genre Renamed" {
+		t.Errorf("expected the cached name until it expires, got %q", got.Name)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if got, _ := c.Genre.Get(ctx, g.UID); got.Name != "Renamed Elsewhere" {
+		t.Errorf("expected the other client's change after the TTL, got %q", got.Name)
+	}
+	if movies.NewFromClient(nil).CacheStats() != nil {
+		t.Error("expected no cache stats without a cache")
+	}
+}
+
+func TestOnNonEntity(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected On to panic for a type that is not an entity")
+		}
+	}()
+	movies.On(movies.NewFromClient(nil), movies.OpAdd, func(ctx context.Context, uid string, before, after *movies.Event) {})
+}
+
+// sentQueries is a Dgraph server that records the queries sent to it, and
+// their contexts, and answers each with no nodes.
+type sentQueries struct {
+	api.DgraphClient
+	queries []string
+	ctxs    []context.Context
+}
+
+func (s *sentQueries) Query(ctx context.Context, req *api.Request, _ ...grpc.CallOption) (*api.Response, error) {
+	s.queries = append(s.queries, req.Query)
+	s.ctxs = append(s.ctxs, ctx)
+	return &api.Response{Json: []byte(`{"data": []}`)}, nil
+}
+
+// depthConn is a connection to a sentQueries server whose queries expand
+// edges two deep, as modusgraph's do when made with
+// modusgraph.WithMaxEdgeTraversal(2).
+type depthConn struct {
+	modusgraph.Client
+	server *sentQueries
+}
+
+func (d *depthConn) DgraphClient() (*dgo.Dgraph, func(), error) {
+	return dgo.NewDgraphClient(d.server), func() {}, nil
+}
+
+func (d *depthConn) Query(ctx context.Context, model any) *dg.Query {
+	return dg.NewReadOnlyTxnContext(ctx, dgo.NewDgraphClient(d.server)).Get(model).All(2)
+}
+
+func TestTraceEdgeDepth(t *testing.T) {
+	for _, tt := range []struct {
+		opts []movies.Option
+		same bool
+	}{
+		{[]movies.Option{movies.WithMaxEdgeTraversal(2)}, true},
+		// Without the option the client expands edges to modusgraph's
+		// default depth, not the connection's.
+		{nil, false},
+	} {
+		server := &sentQueries{}
+		c := movies.NewFromClient(&depthConn{server: server}, tt.opts...)
+		traced, _ := movies.WithTrace(context.Background())
+		for _, ctx := range []context.Context{context.Background(), traced} {
+			if err := c.Film.Query(ctx).Filter(`has(name)`).Exec(&[]movies.Film{}); err != nil {
+				t.Fatalf("Exec: %v", err)
+			}
+		}
+		if len(server.queries) != 2 {
+			t.Fatalf("expected 2 queries, got %d", len(server.queries))
+		}
+		if same := server.queries[0] == server.queries[1]; same != tt.same {
+			t.Errorf("with %d options, traced query same as untraced = %v:\n%s\nvs\n%s", len(tt.opts), same, server.queries[1], server.queries[0])
+		}
+	}
+}
+
+// deadlineConn is a connection that records the deadline of each QueryRaw
+// request and answers it with an empty result.
+type deadlineConn struct {
+	modusgraph.Client
+	deadlines []time.Time
+}
+
+func (d *deadlineConn) QueryRaw(ctx context.Context, _ string, _ map[string]string) ([]byte, error) {
+	deadline, _ := ctx.Deadline()
+	d.deadlines = append(d.deadlines, deadline)
+	return []byte(`{}`), nil
+}
+
+func TestWithTimeout(t *testing.T) {
+	conn := &deadlineConn{}
+	c := movies.NewFromClient(conn, movies.WithTimeout(time.Minute))
+	start := time.Now()
+	if _, err := c.QueryRaw(context.Background(), "{}", nil); err != nil {
+		t.Fatal(err)
+	}
+	own, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	if _, err := c.QueryRaw(own, "{}", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(conn.deadlines) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(conn.deadlines))
+	}
+	if d := conn.deadlines[0].Sub(start); d < time.Minute || d > time.Minute+10*time.Second {
+		t.Errorf("expected a request without a deadline to get the timeout, got %v", d)
+	}
+	if ownDeadline, _ := own.Deadline(); !conn.deadlines[1].Equal(ownDeadline) {
+		t.Errorf("expected a request's own deadline to be kept, got %v", conn.deadlines[1])
+	}
+
+	plain := &deadlineConn{}
+	movies.NewFromClient(plain).QueryRaw(context.Background(), "{}", nil)
+	if !plain.deadlines[0].IsZero() {
+		t.Errorf("expected no deadline without WithTimeout, got %v", plain.deadlines[0])
+	}
+
+	// A query builder's request gets the timeout when it is sent, and
+	// releases it when it returns.
+	server := &sentQueries{}
+	c = movies.NewFromClient(&depthConn{server: server}, movies.WithTimeout(time.Minute), movies.WithMaxEdgeTraversal(2))
+	q := c.Film.Query(context.Background())
+	time.Sleep(10 * time.Millisecond)
+	start = time.Now()
+	if err := q.Exec(&[]movies.Film{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := server.ctxs[0]
+	if deadline, _ := ctx.Deadline(); deadline.Before(start.Add(time.Minute)) {
+		t.Errorf("expected the timeout from when the query ran, got %v", deadline.Sub(start))
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("expected the request's context to be released, got %v", ctx.Err())
 	}
-	t.Logf("QueryRaw empty result response: %s", string(resp))
 }
@@ -0,0 +1,50 @@
+// Package dgraphtest connects the integration tests of the movies packages
+// to the Dgraph named by DGRAPH_TEST_ADDR, a gRPC address such as
+// localhost:9080. Tests that need it are skipped when it is unset or in
+// short mode, so the rest run anywhere.
+package dgraphtest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/matthewmcneely/modusgraph"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+// Addr returns the Dgraph gRPC address, or "" if DGRAPH_TEST_ADDR is not
+// set.
+func Addr() string {
+	return os.Getenv("DGRAPH_TEST_ADDR")
+}
+
+// Skip skips t if DGRAPH_TEST_ADDR is not set or -short is passed.
+func Skip(t testing.TB) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+	if Addr() == "" {
+		t.Skip("Skipping: DGRAPH_TEST_ADDR not set")
+	}
+}
+
+// Client skips t as Skip does, or returns a client of the test Dgraph
+// that updates the schema as it writes. The entities' schema is applied
+// first, so queries never meet a predicate the database lacks. The client
+// is closed when t ends.
+func Client(t testing.TB) *movies.Client {
+	t.Helper()
+	Skip(t)
+	c, err := movies.New("dgraph://"+Addr(), movies.WithClientOptions(modusgraph.WithAutoSchema(true)))
+	if err != nil {
+		t.Fatalf("movies.New: %v", err)
+	}
+	t.Cleanup(c.Close)
+	if _, err := c.ApplySchema(context.Background()); err != nil {
+		t.Fatalf("ApplySchema: %v", err)
+	}
+	return c
+}
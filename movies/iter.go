@@ -0,0 +1,120 @@
+package movies
+
+import "iter"
+
+// queryIter pages through a query's results, starting at offset and
+// fetching first nodes per page, or defaultPageSize without a First.
+func queryIter[T any](first, offset int, exec func(first, offset int, dst *[]T) error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		if first <= 0 {
+			first = defaultPageSize
+		}
+		for {
+			var results []T
+			if err := exec(first, offset, &results); err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, r := range results {
+				if !yield(r, nil) {
+					return
+				}
+			}
+			if len(results) < first {
+				return
+			}
+			offset += len(results)
+		}
+	}
+}
+
+// Iter returns an iterator over every Actor matching the query, starting at
+// Offset and fetching First nodes per page.
+func (q *ActorQuery) Iter() iter.Seq2[Actor, error] {
+	return queryIter(q.first, q.offset, func(first, offset int, dst *[]Actor) error {
+		page := *q
+		page.first, page.offset = first, offset
+		return page.Exec(dst)
+	})
+}
+
+// Iter returns an iterator over every ContentRating matching the query, starting at
+// Offset and fetching First nodes per page.
+func (q *ContentRatingQuery) Iter() iter.Seq2[ContentRating, error] {
+	return queryIter(q.first, q.offset, func(first, offset int, dst *[]ContentRating) error {
+		page := *q
+		page.first, page.offset = first, offset
+		return page.Exec(dst)
+	})
+}
+
+// Iter returns an iterator over every Country matching the query, starting at
+// Offset and fetching First nodes per page.
+func (q *CountryQuery) Iter() iter.Seq2[Country, error] {
+	return queryIter(q.first, q.offset, func(first, offset int, dst *[]Country) error {
+		page := *q
+		page.first, page.offset = first, offset
+		return page.Exec(dst)
+	})
+}
+
+// Iter returns an iterator over every Director matching the query, starting at
+// Offset and fetching First nodes per page.
+func (q *DirectorQuery) Iter() iter.Seq2[Director, error] {
+	return queryIter(q.first, q.offset, func(first, offset int, dst *[]Director) error {
+		page := *q
+		page.first, page.offset = first, offset
+		return page.Exec(dst)
+	})
+}
+
+// Iter returns an iterator over every Film matching the query, starting at
+// Offset and fetching First nodes per page.
+func (q *FilmQuery) Iter() iter.Seq2[Film, error] {
+	return queryIter(q.first, q.offset, func(first, offset int, dst *[]Film) error {
+		page := *q
+		page.first, page.offset = first, offset
+		return page.Exec(dst)
+	})
+}
+
+// Iter returns an iterator over every Genre matching the query, starting at
+// Offset and fetching First nodes per page.
+func (q *GenreQuery) Iter() iter.Seq2[Genre, error] {
+	return queryIter(q.first, q.offset, func(first, offset int, dst *[]Genre) error {
+		page := *q
+		page.first, page.offset = first, offset
+		return page.Exec(dst)
+	})
+}
+
+// Iter returns an iterator over every Location matching the query, starting at
+// Offset and fetching First nodes per page.
+func (q *LocationQuery) Iter() iter.Seq2[Location, error] {
+	return queryIter(q.first, q.offset, func(first, offset int, dst *[]Location) error {
+		page := *q
+		page.first, page.offset = first, offset
+		return page.Exec(dst)
+	})
+}
+
+// Iter returns an iterator over every Performance matching the query, starting at
+// Offset and fetching First nodes per page.
+func (q *PerformanceQuery) Iter() iter.Seq2[Performance, error] {
+	return queryIter(q.first, q.offset, func(first, offset int, dst *[]Performance) error {
+		page := *q
+		page.first, page.offset = first, offset
+		return page.Exec(dst)
+	})
+}
+
+// Iter returns an iterator over every Rating matching the query, starting at
+// Offset and fetching First nodes per page.
+func (q *RatingQuery) Iter() iter.Seq2[Rating, error] {
+	return queryIter(q.first, q.offset, func(first, offset int, dst *[]Rating) error {
+		page := *q
+		page.first, page.offset = first, offset
+		return page.Exec(dst)
+	})
+}
@@ -0,0 +1,265 @@
+// Package jsonrpc serves tools over the movies data model to scripts and
+// agents, as JSON-RPC 2.0 with one message per line:
+//
+//	→ {"jsonrpc": "2.0", "id": 1, "method": "search_films", "params": {"term": "matrix"}}
+//	← {"jsonrpc": "2.0", "id": 1, "result": {"items": [...]}}
+//
+// Every tool is a method taking its parameters by name. The method
+// tools/list describes them, each with a JSON Schema for its parameters,
+// and tools/call calls one as {"name": ..., "arguments": {...}}. Tools
+// checks parameters against the schema before running, so a bad call fails
+// with CodeInvalidParams and the parameter at fault rather than half-way.
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+)
+
+// Error codes. Those above -32100 are the JSON-RPC 2.0 ones; the others
+// are the tools' own.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+
+	// CodeToolError is a tool failing, e.g. on a DQL syntax error.
+	CodeToolError = -32000
+	// CodeNotFound is a node a tool was given not existing.
+	CodeNotFound = -32001
+)
+
+// Error is a JSON-RPC error. Data, if set, says more, such as which
+// parameter was invalid.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Errorf returns an Error with the given code. Tools return it to choose
+// their error's code; other errors get CodeToolError.
+func Errorf(code int, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Tool is a method the server exposes.
+type Tool struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	InputSchema *Schema `json:"inputSchema"`
+
+	call func(ctx context.Context, params map[string]any) (any, error)
+}
+
+// NewTool returns a tool calling fn with its parameters decoded into a P.
+// The parameters' schema is derived from P, a struct, with the JSON names
+// of its fields and these tags:
+//
+//	help:"..."        the description
+//	required:""       the parameter must be given
+//	enum:"a,b,c"      the allowed values
+//	default:"20"      the value used when the parameter is not given
+//	minimum:"1"       the least value of an integer
+//	maximum:"1000"    the greatest value of an integer
+//	pattern:"^0x"     a regular expression a string must match
+//
+// Fields may be strings, booleans, integers, slices of those, and maps from
+// strings to strings.
+func NewTool[P any](name, description string, fn func(ctx context.Context, p P) (any, error)) Tool {
+	return Tool{
+		Name:        name,
+		Description: description,
+		InputSchema: schemaOf(reflect.TypeFor[P]()),
+		call: func(ctx context.Context, params map[string]any) (any, error) {
+			b, err := json.Marshal(params)
+			if err != nil {
+				return nil, err
+			}
+			var p P
+			if err := json.Unmarshal(b, &p); err != nil {
+				return nil, Errorf(CodeInvalidParams, "%v", err)
+			}
+			return fn(ctx, p)
+		},
+	}
+}
+
+// Server dispatches requests to tools.
+type Server struct {
+	tools []Tool
+}
+
+// NewServer returns a server exposing tools.
+func NewServer(tools ...Tool) *Server {
+	return &Server{tools: tools}
+}
+
+// request is a JSON-RPC request, or a notification when ID is nil.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// response is a JSON-RPC response. ID is null when the request's could
+// not be read.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// maxMessage caps the length of a line.
+const maxMessage = 16 << 20
+
+// Serve reads requests from r, one per line, and writes the responses to w
+// in the same order, until r ends or ctx is done. A line may also hold a
+// batch, an array of requests, answered with an array.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	in := bufio.NewScanner(r)
+	in.Buffer(make([]byte, 64<<10), maxMessage)
+	enc := json.NewEncoder(w)
+	for in.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := bytes.TrimSpace(in.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if out := s.handleLine(ctx, line); out != nil {
+			if err := enc.Encode(out); err != nil {
+				return err
+			}
+		}
+	}
+	return in.Err()
+}
+
+// handleLine returns what to write for a line, or nil for notifications.
+func (s *Server) handleLine(ctx context.Context, line []byte) any {
+	if line[0] != '[' {
+		if resp := s.handle(ctx, line); resp != nil {
+			return resp
+		}
+		return nil
+	}
+	var batch []json.RawMessage
+	if err := json.Unmarshal(line, &batch); err != nil {
+		return failed(nil, Errorf(CodeParseError, "parse error: %v", err))
+	}
+	if len(batch) == 0 {
+		return failed(nil, Errorf(CodeInvalidRequest, "empty batch"))
+	}
+	var out []*response
+	for _, msg := range batch {
+		if resp := s.handle(ctx, msg); resp != nil {
+			out = append(out, resp)
+		}
+	}
+	if out == nil {
+		return nil
+	}
+	return out
+}
+
+// handle runs one request and returns its response, or nil for a
+// notification.
+func (s *Server) handle(ctx context.Context, msg []byte) *response {
+	var req request
+	if err := json.Unmarshal(msg, &req); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return failed(nil, Errorf(CodeParseError, "parse error: %v", err))
+		}
+		return failed(nil, Errorf(CodeInvalidRequest, "invalid request: %v", err))
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return failed(nil, Errorf(CodeInvalidRequest, `invalid request: want "jsonrpc": "2.0" and a method`))
+	}
+	result, err := s.call(ctx, req.Method, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		var e *Error
+		if !errors.As(err, &e) {
+			e = &Error{Code: CodeToolError, Message: err.Error()}
+		}
+		return failed(req.ID, e)
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return failed(req.ID, Errorf(CodeInternalError, "encoding the result: %v", err))
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: b}
+}
+
+func failed(id json.RawMessage, e *Error) *response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &response{JSONRPC: "2.0", ID: id, Error: e}
+}
+
+// call runs a method with its raw parameters.
+func (s *Server) call(ctx context.Context, method string, raw json.RawMessage) (any, error) {
+	params, err := decodeParams(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch method {
+	case "tools/list":
+		return map[string]any{"tools": s.tools}, nil
+	case "tools/call":
+		name, _ := params["name"].(string)
+		args, ok := params["arguments"].(map[string]any)
+		if _, given := params["arguments"]; !given {
+			args, ok = map[string]any{}, true
+		}
+		if name == "" || !ok {
+			return nil, Errorf(CodeInvalidParams, `tools/call wants {"name": string, "arguments": object}`)
+		}
+		method, params = name, args
+	}
+	i := slices.IndexFunc(s.tools, func(t Tool) bool { return t.Name == method })
+	if i < 0 {
+		return nil, &Error{Code: CodeMethodNotFound, Message: "no method " + method, Data: map[string]any{"method": method}}
+	}
+	t := s.tools[i]
+	if err := t.InputSchema.check(params, ""); err != nil {
+		return nil, err
+	}
+	return t.call(ctx, params)
+}
+
+// decodeParams decodes by-name parameters; absent ones are an empty object.
+func decodeParams(raw json.RawMessage) (map[string]any, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return map[string]any{}, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var params map[string]any
+	if err := dec.Decode(&params); err != nil {
+		return nil, Errorf(CodeInvalidParams, "params must be an object of named parameters")
+	}
+	if params == nil {
+		params = map[string]any{}
+	}
+	return params, nil
+}
@@ -0,0 +1,241 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/internal/dgraphtest"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/jsonrpc"
+)
+
+// response is a JSON-RPC response with a numeric ID, 0 when it is null.
+type response struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *jsonrpc.Error  `json:"error"`
+}
+
+// serve sends lines to s and returns the responses.
+func serve(t *testing.T, s *jsonrpc.Server, lines ...string) []response {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(strings.Join(lines, "\n")), &out); err != nil {
+		t.Fatal(err)
+	}
+	var resps []response
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r response
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		resps = append(resps, r)
+	}
+	return resps
+}
+
+type echoParams struct {
+	Words []string `json:"words" required:"" help:"What to echo."`
+	Times int      `json:"times" default:"1" minimum:"1" maximum:"3"`
+}
+
+func TestServer(t *testing.T) {
+	echo := jsonrpc.NewTool("echo", "Echo words.", func(ctx context.Context, p echoParams) (any, error) {
+		var out []string
+		for range p.Times {
+			out = append(out, p.Words...)
+		}
+		return out, nil
+	})
+	fail := jsonrpc.NewTool("fail", "Fail.", func(ctx context.Context, p struct{}) (any, error) {
+		return nil, errors.New("it failed")
+	})
+	missing := jsonrpc.NewTool("missing", "Fail with a code.", func(ctx context.Context, p struct{}) (any, error) {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeNotFound, "no such node")
+	})
+	s := jsonrpc.NewServer(echo, fail, missing)
+
+	resps := serve(t, s,
+		`{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "echo", "params": {"words": ["a", "b"], "times": 2}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "tools/call", "params": {"name": "echo", "arguments": {"words": ["c"]}}}`,
+		`{"jsonrpc": "2.0", "method": "echo", "params": {"words": ["unanswered"]}}`,
+		``,
+		`{"jsonrpc": "2.0", "id": 4, "method": "echo", "params": {"words": ["a"], "times": 4}}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "echo", "params": {"words": ["a"], "loud": true}}`,
+		`{"jsonrpc": "2.0", "id": 6, "method": "echo", "params": ["a"]}`,
+		`{"jsonrpc": "2.0", "id": 7, "method": "tools/call", "params": {"arguments": {}}}`,
+		`{"jsonrpc": "2.0", "id": 8, "method": "nowhere"}`,
+		`{"jsonrpc": "2.0", "id": 9, "method": "fail"}`,
+		`{"jsonrpc": "2.0", "id": 10, "method": "missing"}`,
+		`{"jsonrpc": "1.0", "id": 11, "method": "echo"}`,
+		`{"jsonrpc": "2.0", "id": 12, "method": `,
+	)
+	if len(resps) != 12 {
+		t.Fatalf("expected 12 responses, the notification and blank line getting none, got %d", len(resps))
+	}
+
+	var list struct {
+		Tools []jsonrpc.Tool `json:"tools"`
+	}
+	if err := json.Unmarshal(resps[0].Result, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Tools) != 3 || list.Tools[0].Name != "echo" || !slices.Equal(list.Tools[0].InputSchema.Required, []string{"words"}) {
+		t.Errorf("unexpected tools %+v", list.Tools)
+	}
+	for i, want := range [][]string{{"a", "b", "a", "b"}, {"c"}} {
+		var got []string
+		if err := json.Unmarshal(resps[i+1].Result, &got); err != nil || !slices.Equal(got, want) {
+			t.Errorf("request %d: expected %v, got %s", i+2, want, resps[i+1].Result)
+		}
+	}
+
+	for i, want := range []struct{ id, code int }{
+		{4, jsonrpc.CodeInvalidParams},
+		{5, jsonrpc.CodeInvalidParams},
+		{6, jsonrpc.CodeInvalidParams},
+		{7, jsonrpc.CodeInvalidParams},
+		{8, jsonrpc.CodeMethodNotFound},
+		{9, jsonrpc.CodeToolError},
+		{10, jsonrpc.CodeNotFound},
+		{0, jsonrpc.CodeInvalidRequest},
+		{0, jsonrpc.CodeParseError},
+	} {
+		r := resps[i+3]
+		if r.ID != want.id || r.Error == nil || r.Error.Code != want.code {
+			t.Errorf("response %d: expected id %d and error code %d, got %+v", i+4, want.id, want.code, r)
+		}
+	}
+
+	var out bytes.Buffer
+	batch := `[{"jsonrpc": "2.0", "id": 1, "method": "echo", "params": {"words": ["x"]}}, {"jsonrpc": "2.0", "method": "echo", "params": {"words": ["y"]}}, {"jsonrpc": "2.0", "id": 2, "method": "nowhere"}]`
+	if err := s.Serve(context.Background(), strings.NewReader(batch), &out); err != nil {
+		t.Fatal(err)
+	}
+	var answers []response
+	if err := json.Unmarshal(out.Bytes(), &answers); err != nil {
+		t.Fatalf("expected an array for a batch, got %s", out.Bytes())
+	}
+	if len(answers) != 2 || answers[0].ID != 1 || answers[0].Error != nil || answers[1].ID != 2 || answers[1].Error == nil {
+		t.Errorf("unexpected batch answers %+v", answers)
+	}
+}
+
+// TestToolsRefuse checks the calls the tools refuse before reaching the
+// database, so a zero Client serves them.
+func TestToolsRefuse(t *testing.T) {
+	resps := serve(t, jsonrpc.NewServer(jsonrpc.Tools(&movies.Client{})...),
+		`{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "search_films", "params": {"term": "Heat", "first": 0}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "get_entity", "params": {"type": "Studio", "uid": "0x1"}}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "get_entity", "params": {"type": "Film", "uid": "matrix"}}`,
+	)
+	var list struct {
+		Tools []jsonrpc.Tool `json:"tools"`
+	}
+	if err := json.Unmarshal(resps[0].Result, &list); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+	}
+	if !slices.Equal(names, []string{"search_films", "get_entity", "list_entities", "link_edges"}) {
+		t.Errorf("unexpected tools %v", names)
+	}
+	if s := list.Tools[0].InputSchema; !slices.Contains(s.Required, "term") || s.Properties["first"].Type != "integer" {
+		t.Errorf("unexpected search_films schema %+v", s)
+	}
+	if types := list.Tools[1].InputSchema.Properties["type"].Enum; !slices.Contains(types, "Film") || !slices.Contains(types, "Genre") {
+		t.Errorf("expected the entity types as the type enum, got %v", types)
+	}
+	for _, r := range resps[1:] {
+		if r.Error == nil || r.Error.Code != jsonrpc.CodeInvalidParams {
+			t.Errorf("request %d: expected invalid params, got %+v", r.ID, r)
+		}
+	}
+}
+
+func TestTools(t *testing.T) {
+	c := dgraphtest.Client(t)
+	ctx := context.Background()
+	drama := &movies.Genre{Name: "JSON-RPC Test Drama"}
+	linked := &movies.Genre{Name: "JSON-RPC Test Linked"}
+	for _, g := range []*movies.Genre{drama, linked} {
+		if err := c.Genre.Add(ctx, g); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = c.Genre.Delete(ctx, g.UID) })
+	}
+	var films []*movies.Film
+	for _, name := range []string{"Plinthar Awakens", "Plinthar Sleeps"} {
+		f := &movies.Film{Name: name, Genres: []movies.Genre{*drama}}
+		if err := c.Film.Add(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = c.Film.Delete(ctx, f.UID) })
+		films = append(films, f)
+	}
+
+	resps := serve(t, jsonrpc.NewServer(jsonrpc.Tools(c)...),
+		`{"jsonrpc": "2.0", "id": 1, "method": "search_films", "params": {"term": "Plinthar"}}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "tools/call", "params": {"name": "list_entities", "arguments": {"type": "Genre", "filter": "eq(name, \"JSON-RPC Test Drama\")"}}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "link_edges", "params": {"type": "Genre", "uid": "`+linked.UID+`", "edge": "films", "targets": ["`+films[0].UID+`"]}}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "get_entity", "params": {"type": "Film", "uid": "`+drama.UID+`"}}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "list_entities", "params": {"type": "Film", "filter": "eq(no_such_predicate, 1)"}}`,
+		`{"jsonrpc": "2.0", "id": 6, "method": "list_entities", "params": {"type": "Film", "order": ["tagline"]}}`,
+	)
+	if len(resps) != 6 {
+		t.Fatalf("expected 6 responses, got %d", len(resps))
+	}
+	for _, r := range resps[:3] {
+		if r.Error != nil {
+			t.Fatalf("request %d: unexpected error %v", r.ID, r.Error)
+		}
+	}
+
+	var found struct {
+		Items []movies.Film `json:"items"`
+	}
+	if err := json.Unmarshal(resps[0].Result, &found); err != nil {
+		t.Fatal(err)
+	}
+	if len(found.Items) != 2 {
+		t.Errorf("expected both Plinthar films, got %+v", found.Items)
+	}
+	var genres struct {
+		Items []movies.Genre `json:"items"`
+		Count int            `json:"count"`
+	}
+	if err := json.Unmarshal(resps[1].Result, &genres); err != nil {
+		t.Fatal(err)
+	}
+	if genres.Count != 1 || genres.Items[0].UID != drama.UID {
+		t.Errorf("expected %s, got %+v", drama.Name, genres)
+	}
+	var got movies.Genre
+	if err := json.Unmarshal(resps[2].Result, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Films) != 1 || got.Films[0].UID != films[0].UID {
+		t.Errorf("expected the genre to link %s, got %+v", films[0].Name, got.Films)
+	}
+
+	for _, want := range []struct{ id, code int }{
+		{4, jsonrpc.CodeNotFound},
+		{5, jsonrpc.CodeInvalidParams},
+		{6, jsonrpc.CodeInvalidParams},
+	} {
+		r := resps[want.id-1]
+		if r.ID != want.id || r.Error == nil || r.Error.Code != want.code {
+			t.Errorf("request %d: expected error code %d, got %+v", want.id, want.code, r)
+		}
+	}
+}
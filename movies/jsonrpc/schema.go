@@ -0,0 +1,181 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Schema is a JSON Schema, as far as tool parameters need one.
+type Schema struct {
+	Type        string             `json:"type"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	// AdditionalProperties is false for parameter objects, and the schema
+	// of the values for maps.
+	AdditionalProperties any      `json:"additionalProperties,omitempty"`
+	Items                *Schema  `json:"items,omitempty"`
+	Enum                 []string `json:"enum,omitempty"`
+	Pattern              string   `json:"pattern,omitempty"`
+	Minimum              *int64   `json:"minimum,omitempty"`
+	Maximum              *int64   `json:"maximum,omitempty"`
+	Default              any      `json:"default,omitempty"`
+}
+
+// schemaOf returns the schema of parameters decoded into struct type t.
+func schemaOf(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+	for _, f := range reflect.VisibleFields(t) {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" || f.Anonymous {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		p := typeSchema(f.Type)
+		p.Description = f.Tag.Get("help")
+		if enum, ok := f.Tag.Lookup("enum"); ok {
+			p.Enum = strings.Split(enum, ",")
+		}
+		p.Pattern = f.Tag.Get("pattern")
+		p.Minimum = intTag(f, "minimum")
+		p.Maximum = intTag(f, "maximum")
+		if def, ok := f.Tag.Lookup("default"); ok {
+			p.Default = def
+			if n, err := strconv.ParseInt(def, 10, 64); err == nil && p.Type == "integer" {
+				p.Default = n
+			} else if b, err := strconv.ParseBool(def); err == nil && p.Type == "boolean" {
+				p.Default = b
+			}
+		}
+		if _, ok := f.Tag.Lookup("required"); ok {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = p
+	}
+	return s
+}
+
+func typeSchema(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: typeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: typeSchema(t.Elem())}
+	}
+	panic("jsonrpc: parameters cannot be of type " + t.String())
+}
+
+func intTag(f reflect.StructField, key string) *int64 {
+	v, ok := f.Tag.Lookup(key)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		panic(fmt.Sprintf("jsonrpc: %s tag of %s is not an integer", key, f.Name))
+	}
+	return &n
+}
+
+// check checks v, decoded with json.Number for numbers, against the
+// schema, and fills in the defaults of missing properties. path is v's
+// JSON pointer, for errors.
+func (s *Schema) check(v any, path string) error {
+	bad := func(format string, args ...any) error {
+		at := path
+		if at == "" {
+			at = "/"
+		}
+		return &Error{Code: CodeInvalidParams, Message: at + ": " + fmt.Sprintf(format, args...), Data: map[string]any{"path": at}}
+	}
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return bad("want an object")
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return bad("missing required parameter %s", name)
+			}
+		}
+		for _, name := range sortedKeys(obj) {
+			p := s.Properties[name]
+			if p == nil {
+				if ap, ok := s.AdditionalProperties.(*Schema); ok {
+					p = ap
+				} else {
+					return bad("unknown parameter %s", name)
+				}
+			}
+			if err := p.check(obj[name], path+"/"+name); err != nil {
+				return err
+			}
+		}
+		for name, p := range s.Properties {
+			if _, ok := obj[name]; !ok && p.Default != nil {
+				obj[name] = p.Default
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return bad("want an array")
+		}
+		for i, item := range arr {
+			if err := s.Items.check(item, path+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return bad("want a string")
+		}
+		if s.Enum != nil && !slices.Contains(s.Enum, str) {
+			return bad("want one of %s, got %q", strings.Join(s.Enum, ", "), str)
+		}
+		if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(str) {
+			return bad("%q does not match %s", str, s.Pattern)
+		}
+	case "integer":
+		num, ok := v.(json.Number)
+		n, err := num.Int64()
+		if !ok || err != nil {
+			return bad("want an integer")
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			return bad("want at least %d, got %d", *s.Minimum, n)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			return bad("want at most %d, got %d", *s.Maximum, n)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return bad("want a boolean")
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
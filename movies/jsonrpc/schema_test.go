@@ -0,0 +1,85 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFilterPredicates(t *testing.T) {
+	for filter, want := range map[string][]string{
+		``:                                       nil,
+		`eq(name, "Heat")`:                       {"name"},
+		`ge(initial_release_date, "1990")`:       {"initial_release_date"},
+		`has(~genre) AND NOT has(tagline)`:       {"~genre", "tagline"},
+		`eq(name, "eq(nowhere, 1)")`:             {"name"},
+		`eq(name, "say \"has(nowhere)\" twice")`: {"name"},
+		`type(Film) OR uid(0x1) OR val(v)`:       nil,
+		`anyofterms( name , "a b")`:              {"name"},
+	} {
+		if got := filterPredicates(filter); !slices.Equal(got, want) {
+			t.Errorf("filterPredicates(%q) = %q, want %q", filter, got, want)
+		}
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	s := schemaOf(reflect.TypeFor[listEntitiesParams]())
+	if s.Type != "object" || s.AdditionalProperties != false {
+		t.Errorf("expected a closed object, got %+v", s)
+	}
+	if !slices.Equal(s.Required, []string{"type"}) {
+		t.Errorf("expected type to be required, got %v", s.Required)
+	}
+	first := s.Properties["first"]
+	if first.Type != "integer" || first.Default != int64(20) || *first.Minimum != 1 || *first.Maximum != 1000 {
+		t.Errorf("unexpected first %+v", first)
+	}
+	if order := s.Properties["order"]; order.Type != "array" || order.Items.Type != "string" {
+		t.Errorf("unexpected order %+v", order)
+	}
+	if s.Properties["filter"].Description == "" {
+		t.Error("expected filter to have its help as the description")
+	}
+	if p := schemaOf(reflect.TypeFor[getEntityParams]()).Properties["uid"]; p.Pattern != "^0x[0-9a-fA-F]+$" {
+		t.Errorf("unexpected uid pattern %q", p.Pattern)
+	}
+}
+
+func TestSchemaCheck(t *testing.T) {
+	s := schemaOf(reflect.TypeFor[listEntitiesParams]())
+	for params, path := range map[string]string{
+		`{}`:                                     "/",
+		`{"type": "Film", "nope": 1}`:            "/",
+		`{"type": 1}`:                            "/type",
+		`{"type": "Film", "first": 0}`:           "/first",
+		`{"type": "Film", "first": 1.5}`:         "/first",
+		`{"type": "Film", "order": ["name", 2]}`: "/order/1",
+	} {
+		var v map[string]any
+		dec := json.NewDecoder(strings.NewReader(params))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		var e *Error
+		if err := s.check(v, ""); !errors.As(err, &e) || e.Code != CodeInvalidParams {
+			t.Errorf("%s: expected invalid params, got %v", params, err)
+			continue
+		}
+		if at := e.Data.(map[string]any)["path"]; at != path {
+			t.Errorf("%s: expected the error at %s, got %v", params, path, at)
+		}
+	}
+
+	v := map[string]any{"type": "Film"}
+	if err := s.check(v, ""); err != nil {
+		t.Fatal(err)
+	}
+	if v["first"] != int64(20) || v["offset"] != int64(0) {
+		t.Errorf("expected the defaults to be filled in, got %v", v)
+	}
+}
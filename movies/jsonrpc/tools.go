@@ -0,0 +1,316 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	dg "github.com/dolan-in/dgman/v2"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+// entity is one entity type's part in the tools. Its functions close over
+// the typed sub-client.
+type entity struct {
+	name  string
+	model reflect.Type
+
+	get    func(ctx context.Context, uid string) (any, error)
+	list   func(ctx context.Context, filter string, order []string, first, offset int) (items any, count int, err error)
+	update func(ctx context.Context, v any) error
+}
+
+// entities returns the entity types in the order of the "type" enums.
+func entities(c *movies.Client) []*entity {
+	return []*entity{
+		newEntity[movies.Actor, *movies.ActorQuery]("Actor", c.Actor),
+		newEntity[movies.ContentRating, *movies.ContentRatingQuery]("ContentRating", c.ContentRating),
+		newEntity[movies.Country, *movies.CountryQuery]("Country", c.Country),
+		newEntity[movies.Director, *movies.DirectorQuery]("Director", c.Director),
+		newEntity[movies.Film, *movies.FilmQuery]("Film", c.Film),
+		newEntity[movies.Genre, *movies.GenreQuery]("Genre", c.Genre),
+		newEntity[movies.Location, *movies.LocationQuery]("Location", c.Location),
+		newEntity[movies.Performance, *movies.PerformanceQuery]("Performance", c.Performance),
+		newEntity[movies.Rating, *movies.RatingQuery]("Rating", c.Rating),
+	}
+}
+
+// entityClient is the method set of the generated sub-clients the tools
+// use.
+type entityClient[T any, Q any] interface {
+	Get(ctx context.Context, uid string) (*T, error)
+	Update(ctx context.Context, v *T) error
+	Query(ctx context.Context) Q
+}
+
+// entityQuery is the method set of the generated query builders the tools
+// use.
+type entityQuery[T any, Q any] interface {
+	Filter(f string) Q
+	SortAsc(field movies.SortField[T]) Q
+	SortDesc(field movies.SortField[T]) Q
+	First(n int) Q
+	Offset(n int) Q
+	ExecAndCount(dst *[]T) (int, error)
+}
+
+func newEntity[T any, Q entityQuery[T, Q]](name string, c entityClient[T, Q]) *entity {
+	e := &entity{name: name, model: reflect.TypeFor[T]()}
+	e.get = func(ctx context.Context, uid string) (any, error) {
+		v, err := c.Get(ctx, uid)
+		if errors.Is(err, dg.ErrNodeNotFound) || err == nil && !slices.Contains(dgraphType(v), name) {
+			return nil, &Error{Code: CodeNotFound, Message: "no " + name + " with UID " + uid, Data: map[string]any{"uid": uid}}
+		}
+		return v, err
+	}
+	e.list = func(ctx context.Context, filter string, order []string, first, offset int) (any, int, error) {
+		q := c.Query(ctx).First(first).Offset(offset)
+		if filter != "" {
+			q = q.Filter(filter)
+		}
+		for _, o := range order {
+			desc := strings.HasPrefix(o, "-")
+			f, err := movies.ParseField[T](strings.TrimPrefix(o, "-"))
+			if err != nil {
+				return nil, 0, Errorf(CodeInvalidParams, "order: %v", err)
+			}
+			sf, ok := f.(movies.SortField[T])
+			if !ok {
+				return nil, 0, Errorf(CodeInvalidParams, "order: %s cannot be ordered by", strings.TrimPrefix(o, "-"))
+			}
+			if desc {
+				q = q.SortDesc(sf)
+			} else {
+				q = q.SortAsc(sf)
+			}
+		}
+		items := []T{}
+		count, err := q.ExecAndCount(&items)
+		return items, count, err
+	}
+	e.update = func(ctx context.Context, v any) error {
+		return c.Update(ctx, v.(*T))
+	}
+	return e
+}
+
+type searchFilmsParams struct {
+	Term   string `json:"term" required:"" help:"Words to look for in film names, matched as full-text terms."`
+	First  int    `json:"first" default:"20" minimum:"1" maximum:"1000" help:"Maximum number of films to return."`
+	Offset int    `json:"offset" default:"0" minimum:"0" help:"Number of films to skip."`
+}
+
+type getEntityParams struct {
+	Type string `json:"type" required:"" help:"The entity type."`
+	UID  string `json:"uid" required:"" pattern:"^0x[0-9a-fA-F]+$" help:"The node's UID."`
+}
+
+type listEntitiesParams struct {
+	Type   string   `json:"type" required:"" help:"The entity type."`
+	Filter string   `json:"filter" help:"A DQL filter expression, e.g. allofterms(name, \"star wars\") or ge(initial_release_date, \"1990\")."`
+	Order  []string `json:"order" help:"Fields to order by, in priority order; prefix a field with - for descending order. Only indexed fields can be ordered by."`
+	First  int      `json:"first" default:"20" minimum:"1" maximum:"1000" help:"Maximum number of nodes to return."`
+	Offset int      `json:"offset" default:"0" minimum:"0" help:"Number of nodes to skip."`
+}
+
+type linkEdgesParams struct {
+	Type    string   `json:"type" required:"" help:"The type of the node to link from."`
+	UID     string   `json:"uid" required:"" pattern:"^0x[0-9a-fA-F]+$" help:"The UID of the node to link from."`
+	Edge    string   `json:"edge" required:"" help:"The edge's JSON field name, e.g. genres on a Film."`
+	Targets []string `json:"targets" required:"" help:"The UIDs of the nodes to link to."`
+}
+
+// Tools returns the tools over client's entities:
+//
+//	search_films     full-text search on film names
+//	get_entity       a node of a given type by UID
+//	list_entities    nodes of a type matching a DQL filter, with a total count
+//	link_edges       add edges from a node to others
+func Tools(client *movies.Client) []Tool {
+	ents := entities(client)
+	var names []string
+	for _, e := range ents {
+		names = append(names, e.name)
+	}
+	byName := func(name string) *entity {
+		return ents[slices.Index(names, name)]
+	}
+
+	tools := []Tool{
+		NewTool("search_films", "Search films by name. Returns {items: [Film]}, best matches first.",
+			func(ctx context.Context, p searchFilmsParams) (any, error) {
+				films, err := client.Film.Find(ctx, p.Term, movies.First(p.First), movies.Offset(p.Offset))
+				if err != nil {
+					return nil, err
+				}
+				if films == nil {
+					films = []movies.Film{}
+				}
+				return map[string]any{"items": films}, nil
+			}),
+		NewTool("get_entity", "Get a node by type and UID, with its fields and the nodes its edges link to.",
+			func(ctx context.Context, p getEntityParams) (any, error) {
+				return byName(p.Type).get(ctx, p.UID)
+			}),
+		NewTool("list_entities", "List nodes of a type, optionally filtered and ordered. Returns {items: [...], count: n}, where count is the number of nodes matching, however many are returned.",
+			func(ctx context.Context, p listEntitiesParams) (any, error) {
+				if err := checkFilter(ctx, client, p.Filter); err != nil {
+					return nil, err
+				}
+				items, count, err := byName(p.Type).list(ctx, p.Filter, p.Order, p.First, p.Offset)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]any{"items": items, "count": count}, nil
+			}),
+		NewTool("link_edges", "Add edges from a node to other nodes, e.g. a Film's genres. Existing edges are kept. Reverse edges, such as a Genre's films, are stored on the other nodes and are linked there. Returns the node.",
+			func(ctx context.Context, p linkEdgesParams) (any, error) {
+				return link(ctx, byName, p)
+			}),
+	}
+	for _, t := range tools {
+		if p := t.InputSchema.Properties["type"]; p != nil {
+			p.Enum = names
+		}
+	}
+	return tools
+}
+
+var (
+	quoted = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+	// call matches a function call on a predicate, such as eq(name, or
+	// has(~genre).
+	call = regexp.MustCompile(`([A-Za-z_]+)\s*\(\s*(~?[A-Za-z_][\w.]*)\s*[,)]`)
+)
+
+// checkFilter fails when filter names a predicate the database does not
+// have, which the embedded engine cannot recover from.
+func checkFilter(ctx context.Context, client *movies.Client, filter string) error {
+	preds := filterPredicates(filter)
+	if preds == nil {
+		return nil
+	}
+	db, err := client.Schema(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range preds {
+		name, reverse := strings.CutPrefix(p, "~")
+		if ps, ok := db.Predicate(name); !ok || reverse && !ps.Reverse {
+			return &Error{Code: CodeInvalidParams, Message: "filter: the database has no predicate " + p, Data: map[string]any{"path": "/filter"}}
+		}
+	}
+	return nil
+}
+
+// filterPredicates returns the predicates the functions in filter are
+// called on, with ~ for reverse ones. Words in strings and the arguments
+// of type, val and uid are not predicates.
+func filterPredicates(filter string) []string {
+	var preds []string
+	for _, c := range call.FindAllStringSubmatch(quoted.ReplaceAllString(filter, `""`), -1) {
+		if c[1] != "type" && c[1] != "val" && c[1] != "uid" {
+			preds = append(preds, c[2])
+		}
+	}
+	return preds
+}
+
+// link adds the edges p asks for.
+func link(ctx context.Context, byName func(string) *entity, p linkEdgesParams) (any, error) {
+	from := byName(p.Type)
+	if _, err := from.get(ctx, p.UID); err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(reflect.VisibleFields(from.model), func(f reflect.StructField) bool {
+		return jsonName(f) == p.Edge && isEdge(f)
+	})
+	if i < 0 {
+		return nil, &Error{Code: CodeInvalidParams, Message: p.Type + " has no edge " + p.Edge, Data: map[string]any{"path": "/edge"}}
+	}
+	edge := reflect.VisibleFields(from.model)[i]
+	if len(p.Targets) == 0 {
+		return nil, &Error{Code: CodeInvalidParams, Message: "give the UIDs to link to", Data: map[string]any{"path": "/targets"}}
+	}
+	to := byName(edge.Type.Elem().Name())
+	for n, uid := range p.Targets {
+		if !movies.IsUID(uid) {
+			return nil, &Error{Code: CodeInvalidParams, Message: strconv.Quote(uid) + " is not a UID", Data: map[string]any{"path": "/targets/" + strconv.Itoa(n)}}
+		}
+		if _, err := to.get(ctx, uid); err != nil {
+			return nil, err
+		}
+	}
+
+	if pred, ok := strings.CutPrefix(predicateOf(edge), "~"); ok {
+		// Set the forward edge on each target.
+		j := slices.IndexFunc(reflect.VisibleFields(to.model), func(f reflect.StructField) bool {
+			return predicateOf(f) == pred
+		})
+		if j < 0 {
+			return nil, Errorf(CodeInternalError, "%s has no %s edge for %s.%s", to.name, pred, p.Type, p.Edge)
+		}
+		back := reflect.VisibleFields(to.model)[j]
+		for _, uid := range p.Targets {
+			v := withUID(to.model, uid)
+			v.Elem().FieldByIndex(back.Index).Set(nodes(back.Type, p.UID))
+			if err := to.update(ctx, v.Interface()); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		v := withUID(from.model, p.UID)
+		v.Elem().FieldByIndex(edge.Index).Set(nodes(edge.Type, p.Targets...))
+		if err := from.update(ctx, v.Interface()); err != nil {
+			return nil, err
+		}
+	}
+	return from.get(ctx, p.UID)
+}
+
+// withUID returns a new *t holding only the given UID.
+func withUID(t reflect.Type, uid string) reflect.Value {
+	v := reflect.New(t)
+	v.Elem().FieldByName("UID").SetString(uid)
+	return v
+}
+
+// nodes returns a slice of type t of nodes holding only the given UIDs.
+func nodes(t reflect.Type, uids ...string) reflect.Value {
+	s := reflect.MakeSlice(t, 0, len(uids))
+	for _, uid := range uids {
+		s = reflect.Append(s, withUID(t.Elem(), uid).Elem())
+	}
+	return s
+}
+
+// isEdge reports whether f holds other entities.
+func isEdge(f reflect.StructField) bool {
+	return f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct
+}
+
+func dgraphType(v any) []string {
+	return reflect.ValueOf(v).Elem().FieldByName("DType").Interface().([]string)
+}
+
+// jsonName returns the JSON field name of f.
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+// predicateOf returns the Dgraph predicate of f: its predicate= directive,
+// or else its JSON name.
+func predicateOf(f reflect.StructField) string {
+	for d := range strings.FieldsSeq(f.Tag.Get("dgraph")) {
+		if p, ok := strings.CutPrefix(d, "predicate="); ok {
+			return p
+		}
+	}
+	return jsonName(f)
+}
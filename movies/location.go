@@ -4,6 +4,18 @@ type Location struct {
 	UID   string    `json:"uid,omitempty"`
 	DType []string  `json:"dgraph.type,omitempty"`
 	Name  string    `json:"name,omitempty" dgraph:"index=hash,term,trigram,fulltext"`
-	Loc   []float64 `json:"loc,omitempty" dgraph:"index=geo type=geo"`
+	Loc   *GeoPoint `json:"loc,omitempty" dgraph:"index=geo type=geo"`
 	Email string    `json:"email,omitempty" dgraph:"index=exact upsert"`
 }
+
+// GeoPoint is a GeoJSON point. Dgraph stores geo predicates as GeoJSON, so
+// coordinates are longitude first.
+type GeoPoint struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// NewGeoPoint returns the point at the given latitude and longitude.
+func NewGeoPoint(lat, lng float64) *GeoPoint {
+	return &GeoPoint{Type: "Point", Coordinates: []float64{lng, lat}}
+}
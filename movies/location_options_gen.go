@@ -13,7 +13,7 @@ func WithLocationName(v string) LocationOption {
 }
 
 // WithLocationLoc sets the Loc field on a Location.
-func WithLocationLoc(v []float64) LocationOption {
+func WithLocationLoc(v *GeoPoint) LocationOption {
 	return func(e *Location) {
 		e.Loc = v
 	}
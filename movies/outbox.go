@@ -0,0 +1,400 @@
+package movies
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgo/v250"
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	dg "github.com/dolan-in/dgman/v2"
+	"github.com/matthewmcneely/modusgraph"
+	"google.golang.org/grpc"
+)
+
+// OutboxStatus is where an outbox record is in its delivery.
+type OutboxStatus string
+
+const (
+	// OutboxPending records wait for delivery, or for their next attempt.
+	OutboxPending OutboxStatus = "pending"
+	// OutboxDelivered records reached every webhook.
+	OutboxDelivered OutboxStatus = "delivered"
+	// OutboxDead records ran out of attempts; they stay for inspection
+	// until queued again.
+	OutboxDead OutboxStatus = "dead"
+)
+
+// OutboxRecord is a change held in the outbox for delivery to other
+// systems. It is stored as an OutboxRecord node, written in the request
+// that makes the change, so a record exists exactly when its change was
+// committed.
+type OutboxRecord struct {
+	UID     string    `json:"uid"`
+	Event   Event     `json:"event"`
+	Created time.Time `json:"created"`
+
+	Status      OutboxStatus `json:"status"`
+	Attempts    int          `json:"attempts"`
+	NextAttempt time.Time    `json:"nextAttempt,omitzero"`
+	LastError   string       `json:"lastError,omitempty"`
+	// DeliveredTo lists the webhooks that accepted the record, which later
+	// attempts skip.
+	DeliveredTo []string `json:"deliveredTo,omitempty"`
+}
+
+// outboxSchema defines the outbox's predicates and type. The status and
+// next attempt are indexed for finding the records that are due.
+var outboxSchema = Schema{
+	Predicates: []PredicateSchema{
+		{Predicate: "outbox.attempts", Type: "int"},
+		{Predicate: "outbox.created", Type: "datetime", Index: true, Tokenizer: []string{"hour"}},
+		{Predicate: "outbox.delivered_to", Type: "string", List: true},
+		{Predicate: "outbox.event", Type: "string"},
+		{Predicate: "outbox.last_error", Type: "string"},
+		{Predicate: "outbox.next_attempt", Type: "datetime", Index: true, Tokenizer: []string{"hour"}},
+		{Predicate: "outbox.node", Type: "uid"},
+		{Predicate: "outbox.status", Type: "string", Index: true, Tokenizer: []string{"exact"}},
+	},
+	Types: []TypeSchema{{Name: "OutboxRecord", Fields: []TypeField{
+		{Name: "outbox.attempts"}, {Name: "outbox.created"}, {Name: "outbox.delivered_to"},
+		{Name: "outbox.event"}, {Name: "outbox.last_error"}, {Name: "outbox.next_attempt"},
+		{Name: "outbox.node"}, {Name: "outbox.status"},
+	}}},
+}
+
+// EnableOutbox makes the sub-clients' Add, Update and Delete write an
+// outbox record of every change, holding the Event a handler registered
+// with Subscribe would get, in the same request as the change. The outbox
+// predicates are added to the schema first, if missing.
+//
+// With the outbox enabled, changes are sent to Dgraph directly rather than
+// through modusgraph's Insert, Update and Delete, whose requests cannot
+// carry the records. They differ from those in two ways: auto-schema does
+// not apply, so the entity schema must be in place, as ApplySchema makes
+// it, and a validator given with modusgraph.WithValidator is not run.
+// Unique constraints are enforced by Dgraph all the same, and a violation
+// is reported as a *modusgraph.UniqueError, as modusgraph reports it.
+// Updates and deletes read the node before the change, as they do for
+// subscribers.
+func (c *Client) EnableOutbox(ctx context.Context) error {
+	live, err := c.Schema(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := live.Type("OutboxRecord"); !ok {
+		client, cleanup, err := c.conn.DgraphClient()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		if err := client.Alter(ctx, &api.Operation{Schema: outboxSchema.String()}); err != nil {
+			return fmt.Errorf("add outbox schema: %w", err)
+		}
+	}
+	c.ext().outbox.Store(true)
+	return nil
+}
+
+// OutboxRecords returns up to first records with the given status, oldest
+// first.
+func (c *Client) OutboxRecords(ctx context.Context, status OutboxStatus, first int) ([]OutboxRecord, error) {
+	return c.outboxRecords(ctx, status, time.Time{}, first)
+}
+
+// DueOutboxRecords returns up to first pending records whose next attempt
+// is due at now, oldest first.
+func (c *Client) DueOutboxRecords(ctx context.Context, now time.Time, first int) ([]OutboxRecord, error) {
+	return c.outboxRecords(ctx, OutboxPending, now, first)
+}
+
+type outboxNode struct {
+	UID         string       `json:"uid"`
+	Event       string       `json:"outbox.event"`
+	Created     time.Time    `json:"outbox.created"`
+	Status      OutboxStatus `json:"outbox.status"`
+	Attempts    int          `json:"outbox.attempts"`
+	NextAttempt time.Time    `json:"outbox.next_attempt"`
+	LastError   string       `json:"outbox.last_error"`
+	DeliveredTo []string     `json:"outbox.delivered_to"`
+	Node        *struct {
+		UID string `json:"uid"`
+	} `json:"outbox.node"`
+}
+
+func (c *Client) outboxRecords(ctx context.Context, status OutboxStatus, due time.Time, first int) ([]OutboxRecord, error) {
+	// Querying the outbox predicates before they exist would crash the
+	// embedded engine; a database without them has no records.
+	live, err := c.Schema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := live.Type("OutboxRecord"); !ok {
+		return []OutboxRecord{}, nil
+	}
+	filter := ""
+	vars := map[string]string{"$status": string(status)}
+	if !due.IsZero() {
+		filter = "@filter(le(outbox.next_attempt, $due))"
+		vars["$due"] = due.UTC().Format(time.RFC3339Nano)
+	}
+	query := fmt.Sprintf(`query q($status: string, $due: string) {
+  records(func: eq(outbox.status, $status), orderasc: outbox.created, first: %d) %s {
+    uid
+    outbox.event
+    outbox.created
+    outbox.status
+    outbox.attempts
+    outbox.next_attempt
+    outbox.last_error
+    outbox.delivered_to
+    outbox.node { uid }
+  }
+}`, max(first, 1), filter)
+	resp, err := c.conn.QueryRaw(ctx, query, vars)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Records []outboxNode `json:"records"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, err
+	}
+	records := make([]OutboxRecord, len(result.Records))
+	for i, n := range result.Records {
+		r := OutboxRecord{
+			UID:         n.UID,
+			Created:     n.Created,
+			Status:      n.Status,
+			Attempts:    n.Attempts,
+			NextAttempt: n.NextAttempt,
+			LastError:   n.LastError,
+			DeliveredTo: n.DeliveredTo,
+		}
+		if err := json.Unmarshal([]byte(n.Event), &r.Event); err != nil {
+			return nil, fmt.Errorf("outbox record %s: %w", n.UID, err)
+		}
+		// An added node's UID is only known once committed; the record
+		// reaches it through its edge.
+		if r.Event.UID == "" && n.Node != nil {
+			r.Event.UID = n.Node.UID
+			if after, ok := r.Event.After.(map[string]any); ok {
+				after["uid"] = n.Node.UID
+			}
+		}
+		records[i] = r
+	}
+	return records, nil
+}
+
+// SaveOutboxRecord stores the delivery state of r: its status, attempts,
+// next attempt, last error and the webhooks it was delivered to.
+func (c *Client) SaveOutboxRecord(ctx context.Context, r *OutboxRecord) error {
+	if r.UID == "" {
+		return errors.New("outbox record has no uid")
+	}
+	switch r.Status {
+	case OutboxPending, OutboxDelivered, OutboxDead:
+	default:
+		return fmt.Errorf("unknown outbox status %q", r.Status)
+	}
+	set := map[string]any{
+		"uid":                 r.UID,
+		"outbox.status":       r.Status,
+		"outbox.attempts":     r.Attempts,
+		"outbox.next_attempt": r.NextAttempt.UTC().Format(time.RFC3339Nano),
+		"outbox.last_error":   r.LastError,
+	}
+	if len(r.DeliveredTo) > 0 {
+		set["outbox.delivered_to"] = r.DeliveredTo
+	}
+	b, err := json.Marshal(set)
+	if err != nil {
+		return err
+	}
+	client, cleanup, err := c.conn.DgraphClient()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	_, err = client.NewTxn().Mutate(ctx, &api.Mutation{SetJson: b, CommitNow: true})
+	return err
+}
+
+// newNodeAlias is the blank node an added node is sent as, so that its
+// outbox record can link to it in the same request.
+const newNodeAlias = "_:outbox.node"
+
+// insertOutboxed inserts obj with the outbox record of its addition.
+func (t *tracingConn) insertOutboxed(ctx context.Context, obj any) error {
+	typ := typeName(obj)
+	uid := reflect.ValueOf(obj).Elem().FieldByName("UID")
+	e := Event{Op: OpAdd, Type: typ, UID: uid.String(), After: clone(obj)}
+	node := e.UID
+	if node == "" {
+		node = newNodeAlias
+		uid.SetString(node)
+	}
+	err := t.writeOutbox(ctx, []Event{e}, []string{node}, func(tx *dg.TxnContext) error {
+		_, err := tx.MutateBasic(obj)
+		return err
+	})
+	if err != nil && uid.String() == newNodeAlias {
+		uid.SetString("")
+	}
+	return err
+}
+
+// updateOutboxed updates the node from obj with the outbox record of the
+// update. before is the node's snapshot.
+func (t *tracingConn) updateOutboxed(ctx context.Context, obj, before any) error {
+	typ := typeName(obj)
+	uid := uidOf(obj)
+	e := Event{Op: OpUpdate, Type: typ, UID: uid, Before: before, After: clone(obj)}
+	return t.writeOutbox(ctx, []Event{e}, []string{uid}, func(tx *dg.TxnContext) error {
+		_, err := tx.MutateBasic(obj)
+		return err
+	})
+}
+
+// deleteOutboxed deletes the nodes uids, of the given types, with an outbox
+// record of each deletion. before holds their snapshots.
+func (t *tracingConn) deleteOutboxed(ctx context.Context, uids []string, types map[string]string, before []any) error {
+	events := make([]Event, len(uids))
+	for i, uid := range uids {
+		events[i] = Event{Op: OpDelete, Type: types[uid], UID: uid, Before: before[i]}
+	}
+	return t.writeOutbox(ctx, events, uids, func(tx *dg.TxnContext) error {
+		return tx.DeleteNode(uids...)
+	})
+}
+
+// writeOutbox runs mutate in a transaction whose request also sets a
+// record of each event, linked to the node of the same index.
+//
+// The change and its records must be one request: the embedded engine
+// commits every request on its own, so only a single request is atomic on
+// both it and a Dgraph cluster. The transaction commits with that request,
+// so should dgman ever send a change as several, the later ones fail
+// rather than commit without records.
+func (t *tracingConn) writeOutbox(ctx context.Context, events []Event, nodes []string, mutate func(*dg.TxnContext) error) error {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	records := make([]map[string]any, len(events))
+	for i, e := range events {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		records[i] = map[string]any{
+			"uid":                 fmt.Sprintf("_:outbox.record%d", i),
+			"dgraph.type":         "OutboxRecord",
+			"outbox.event":        string(b),
+			"outbox.node":         map[string]string{"uid": nodes[i]},
+			"outbox.status":       OutboxPending,
+			"outbox.attempts":     0,
+			"outbox.created":      now,
+			"outbox.next_attempt": now,
+		}
+	}
+	set, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	client, cleanup, err := t.DgraphClient()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	oc := &outboxClient{dg: client, records: &api.Mutation{SetJson: set}}
+	ctx, cancel := bound(t, ctx)
+	defer cancel()
+	if err := mutate(dg.NewTxnContext(ctx, dgo.NewDgraphClient(oc)).SetCommitNow()); err != nil {
+		if u := uniqueError(err); u != nil {
+			return u
+		}
+		return err
+	}
+	if oc.records != nil {
+		return errors.New("outbox: the change sent no mutation to record")
+	}
+	return nil
+}
+
+// duplicateValue matches Dgraph's report of a unique constraint violation.
+var duplicateValue = regexp.MustCompile(`could not insert duplicate value \[([^\]]+)\] for predicate \[([^\]]+)\]`)
+
+// uniqueError returns err as the *modusgraph.UniqueError modusgraph's
+// Insert and Update make of a unique constraint violation, or nil if it is
+// not one.
+func uniqueError(err error) *modusgraph.UniqueError {
+	m := duplicateValue.FindStringSubmatch(err.Error())
+	if m == nil {
+		return nil
+	}
+	return &modusgraph.UniqueError{Field: m[2], Value: m[1]}
+}
+
+// outboxClient is the api.DgraphClient behind changes written with the
+// outbox enabled. It adds the records to the request that makes the
+// change, so that Dgraph commits both or neither.
+type outboxClient struct {
+	api.DgraphClient
+
+	dg      *dgo.Dgraph
+	records *api.Mutation
+}
+
+func (o *outboxClient) Query(ctx context.Context, req *api.Request, _ ...grpc.CallOption) (*api.Response, error) {
+	if len(req.Mutations) > 0 {
+		if o.records == nil {
+			return nil, errors.New("outbox: the change took more than one request")
+		}
+		req.Mutations = append(req.Mutations, o.records)
+		o.records = nil
+	}
+	return o.dg.NewTxn().Do(ctx, req)
+}
+
+// CommitOrAbort is called to discard the transaction after a failed
+// request; each request is its own transaction, committed or discarded
+// already.
+func (o *outboxClient) CommitOrAbort(_ context.Context, tc *api.TxnContext, _ ...grpc.CallOption) (*api.TxnContext, error) {
+	return tc, nil
+}
+
+// read returns the node uid of the entity type typ, as an Event's Before,
+// or nil when it cannot be read.
+func (t *tracingConn) read(ctx context.Context, typ, uid string) any {
+	rt, ok := entityTypes()[typ]
+	if !ok {
+		return nil
+	}
+	v := reflect.New(rt)
+	if t.Client.Get(ctx, v.Interface(), uid) != nil {
+		return nil
+	}
+	return v.Interface()
+}
+
+// clone returns a copy of the entity obj points to, as an Event's After.
+func clone(obj any) any {
+	v := reflect.New(reflect.TypeOf(obj).Elem())
+	v.Elem().Set(reflect.ValueOf(obj).Elem())
+	return v.Interface()
+}
+
+// entityTypes maps the entity type names to their struct types.
+var entityTypes = sync.OnceValue(func() map[string]reflect.Type {
+	types := make(map[string]reflect.Type, len(entities))
+	for _, v := range entities {
+		t := reflect.TypeOf(v).Elem()
+		types[t.Name()] = t
+	}
+	return types
+})
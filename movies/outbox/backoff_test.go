@@ -0,0 +1,30 @@
+package outbox
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		relay    Relay
+		attempts int
+		want     time.Duration
+	}{
+		{Relay{}, 1, time.Second},
+		{Relay{}, 2, 2 * time.Second},
+		{Relay{}, 4, 8 * time.Second},
+		{Relay{}, 10, 512 * time.Second},
+		{Relay{}, 11, 10 * time.Minute},
+		{Relay{}, 1000, 10 * time.Minute},
+		{Relay{Backoff: time.Millisecond}, 3, 4 * time.Millisecond},
+		{Relay{Backoff: time.Second, MaxBackoff: 3 * time.Second}, 2, 2 * time.Second},
+		{Relay{Backoff: time.Second, MaxBackoff: 3 * time.Second}, 3, 3 * time.Second},
+		{Relay{Backoff: time.Minute, MaxBackoff: time.Second}, 1, time.Second},
+	}
+	for _, tt := range tests {
+		if got := tt.relay.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) with %v and %v = %v, want %v", tt.attempts, tt.relay.Backoff, tt.relay.MaxBackoff, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Package outbox delivers the changes a movies.Client records in its
+// outbox, as movies.Client.EnableOutbox describes, to HTTP webhooks.
+//
+// Each record is POSTed to every webhook as JSON:
+//
+//	{"id": "0x2a", "created": "...", "op": "update", "type": "Film", "uid": "0x1f", "before": {...}, "after": {...}}
+//
+// with these headers:
+//
+//	X-Movies-Delivery   the record's UID, the same on every attempt
+//	X-Movies-Timestamp  Unix seconds when the attempt was made
+//	X-Movies-Signature  "sha256=" and the hex HMAC-SHA256, keyed with the
+//	                    relay's secret, of the timestamp, a ".", and the body
+//
+// Any 2xx response accepts the record. A record is retried with
+// exponential backoff until every webhook has accepted it, and is set dead
+// once it runs out of attempts. Delivery is at least once: receivers should
+// ignore deliveries they have seen.
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+// Relay delivers outbox records to webhooks. Only one relay should run
+// against a database at a time.
+type Relay struct {
+	Client   *movies.Client
+	Webhooks []string
+	// Secret keys the signature; without one, deliveries are not signed.
+	Secret []byte
+
+	// HTTPClient sends the deliveries; the default times out after 10s.
+	HTTPClient *http.Client
+	// MaxAttempts is how often a record is tried before it is set dead;
+	// the default is 8.
+	MaxAttempts int
+	// Backoff is the wait after the first failed attempt, doubling after
+	// each one up to MaxBackoff. The defaults are 1s and 10m.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Batch is how many due records a pass reads at most; the default is
+	// 100.
+	Batch int
+
+	// Logf, when set, is called for every attempt.
+	Logf func(format string, args ...any)
+}
+
+// Delivery is the JSON body POSTed for a record.
+type Delivery struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	movies.Event
+}
+
+// Headers set on every delivery.
+const (
+	HeaderDelivery  = "X-Movies-Delivery"
+	HeaderTimestamp = "X-Movies-Timestamp"
+	HeaderSignature = "X-Movies-Signature"
+)
+
+// Sign returns the signature header value for body sent at timestamp.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether a request's signature header matches its body,
+// for receivers. It does not judge the timestamp's age.
+func Verify(secret []byte, r *http.Request, body []byte) bool {
+	want := Sign(secret, r.Header.Get(HeaderTimestamp), body)
+	return hmac.Equal([]byte(want), []byte(r.Header.Get(HeaderSignature)))
+}
+
+// Run delivers the due records every interval until ctx is done.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Deliver(ctx); err != nil && ctx.Err() == nil {
+			r.logf("outbox: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Deliver makes one pass over the records that are due, oldest first, and
+// returns how many were delivered to every webhook.
+func (r *Relay) Deliver(ctx context.Context) (delivered int, err error) {
+	if len(r.Webhooks) == 0 {
+		return 0, errors.New("no webhooks to deliver to")
+	}
+	records, err := r.Client.DueOutboxRecords(ctx, time.Now(), orDefault(r.Batch, 100))
+	if err != nil {
+		return 0, err
+	}
+	for i := range records {
+		rec := &records[i]
+		if err := r.deliver(ctx, rec); err != nil {
+			return delivered, err
+		}
+		if rec.Status == movies.OutboxDelivered {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// deliver attempts rec on the webhooks it has not reached and saves the
+// outcome.
+func (r *Relay) deliver(ctx context.Context, rec *movies.OutboxRecord) error {
+	body, err := json.Marshal(Delivery{ID: rec.UID, Created: rec.Created, Event: rec.Event})
+	if err != nil {
+		return err
+	}
+	var failures []string
+	for _, url := range r.Webhooks {
+		if slices.Contains(rec.DeliveredTo, url) {
+			continue
+		}
+		if err := r.post(ctx, url, rec.UID, body); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			failures = append(failures, err.Error())
+			r.logf("%s %s %s: %v", rec.UID, rec.Event.Op, rec.Event.Type, err)
+			continue
+		}
+		rec.DeliveredTo = append(rec.DeliveredTo, url)
+		r.logf("%s %s %s %s: delivered", rec.UID, rec.Event.Op, rec.Event.Type, url)
+	}
+	rec.Attempts++
+	switch {
+	case len(failures) == 0:
+		rec.Status, rec.LastError = movies.OutboxDelivered, ""
+	case rec.Attempts >= orDefault(r.MaxAttempts, 8):
+		rec.Status, rec.LastError = movies.OutboxDead, strings.Join(failures, "; ")
+		r.logf("%s dead after %d attempts", rec.UID, rec.Attempts)
+	default:
+		rec.LastError = strings.Join(failures, "; ")
+		rec.NextAttempt = time.Now().Add(r.backoff(rec.Attempts))
+	}
+	return r.Client.SaveOutboxRecord(ctx, rec)
+}
+
+func (r *Relay) post(ctx context.Context, url, id string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDelivery, id)
+	req.Header.Set(HeaderTimestamp, ts)
+	if len(r.Secret) > 0 {
+		req.Header.Set(HeaderSignature, Sign(r.Secret, ts, body))
+	}
+	hc := r.HTTPClient
+	if hc == nil {
+		hc = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST %s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (r *Relay) backoff(attempts int) time.Duration {
+	d, limit := orDefault(r.Backoff, time.Second), orDefault(r.MaxBackoff, 10*time.Minute)
+	for range attempts - 1 {
+		if d >= limit/2 {
+			return limit
+		}
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+func (r *Relay) logf(format string, args ...any) {
+	if r.Logf != nil {
+		r.Logf(format, args...)
+	}
+}
+
+// orDefault returns v, or def when v is not positive.
+func orDefault[T int | time.Duration](v, def T) T {
+	if v > 0 {
+		return v
+	}
+	return def
+}
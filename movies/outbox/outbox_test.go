@@ -0,0 +1,224 @@
+package outbox_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/internal/dgraphtest"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/outbox"
+)
+
+func TestVerify(t *testing.T) {
+	secret := []byte("outbox-test-secret")
+	body := []byte(`{"a":1}`)
+	signed := func(ts, sig string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if ts != "" {
+			req.Header.Set(outbox.HeaderTimestamp, ts)
+		}
+		if sig != "" {
+			req.Header.Set(outbox.HeaderSignature, sig)
+		}
+		return req
+	}
+	sig := outbox.Sign(secret, "1", body)
+	if !strings.HasPrefix(sig, "sha256=") || len(sig) != len("sha256=")+64 {
+		t.Errorf("expected sha256= and a hex HMAC, got %q", sig)
+	}
+	if sig != outbox.Sign(secret, "1", body) {
+		t.Error("expected signing to be deterministic")
+	}
+	if !outbox.Verify(secret, signed("1", sig), body) {
+		t.Error("expected the signature to match its body")
+	}
+	for name, tt := range map[string]struct {
+		secret []byte
+		req    *http.Request
+		body   []byte
+	}{
+		"other body":        {secret, signed("1", sig), []byte(`{"a":2}`)},
+		"other timestamp":   {secret, signed("2", sig), body},
+		"other secret":      {[]byte("other"), signed("1", sig), body},
+		"no signature":      {secret, signed("1", ""), body},
+		"no timestamp":      {secret, signed("", sig), body},
+		"signature as body": {secret, signed("1", outbox.Sign(secret, "", []byte(`1.{"a":1}`))), body},
+	} {
+		if outbox.Verify(tt.secret, tt.req, tt.body) {
+			t.Errorf("%s: expected Verify to fail", name)
+		}
+	}
+}
+
+func TestDeliverWithoutWebhooks(t *testing.T) {
+	relay := &outbox.Relay{Client: &movies.Client{}}
+	if _, err := relay.Deliver(context.Background()); err == nil {
+		t.Error("expected an error with no webhooks")
+	}
+}
+
+// webhookReceiver is an httptest webhook that checks signatures, fails the
+// first failFirst attempts of each delivery and then every attempt while
+// down, and keeps what it accepted.
+type webhookReceiver struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	attempts  map[string]int
+	accepted  []outbox.Delivery
+	failFirst int
+	down      bool
+	unsigned  int
+}
+
+func newWebhookReceiver(t *testing.T, secret []byte, failFirst int, down bool) *webhookReceiver {
+	w := &webhookReceiver{attempts: map[string]int{}, failFirst: failFirst, down: down}
+	w.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if !outbox.Verify(secret, r, body) {
+			w.unsigned++
+			http.Error(rw, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		id := r.Header.Get(outbox.HeaderDelivery)
+		w.attempts[id]++
+		if w.down || w.attempts[id] <= w.failFirst {
+			http.Error(rw, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		var d outbox.Delivery
+		if err := json.Unmarshal(body, &d); err != nil || d.ID != id {
+			http.Error(rw, "bad delivery", http.StatusBadRequest)
+			return
+		}
+		w.accepted = append(w.accepted, d)
+	}))
+	t.Cleanup(w.Close)
+	return w
+}
+
+// deliveries returns the accepted deliveries of changes to uid.
+func (w *webhookReceiver) deliveries(uid string) []outbox.Delivery {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var ds []outbox.Delivery
+	for _, d := range w.accepted {
+		if d.UID == uid {
+			ds = append(ds, d)
+		}
+	}
+	return ds
+}
+
+func TestRelay(t *testing.T) {
+	c := dgraphtest.Client(t)
+	ctx := context.Background()
+	if err := c.EnableOutbox(ctx); err != nil {
+		t.Fatalf("EnableOutbox: %v", err)
+	}
+	secret := []byte("outbox-test-secret")
+
+	// Deliveries that fail once are retried until accepted.
+	flaky := newWebhookReceiver(t, secret, 1, false)
+	relay := &outbox.Relay{
+		Client:   c,
+		Webhooks: []string{flaky.URL},
+		Secret:   secret,
+		Backoff:  time.Millisecond,
+	}
+	g := &movies.Genre{Name: "Outboxed Genre"}
+	if err := c.Genre.Add(ctx, g); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(g.UID, "0x") {
+		t.Fatalf("expected the added node's UID, got %q", g.UID)
+	}
+	if err := c.Genre.Update(ctx, &movies.Genre{UID: g.UID, Name: "Outboxed Genre Renamed"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Genre.Delete(ctx, g.UID); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20 && len(flaky.deliveries(g.UID)) < 3; i++ {
+		if _, err := relay.Deliver(ctx); err != nil {
+			t.Fatalf("Deliver: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	ds := flaky.deliveries(g.UID)
+	if len(ds) != 3 {
+		t.Fatalf("expected 3 deliveries for %s, got %+v", g.UID, ds)
+	}
+	if ds[0].Op != movies.OpAdd || ds[1].Op != movies.OpUpdate || ds[2].Op != movies.OpDelete {
+		t.Fatalf("expected add, update and delete in order, got %v, %v, %v", ds[0].Op, ds[1].Op, ds[2].Op)
+	}
+	if a, _ := ds[0].After.(map[string]any); a["name"] != "Outboxed Genre" || a["uid"] != g.UID {
+		t.Errorf("unexpected add payload %v", ds[0].After)
+	}
+	if b, _ := ds[1].Before.(map[string]any); b["name"] != "Outboxed Genre" {
+		t.Errorf("unexpected update before %v", ds[1].Before)
+	}
+	if b, _ := ds[2].Before.(map[string]any); b["name"] != "Outboxed Genre Renamed" || ds[2].After != nil {
+		t.Errorf("unexpected delete payload %v, %v", ds[2].Before, ds[2].After)
+	}
+	flaky.mu.Lock()
+	if flaky.attempts[ds[0].ID] != 2 || flaky.unsigned != 0 {
+		t.Errorf("expected one retry of each signed delivery, got %d attempts, %d unsigned", flaky.attempts[ds[0].ID], flaky.unsigned)
+	}
+	flaky.mu.Unlock()
+	delivered, err := c.OutboxRecords(ctx, movies.OutboxDelivered, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(delivered, func(r movies.OutboxRecord) bool { return r.UID == ds[2].ID }) {
+		t.Errorf("expected record %s to be delivered", ds[2].ID)
+	}
+
+	// A webhook that stays down dead-letters the record; the one that
+	// accepted it is not sent it again.
+	ok := newWebhookReceiver(t, secret, 0, false)
+	down := newWebhookReceiver(t, secret, 0, true)
+	relay = &outbox.Relay{
+		Client:      c,
+		Webhooks:    []string{ok.URL, down.URL},
+		Secret:      secret,
+		MaxAttempts: 2,
+		Backoff:     time.Millisecond,
+	}
+	lost := &movies.Genre{Name: "Undeliverable Genre"}
+	if err := c.Genre.Add(ctx, lost); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Genre.Delete(ctx, lost.UID) })
+	isLost := func(r movies.OutboxRecord) bool { return r.Event.UID == lost.UID }
+	var dead []movies.OutboxRecord
+	for i := 0; i < 20 && !slices.ContainsFunc(dead, isLost); i++ {
+		if _, err := relay.Deliver(ctx); err != nil {
+			t.Fatalf("Deliver: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+		if dead, err = c.OutboxRecords(ctx, movies.OutboxDead, 1000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	i := slices.IndexFunc(dead, isLost)
+	if i < 0 {
+		t.Fatalf("expected the record of %s among the dead letters", lost.UID)
+	}
+	if r := dead[i]; r.Attempts != 2 || !slices.Equal(r.DeliveredTo, []string{ok.URL}) || !strings.Contains(r.LastError, "503") {
+		t.Errorf("unexpected dead letter %+v", r)
+	}
+	if n := len(ok.deliveries(lost.UID)); n != 1 {
+		t.Errorf("expected one delivery to the webhook that accepted it, got %d", n)
+	}
+}
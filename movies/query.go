@@ -0,0 +1,200 @@
+package movies
+
+import (
+	"context"
+
+	"github.com/matthewmcneely/modusgraph"
+)
+
+// queryDQL returns the DQL of the query the generated Exec builds from a
+// query builder's settings.
+func queryDQL(ctx context.Context, conn modusgraph.Client, model any, filter string, first, offset int, orderBy string, orderDesc bool) string {
+	dq := conn.Query(ctx, model)
+	if filter != "" {
+		dq = dq.Filter(filter)
+	}
+	if first > 0 {
+		dq = dq.First(first)
+	}
+	if offset > 0 {
+		dq = dq.Offset(offset)
+	}
+	if orderBy != "" {
+		if orderDesc {
+			dq = dq.OrderDesc(orderBy)
+		} else {
+			dq = dq.OrderAsc(orderBy)
+		}
+	}
+	return dq.String()
+}
+
+// addOrder returns a query builder's orderBy and orderDesc with a further
+// sort key on predicate. The generated Exec hands orderBy to dgman as a
+// single order clause, so keys after the first are appended to it in DQL's
+// own ", orderasc: p" form and keep the first key's direction in orderDesc.
+func addOrder(orderBy string, orderDesc bool, predicate string, desc bool) (string, bool) {
+	switch {
+	case predicate == "":
+		return orderBy, orderDesc
+	case orderBy == "":
+		return predicate, desc
+	case desc:
+		return orderBy + ", orderdesc: " + predicate, orderDesc
+	}
+	return orderBy + ", orderasc: " + predicate, orderDesc
+}
+
+// ToDQL returns the DQL that Exec sends.
+func (q *ActorQuery) ToDQL() string {
+	return queryDQL(q.ctx, q.conn, Actor{}, q.filter, q.first, q.offset, q.orderBy, q.orderDesc)
+}
+
+// ToDQL returns the DQL that Exec sends.
+func (q *ContentRatingQuery) ToDQL() string {
+	return queryDQL(q.ctx, q.conn, ContentRating{}, q.filter, q.first, q.offset, q.orderBy, q.orderDesc)
+}
+
+// ToDQL returns the DQL that Exec sends.
+func (q *CountryQuery) ToDQL() string {
+	return queryDQL(q.ctx, q.conn, Country{}, q.filter, q.first, q.offset, q.orderBy, q.orderDesc)
+}
+
+// ToDQL returns the DQL that Exec sends.
+func (q *DirectorQuery) ToDQL() string {
+	return queryDQL(q.ctx, q.conn, Director{}, q.filter, q.first, q.offset, q.orderBy, q.orderDesc)
+}
+
+// ToDQL returns the DQL that Exec sends.
+func (q *FilmQuery) ToDQL() string {
+	return queryDQL(q.ctx, q.conn, Film{}, q.filter, q.first, q.offset, q.orderBy, q.orderDesc)
+}
+
+// ToDQL returns the DQL that Exec sends.
+func (q *GenreQuery) ToDQL() string {
+	return queryDQL(q.ctx, q.conn, Genre{}, q.filter, q.first, q.offset, q.orderBy, q.orderDesc)
+}
+
+// ToDQL returns the DQL that Exec sends.
+func (q *LocationQuery) ToDQL() string {
+	return queryDQL(q.ctx, q.conn, Location{}, q.filter, q.first, q.offset, q.orderBy, q.orderDesc)
+}
+
+// ToDQL returns the DQL that Exec sends.
+func (q *PerformanceQuery) ToDQL() string {
+	return queryDQL(q.ctx, q.conn, Performance{}, q.filter, q.first, q.offset, q.orderBy, q.orderDesc)
+}
+
+// ToDQL returns the DQL that Exec sends.
+func (q *RatingQuery) ToDQL() string {
+	return queryDQL(q.ctx, q.conn, Rating{}, q.filter, q.first, q.offset, q.orderBy, q.orderDesc)
+}
+
+// SortAsc adds ascending order on field. Each call adds a further sort key,
+// applied after those already set; OrderAsc and OrderDesc replace them all.
+func (q *ActorQuery) SortAsc(field SortField[Actor]) *ActorQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, false)
+	return q
+}
+
+// SortDesc adds descending order on field; see ActorQuery.SortAsc.
+func (q *ActorQuery) SortDesc(field SortField[Actor]) *ActorQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, true)
+	return q
+}
+
+// SortAsc adds ascending order on field; see ActorQuery.SortAsc.
+func (q *ContentRatingQuery) SortAsc(field SortField[ContentRating]) *ContentRatingQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, false)
+	return q
+}
+
+// SortDesc adds descending order on field; see ActorQuery.SortAsc.
+func (q *ContentRatingQuery) SortDesc(field SortField[ContentRating]) *ContentRatingQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, true)
+	return q
+}
+
+// SortAsc adds ascending order on field; see ActorQuery.SortAsc.
+func (q *CountryQuery) SortAsc(field SortField[Country]) *CountryQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, false)
+	return q
+}
+
+// SortDesc adds descending order on field; see ActorQuery.SortAsc.
+func (q *CountryQuery) SortDesc(field SortField[Country]) *CountryQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, true)
+	return q
+}
+
+// SortAsc adds ascending order on field; see ActorQuery.SortAsc.
+func (q *DirectorQuery) SortAsc(field SortField[Director]) *DirectorQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, false)
+	return q
+}
+
+// SortDesc adds descending order on field; see ActorQuery.SortAsc.
+func (q *DirectorQuery) SortDesc(field SortField[Director]) *DirectorQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, true)
+	return q
+}
+
+// SortAsc adds ascending order on field; see ActorQuery.SortAsc.
+func (q *FilmQuery) SortAsc(field SortField[Film]) *FilmQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, false)
+	return q
+}
+
+// SortDesc adds descending order on field; see ActorQuery.SortAsc.
+func (q *FilmQuery) SortDesc(field SortField[Film]) *FilmQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, true)
+	return q
+}
+
+// SortAsc adds ascending order on field; see ActorQuery.SortAsc.
+func (q *GenreQuery) SortAsc(field SortField[Genre]) *GenreQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, false)
+	return q
+}
+
+// SortDesc adds descending order on field; see ActorQuery.SortAsc.
+func (q *GenreQuery) SortDesc(field SortField[Genre]) *GenreQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, true)
+	return q
+}
+
+// SortAsc adds ascending order on field; see ActorQuery.SortAsc.
+func (q *LocationQuery) SortAsc(field SortField[Location]) *LocationQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, false)
+	return q
+}
+
+// SortDesc adds descending order on field; see ActorQuery.SortAsc.
+func (q *LocationQuery) SortDesc(field SortField[Location]) *LocationQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, true)
+	return q
+}
+
+// SortAsc adds ascending order on field; see ActorQuery.SortAsc.
+func (q *PerformanceQuery) SortAsc(field SortField[Performance]) *PerformanceQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, false)
+	return q
+}
+
+// SortDesc adds descending order on field; see ActorQuery.SortAsc.
+func (q *PerformanceQuery) SortDesc(field SortField[Performance]) *PerformanceQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, true)
+	return q
+}
+
+// SortAsc adds ascending order on field; see ActorQuery.SortAsc.
+func (q *RatingQuery) SortAsc(field SortField[Rating]) *RatingQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, false)
+	return q
+}
+
+// SortDesc adds descending order on field; see ActorQuery.SortAsc.
+func (q *RatingQuery) SortDesc(field SortField[Rating]) *RatingQuery {
+	q.orderBy, q.orderDesc = addOrder(q.orderBy, q.orderDesc, field.predicate, true)
+	return q
+}
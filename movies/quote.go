@@ -0,0 +1,46 @@
+package movies
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Quote returns s as a double-quoted DQL string literal. DQL accepts fewer
+// escapes than Go, so strconv.Quote, which writes \x07 or \U0001f600 for
+// some characters, can give a literal Dgraph rejects.
+func Quote(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\v':
+			b.WriteString(`\v`)
+		default:
+			// Invalid UTF-8 decodes as utf8.RuneError and is written as it.
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
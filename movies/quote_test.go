@@ -0,0 +1,31 @@
+package movies_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Star Wars", `"Star Wars"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`C:\films\`, `"C:\\films\\"`},
+		{"a\nb\tc\r", `"a\nb\tc\r"`},
+		{"bell\a del\x7f", `"bell\u0007 del\u007f"`},
+		{"😀 é", `"😀 é"`},
+	}
+	for _, tt := range tests {
+		got := movies.Quote(tt.in)
+		if got != tt.want {
+			t.Errorf("Quote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+		// Dgraph unquotes literals with strconv.Unquote.
+		if back, err := strconv.Unquote(got); err != nil || back != tt.in {
+			t.Errorf("Unquote(%s) = %q, %v; want %q", got, back, err, tt.in)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package rest
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+// object is a JSON object in the OpenAPI document.
+type object = map[string]any
+
+// OpenAPI returns the OpenAPI 3 document for the API Handler serves,
+// derived from the entity structs.
+func OpenAPI() map[string]any {
+	schemas := object{
+		"Error": object{
+			"type":     "object",
+			"required": []string{"error"},
+			"properties": object{
+				"error": object{
+					"type":     "object",
+					"required": []string{"status", "code", "message"},
+					"properties": object{
+						"status":  object{"type": "integer"},
+						"code":    object{"type": "string", "enum": []string{"bad_request", "not_found", "method_not_allowed", "too_large", "internal"}},
+						"message": object{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+	paths := object{}
+	all := resources(&movies.Client{})
+	for _, rs := range all {
+		schemas[rs.typeName] = entitySchema(rs.model, schemas)
+		paths["/"+rs.path] = collectionPath(rs)
+		paths["/"+rs.path+"/{uid}"] = nodePath(rs)
+		for _, edge := range edges(rs.model) {
+			paths["/"+rs.path+"/{uid}/"+edge] = edgePath(rs, edge, targetOf(rs.model, edge, all))
+		}
+	}
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":       "movies",
+			"version":     "1.0.0",
+			"description": "The movies data model over REST.",
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": schemas,
+			"parameters": object{
+				"uid":    object{"name": "uid", "in": "path", "required": true, "schema": object{"type": "string", "pattern": "^0x[0-9a-f]+$"}},
+				"first":  object{"name": "first", "in": "query", "description": "Page size.", "schema": object{"type": "integer", "minimum": 1, "maximum": maxFirst, "default": defaultFirst}},
+				"cursor": object{"name": "cursor", "in": "query", "description": "The nextCursor of the previous page.", "schema": object{"type": "string"}},
+				"fields": object{"name": "fields", "in": "query", "description": "Comma-separated JSON field names to fetch.", "schema": object{"type": "string"}},
+			},
+			"responses": object{
+				"Error": object{
+					"description": "An error.",
+					"content":     jsonContent(ref("schemas", "Error")),
+				},
+			},
+		},
+	}
+}
+
+func ref(kind, name string) object {
+	return object{"$ref": "#/components/" + kind + "/" + name}
+}
+
+func jsonContent(schema object) object {
+	return object{"application/json": object{"schema": schema}}
+}
+
+// responses returns an operation's responses: status with schema, and the
+// error response for every other status.
+func responses(status int, description string, schema object) object {
+	r := object{"description": description}
+	if schema != nil {
+		r["content"] = jsonContent(schema)
+	}
+	return object{
+		strconv.Itoa(status): r,
+		"default":            ref("responses", "Error"),
+	}
+}
+
+func pageSchema(items object) object {
+	return object{
+		"type":     "object",
+		"required": []string{"items"},
+		"properties": object{
+			"items":      object{"type": "array", "items": items},
+			"nextCursor": object{"type": "string", "description": "Set while more pages may follow."},
+		},
+	}
+}
+
+func collectionPath(rs *resource) object {
+	params := []any{ref("parameters", "first"), ref("parameters", "cursor"), ref("parameters", "fields")}
+	if rs.search {
+		params = append(params, object{"name": "search", "in": "query", "description": "Fulltext search on name.", "schema": object{"type": "string"}})
+	}
+	return object{
+		"get": object{
+			"operationId": "list" + rs.typeName,
+			"summary":     "List " + rs.typeName + " entities.",
+			"parameters":  params,
+			"responses":   responses(http.StatusOK, "A page of "+rs.typeName+" entities.", pageSchema(ref("schemas", rs.typeName))),
+		},
+		"post": object{
+			"operationId": "add" + rs.typeName,
+			"summary":     "Add a " + rs.typeName + ".",
+			"requestBody": object{"required": true, "content": jsonContent(ref("schemas", rs.typeName))},
+			"responses":   responses(http.StatusCreated, "The added "+rs.typeName+".", ref("schemas", rs.typeName)),
+		},
+	}
+}
+
+func nodePath(rs *resource) object {
+	return object{
+		"parameters": []any{ref("parameters", "uid")},
+		"get": object{
+			"operationId": "get" + rs.typeName,
+			"summary":     "Get a " + rs.typeName + ".",
+			"parameters":  []any{ref("parameters", "fields")},
+			"responses":   responses(http.StatusOK, "The "+rs.typeName+".", ref("schemas", rs.typeName)),
+		},
+		"patch": object{
+			"operationId": "update" + rs.typeName,
+			"summary":     "Set the given fields of a " + rs.typeName + " and add the given edges.",
+			"requestBody": object{"required": true, "content": jsonContent(ref("schemas", rs.typeName))},
+			"responses":   responses(http.StatusOK, "The updated "+rs.typeName+".", ref("schemas", rs.typeName)),
+		},
+		"delete": object{
+			"operationId": "delete" + rs.typeName,
+			"summary":     "Delete a " + rs.typeName + ".",
+			"responses":   responses(http.StatusNoContent, "Deleted.", nil),
+		},
+	}
+}
+
+func edgePath(rs *resource, edge string, target *resource) object {
+	return object{
+		"parameters": []any{ref("parameters", "uid")},
+		"get": object{
+			"operationId": "get" + rs.typeName + strings.ToUpper(edge[:1]) + edge[1:],
+			"summary":     "List the " + target.typeName + " entities a " + rs.typeName + "'s " + edge + " edge links to.",
+			"parameters":  []any{ref("parameters", "first"), ref("parameters", "cursor")},
+			"responses":   responses(http.StatusOK, "A page of "+target.typeName+" entities.", pageSchema(ref("schemas", target.typeName))),
+		},
+	}
+}
+
+// entitySchema describes an entity struct by its JSON fields.
+func entitySchema(t reflect.Type, schemas object) object {
+	props := object{}
+	for _, f := range reflect.VisibleFields(t) {
+		name := jsonName(f)
+		if name == "" || name == "-" {
+			continue
+		}
+		s := typeSchema(f.Type, schemas)
+		switch {
+		case name == "uid":
+			s["description"] = "Set by the server; give it to link an existing node in an edge."
+		case name == "dgraph.type":
+			s["readOnly"] = true
+		case strings.HasPrefix(predicateOf(f), "~"):
+			s["readOnly"] = true
+			s["description"] = "Reverse edge; link it from the other node."
+		}
+		props[name] = s
+	}
+	return object{"type": "object", "properties": props}
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func typeSchema(t reflect.Type, schemas object) object {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if e := entityType(t); e != nil {
+		return object{"type": "array", "items": ref("schemas", e.Name())}
+	}
+	switch {
+	case t == timeType:
+		return object{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return object{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return object{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return object{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return object{"type": "number"}
+	case t.Kind() == reflect.Slice:
+		return object{"type": "array", "items": typeSchema(t.Elem(), schemas)}
+	case t.Kind() == reflect.Struct:
+		// Value structs such as GeoPoint get a schema of their own.
+		if _, ok := schemas[t.Name()]; !ok {
+			schemas[t.Name()] = entitySchema(t, schemas)
+		}
+		return ref("schemas", t.Name())
+	}
+	return object{}
+}
@@ -0,0 +1,558 @@
+// Package rest serves the typed movies client over HTTP as JSON resources,
+// one collection per entity:
+//
+//	GET    /films                 list, or search with ?search=
+//	POST   /films                 add
+//	GET    /films/{uid}           get
+//	PATCH  /films/{uid}           set the given fields and add the given edges
+//	DELETE /films/{uid}           delete
+//	GET    /films/{uid}/{edge}    the nodes an edge links to, e.g. /films/{uid}/genres
+//	GET    /openapi.json          the OpenAPI 3 document describing all of these
+//
+// Lists take ?first= and ?cursor=, and return {"items": [...], "nextCursor": "..."}
+// with nextCursor set while more pages may follow. Gets and lists take
+// ?fields= with a comma-separated list of JSON field names. Errors are
+// returned as {"error": {"status": 404, "code": "not_found", "message": "..."}}.
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	dg "github.com/dolan-in/dgman/v2"
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+)
+
+const (
+	// defaultFirst is the page size when ?first= is not given.
+	defaultFirst = 20
+	// maxFirst caps ?first=.
+	maxFirst = 1000
+)
+
+// Handler returns the REST API for client.
+func Handler(client *movies.Client) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, errMethod(r))
+			return
+		}
+		writeJSON(w, http.StatusOK, OpenAPI())
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, &Error{Status: http.StatusNotFound, Code: "not_found", Message: "no resource at " + r.URL.Path})
+	})
+	res := resources(client)
+	for _, rs := range res {
+		mux.HandleFunc("/"+rs.path, func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				serve(w, http.StatusOK)(rs.list(r.Context(), r.URL.Query()))
+			case http.MethodPost:
+				v, err := rs.add(r.Context(), r)
+				if err == nil {
+					w.Header().Set("Location", "/"+rs.path+"/"+uidOf(v))
+				}
+				serve(w, http.StatusCreated)(v, err)
+			default:
+				writeError(w, errMethod(r))
+			}
+		})
+		mux.HandleFunc("/"+rs.path+"/{uid}", func(w http.ResponseWriter, r *http.Request) {
+			uid := r.PathValue("uid")
+			switch r.Method {
+			case http.MethodGet:
+				serve(w, http.StatusOK)(rs.get(r.Context(), uid, r.URL.Query().Get("fields")))
+			case http.MethodPatch:
+				serve(w, http.StatusOK)(rs.update(r.Context(), uid, r))
+			case http.MethodDelete:
+				if err := rs.delete(r.Context(), uid); err != nil {
+					writeError(w, err)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				writeError(w, errMethod(r))
+			}
+		})
+		mux.HandleFunc("/"+rs.path+"/{uid}/{edge}", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				writeError(w, errMethod(r))
+				return
+			}
+			serve(w, http.StatusOK)(rs.edge(r.Context(), client, res, r.PathValue("uid"), r.PathValue("edge"), r.URL.Query()))
+		})
+	}
+	return mux
+}
+
+// Error is the body of every error response, under "error".
+type Error struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func badRequest(format string, args ...any) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: "bad_request", Message: fmt.Sprintf(format, args...)}
+}
+
+func notFound(typeName, uid string) *Error {
+	return &Error{Status: http.StatusNotFound, Code: "not_found", Message: fmt.Sprintf("no %s with UID %s", typeName, uid)}
+}
+
+func errMethod(r *http.Request) *Error {
+	return &Error{Status: http.StatusMethodNotAllowed, Code: "method_not_allowed",
+		Message: fmt.Sprintf("%s is not supported on %s", r.Method, r.URL.Path)}
+}
+
+// serve returns a function writing a handler's result: the value with
+// status, or the error.
+func serve(w http.ResponseWriter, status int) func(any, error) {
+	return func(v any, err error) {
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, status, v)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// writeError writes err as an Error body. Errors that are not already an
+// Error are the database's and are reported as internal.
+func writeError(w http.ResponseWriter, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Status: http.StatusInternalServerError, Code: "internal", Message: err.Error()}
+	}
+	writeJSON(w, e.Status, map[string]*Error{"error": e})
+}
+
+// Page is the body of list responses.
+type Page struct {
+	Items      any    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// cursor encodes an offset as an opaque page token.
+func cursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.Itoa(offset)))
+}
+
+// paging reads ?first= and ?cursor=.
+func paging(q map[string][]string) (first, offset int, err error) {
+	first = defaultFirst
+	if s := firstValue(q, "first"); s != "" {
+		if first, err = strconv.Atoi(s); err != nil || first < 1 || first > maxFirst {
+			return 0, 0, badRequest("first must be a number from 1 to %d", maxFirst)
+		}
+	}
+	if s := firstValue(q, "cursor"); s != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(s)
+		n, ok := strings.CutPrefix(string(raw), "o:")
+		if err == nil && ok {
+			offset, err = strconv.Atoi(n)
+		}
+		if err != nil || !ok || offset < 0 {
+			return 0, 0, badRequest("invalid cursor %q", s)
+		}
+	}
+	return first, offset, nil
+}
+
+func firstValue(q map[string][]string, key string) string {
+	if v := q[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// page wraps a page of items, with a cursor for the next one when the page
+// is full.
+func page[T any](items []T, first, offset int) *Page {
+	p := &Page{Items: items}
+	if items == nil {
+		p.Items = []T{}
+	}
+	if len(items) == first {
+		p.NextCursor = cursor(offset + first)
+	}
+	return p
+}
+
+// resource serves one entity type. Its functions close over the typed sub-client.
+type resource struct {
+	path     string
+	typeName string
+	model    reflect.Type
+	search   bool
+
+	list   func(ctx context.Context, q map[string][]string) (any, error)
+	get    func(ctx context.Context, uid, fields string) (any, error)
+	add    func(ctx context.Context, r *http.Request) (any, error)
+	update func(ctx context.Context, uid string, r *http.Request) (any, error)
+	delete func(ctx context.Context, uid string) error
+	// byUID returns the nodes with the given UIDs.
+	byUID func(ctx context.Context, uids []string) (any, error)
+	// predicate returns the Dgraph predicate of an edge's JSON name.
+	predicate func(edge string) (string, bool)
+}
+
+// resources returns the served entity types. A zero Client is enough for
+// describing them, as OpenAPI does.
+func resources(c *movies.Client) []*resource {
+	return []*resource{
+		newResource[movies.Actor, *movies.ActorQuery]("actors", "Actor", c.Actor, c.Actor.Select),
+		newResource[movies.ContentRating, *movies.ContentRatingQuery]("content-ratings", "ContentRating", c.ContentRating, c.ContentRating.Select),
+		newResource[movies.Country, *movies.CountryQuery]("countries", "Country", c.Country, c.Country.Select),
+		newResource[movies.Director, *movies.DirectorQuery]("directors", "Director", c.Director, c.Director.Select),
+		newResource[movies.Film, *movies.FilmQuery]("films", "Film", c.Film, c.Film.Select),
+		newResource[movies.Genre, *movies.GenreQuery]("genres", "Genre", c.Genre, c.Genre.Select),
+		newResource[movies.Location, *movies.LocationQuery]("locations", "Location", c.Location, c.Location.Select),
+		newResource[movies.Performance, *movies.PerformanceQuery]("performances", "Performance", c.Performance, c.Performance.Select),
+		newResource[movies.Rating, *movies.RatingQuery]("ratings", "Rating", c.Rating, c.Rating.Select),
+	}
+}
+
+// entityClient is the method set every generated sub-client shares.
+type entityClient[T any, Q any] interface {
+	Get(ctx context.Context, uid string) (*T, error)
+	List(ctx context.Context, opts ...movies.PageOption) ([]T, error)
+	Add(ctx context.Context, v *T) error
+	Update(ctx context.Context, v *T) error
+	Delete(ctx context.Context, uid string) error
+	Query(ctx context.Context) Q
+}
+
+// searcher is implemented by the sub-clients of types with a name index.
+type searcher[T any] interface {
+	Find(ctx context.Context, term string, opts ...movies.PageOption) ([]T, error)
+}
+
+// entityQuery is the method set every generated query builder shares.
+type entityQuery[T any, Q any] interface {
+	Filter(f string) Q
+	First(n int) Q
+	Exec(dst *[]T) error
+}
+
+func newResource[T any, Q entityQuery[T, Q], C entityClient[T, Q]](path, typeName string, c C,
+	sel func(...movies.Field[T]) C) *resource {
+	rs := &resource{path: path, typeName: typeName, model: reflect.TypeFor[T]()}
+	_, searchable := any(c).(searcher[T])
+	rs.search = searchable
+
+	// selection returns the client to read with: a Select view when
+	// fields, a comma-separated list, are given.
+	selection := func(fields string) (C, error) {
+		if fields == "" {
+			return c, nil
+		}
+		var parsed []movies.Field[T]
+		for name := range strings.SplitSeq(fields, ",") {
+			f, err := movies.ParseField[T](strings.TrimSpace(name))
+			if err != nil {
+				return c, badRequest("%v", err)
+			}
+			parsed = append(parsed, f)
+		}
+		return sel(parsed...), nil
+	}
+	// get fetches a node, failing when it does not exist or is not a T.
+	get := func(ctx context.Context, c C, uid string) (*T, error) {
+		if !movies.IsUID(uid) {
+			return nil, badRequest("%q is not a UID", uid)
+		}
+		v, err := c.Get(ctx, uid)
+		if errors.Is(err, dg.ErrNodeNotFound) || err == nil && !slices.Contains(dgraphType(v), typeName) {
+			return nil, notFound(typeName, uid)
+		}
+		return v, err
+	}
+
+	rs.list = func(ctx context.Context, q map[string][]string) (any, error) {
+		first, offset, err := paging(q)
+		if err != nil {
+			return nil, err
+		}
+		c, err := selection(firstValue(q, "fields"))
+		if err != nil {
+			return nil, err
+		}
+		opts := []movies.PageOption{movies.First(first), movies.Offset(offset)}
+		var items []T
+		if term := firstValue(q, "search"); term != "" {
+			if !searchable {
+				return nil, badRequest("%s cannot be searched", typeName)
+			}
+			items, err = any(c).(searcher[T]).Find(ctx, term, opts...)
+		} else {
+			items, err = c.List(ctx, opts...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return page(items, first, offset), nil
+	}
+	rs.get = func(ctx context.Context, uid, fields string) (any, error) {
+		c, err := selection(fields)
+		if err != nil {
+			return nil, err
+		}
+		return get(ctx, c, uid)
+	}
+	rs.add = func(ctx context.Context, r *http.Request) (any, error) {
+		v := new(T)
+		if err := rs.decode(r, v); err != nil {
+			return nil, err
+		}
+		if uidOf(v) != "" {
+			return nil, badRequest("a new %s cannot have a uid", typeName)
+		}
+		setDgraphType(v, typeName)
+		if err := c.Add(ctx, v); err != nil {
+			return nil, err
+		}
+		return get(ctx, c, uidOf(v))
+	}
+	rs.update = func(ctx context.Context, uid string, r *http.Request) (any, error) {
+		v := new(T)
+		if err := rs.decode(r, v); err != nil {
+			return nil, err
+		}
+		if id := uidOf(v); id != "" && id != uid {
+			return nil, badRequest("the body has uid %s, not %s", id, uid)
+		}
+		if _, err := get(ctx, c, uid); err != nil {
+			return nil, err
+		}
+		reflect.ValueOf(v).Elem().FieldByName("UID").SetString(uid)
+		if err := c.Update(ctx, v); err != nil {
+			return nil, err
+		}
+		return get(ctx, c, uid)
+	}
+	rs.delete = func(ctx context.Context, uid string) error {
+		if _, err := get(ctx, c, uid); err != nil {
+			return err
+		}
+		return c.Delete(ctx, uid)
+	}
+	rs.byUID = func(ctx context.Context, uids []string) (any, error) {
+		items := []T{}
+		if len(uids) == 0 {
+			return items, nil
+		}
+		err := c.Query(ctx).Filter("uid(" + strings.Join(uids, ", ") + ")").First(len(uids)).Exec(&items)
+		return items, err
+	}
+	rs.predicate = func(edge string) (string, bool) {
+		if !slices.Contains(edges(rs.model), edge) {
+			return "", false
+		}
+		f, err := movies.ParseField[T](edge)
+		if err != nil {
+			return "", false
+		}
+		return f.Predicate(), true
+	}
+	return rs
+}
+
+// decode reads a request body into v. Unknown fields are refused, and so
+// are reverse edges, which are stored on the other node.
+func (rs *resource) decode(r *http.Request, v any) error {
+	var raw map[string]json.RawMessage
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return badRequest("invalid %s: %v", rs.typeName, err)
+	}
+	for _, f := range reflect.VisibleFields(rs.model) {
+		if _, ok := raw[jsonName(f)]; ok && strings.HasPrefix(predicateOf(f), "~") {
+			return badRequest("%s is a reverse edge; link it from the other node", jsonName(f))
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("invalid %s: %v", rs.typeName, err)
+	}
+	return nil
+}
+
+// maxBody caps request bodies.
+const maxBody = 1 << 20
+
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+	switch {
+	case err != nil:
+		return nil, badRequest("reading body: %v", err)
+	case len(body) == 0:
+		return nil, badRequest("the request has no body")
+	case len(body) > maxBody:
+		return nil, &Error{Status: http.StatusRequestEntityTooLarge, Code: "too_large", Message: fmt.Sprintf("the body is over %d bytes", maxBody)}
+	}
+	return body, nil
+}
+
+// edge lists a page of the nodes that the edge of node uid links to.
+func (rs *resource) edge(ctx context.Context, client *movies.Client, all []*resource, uid, edge string, q map[string][]string) (any, error) {
+	pred, ok := rs.predicate(edge)
+	if !ok {
+		return nil, &Error{Status: http.StatusNotFound, Code: "not_found", Message: fmt.Sprintf("%s has no edge %q", rs.typeName, edge)}
+	}
+	if !movies.IsUID(uid) {
+		return nil, badRequest("%q is not a UID", uid)
+	}
+	first, offset, err := paging(q)
+	if err != nil {
+		return nil, err
+	}
+	target := targetOf(rs.model, edge, all)
+	// Embedded Dgraph panics on a predicate it has never seen, which an
+	// edge's is until a node with it is stored.
+	schema, err := client.Schema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := schema.Predicate(strings.TrimPrefix(pred, "~")); !ok {
+		if _, err := rs.get(ctx, uid, ""); err != nil {
+			return nil, err
+		}
+		return page([]string(nil), first, offset), nil
+	}
+	query := fmt.Sprintf(`query edge($uid: string) {
+	node(func: uid($uid)) @filter(type(%s)) {
+		%s (first: %d, offset: %d) { uid }
+	}
+}`, rs.typeName, pred, first, offset)
+	resp, err := client.QueryRaw(ctx, query, map[string]string{"$uid": uid})
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Node []map[string][]struct {
+			UID string `json:"uid"`
+		} `json:"node"`
+	}
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return nil, fmt.Errorf("parsing edge: %w", err)
+	}
+	if len(data.Node) == 0 {
+		// A node with no outgoing edges is left out of the response too.
+		if _, err := rs.get(ctx, uid, ""); err != nil {
+			return nil, err
+		}
+		return page([]string(nil), first, offset), nil
+	}
+	var uids []string
+	for _, n := range data.Node[0][pred] {
+		uids = append(uids, n.UID)
+	}
+	items, err := target.byUID(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	p := &Page{Items: items}
+	if len(uids) == first {
+		p.NextCursor = cursor(offset + first)
+	}
+	return p, nil
+}
+
+func uidOf(v any) string {
+	return reflect.ValueOf(v).Elem().FieldByName("UID").String()
+}
+
+func dgraphType(v any) []string {
+	return reflect.ValueOf(v).Elem().FieldByName("DType").Interface().([]string)
+}
+
+func setDgraphType(v any, typeName string) {
+	reflect.ValueOf(v).Elem().FieldByName("DType").Set(reflect.ValueOf([]string{typeName}))
+}
+
+// jsonName returns the JSON field name of f.
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+// predicateOf returns the Dgraph predicate of f: its predicate= directive,
+// or else its JSON name.
+func predicateOf(f reflect.StructField) string {
+	for d := range strings.FieldsSeq(f.Tag.Get("dgraph")) {
+		if p, ok := strings.CutPrefix(d, "predicate="); ok {
+			return p
+		}
+	}
+	return jsonName(f)
+}
+
+// edges returns the JSON names of model's edges: its fields holding other
+// entities.
+func edges(model reflect.Type) []string {
+	var out []string
+	for _, f := range reflect.VisibleFields(model) {
+		if entityType(f.Type) != nil {
+			out = append(out, jsonName(f))
+		}
+	}
+	return out
+}
+
+// entityType returns the entity a field of type t links to, or nil when t
+// is not an edge. Entities are the structs with a UID.
+func entityType(t reflect.Type) reflect.Type {
+	if t.Kind() != reflect.Slice {
+		return nil
+	}
+	t = t.Elem()
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if _, ok := t.FieldByName("UID"); !ok {
+		return nil
+	}
+	return t
+}
+
+// targetOf returns the resource an edge of model links to.
+func targetOf(model reflect.Type, edge string, all []*resource) *resource {
+	for _, f := range reflect.VisibleFields(model) {
+		if jsonName(f) == edge {
+			t := entityType(f.Type)
+			for _, rs := range all {
+				if rs.model == t {
+					return rs
+				}
+			}
+		}
+	}
+	return nil
+}
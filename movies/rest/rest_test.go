@@ -0,0 +1,164 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mlwelles/modusGraphMoviesProject/movies"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/internal/dgraphtest"
+	"github.com/mlwelles/modusGraphMoviesProject/movies/rest"
+)
+
+// do sends a request to srv, fails t unless the response has wantStatus,
+// and decodes the body into v when it is not nil.
+func do(t *testing.T, srv *httptest.Server, method, path, body string, wantStatus int, v any) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s: expected status %d, got %d", method, path, wantStatus, resp.StatusCode)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: decoding: %v", method, path, err)
+		}
+	}
+}
+
+// TestHandlerRefuses checks the requests the handler answers before
+// reaching the database, so a zero Client serves them.
+func TestHandlerRefuses(t *testing.T) {
+	srv := httptest.NewServer(rest.Handler(&movies.Client{}))
+	defer srv.Close()
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{"GET", "/nowhere", "", http.StatusNotFound, "not_found"},
+		{"PUT", "/films", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"POST", "/openapi.json", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"GET", "/films?first=0", "", http.StatusBadRequest, "bad_request"},
+		{"GET", "/films?first=1001", "", http.StatusBadRequest, "bad_request"},
+		{"GET", "/films?cursor=bm90LWFuLW9mZnNldA", "", http.StatusBadRequest, "bad_request"},
+		{"GET", "/films?fields=nme", "", http.StatusBadRequest, "bad_request"},
+		{"GET", "/performances?search=Neo", "", http.StatusBadRequest, "bad_request"},
+		{"GET", "/films/matrix", "", http.StatusBadRequest, "bad_request"},
+		{"GET", "/films/0x1/nowhere", "", http.StatusNotFound, "not_found"},
+		{"GET", "/films/matrix/genres", "", http.StatusBadRequest, "bad_request"},
+		{"POST", "/films", "", http.StatusBadRequest, "bad_request"},
+		{"POST", "/films", `{"nme": "Typo"}`, http.StatusBadRequest, "bad_request"},
+		{"POST", "/films", `{"uid": "0x1", "name": "Heat"}`, http.StatusBadRequest, "bad_request"},
+		{"POST", "/genres", `{"name": "Noir", "films": [{"uid": "0x1"}]}`, http.StatusBadRequest, "bad_request"},
+		{"POST", "/genres", strings.Repeat(" ", 1<<20+1), http.StatusRequestEntityTooLarge, "too_large"},
+		{"PATCH", "/genres/0x1", `{"uid": "0x2"}`, http.StatusBadRequest, "bad_request"},
+	}
+	for _, tt := range tests {
+		var body struct {
+			Error rest.Error `json:"error"`
+		}
+		do(t, srv, tt.method, tt.path, tt.body, tt.status, &body)
+		if body.Error.Status != tt.status || body.Error.Code != tt.code {
+			t.Errorf("%s %s: expected %d %s, got %+v", tt.method, tt.path, tt.status, tt.code, body.Error)
+		}
+	}
+
+	var doc struct {
+		Paths      map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]any `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	do(t, srv, "GET", "/openapi.json", "", http.StatusOK, &doc)
+	for _, path := range []string{"/films", "/films/{uid}", "/films/{uid}/genres", "/genres/{uid}/films"} {
+		if doc.Paths[path] == nil {
+			t.Errorf("expected the OpenAPI document to describe %s", path)
+		}
+	}
+	if p := doc.Components.Schemas["Genre"].Properties["films"]; p["readOnly"] != true {
+		t.Errorf("expected the reverse edge Genre.films to be read-only, got %v", p)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	c := dgraphtest.Client(t)
+	ctx := context.Background()
+	noir := &movies.Genre{Name: "REST Test Noir"}
+	heist := &movies.Genre{Name: "REST Test Heist"}
+	for _, g := range []*movies.Genre{noir, heist} {
+		if err := c.Genre.Add(ctx, g); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = c.Genre.Delete(ctx, g.UID) })
+	}
+	for _, name := range []string{"Zorblax Rising", "Zorblax Returns"} {
+		f := &movies.Film{Name: name, Genres: []movies.Genre{*noir, *heist}}
+		if err := c.Film.Add(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = c.Film.Delete(ctx, f.UID) })
+	}
+	srv := httptest.NewServer(rest.Handler(c))
+	defer srv.Close()
+
+	var films struct {
+		Items      []movies.Film `json:"items"`
+		NextCursor string        `json:"nextCursor"`
+	}
+	do(t, srv, "GET", "/films?search=Zorblax&first=1", "", http.StatusOK, &films)
+	if len(films.Items) != 1 || films.NextCursor == "" {
+		t.Fatalf("expected one film and a cursor, got %+v", films)
+	}
+	first := films.Items[0]
+	do(t, srv, "GET", "/films?search=Zorblax&first=1&cursor="+films.NextCursor, "", http.StatusOK, &films)
+	if len(films.Items) != 1 || films.Items[0].UID == first.UID {
+		t.Errorf("expected the next film, got %+v", films.Items)
+	}
+
+	var film movies.Film
+	do(t, srv, "GET", "/films/"+first.UID+"?fields=name", "", http.StatusOK, &film)
+	if film.Name != first.Name || len(film.Genres) != 0 {
+		t.Errorf("expected only the name of %q, got %+v", first.Name, film)
+	}
+	var genres struct {
+		Items []movies.Genre `json:"items"`
+	}
+	do(t, srv, "GET", "/films/"+first.UID+"/genres", "", http.StatusOK, &genres)
+	if len(genres.Items) != 2 {
+		t.Errorf("expected 2 genres, got %+v", genres.Items)
+	}
+
+	var apiErr struct {
+		Error rest.Error `json:"error"`
+	}
+	do(t, srv, "GET", "/genres/"+first.UID, "", http.StatusNotFound, &apiErr)
+	if apiErr.Error.Code != "not_found" {
+		t.Errorf("expected not_found, got %+v", apiErr.Error)
+	}
+
+	var added movies.Genre
+	do(t, srv, "POST", "/genres", `{"name": "REST Genre"}`, http.StatusCreated, &added)
+	if added.UID == "" || added.Name != "REST Genre" {
+		t.Fatalf("unexpected added genre %+v", added)
+	}
+	do(t, srv, "PATCH", "/genres/"+added.UID, `{"name": "REST Genre Renamed"}`, http.StatusOK, &added)
+	if added.Name != "REST Genre Renamed" {
+		t.Errorf("expected the new name, got %q", added.Name)
+	}
+	do(t, srv, "DELETE", "/genres/"+added.UID, "", http.StatusNoContent, nil)
+	do(t, srv, "GET", "/genres/"+added.UID, "", http.StatusNotFound, nil)
+}
@@ -0,0 +1,307 @@
+package movies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	dg "github.com/dolan-in/dgman/v2"
+)
+
+// entities holds a zero value of every entity type, from which the schema is
+// derived.
+var entities = []any{
+	&Actor{}, &ContentRating{}, &Country{}, &Director{}, &Film{},
+	&Genre{}, &Location{}, &Performance{}, &Rating{},
+}
+
+// Schema is a Dgraph schema: predicate definitions and the types that group
+// them. Its JSON form matches Dgraph's schema query response.
+type Schema struct {
+	Predicates []PredicateSchema `json:"schema"`
+	Types      []TypeSchema      `json:"types"`
+}
+
+// PredicateSchema defines one predicate.
+type PredicateSchema struct {
+	Predicate string   `json:"predicate"`
+	Type      string   `json:"type"`
+	Index     bool     `json:"index,omitempty"`
+	Tokenizer []string `json:"tokenizer,omitempty"`
+	Reverse   bool     `json:"reverse,omitempty"`
+	Count     bool     `json:"count,omitempty"`
+	List      bool     `json:"list,omitempty"`
+	Upsert    bool     `json:"upsert,omitempty"`
+	Unique    bool     `json:"unique,omitempty"`
+	Lang      bool     `json:"lang,omitempty"`
+}
+
+// TypeSchema is a type and the predicates its nodes hold.
+type TypeSchema struct {
+	Name   string      `json:"name"`
+	Fields []TypeField `json:"fields"`
+}
+
+// TypeField names a predicate of a type.
+type TypeField struct {
+	Name string `json:"name"`
+}
+
+// EntitySchema returns the schema derived from the entity structs' dgraph
+// tags, which is what auto-schema applies.
+func EntitySchema() Schema {
+	ts := dg.NewTypeSchema()
+	ts.Marshal("", entities...)
+	var s Schema
+	for _, p := range ts.Schema {
+		s.Predicates = append(s.Predicates, PredicateSchema{
+			Predicate: p.Predicate,
+			Type:      p.Type,
+			Index:     p.Index,
+			Tokenizer: p.Tokenizer,
+			Reverse:   p.Reverse,
+			Count:     p.Count,
+			List:      p.List,
+			Upsert:    p.Upsert || p.Unique,
+			Unique:    p.Unique,
+			Lang:      p.Lang,
+		})
+	}
+	for name, fields := range ts.Types {
+		t := TypeSchema{Name: name}
+		for f := range fields {
+			t.Fields = append(t.Fields, TypeField{Name: f})
+		}
+		s.Types = append(s.Types, t)
+	}
+	s.sort()
+	return s
+}
+
+// Schema returns the live schema, leaving out Dgraph's internal dgraph.*
+// predicates and types. Predicates that belong to no type are included, so
+// that stray ones show up, except on the embedded engine, which does not
+// list them.
+func (c *Client) Schema(ctx context.Context) (Schema, error) {
+	// Predicates of the listed types are also requested by name: embedded
+	// Dgraph lists only types for a bare schema query, and panics on one
+	// naming a predicate it has never seen.
+	var s Schema
+	if err := c.schemaQuery(ctx, "schema {}", &s); err != nil {
+		return Schema{}, err
+	}
+	s.Types = slices.DeleteFunc(s.Types, func(t TypeSchema) bool {
+		return strings.HasPrefix(t.Name, "dgraph.")
+	})
+	s.Predicates = slices.DeleteFunc(s.Predicates, func(p PredicateSchema) bool {
+		return strings.HasPrefix(p.Predicate, "dgraph.")
+	})
+	var names []string
+	for _, t := range s.Types {
+		for _, f := range t.Fields {
+			if _, ok := s.Predicate(f.Name); !ok && !slices.Contains(names, f.Name) {
+				names = append(names, f.Name)
+			}
+		}
+	}
+	if len(names) > 0 {
+		var preds Schema
+		if err := c.schemaQuery(ctx, "schema(pred: ["+strings.Join(names, ", ")+"]) {}", &preds); err != nil {
+			return Schema{}, err
+		}
+		s.Predicates = append(s.Predicates, preds.Predicates...)
+	}
+	s.sort()
+	return s, nil
+}
+
+func (c *Client) schemaQuery(ctx context.Context, query string, s *Schema) error {
+	resp, err := c.conn.QueryRaw(ctx, query, nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(resp, s); err != nil {
+		return fmt.Errorf("parsing schema: %w", err)
+	}
+	return nil
+}
+
+// PendingSchema returns the part of EntitySchema that live lacks or defines
+// differently, which is what ApplySchema sends. A live predicate with more
+// index tokenizers or directives than the structs ask for, such as @lang,
+// is compatible, as is a live type with more fields. When a predicate or
+// type does need altering, what it has beyond the structs is kept in the
+// definition sent, so applying never takes anything away.
+func PendingSchema(live Schema) Schema {
+	var pending Schema
+	want := EntitySchema()
+	for _, p := range want.Predicates {
+		have, ok := live.Predicate(p.Predicate)
+		if !ok {
+			pending.Predicates = append(pending.Predicates, p)
+			continue
+		}
+		if merged := p.merge(have); merged.String() != have.String() {
+			pending.Predicates = append(pending.Predicates, merged)
+		}
+	}
+	for _, t := range want.Types {
+		have, ok := live.Type(t.Name)
+		if !ok {
+			pending.Types = append(pending.Types, t)
+			continue
+		}
+		if merged := t.merge(have); merged.String() != have.String() {
+			pending.Types = append(pending.Types, merged)
+		}
+	}
+	pending.sort()
+	return pending
+}
+
+// merge returns p with the index tokenizers and directives of have added.
+// The type is p's.
+func (p PredicateSchema) merge(have PredicateSchema) PredicateSchema {
+	if have.Index {
+		p.Tokenizer = slices.Clone(p.Tokenizer)
+		for _, tok := range have.Tokenizer {
+			if !slices.Contains(p.Tokenizer, tok) {
+				p.Tokenizer = append(p.Tokenizer, tok)
+			}
+		}
+		p.Index = len(p.Tokenizer) > 0
+	}
+	p.Reverse = p.Reverse || have.Reverse
+	p.Count = p.Count || have.Count
+	p.Upsert = p.Upsert || have.Upsert
+	p.Unique = p.Unique || have.Unique
+	p.Lang = p.Lang || have.Lang
+	return p
+}
+
+// merge returns t with the fields of have added.
+func (t TypeSchema) merge(have TypeSchema) TypeSchema {
+	t.Fields = slices.Clone(t.Fields)
+	for _, f := range have.Fields {
+		if !slices.Contains(t.Fields, f) {
+			t.Fields = append(t.Fields, f)
+		}
+	}
+	return t
+}
+
+// ApplySchema alters the live schema to match EntitySchema and returns what
+// was sent. Only predicates and types that are missing or differ are
+// altered; predicates and types the structs do not use are left alone.
+//
+// The embedded engine can add predicates but not redefine existing ones;
+// there, changing a predicate returns an error and needs a Dgraph server.
+func (c *Client) ApplySchema(ctx context.Context) (applied Schema, err error) {
+	live, err := c.Schema(ctx)
+	if err != nil {
+		return Schema{}, err
+	}
+	pending := PendingSchema(live)
+	if len(pending.Predicates) == 0 && len(pending.Types) == 0 {
+		return pending, nil
+	}
+	client, cleanup, err := c.conn.DgraphClient()
+	if err != nil {
+		return Schema{}, err
+	}
+	defer cleanup()
+	defer func() {
+		// Embedded Dgraph panics when an alter needs an index rebuild.
+		if r := recover(); r != nil {
+			applied, err = Schema{}, fmt.Errorf("altering existing predicates is not supported by the embedded engine: %v", r)
+		}
+	}()
+	ctx, cancel := bound(c.conn, ctx)
+	defer cancel()
+	if err := client.Alter(ctx, &api.Operation{Schema: pending.String()}); err != nil {
+		return Schema{}, err
+	}
+	return pending, nil
+}
+
+// Predicate returns the definition of the named predicate.
+func (s Schema) Predicate(name string) (PredicateSchema, bool) {
+	i := slices.IndexFunc(s.Predicates, func(p PredicateSchema) bool { return p.Predicate == name })
+	if i < 0 {
+		return PredicateSchema{}, false
+	}
+	return s.Predicates[i], true
+}
+
+// Type returns the named type.
+func (s Schema) Type(name string) (TypeSchema, bool) {
+	i := slices.IndexFunc(s.Types, func(t TypeSchema) bool { return t.Name == name })
+	if i < 0 {
+		return TypeSchema{}, false
+	}
+	return s.Types[i], true
+}
+
+// String formats the schema as DQL, as accepted by an alter operation.
+func (s Schema) String() string {
+	var sb strings.Builder
+	for _, p := range s.Predicates {
+		sb.WriteString(p.String() + "\n")
+	}
+	for _, t := range s.Types {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(t.String())
+	}
+	return sb.String()
+}
+
+// String formats the predicate as a DQL schema line, e.g.
+// "name: string @index(hash, term) .".
+func (p PredicateSchema) String() string {
+	typ := p.Type
+	if p.List {
+		typ = "[" + typ + "]"
+	}
+	parts := []string{p.Predicate + ":", typ}
+	if p.Index {
+		parts = append(parts, "@index("+strings.Join(p.Tokenizer, ", ")+")")
+	}
+	for _, d := range []struct {
+		on   bool
+		name string
+	}{{p.Reverse, "@reverse"}, {p.Count, "@count"}, {p.Upsert, "@upsert"}, {p.Unique, "@unique"}, {p.Lang, "@lang"}} {
+		if d.on {
+			parts = append(parts, d.name)
+		}
+	}
+	return strings.Join(parts, " ") + " ."
+}
+
+// String formats the type as a DQL type definition.
+func (t TypeSchema) String() string {
+	var sb strings.Builder
+	sb.WriteString("type " + t.Name + " {\n")
+	for _, f := range t.Fields {
+		sb.WriteString("  " + f.Name + "\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// sort orders predicates, types, fields and tokenizers by name so that
+// schemas compare and print consistently.
+func (s *Schema) sort() {
+	slices.SortFunc(s.Predicates, func(a, b PredicateSchema) int { return strings.Compare(a.Predicate, b.Predicate) })
+	for i := range s.Predicates {
+		slices.Sort(s.Predicates[i].Tokenizer)
+	}
+	slices.SortFunc(s.Types, func(a, b TypeSchema) int { return strings.Compare(a.Name, b.Name) })
+	for _, t := range s.Types {
+		slices.SortFunc(t.Fields, func(a, b TypeField) int { return strings.Compare(a.Name, b.Name) })
+	}
+}
@@ -0,0 +1,126 @@
+package movies
+
+import (
+	"context"
+	"iter"
+
+	"github.com/matthewmcneely/modusgraph"
+)
+
+// findNodes runs the fulltext search that the generated Search does, with
+// term sent as a query variable instead of spliced into the filter.
+func findNodes[T any](ctx context.Context, conn modusgraph.Client, term string, opts []PageOption) ([]T, error) {
+	var model T
+	cfg := pageConfig{first: defaultPageSize}
+	for _, opt := range opts {
+		opt.applyPage(&cfg)
+	}
+	q := conn.Query(ctx, model).
+		Vars("find($term: string)", map[string]string{"$term": term}).
+		Filter("alloftext(name, $term)")
+	if cfg.first > 0 {
+		q = q.First(cfg.first)
+	}
+	if cfg.offset > 0 {
+		q = q.Offset(cfg.offset)
+	}
+	var results []T
+	if err := q.Nodes(&results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// findIter pages through findNodes.
+func findIter[T any](ctx context.Context, conn modusgraph.Client, term string) iter.Seq2[T, error] {
+	return queryIter(defaultPageSize, 0, func(first, offset int, dst *[]T) error {
+		results, err := findNodes[T](ctx, conn, term, []PageOption{First(first), Offset(offset)})
+		*dst = results
+		return err
+	})
+}
+
+// Find finds Actor entities whose Name matches term using fulltext search,
+// as Search does. Search places term in the query as it is, so a quote in
+// it ends the string and the rest is read as DQL; Find sends term as a
+// query variable, so it may be any string. Use Find for terms from users
+// and requests.
+func (c *ActorClient) Find(ctx context.Context, term string, opts ...PageOption) ([]Actor, error) {
+	return findNodes[Actor](ctx, c.conn, term, opts)
+}
+
+// FindIter returns an iterator over the Actor entities that Find matches,
+// paging through them as SearchIter does.
+func (c *ActorClient) FindIter(ctx context.Context, term string) iter.Seq2[Actor, error] {
+	return findIter[Actor](ctx, c.conn, term)
+}
+
+// Find finds ContentRating entities whose Name matches term; see ActorClient.Find.
+func (c *ContentRatingClient) Find(ctx context.Context, term string, opts ...PageOption) ([]ContentRating, error) {
+	return findNodes[ContentRating](ctx, c.conn, term, opts)
+}
+
+// FindIter iterates over the ContentRating entities Find matches.
+func (c *ContentRatingClient) FindIter(ctx context.Context, term string) iter.Seq2[ContentRating, error] {
+	return findIter[ContentRating](ctx, c.conn, term)
+}
+
+// Find finds Country entities whose Name matches term; see ActorClient.Find.
+func (c *CountryClient) Find(ctx context.Context, term string, opts ...PageOption) ([]Country, error) {
+	return findNodes[Country](ctx, c.conn, term, opts)
+}
+
+// FindIter iterates over the Country entities Find matches.
+func (c *CountryClient) FindIter(ctx context.Context, term string) iter.Seq2[Country, error] {
+	return findIter[Country](ctx, c.conn, term)
+}
+
+// Find finds Director entities whose Name matches term; see ActorClient.Find.
+func (c *DirectorClient) Find(ctx context.Context, term string, opts ...PageOption) ([]Director, error) {
+	return findNodes[Director](ctx, c.conn, term, opts)
+}
+
+// FindIter iterates over the Director entities Find matches.
+func (c *DirectorClient) FindIter(ctx context.Context, term string) iter.Seq2[Director, error] {
+	return findIter[Director](ctx, c.conn, term)
+}
+
+// Find finds Film entities whose Name matches term; see ActorClient.Find.
+func (c *FilmClient) Find(ctx context.Context, term string, opts ...PageOption) ([]Film, error) {
+	return findNodes[Film](ctx, c.conn, term, opts)
+}
+
+// FindIter iterates over the Film entities Find matches.
+func (c *FilmClient) FindIter(ctx context.Context, term string) iter.Seq2[Film, error] {
+	return findIter[Film](ctx, c.conn, term)
+}
+
+// Find finds Genre entities whose Name matches term; see ActorClient.Find.
+func (c *GenreClient) Find(ctx context.Context, term string, opts ...PageOption) ([]Genre, error) {
+	return findNodes[Genre](ctx, c.conn, term, opts)
+}
+
+// FindIter iterates over the Genre entities Find matches.
+func (c *GenreClient) FindIter(ctx context.Context, term string) iter.Seq2[Genre, error] {
+	return findIter[Genre](ctx, c.conn, term)
+}
+
+// Find finds Location entities whose Name matches term; see ActorClient.Find.
+func (c *LocationClient) Find(ctx context.Context, term string, opts ...PageOption) ([]Location, error) {
+	return findNodes[Location](ctx, c.conn, term, opts)
+}
+
+// FindIter iterates over the Location entities Find matches.
+func (c *LocationClient) FindIter(ctx context.Context, term string) iter.Seq2[Location, error] {
+	return findIter[Location](ctx, c.conn, term)
+}
+
+// Find finds Rating entities whose Name matches term; see ActorClient.Find.
+func (c *RatingClient) Find(ctx context.Context, term string, opts ...PageOption) ([]Rating, error) {
+	return findNodes[Rating](ctx, c.conn, term, opts)
+}
+
+// FindIter iterates over the Rating entities Find matches.
+func (c *RatingClient) FindIter(ctx context.Context, term string) iter.Seq2[Rating, error] {
+	return findIter[Rating](ctx, c.conn, term)
+}
@@ -0,0 +1,93 @@
+package movies
+
+import (
+	"context"
+	"strings"
+
+	dg "github.com/dolan-in/dgman/v2"
+	"github.com/matthewmcneely/modusgraph"
+)
+
+// selectConn is a modusgraph.Client whose reads fetch only some
+// predicates: the UID, dgraph.type and the select clauses of the fields
+// chosen, edges one level deep. Writes pass through unchanged.
+type selectConn struct {
+	modusgraph.Client
+	block string
+}
+
+func newSelectConn[F interface{ selectClause() string }](conn modusgraph.Client, fields []F) selectConn {
+	clauses := make([]string, len(fields))
+	for i, f := range fields {
+		clauses[i] = f.selectClause()
+	}
+	return selectConn{
+		Client: conn,
+		block:  "{\n\t\tuid\n\t\tdgraph.type\n\t\t" + strings.Join(clauses, "\n\t\t") + "\n\t}",
+	}
+}
+
+func (s selectConn) Query(ctx context.Context, model any) *dg.Query {
+	return s.Client.Query(ctx, model).Query(s.block)
+}
+
+func (s selectConn) Get(ctx context.Context, obj any, uid string) error {
+	return s.Query(ctx, obj).UID(uid).Node(obj)
+}
+
+// Select returns a view of the client whose Get, List, Search and Query
+// fetch only the given predicates, as well as the UID and dgraph.type.
+// Edges are fetched one level deep:
+//
+//	films, err := client.Actor.Select(movies.ActorFieldName()).List(ctx)
+func (c *ActorClient) Select(fields ...Field[Actor]) *ActorClient {
+	return &ActorClient{conn: newSelectConn(c.conn, fields)}
+}
+
+// Select returns a view of the client that fetches only the given
+// predicates; see ActorClient.Select.
+func (c *ContentRatingClient) Select(fields ...Field[ContentRating]) *ContentRatingClient {
+	return &ContentRatingClient{conn: newSelectConn(c.conn, fields)}
+}
+
+// Select returns a view of the client that fetches only the given
+// predicates; see ActorClient.Select.
+func (c *CountryClient) Select(fields ...Field[Country]) *CountryClient {
+	return &CountryClient{conn: newSelectConn(c.conn, fields)}
+}
+
+// Select returns a view of the client that fetches only the given
+// predicates; see ActorClient.Select.
+func (c *DirectorClient) Select(fields ...Field[Director]) *DirectorClient {
+	return &DirectorClient{conn: newSelectConn(c.conn, fields)}
+}
+
+// Select returns a view of the client that fetches only the given
+// predicates; see ActorClient.Select.
+func (c *FilmClient) Select(fields ...Field[Film]) *FilmClient {
+	return &FilmClient{conn: newSelectConn(c.conn, fields)}
+}
+
+// Select returns a view of the client that fetches only the given
+// predicates; see ActorClient.Select.
+func (c *GenreClient) Select(fields ...Field[Genre]) *GenreClient {
+	return &GenreClient{conn: newSelectConn(c.conn, fields)}
+}
+
+// Select returns a view of the client that fetches only the given
+// predicates; see ActorClient.Select.
+func (c *LocationClient) Select(fields ...Field[Location]) *LocationClient {
+	return &LocationClient{conn: newSelectConn(c.conn, fields)}
+}
+
+// Select returns a view of the client that fetches only the given
+// predicates; see ActorClient.Select.
+func (c *PerformanceClient) Select(fields ...Field[Performance]) *PerformanceClient {
+	return &PerformanceClient{conn: newSelectConn(c.conn, fields)}
+}
+
+// Select returns a view of the client that fetches only the given
+// predicates; see ActorClient.Select.
+func (c *RatingClient) Select(fields ...Field[Rating]) *RatingClient {
+	return &RatingClient{conn: newSelectConn(c.conn, fields)}
+}
@@ -0,0 +1,231 @@
+package movies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Stats summarises what is loaded: nodes per type, nodes and edges per
+// predicate, how many edges nodes have, and nodes lacking predicates they
+// should have.
+type Stats struct {
+	Types      []TypeStats      `json:"types"`
+	Predicates []PredicateStats `json:"predicates"`
+	FanOut     []FanOutStats    `json:"fanOut"`
+	Missing    []MissingStats   `json:"missing"`
+}
+
+// TypeStats counts the nodes of a dgraph.type.
+type TypeStats struct {
+	Type  string `json:"type"`
+	Nodes int    `json:"nodes"`
+}
+
+// PredicateStats counts the nodes that have a predicate and, for uid
+// predicates, the edges it holds.
+type PredicateStats struct {
+	Predicate string `json:"predicate"`
+	Type      string `json:"type"`
+	Nodes     int    `json:"nodes"`
+	Edges     int    `json:"edges,omitempty"`
+}
+
+// FanOutStats summarises the number of edges per node of Type along Edge,
+// the entity field, e.g. Film.starring for cast size. Nodes without any
+// such edge count as zero.
+type FanOutStats struct {
+	Type      string  `json:"type"`
+	Edge      string  `json:"edge"`
+	Predicate string  `json:"predicate"`
+	Nodes     int     `json:"nodes"`
+	Min       int     `json:"min"`
+	Median    float64 `json:"median"`
+	Max       int     `json:"max"`
+	Mean      float64 `json:"mean"`
+}
+
+// MissingStats counts the nodes of Type without Predicate.
+type MissingStats struct {
+	Type      string `json:"type"`
+	Predicate string `json:"predicate"`
+	Nodes     int    `json:"nodes"`
+}
+
+// fanOuts are the edges Stats summarises, keyed by the entity field.
+var fanOuts = []struct {
+	typ, edge string
+	predicate string
+}{
+	{"Film", "starring", FilmFieldStarring().Predicate()},
+	{"Film", "genres", FilmFieldGenres().Predicate()},
+	{"Film", "countries", FilmFieldCountries().Predicate()},
+	{"Film", "ratings", FilmFieldRatings().Predicate()},
+	{"Film", "contentRatings", FilmFieldContentRatings().Predicate()},
+	{"Director", "films", DirectorFieldFilms().Predicate()},
+	{"Actor", "films", ActorFieldFilms().Predicate()},
+	{"Genre", "films", GenreFieldFilms().Predicate()},
+	{"Country", "films", CountryFieldFilms().Predicate()},
+	{"Rating", "films", RatingFieldFilms().Predicate()},
+	{"ContentRating", "films", ContentRatingFieldFilms().Predicate()},
+}
+
+// required lists the predicates every node of a type should have. A
+// performance belongs to a film and an actor through their edges to it.
+var required = map[string][]string{
+	"Actor":         {"name"},
+	"ContentRating": {"name"},
+	"Country":       {"name"},
+	"Director":      {"name"},
+	"Film":          {"name", "initial_release_date"},
+	"Genre":         {"name"},
+	"Location":      {"name"},
+	"Performance":   {"~" + FilmFieldStarring().Predicate(), "~" + ActorFieldFilms().Predicate()},
+	"Rating":        {"name"},
+}
+
+// Stats counts nodes, predicates, edges per node and missing predicates.
+// Only types and predicates in the live schema are queried, since embedded
+// Dgraph panics on ones it has never seen.
+func (c *Client) Stats(ctx context.Context) (*Stats, error) {
+	schema, err := c.Schema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hasType := func(t string) bool { _, ok := schema.Type(t); return ok }
+	hasPred := func(p string) bool {
+		name, reverse := strings.CutPrefix(p, "~")
+		ps, ok := schema.Predicate(name)
+		return ok && (!reverse || ps.Reverse)
+	}
+
+	// The first query counts everything and aggregates fan-out.
+	var q strings.Builder
+	q.WriteString("{\n")
+	for i, t := range schema.Types {
+		fmt.Fprintf(&q, "  t%d(func: type(%s)) { n: count(uid) }\n", i, t.Name)
+	}
+	for i, p := range schema.Predicates {
+		fmt.Fprintf(&q, "  p%d(func: has(%s)) { n: count(uid) }\n", i, p.Predicate)
+		if p.Type == "uid" {
+			fmt.Fprintf(&q, "  var(func: has(%s)) { e%d as count(%s) }\n", p.Predicate, i, p.Predicate)
+			fmt.Fprintf(&q, "  e%d() { n: sum(val(e%d)) }\n", i, i)
+		}
+	}
+	var fan []FanOutStats
+	for _, f := range fanOuts {
+		if !hasType(f.typ) || !hasPred(f.predicate) {
+			continue
+		}
+		i := len(fan)
+		fan = append(fan, FanOutStats{Type: f.typ, Edge: f.edge, Predicate: f.predicate})
+		fmt.Fprintf(&q, "  var(func: type(%s)) { f%d as count(%s) }\n", f.typ, i, f.predicate)
+		fmt.Fprintf(&q, "  f%d() { min: min(val(f%d)) max: max(val(f%d)) mean: avg(val(f%d)) }\n", i, i, i, i)
+	}
+	var missing []MissingStats
+	for _, t := range schema.Types {
+		for _, p := range required[t.Name] {
+			i := len(missing)
+			missing = append(missing, MissingStats{Type: t.Name, Predicate: p})
+			if hasPred(p) {
+				fmt.Fprintf(&q, "  m%d(func: type(%s)) @filter(NOT has(%s)) { n: count(uid) }\n", i, t.Name, p)
+			} else {
+				fmt.Fprintf(&q, "  m%d(func: type(%s)) { n: count(uid) }\n", i, t.Name)
+			}
+		}
+	}
+	q.WriteString("}")
+	blocks, err := c.statsQuery(ctx, q.String())
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stats{}
+	types := map[string]int{}
+	for i, t := range schema.Types {
+		n := blocks.int(fmt.Sprintf("t%d", i), "n")
+		types[t.Name] = n
+		s.Types = append(s.Types, TypeStats{Type: t.Name, Nodes: n})
+	}
+	for i, p := range schema.Predicates {
+		ps := PredicateStats{Predicate: p.Predicate, Type: p.Type, Nodes: blocks.int(fmt.Sprintf("p%d", i), "n")}
+		if p.Type == "uid" {
+			ps.Edges = blocks.int(fmt.Sprintf("e%d", i), "n")
+		}
+		s.Predicates = append(s.Predicates, ps)
+	}
+	for i := range fan {
+		f := &fan[i]
+		name := fmt.Sprintf("f%d", i)
+		f.Nodes = types[f.Type]
+		f.Min, f.Max, f.Mean = blocks.int(name, "min"), blocks.int(name, "max"), blocks.float(name, "mean")
+	}
+	for i := range missing {
+		missing[i].Nodes = blocks.int(fmt.Sprintf("m%d", i), "n")
+	}
+	s.Missing = slices.DeleteFunc(missing, func(m MissingStats) bool { return m.Nodes == 0 })
+
+	// The median needs the node count, so it takes a second query that
+	// reads the middle value, or the two middle values, in order.
+	q.Reset()
+	q.WriteString("{\n")
+	for i, f := range fan {
+		if f.Nodes == 0 {
+			continue
+		}
+		fmt.Fprintf(&q, "  var(func: type(%s)) { f%d as count(%s) }\n", f.Type, i, f.Predicate)
+		fmt.Fprintf(&q, "  f%d(func: uid(f%d), orderasc: val(f%d), offset: %d, first: %d) { n: val(f%d) }\n",
+			i, i, i, (f.Nodes-1)/2, 2-f.Nodes%2, i)
+	}
+	q.WriteString("}")
+	if len(fan) > 0 {
+		medians, err := c.statsQuery(ctx, q.String())
+		if err != nil {
+			return nil, err
+		}
+		for i := range fan {
+			var sum float64
+			vals := medians[fmt.Sprintf("f%d", i)]
+			for _, v := range vals {
+				sum += v["n"]
+			}
+			if len(vals) > 0 {
+				fan[i].Median = sum / float64(len(vals))
+			}
+		}
+	}
+	s.FanOut = fan
+	return s, nil
+}
+
+// statsBlocks holds the numeric results of a stats query by block name.
+type statsBlocks map[string][]map[string]float64
+
+func (c *Client) statsQuery(ctx context.Context, query string) (statsBlocks, error) {
+	resp, err := c.conn.QueryRaw(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	var blocks statsBlocks
+	if err := json.Unmarshal(resp, &blocks); err != nil {
+		return nil, fmt.Errorf("parsing stats: %w", err)
+	}
+	return blocks, nil
+}
+
+// float returns the named value from a block. Aggregates come back as one
+// object per aggregate, so every object is searched.
+func (b statsBlocks) float(block, name string) float64 {
+	for _, v := range b[block] {
+		if n, ok := v[name]; ok {
+			return n
+		}
+	}
+	return 0
+}
+
+func (b statsBlocks) int(block, name string) int {
+	return int(b.float(block, name))
+}
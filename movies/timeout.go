@@ -0,0 +1,103 @@
+package movies
+
+import (
+	"context"
+	"time"
+
+	"github.com/matthewmcneely/modusgraph"
+)
+
+// WithTimeout bounds every request the client sends whose context has no
+// deadline of its own to d. A context with a deadline, even a longer one,
+// is used as it is.
+func WithTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *clientConfig) { cfg.timeout = d })
+}
+
+// timeoutConn is the connection of a client made with WithTimeout. Query
+// builders run after Query returns, so their requests are bounded as they
+// are sent, by the tracing connection's recordingClient.
+type timeoutConn struct {
+	modusgraph.Client
+	timeout time.Duration
+}
+
+// bound returns ctx with the connection's timeout, unless ctx has a
+// deadline already or conn has no timeout. Requests sent on a pooled
+// Dgraph client rather than through conn are bounded with it.
+func bound(conn modusgraph.Client, ctx context.Context) (context.Context, context.CancelFunc) {
+	if tc, ok := conn.(*tracingConn); ok {
+		conn = tc.Client
+	}
+	t, ok := conn.(*timeoutConn)
+	if _, set := ctx.Deadline(); !ok || set {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, t.timeout)
+}
+
+func (t *timeoutConn) Insert(ctx context.Context, obj any) error {
+	ctx, cancel := bound(t, ctx)
+	defer cancel()
+	return t.Client.Insert(ctx, obj)
+}
+
+func (t *timeoutConn) InsertRaw(ctx context.Context, obj any) error {
+	ctx, cancel := bound(t, ctx)
+	defer cancel()
+	return t.Client.InsertRaw(ctx, obj)
+}
+
+func (t *timeoutConn) Upsert(ctx context.Context, obj any, predicates ...string) error {
+	ctx, cancel := bound(t, ctx)
+	defer cancel()
+	return t.Client.Upsert(ctx, obj, predicates...)
+}
+
+func (t *timeoutConn) Update(ctx context.Context, obj any) error {
+	ctx, cancel := bound(t, ctx)
+	defer cancel()
+	return t.Client.Update(ctx, obj)
+}
+
+func (t *timeoutConn) Get(ctx context.Context, obj any, uid string) error {
+	ctx, cancel := bound(t, ctx)
+	defer cancel()
+	return t.Client.Get(ctx, obj, uid)
+}
+
+func (t *timeoutConn) Delete(ctx context.Context, uids []string) error {
+	ctx, cancel := bound(t, ctx)
+	defer cancel()
+	return t.Client.Delete(ctx, uids)
+}
+
+func (t *timeoutConn) UpdateSchema(ctx context.Context, obj ...any) error {
+	ctx, cancel := bound(t, ctx)
+	defer cancel()
+	return t.Client.UpdateSchema(ctx, obj...)
+}
+
+func (t *timeoutConn) GetSchema(ctx context.Context) (string, error) {
+	ctx, cancel := bound(t, ctx)
+	defer cancel()
+	return t.Client.GetSchema(ctx)
+}
+
+func (t *timeoutConn) DropAll(ctx context.Context) error {
+	ctx, cancel := bound(t, ctx)
+	defer cancel()
+	return t.Client.DropAll(ctx)
+}
+
+func (t *timeoutConn) DropData(ctx context.Context) error {
+	ctx, cancel := bound(t, ctx)
+	defer cancel()
+	return t.Client.DropData(ctx)
+}
+
+func (t *timeoutConn) QueryRaw(ctx context.Context, query string, vars map[string]string) ([]byte, error) {
+	ctx, cancel := bound(t, ctx)
+	defer cancel()
+	return t.Client.QueryRaw(ctx, query, vars)
+}
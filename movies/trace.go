@@ -0,0 +1,392 @@
+package movies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/dgo/v250"
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	dg "github.com/dolan-in/dgman/v2"
+	"github.com/matthewmcneely/modusgraph"
+	"google.golang.org/grpc"
+)
+
+// QueryMeta describes one query sent to Dgraph: the DQL, its variables and
+// where the time went.
+type QueryMeta struct {
+	Query string            `json:"query"`
+	Vars  map[string]string `json:"vars,omitempty"`
+	// Latency is the server's breakdown; Elapsed is the round trip the
+	// client saw.
+	Latency Latency       `json:"latency"`
+	Elapsed time.Duration `json:"elapsed"`
+	Err     error         `json:"-"`
+}
+
+// Latency is Dgraph's account of the time spent serving a query.
+type Latency struct {
+	Parsing         time.Duration `json:"parsing"`
+	Processing      time.Duration `json:"processing"`
+	Encoding        time.Duration `json:"encoding"`
+	AssignTimestamp time.Duration `json:"assignTimestamp"`
+	Total           time.Duration `json:"total"`
+}
+
+// String formats the metadata as DQL comments followed by the query, so
+// the output can be pasted back into a shell.
+func (m QueryMeta) String() string {
+	var sb strings.Builder
+	us := func(d time.Duration) time.Duration { return d.Round(time.Microsecond) }
+	fmt.Fprintf(&sb, "# %s round trip; server: parsing %s, processing %s, encoding %s, total %s\n",
+		us(m.Elapsed), us(m.Latency.Parsing), us(m.Latency.Processing), us(m.Latency.Encoding), us(m.Latency.Total))
+	for _, name := range slices.Sorted(maps.Keys(m.Vars)) {
+		fmt.Fprintf(&sb, "# %s = %q\n", name, m.Vars[name])
+	}
+	if m.Err != nil {
+		fmt.Fprintf(&sb, "# error: %v\n", m.Err)
+	}
+	sb.WriteString(strings.TrimSpace(m.Query) + "\n")
+	return sb.String()
+}
+
+// Trace collects the metadata of the queries run with its context.
+type Trace struct {
+	mu      sync.Mutex
+	queries []QueryMeta
+}
+
+type traceKey struct{}
+
+// WithTrace returns a context that records every query run with it, and
+// the Trace to read them from:
+//
+//	ctx, trace := movies.WithTrace(ctx)
+//	err := client.Film.Query(ctx).Filter(`has(tagline)`).Exec(&films)
+//	for _, q := range trace.Queries() {
+//		fmt.Println(q.Latency.Processing)
+//	}
+func WithTrace(ctx context.Context) (context.Context, *Trace) {
+	t := &Trace{}
+	return context.WithValue(ctx, traceKey{}, t), t
+}
+
+// Queries returns the queries recorded so far, oldest first.
+func (t *Trace) Queries() []QueryMeta {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.queries)
+}
+
+func (t *Trace) add(m QueryMeta) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.queries = append(t.queries, m)
+}
+
+// SetDebug writes every query the client sends to w, with its variables and
+// timings, formatted as QueryMeta.String does. A nil w turns this off.
+func (c *Client) SetDebug(w io.Writer) {
+	c.ext().setDebug(w)
+}
+
+// tracingConn is a modusgraph.Client that records read queries while a
+// debug writer is set or the context carries a Trace, and serves them from
+// the cache when the client has one. Otherwise it passes every call
+// through unchanged.
+type tracingConn struct {
+	modusgraph.Client
+	cache *cache
+	// outbox makes Insert, Update and Delete record each change in the
+	// outbox; see EnableOutbox.
+	outbox atomic.Bool
+	hooks  hooks
+
+	// depth is how deep the connection's queries expand edges; see
+	// WithMaxEdgeTraversal.
+	depth int
+
+	mu    sync.Mutex
+	debug io.Writer
+}
+
+func (t *tracingConn) setDebug(w io.Writer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.debug = w
+}
+
+func (t *tracingConn) tracing(ctx context.Context) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.debug != nil || ctx.Value(traceKey{}) != nil
+}
+
+func (t *tracingConn) record(ctx context.Context, m QueryMeta) {
+	if tr, ok := ctx.Value(traceKey{}).(*Trace); ok {
+		tr.add(m)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.debug != nil {
+		fmt.Fprintln(t.debug, m)
+	}
+}
+
+// Query returns the query builder modusgraph would, bound to a transaction
+// whose responses are recorded, or served from the cache, and whose
+// requests get the client's timeout.
+func (t *tracingConn) Query(ctx context.Context, model any) *dg.Query {
+	_, timed := t.Client.(*timeoutConn)
+	if t.cache == nil && !timed && !t.tracing(ctx) {
+		return t.Client.Query(ctx, model)
+	}
+	client, cleanup, err := t.DgraphClient()
+	if err != nil {
+		return t.Client.Query(ctx, model)
+	}
+	// The pooled client is shared; modusgraph likewise returns it before
+	// the query runs.
+	cleanup()
+	rec := &recordingClient{dg: client, conn: t, typ: typeName(model)}
+	// Build the query modusgraph would, expanding edges as deep, on the
+	// recording transaction.
+	return dg.NewReadOnlyTxnContext(ctx, dgo.NewDgraphClient(rec)).Get(model).All(t.depth)
+}
+
+// Get retrieves a node as modusgraph does, through Query so that it is
+// recorded or cached.
+func (t *tracingConn) Get(ctx context.Context, obj any, uid string) error {
+	if t.cache == nil && !t.tracing(ctx) {
+		return t.Client.Get(ctx, obj, uid)
+	}
+	q := t.Query(ctx, obj)
+	if q == nil {
+		return t.Client.Get(ctx, obj, uid)
+	}
+	return q.UID(uid).Node()
+}
+
+// Insert, Update and Delete write the change's outbox records when the
+// outbox is enabled, drop the cache entries the change may make stale and
+// then publish its events. The node is read before and after an update,
+// and before a delete, when a handler or the outbox needs the snapshots.
+func (t *tracingConn) Insert(ctx context.Context, obj any) error {
+	typ := typeName(obj)
+	write := t.Client.Insert
+	if t.outbox.Load() {
+		write = t.insertOutboxed
+	}
+	if err := write(ctx, obj); err != nil {
+		return err
+	}
+	t.invalidate(typ)
+	if t.hooks.active(typ) {
+		uid := uidOf(obj)
+		t.hooks.publish(ctx, Event{Op: OpAdd, Type: typ, UID: uid, After: clone(obj)})
+	}
+	return nil
+}
+
+func (t *tracingConn) Update(ctx context.Context, obj any) error {
+	typ := typeName(obj)
+	outbox, hooked := t.outbox.Load(), t.hooks.active(typ)
+	if !outbox && !hooked {
+		if err := t.Client.Update(ctx, obj); err != nil {
+			return err
+		}
+		t.invalidate(typ)
+		return nil
+	}
+	uid := uidOf(obj)
+	before := t.read(ctx, typ, uid)
+	var err error
+	if outbox {
+		err = t.updateOutboxed(ctx, obj, before)
+	} else {
+		err = t.Client.Update(ctx, obj)
+	}
+	if err != nil {
+		return err
+	}
+	t.invalidate(typ)
+	if hooked {
+		after := t.read(ctx, typ, uid)
+		if after == nil {
+			after = clone(obj)
+		}
+		t.hooks.publish(ctx, Event{Op: OpUpdate, Type: typ, UID: uid, Before: before, After: after})
+	}
+	return nil
+}
+
+func (t *tracingConn) Delete(ctx context.Context, uids []string) error {
+	outbox := t.outbox.Load()
+	if t.cache == nil && !outbox && !t.hooks.active("") {
+		return t.Client.Delete(ctx, uids)
+	}
+	types, err := t.nodeTypes(ctx, uids)
+	if err != nil {
+		return err
+	}
+	before := make([]any, len(uids))
+	for i, uid := range uids {
+		if typ := types[uid]; outbox || t.hooks.active(typ) {
+			before[i] = t.read(ctx, typ, uid)
+		}
+	}
+	if outbox {
+		err = t.deleteOutboxed(ctx, uids, types, before)
+	} else {
+		err = t.Client.Delete(ctx, uids)
+	}
+	if err != nil {
+		return err
+	}
+	t.invalidate(slices.Collect(maps.Values(types))...)
+	for i, uid := range uids {
+		if typ := types[uid]; t.hooks.active(typ) {
+			t.hooks.publish(ctx, Event{Op: OpDelete, Type: typ, UID: uid, Before: before[i]})
+		}
+	}
+	return nil
+}
+
+func (t *tracingConn) invalidate(types ...string) {
+	if t.cache == nil {
+		return
+	}
+	slices.Sort(types)
+	for _, typ := range slices.Compact(types) {
+		t.cache.invalidate(typ)
+	}
+}
+
+// nodeTypes returns the entity type of each of the nodes uids that has
+// one.
+func (t *tracingConn) nodeTypes(ctx context.Context, uids []string) (map[string]string, error) {
+	types := map[string]string{}
+	uids = slices.DeleteFunc(slices.Clone(uids), func(uid string) bool { return !IsUID(uid) })
+	if len(uids) == 0 {
+		return types, nil
+	}
+	resp, err := t.Client.QueryRaw(ctx, fmt.Sprintf("{ nodes(func: uid(%s)) { uid dgraph.type } }", strings.Join(uids, ", ")), nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Nodes []struct {
+			UID   string   `json:"uid"`
+			Types []string `json:"dgraph.type"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, err
+	}
+	for _, n := range result.Nodes {
+		for _, typ := range n.Types {
+			if _, ok := entityTypes()[typ]; ok {
+				types[n.UID] = typ
+				break
+			}
+		}
+	}
+	return types, nil
+}
+
+// uidOf returns the UID of the entity obj points to.
+func uidOf(obj any) string {
+	return reflect.ValueOf(obj).Elem().FieldByName("UID").String()
+}
+
+// typeName returns the name of the entity type v is or points to.
+func typeName(v any) string {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+func (t *tracingConn) QueryRaw(ctx context.Context, query string, vars map[string]string) ([]byte, error) {
+	if !t.tracing(ctx) {
+		return t.Client.QueryRaw(ctx, query, vars)
+	}
+	client, cleanup, err := t.DgraphClient()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	rec := &recordingClient{dg: client, conn: t}
+	resp, err := dgo.NewDgraphClient(rec).NewReadOnlyTxn().QueryWithVars(ctx, query, vars)
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetJson(), nil
+}
+
+// recordingClient is the api.DgraphClient behind traced, cached or timed
+// read-only transactions. It sends each request through a real client,
+// bounded by the client's timeout, and records the response latency,
+// unless the cache holds the response. Read-only transactions only call
+// Query.
+type recordingClient struct {
+	api.DgraphClient
+
+	dg   *dgo.Dgraph
+	conn *tracingConn
+	// typ is the entity type read, whose entries the cache keeps.
+	typ string
+}
+
+func (r *recordingClient) Query(ctx context.Context, req *api.Request, _ ...grpc.CallOption) (*api.Response, error) {
+	var key string
+	var gen uint64
+	if c := r.conn.cache; c != nil {
+		key = cacheKey(req)
+		b, g, ok := c.get(r.typ, key)
+		if ok {
+			return &api.Response{Json: b}, nil
+		}
+		gen = g
+	}
+	ctx, cancel := bound(r.conn, ctx)
+	defer cancel()
+	start := time.Now()
+	resp, err := r.dg.NewReadOnlyTxn().Do(ctx, req)
+	if r.conn.tracing(ctx) {
+		m := QueryMeta{Query: req.Query, Vars: req.Vars, Elapsed: time.Since(start), Err: err}
+		if l := resp.GetLatency(); l != nil {
+			m.Latency = Latency{
+				Parsing:         time.Duration(l.ParsingNs),
+				Processing:      time.Duration(l.ProcessingNs),
+				Encoding:        time.Duration(l.EncodingNs),
+				AssignTimestamp: time.Duration(l.AssignTimestampNs),
+				Total:           time.Duration(l.TotalNs),
+			}
+		}
+		r.conn.record(ctx, m)
+	}
+	if err == nil && r.conn.cache != nil {
+		r.conn.cache.set(r.typ, key, gen, resp.GetJson())
+	}
+	return resp, err
+}
+
+// cacheKey identifies a read by its query and variables.
+func cacheKey(req *api.Request) string {
+	var sb strings.Builder
+	sb.WriteString(req.Query)
+	for _, name := range slices.Sorted(maps.Keys(req.Vars)) {
+		fmt.Fprintf(&sb, "\n%s=%q", name, req.Vars[name])
+	}
+	return sb.String()
+}
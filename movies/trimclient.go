@@ -0,0 +1,30 @@
+//go:build ignore
+
+// trimclient removes the generated New and NewFromClient from
+// client_gen.go. client.go defines them by hand so that they take this
+// package's options, which the generator has no way to declare.
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+)
+
+const path = "client_gen.go"
+
+func main() {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	start := bytes.Index(src, []byte("// New creates a new Client"))
+	end := bytes.Index(src, []byte("// QueryRaw executes a raw DQL query"))
+	if start < 0 || end < start {
+		log.Fatalf("%s: the generated constructors are not where trimclient expects them", path)
+	}
+	out := append(src[:start:start], src[end:]...)
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
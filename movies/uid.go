@@ -0,0 +1,18 @@
+package movies
+
+import (
+	"strconv"
+	"strings"
+)
+
+// IsUID reports whether s is a Dgraph UID such as 0x1a: hex digits after
+// an 0x prefix, fitting in 64 bits. Check references with it before
+// placing them in a query.
+func IsUID(s string) bool {
+	rest, ok := strings.CutPrefix(s, "0x")
+	if !ok || rest == "" {
+		return false
+	}
+	_, err := strconv.ParseUint(rest, 16, 64)
+	return err == nil
+}